@@ -82,6 +82,7 @@ Gitea or set your environment appropriately.`, "")
 	userID, _ := strconv.ParseInt(os.Getenv(models.EnvPusherID), 10, 64)
 	prID, _ := strconv.ParseInt(os.Getenv(models.ProtectedBranchPRID), 10, 64)
 	isDeployKey, _ := strconv.ParseBool(os.Getenv(models.EnvIsDeployKey))
+	isForceMerge, _ := strconv.ParseBool(os.Getenv(models.EnvForceMerge))
 
 	hookOptions := private.HookOptions{
 		UserID:                          userID,
@@ -90,6 +91,7 @@ Gitea or set your environment appropriately.`, "")
 		GitQuarantinePath:               os.Getenv(private.GitQuarantinePath),
 		ProtectedBranchID:               prID,
 		IsDeployKey:                     isDeployKey,
+		IsForceMerge:                    isForceMerge,
 	}
 
 	scanner := bufio.NewScanner(os.Stdin)
@@ -11,6 +11,7 @@ import (
 
 	"code.gitea.io/gitea/models"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/task"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -144,3 +145,22 @@ func TestAPIListUsersNonAdmin(t *testing.T) {
 	req := NewRequestf(t, "GET", "/api/v1/admin/users?token=%s", token)
 	session.MakeRequest(t, req, http.StatusForbidden)
 }
+
+func TestAPIAdminPauseTaskQueue(t *testing.T) {
+	defer prepareTestEnv(t)()
+	defer task.ResumeQueue()
+
+	token := getTokenForLoggedInUser(t, loginUser(t, "user2"))
+	req := NewRequestf(t, "POST", "/api/v1/admin/tasks/pause?token=%s", token)
+	MakeRequest(t, req, http.StatusForbidden)
+	assert.False(t, task.IsQueuePaused())
+
+	token = getTokenForLoggedInUser(t, loginUser(t, "user1"))
+	req = NewRequestf(t, "POST", "/api/v1/admin/tasks/pause?token=%s", token)
+	MakeRequest(t, req, http.StatusNoContent)
+	assert.True(t, task.IsQueuePaused())
+
+	req = NewRequestf(t, "POST", "/api/v1/admin/tasks/resume?token=%s", token)
+	MakeRequest(t, req, http.StatusNoContent)
+	assert.False(t, task.IsQueuePaused())
+}
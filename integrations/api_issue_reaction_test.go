@@ -34,7 +34,7 @@ func TestAPIIssuesReactions(t *testing.T) {
 	req := NewRequestWithJSON(t, "POST", urlStr, &api.EditReactionOption{
 		Reaction: "wrong",
 	})
-	resp := session.MakeRequest(t, req, http.StatusForbidden)
+	resp := session.MakeRequest(t, req, http.StatusUnprocessableEntity)
 
 	//Delete not allowed reaction
 	req = NewRequestWithJSON(t, "DELETE", urlStr, &api.EditReactionOption{
@@ -50,8 +50,14 @@ func TestAPIIssuesReactions(t *testing.T) {
 	var apiNewReaction api.ReactionResponse
 	DecodeJSON(t, resp, &apiNewReaction)
 
-	//Add existing reaction
-	resp = session.MakeRequest(t, req, http.StatusForbidden)
+	//Add existing reaction, which is a no-op
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.EditReactionOption{
+		Reaction: "rocket",
+	})
+	resp = session.MakeRequest(t, req, http.StatusCreated)
+	var apiExistingReaction api.ReactionResponse
+	DecodeJSON(t, resp, &apiExistingReaction)
+	assert.Equal(t, apiNewReaction.Created.Unix(), apiExistingReaction.Created.Unix())
 
 	//Get end result of reaction list of issue #1
 	req = NewRequestf(t, "GET", urlStr)
@@ -73,6 +79,38 @@ func TestAPIIssuesReactions(t *testing.T) {
 	}
 }
 
+func TestAPIIssuesReactionsExport(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	issue := models.AssertExistsAndLoadBean(t, &models.Issue{ID: 1}).(*models.Issue)
+	_ = issue.LoadRepo()
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: issue.Repo.OwnerID}).(*models.User)
+
+	// only site admins can export the reactions
+	session := loginUser(t, owner.Name)
+	token := getTokenForLoggedInUser(t, session)
+	req := NewRequestf(t, "GET", "/api/v1/repos/%s/%s/issues/%d/reactions/export?token=%s",
+		owner.Name, issue.Repo.Name, issue.Index, token)
+	session.MakeRequest(t, req, http.StatusForbidden)
+
+	session = loginUser(t, "user1")
+	token = getTokenForLoggedInUser(t, session)
+	req = NewRequestf(t, "GET", "/api/v1/repos/%s/%s/issues/%d/reactions/export?token=%s",
+		owner.Name, issue.Repo.Name, issue.Index, token)
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	var apiDumps []*api.ReactionDump
+	DecodeJSON(t, resp, &apiDumps)
+	if assert.Len(t, apiDumps, 3) {
+		assert.Equal(t, "zzz", apiDumps[0].Reaction)
+		assert.EqualValues(t, 2, apiDumps[0].UserID)
+		assert.Equal(t, "user2", apiDumps[0].UserName)
+		assert.EqualValues(t, 1573248001, apiDumps[0].Created.Unix())
+		assert.Equal(t, "zzz", apiDumps[1].Reaction)
+		assert.Equal(t, "user1", apiDumps[1].UserName)
+		assert.Equal(t, "eyes", apiDumps[2].Reaction)
+	}
+}
+
 func TestAPICommentReactions(t *testing.T) {
 	defer prepareTestEnv(t)()
 
@@ -94,7 +132,7 @@ func TestAPICommentReactions(t *testing.T) {
 	req := NewRequestWithJSON(t, "POST", urlStr, &api.EditReactionOption{
 		Reaction: "wrong",
 	})
-	resp := session.MakeRequest(t, req, http.StatusForbidden)
+	resp := session.MakeRequest(t, req, http.StatusUnprocessableEntity)
 
 	//Delete none existing reaction
 	req = NewRequestWithJSON(t, "DELETE", urlStr, &api.EditReactionOption{
@@ -110,8 +148,14 @@ func TestAPICommentReactions(t *testing.T) {
 	var apiNewReaction api.ReactionResponse
 	DecodeJSON(t, resp, &apiNewReaction)
 
-	//Add existing reaction
-	resp = session.MakeRequest(t, req, http.StatusForbidden)
+	//Add existing reaction, which is a no-op
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.EditReactionOption{
+		Reaction: "+1",
+	})
+	resp = session.MakeRequest(t, req, http.StatusCreated)
+	var apiExistingReaction api.ReactionResponse
+	DecodeJSON(t, resp, &apiExistingReaction)
+	assert.Equal(t, apiNewReaction.Created.Unix(), apiExistingReaction.Created.Unix())
 
 	//Get end result of reaction list of issue #1
 	req = NewRequestf(t, "GET", urlStr)
@@ -137,3 +181,25 @@ func TestAPICommentReactions(t *testing.T) {
 		assert.Equal(t, expectResponse[i].User.ID, r.User.ID)
 	}
 }
+
+func TestAPIPullReviewReactionsPending(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	// pending review of user1 on a pull request of user2/repo1
+	review := models.AssertExistsAndLoadBean(t, &models.Review{ID: 4, Type: models.ReviewTypePending}).(*models.Review)
+	reviewer := models.AssertExistsAndLoadBean(t, &models.User{ID: review.ReviewerID}).(*models.User)
+
+	session := loginUser(t, "user2")
+	token := getTokenForLoggedInUser(t, session)
+	req := NewRequestf(t, "GET", "/api/v1/repos/user2/repo1/pulls/reviews/%d/reactions?token=%s", review.ID, token)
+	session.MakeRequest(t, req, http.StatusNotFound)
+	req = NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/repos/user2/repo1/pulls/reviews/%d/reactions?token=%s", review.ID, token), &api.EditReactionOption{
+		Reaction: "rocket",
+	})
+	session.MakeRequest(t, req, http.StatusNotFound)
+
+	session = loginUser(t, reviewer.Name)
+	token = getTokenForLoggedInUser(t, session)
+	req = NewRequestf(t, "GET", "/api/v1/repos/user2/repo1/pulls/reviews/%d/reactions?token=%s", review.ID, token)
+	session.MakeRequest(t, req, http.StatusOK)
+}
@@ -136,3 +136,38 @@ func TestAPIOrgDeny(t *testing.T) {
 		MakeRequest(t, req, http.StatusNotFound)
 	})
 }
+
+func TestAPIOrgMemberEmails(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	listMemberEmails := func(username string) []*api.MemberEmails {
+		token := getTokenForLoggedInUser(t, loginUser(t, username))
+		req := NewRequestf(t, "GET", "/api/v1/orgs/user3/member_emails?token=%s", token)
+		resp := MakeRequest(t, req, http.StatusOK)
+
+		var members []*api.MemberEmails
+		DecodeJSON(t, resp, &members)
+		return members
+	}
+
+	// owners also see members who keep their email address private
+	members := listMemberEmails("user2")
+	if assert.Len(t, members, 3) {
+		assert.Equal(t, "user2", members[0].Username)
+		if assert.Len(t, members[0].Emails, 1) {
+			assert.Equal(t, "user2@example.com", members[0].Emails[0].Email)
+			assert.True(t, members[0].Emails[0].Verified)
+			assert.True(t, members[0].Emails[0].Primary)
+		}
+	}
+
+	// other members only see themselves and members with a public email address
+	members = listMemberEmails("user4")
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "user4", members[0].Username)
+	}
+
+	token := getTokenForLoggedInUser(t, loginUser(t, "user5"))
+	req := NewRequestf(t, "GET", "/api/v1/orgs/user3/member_emails?token=%s", token)
+	MakeRequest(t, req, http.StatusForbidden)
+}
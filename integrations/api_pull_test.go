@@ -93,3 +93,89 @@ func TestAPICreatePullSuccess2(t *testing.T) {
 
 	session.MakeRequest(t, req, 201)
 }
+
+func TestAPIPullRequestMergedCommit(t *testing.T) {
+	defer prepareTestEnv(t)()
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: repo.OwnerID}).(*models.User)
+
+	session := loginUser(t, owner.Name)
+	token := getTokenForLoggedInUser(t, session)
+
+	// not merged
+	req := NewRequestf(t, "GET", "/api/v1/repos/%s/%s/pulls/3/merged-commit?token=%s", owner.Name, repo.Name, token)
+	session.MakeRequest(t, req, http.StatusNotFound)
+
+	// merged, but the merged commit is unknown
+	req = NewRequestf(t, "GET", "/api/v1/repos/%s/%s/pulls/2/merged-commit?token=%s", owner.Name, repo.Name, token)
+	session.MakeRequest(t, req, http.StatusNotFound)
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 1}).(*models.PullRequest)
+	pr.MergedCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	assert.NoError(t, pr.UpdateCols("merged_commit_id"))
+
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	var commit api.Commit
+	DecodeJSON(t, resp, &commit)
+	assert.Equal(t, pr.MergedCommitID, commit.SHA)
+	assert.Equal(t, "Initial commit", commit.RepoCommit.Message)
+	assert.Empty(t, commit.Parents)
+}
+
+func TestAPIPullRequestDeployments(t *testing.T) {
+	defer prepareTestEnv(t)()
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: repo.OwnerID}).(*models.User)
+
+	session := loginUser(t, owner.Name)
+	token := getTokenForLoggedInUser(t, session)
+	urlStr := fmt.Sprintf("/api/v1/repos/%s/%s/pulls/2/deployments?token=%s", owner.Name, repo.Name, token)
+
+	req := NewRequestWithJSON(t, "POST", urlStr, &api.CreatePullRequestDeploymentOption{
+		Environment: "preview",
+		URL:         "https://preview.example.com",
+		State:       "pending",
+	})
+	resp := session.MakeRequest(t, req, http.StatusCreated)
+	var deployment api.PullRequestDeployment
+	DecodeJSON(t, resp, &deployment)
+	assert.Equal(t, "preview", deployment.Environment)
+	assert.Equal(t, "pending", deployment.State)
+	assert.Equal(t, owner.Name, deployment.Creator.UserName)
+
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.CreatePullRequestDeploymentOption{Environment: "preview", State: "success"})
+	session.MakeRequest(t, req, http.StatusCreated)
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.CreatePullRequestDeploymentOption{Environment: "preview", State: "failure"})
+	session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.CreatePullRequestDeploymentOption{Environment: "preview", State: "unknown"})
+	session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.CreatePullRequestDeploymentOption{
+		Environment: "preview",
+		URL:         "javascript:alert(1)",
+		State:       "pending",
+	})
+	session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+	req = NewRequest(t, "GET", urlStr)
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	var deployments []*api.PullRequestDeployment
+	DecodeJSON(t, resp, &deployments)
+	if assert.Len(t, deployments, 2) {
+		assert.Equal(t, "success", deployments[0].State)
+	}
+
+	req = NewRequestf(t, "GET", "/api/v1/repos/%s/%s/pulls/2?deployments=true&token=%s", owner.Name, repo.Name, token)
+	resp = session.MakeRequest(t, req, http.StatusOK)
+	var pull api.PullRequest
+	DecodeJSON(t, resp, &pull)
+	if assert.Len(t, pull.Deployments, 1) {
+		assert.Equal(t, "success", pull.Deployments[0].State)
+	}
+
+	// user5 has no write access
+	session5 := loginUser(t, "user5")
+	token5 := getTokenForLoggedInUser(t, session5)
+	req = NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/repos/%s/%s/pulls/2/deployments?token=%s", owner.Name, repo.Name, token5),
+		&api.CreatePullRequestDeploymentOption{Environment: "preview", State: "pending"})
+	session5.MakeRequest(t, req, http.StatusForbidden)
+}
@@ -227,6 +227,21 @@ func TestAPIRepoEdit(t *testing.T) {
 		repoEditOption.ExternalWiki.ExternalWikiURL = "htp://www.somewebsite.com"
 		req = NewRequestWithJSON(t, "PATCH", url, &repoEditOption)
 		resp = session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+		repoEditOption.ExternalWiki.ExternalWikiURL = "http://www.somewebsite.com"
+
+		// Do some tests with unknown and disallowed default merge styles
+		hasPullRequests := true
+		allowSquash := false
+		defaultMergeStyle := "fast-forward"
+		repoEditOption.HasPullRequests = &hasPullRequests
+		repoEditOption.AllowSquash = &allowSquash
+		repoEditOption.DefaultMergeStyle = &defaultMergeStyle
+		req = NewRequestWithJSON(t, "PATCH", url, &repoEditOption)
+		resp = session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+		defaultMergeStyle = "squash"
+		req = NewRequestWithJSON(t, "PATCH", url, &repoEditOption)
+		resp = session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+		repoEditOption.DefaultMergeStyle = nil
 
 		//Test small repo change through API with issue and wiki option not set; They shall not be touched.
 		*repoEditOption.Description = "small change"
@@ -11,8 +11,10 @@ import (
 	"net/url"
 	"os"
 	"testing"
+	"time"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/repository"
 	api "code.gitea.io/gitea/modules/structs"
 
 	"github.com/stretchr/testify/assert"
@@ -392,3 +394,43 @@ func testAPIRepoCreateConflict(t *testing.T, u *url.URL) {
 		assert.Equal(t, respJSON["message"], "The repository with the same name already exists.")
 	})
 }
+
+func TestAPIRepoConvertToMirror(t *testing.T) {
+	defer prepareTestEnv(t)()
+	user := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	opts := api.MigrateRepoOption{
+		CloneAddr: models.RepoPath(user.Name, "repo1"),
+		RepoName:  "repo1-migrated",
+	}
+	migrateTask, err := models.CreateMigrateTask(user, user, opts)
+	assert.NoError(t, err)
+	assert.NoError(t, migrateTask.LoadRepo())
+	_, err = repository.MigrateRepositoryGitData(user, user, migrateTask.Repo, opts)
+	assert.NoError(t, err)
+
+	session := loginUser(t, user.Name)
+	token := getTokenForLoggedInUser(t, session)
+	urlStr := fmt.Sprintf("/api/v1/repos/%s/%s/convert-to-mirror?token=%s", user.Name, opts.RepoName, token)
+
+	// the migration has to be finished first
+	req := NewRequestWithJSON(t, "POST", urlStr, &api.ConvertToMirrorOption{})
+	session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+	assert.NoError(t, models.FinishMigrateTask(migrateTask))
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.ConvertToMirrorOption{Interval: "1s"})
+	session.MakeRequest(t, req, http.StatusUnprocessableEntity)
+
+	req = NewRequestWithJSON(t, "POST", urlStr, &api.ConvertToMirrorOption{Interval: "2h"})
+	resp := session.MakeRequest(t, req, http.StatusOK)
+	var apiRepo api.Repository
+	DecodeJSON(t, resp, &apiRepo)
+	assert.True(t, apiRepo.Mirror)
+
+	mirror, err := models.GetMirrorByRepoID(apiRepo.ID)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2*time.Hour, mirror.Interval)
+
+	// repositories which were not migrated can't be converted
+	req = NewRequestWithJSON(t, "POST", fmt.Sprintf("/api/v1/repos/%s/repo1/convert-to-mirror?token=%s", user.Name, token), &api.ConvertToMirrorOption{})
+	session.MakeRequest(t, req, http.StatusNotFound)
+}
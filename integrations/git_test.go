@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/auth"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
@@ -69,6 +70,7 @@ func testGit(t *testing.T, u *url.URL) {
 		mediaTest(t, &httpContext, little, big, littleLFS, bigLFS)
 
 		t.Run("BranchProtectMerge", doBranchProtectPRMerge(&httpContext, dstPath))
+		t.Run("BranchProtectForceMerge", doBranchProtectPRForceMerge(&httpContext, dstPath))
 		t.Run("MergeFork", func(t *testing.T) {
 			t.Run("CreatePRAndMerge", doMergeFork(httpContext, forkedUserCtx, "master", httpContext.Username+":master"))
 			t.Run("DeleteRepository", doAPIDeleteRepository(httpContext))
@@ -368,6 +370,56 @@ func doBranchProtectPRMerge(baseCtx *APITestContext, dstPath string) func(t *tes
 	}
 }
 
+func doBranchProtectPRForceMerge(baseCtx *APITestContext, dstPath string) func(t *testing.T) {
+	return func(t *testing.T) {
+		defer PrintCurrentTest(t)()
+		ctx := NewAPITestContext(t, baseCtx.Username, baseCtx.Reponame)
+
+		t.Run("CheckoutProtected", doGitCheckoutBranch(dstPath, "protected"))
+		t.Run("GenerateCommit", func(t *testing.T) {
+			_, err := generateCommitWithNewData(littleSize, dstPath, "user2@example.com", "User Two", "branch-data-file-")
+			assert.NoError(t, err)
+		})
+		t.Run("PushToUnprotectedBranch", doGitPushTestRepository(dstPath, "origin", "protected:toforcemerge"))
+		var pr api.PullRequest
+		var err error
+		t.Run("CreatePullRequest", func(t *testing.T) {
+			pr, err = doAPICreatePullRequest(ctx, baseCtx.Username, baseCtx.Reponame, "protected", "toforcemerge")(t)
+			assert.NoError(t, err)
+		})
+		t.Run("RequireApproval", func(t *testing.T) {
+			repo, err := models.GetRepositoryByOwnerAndName(baseCtx.Username, baseCtx.Reponame)
+			assert.NoError(t, err)
+			protectedBranch, err := models.GetProtectedBranchBy(repo.ID, "protected")
+			assert.NoError(t, err)
+			protectedBranch.RequiredApprovals = 1
+			assert.NoError(t, models.UpdateProtectBranch(repo, protectedBranch, models.WhitelistOptions{}))
+		})
+
+		merge := func(form *auth.MergePullRequestForm, expectedStatus int) func(t *testing.T) {
+			return func(t *testing.T) {
+				form.Do = string(models.MergeStyleMerge)
+				req := NewRequestWithJSON(t, http.MethodPost, fmt.Sprintf("/api/v1/repos/%s/%s/pulls/%d/merge?token=%s",
+					baseCtx.Username, baseCtx.Reponame, pr.Index, ctx.Token), form)
+				ctx.Session.MakeRequest(t, req, expectedStatus)
+			}
+		}
+		t.Run("FailToMerge", merge(&auth.MergePullRequestForm{}, http.StatusMethodNotAllowed))
+		t.Run("FailToForceMergeWithoutReason", merge(&auth.MergePullRequestForm{ForceMerge: true}, http.StatusMethodNotAllowed))
+		t.Run("ForceMerge", merge(&auth.MergePullRequestForm{ForceMerge: true, ForceMergeReason: "hotfix"}, http.StatusOK))
+		t.Run("CheckOverrideComment", func(t *testing.T) {
+			merged := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: pr.ID}).(*models.PullRequest)
+			assert.True(t, merged.HasMerged)
+			models.AssertExistsAndLoadBean(t, &models.Comment{
+				IssueID: merged.IssueID,
+				Type:    models.CommentTypeMergeOverride,
+				Content: "hotfix",
+			})
+		})
+		t.Run("CheckoutMasterAgain", doGitCheckoutBranch(dstPath, "master"))
+	}
+}
+
 func doProtectBranch(ctx APITestContext, branch string, userToWhitelist string) func(t *testing.T) {
 	// We are going to just use the owner to set the protection.
 	return func(t *testing.T) {
@@ -144,6 +144,13 @@ func TestPullSquash(t *testing.T) {
 
 		elem := strings.Split(test.RedirectURL(resp), "/")
 		assert.EqualValues(t, "pulls", elem[3])
+
+		req := NewRequest(t, "GET", path.Join(elem[1], elem[2], "pulls", elem[4]))
+		resp = session.MakeRequest(t, req, http.StatusOK)
+		htmlDoc := NewHTMLParser(t, resp.Body)
+		assert.EqualValues(t, i18n.Tr("en", "repo.pulls.squash_commits_count", 2),
+			strings.TrimSpace(htmlDoc.doc.Find(".squash-fields .squash-commits-count").Text()))
+
 		testPullMerge(t, session, elem[1], elem[2], elem[4], models.MergeStyleSquash)
 
 		hookTasks, err = models.HookTasks(1, 1)
@@ -152,6 +159,56 @@ func TestPullSquash(t *testing.T) {
 	})
 }
 
+func TestPullMergeUpToCommit(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		defer prepareTestEnv(t)()
+
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+		testEditFile(t, session, "user1", "repo1", "master", "README.md", "Hello, World (Edited)\n")
+		testEditFile(t, session, "user1", "repo1", "master", "README.md", "Hello, World (Edited!)\n")
+
+		headRepo, err := git.OpenRepository(models.RepoPath("user1", "repo1"))
+		assert.NoError(t, err)
+		defer headRepo.Close()
+		headCommit, err := headRepo.GetBranchCommit("master")
+		assert.NoError(t, err)
+		upToCommit, err := headCommit.ParentID(0)
+		assert.NoError(t, err)
+
+		resp := testPullCreate(t, session, "user1", "repo1", "master", "This is a pull title")
+
+		elem := strings.Split(test.RedirectURL(resp), "/")
+		assert.EqualValues(t, "pulls", elem[3])
+
+		req := NewRequest(t, "GET", path.Join(elem[1], elem[2], "pulls", elem[4]))
+		resp = session.MakeRequest(t, req, http.StatusOK)
+		htmlDoc := NewHTMLParser(t, resp.Body)
+		link, exists := htmlDoc.doc.Find(".ui.form.merge-fields > form").Attr("action")
+		assert.True(t, exists, "The template has changed")
+		req = NewRequestWithValues(t, "POST", link, map[string]string{
+			"_csrf":              htmlDoc.GetCSRF(),
+			"do":                 string(models.MergeStyleMerge),
+			"merge_up_to_commit": upToCommit.String(),
+		})
+		session.MakeRequest(t, req, http.StatusFound)
+
+		// only the first commit has been merged, so the pull request stays open
+		issue := models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: 1, Title: "This is a pull title"}).(*models.Issue)
+		pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{IssueID: issue.ID}).(*models.PullRequest)
+		assert.False(t, pr.HasMerged)
+
+		baseRepo, err := git.OpenRepository(models.RepoPath("user2", "repo1"))
+		assert.NoError(t, err)
+		defer baseRepo.Close()
+		mergeCommit, err := baseRepo.GetBranchCommit("master")
+		assert.NoError(t, err)
+		mergedID, err := mergeCommit.ParentID(1)
+		assert.NoError(t, err)
+		assert.Equal(t, upToCommit.String(), mergedID.String())
+	})
+}
+
 func TestPullCleanUpAfterMerge(t *testing.T) {
 	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
 		defer prepareTestEnv(t)()
@@ -188,6 +245,65 @@ func TestPullCleanUpAfterMerge(t *testing.T) {
 	})
 }
 
+func TestPullMergeDeleteBranchAfterMerge(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		defer prepareTestEnv(t)()
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+		testEditFileToNewBranch(t, session, "user1", "repo1", "master", "feature/test", "README.md", "Hello, World (Edited)\n")
+
+		resp := testPullCreate(t, session, "user1", "repo1", "feature/test", "This is a pull title")
+
+		elem := strings.Split(test.RedirectURL(resp), "/")
+		assert.EqualValues(t, "pulls", elem[3])
+
+		req := NewRequest(t, "GET", path.Join(elem[1], elem[2], "pulls", elem[4]))
+		resp = session.MakeRequest(t, req, http.StatusOK)
+		htmlDoc := NewHTMLParser(t, resp.Body)
+		link, exists := htmlDoc.doc.Find(".ui.form.merge-fields > form").Attr("action")
+		assert.True(t, exists, "The template has changed")
+		assert.Equal(t, 1, htmlDoc.doc.Find(".ui.form.merge-fields input[name=delete_branch_after_merge]").Length())
+		req = NewRequestWithValues(t, "POST", link, map[string]string{
+			"_csrf":                     htmlDoc.GetCSRF(),
+			"do":                        string(models.MergeStyleMerge),
+			"delete_branch_after_merge": "on",
+		})
+		session.MakeRequest(t, req, http.StatusFound)
+
+		issue := models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: 1, Title: "This is a pull title"}).(*models.Issue)
+		pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{IssueID: issue.ID}).(*models.PullRequest)
+		assert.True(t, pr.HasMerged)
+		assert.False(t, git.IsBranchExist(models.RepoPath("user1", "repo1"), "feature/test"))
+	})
+}
+
+func TestAPIPullSquashKeepAuthor(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		defer prepareTestEnv(t)()
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+
+		testEditFileToNewBranch(t, session, "user1", "repo1", "master", "feature/test", "README.md", "Hello, World (Edited)\n")
+		resp := testPullCreate(t, session, "user1", "repo1", "feature/test", "This is a pull title")
+		elem := strings.Split(test.RedirectURL(resp), "/")
+		assert.EqualValues(t, "pulls", elem[3])
+
+		// SquashKeepAuthor is absent, so the poster is kept as the author
+		token := getTokenForLoggedInUser(t, loginUser(t, "user2"))
+		req := NewRequestWithJSON(t, http.MethodPost, fmt.Sprintf("/api/v1/repos/user2/repo1/pulls/%s/merge?token=%s", elem[4], token), map[string]interface{}{
+			"Do": string(models.MergeStyleSquash),
+		})
+		MakeRequest(t, req, http.StatusOK)
+
+		gitRepo, err := git.OpenRepository(models.RepoPath("user2", "repo1"))
+		assert.NoError(t, err)
+		defer gitRepo.Close()
+		commit, err := gitRepo.GetBranchCommit("master")
+		assert.NoError(t, err)
+		assert.Equal(t, "user1@example.com", commit.Author.Email)
+	})
+}
+
 func TestCantMergeWorkInProgress(t *testing.T) {
 	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
 		defer prepareTestEnv(t)()
@@ -339,3 +455,139 @@ func TestCantMergeUnrelated(t *testing.T) {
 		assert.True(t, models.IsErrMergeUnrelatedHistories(err), "Merge error is not a unrelated histories error")
 	})
 }
+
+func TestMergeEmptyDiff(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		defer prepareTestEnv(t)()
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+
+		user1 := models.AssertExistsAndLoadBean(t, &models.User{
+			Name: "user1",
+		}).(*models.User)
+		repo1 := models.AssertExistsAndLoadBean(t, &models.Repository{
+			OwnerID: user1.ID,
+			Name:    "repo1",
+		}).(*models.Repository)
+		path := models.RepoPath(user1.Name, repo1.Name)
+
+		// Create a branch with a commit that does not change anything
+		doerSig := user1.NewGitSig()
+		commitSha := models.CreateTestCommit(t, path, models.TestCommitOptions{
+			Parents:   []string{"master"},
+			Message:   "Trigger deployment",
+			Author:    doerSig,
+			Committer: doerSig,
+		})
+		models.UpdateTestRef(t, path, git.BranchPrefix+"empty", commitSha)
+
+		token := getTokenForLoggedInUser(t, session)
+		req := NewRequestWithJSON(t, http.MethodPost, fmt.Sprintf("/api/v1/repos/%s/%s/pulls?token=%s", "user1", "repo1", token), &api.CreatePullRequestOption{
+			Head:  "empty",
+			Base:  "master",
+			Title: "create an empty pr",
+		})
+		session.MakeRequest(t, req, 201)
+
+		gitRepo, err := git.OpenRepository(path)
+		assert.NoError(t, err)
+		defer gitRepo.Close()
+		pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{
+			HeadRepoID: repo1.ID,
+			BaseRepoID: repo1.ID,
+			HeadBranch: "empty",
+			BaseBranch: "master",
+		}).(*models.PullRequest)
+
+		err = pull.Merge(pr, user1, gitRepo, models.MergeStyleSquash, "EMPTY")
+		assert.True(t, models.IsErrMergeEmptyDiff(err), "Merge error is not an empty diff error")
+
+		assert.NoError(t, pull.MergeWithOptions(pr, user1, gitRepo, &pull.MergeOptions{
+			Style:      models.MergeStyleSquash,
+			Message:    "EMPTY",
+			AllowEmpty: true,
+		}))
+		pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: pr.ID}).(*models.PullRequest)
+		assert.True(t, pr.HasMerged)
+
+		commit, err := gitRepo.GetBranchCommit("master")
+		assert.NoError(t, err)
+		assert.Equal(t, "EMPTY\n", commit.Message())
+	})
+}
+
+func TestRebasePreserveCommitterDate(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, giteaURL *url.URL) {
+		defer prepareTestEnv(t)()
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+
+		user1 := models.AssertExistsAndLoadBean(t, &models.User{
+			Name: "user1",
+		}).(*models.User)
+		repo1 := models.AssertExistsAndLoadBean(t, &models.Repository{
+			OwnerID: user1.ID,
+			Name:    "repo1",
+		}).(*models.Repository)
+		path := models.RepoPath(user1.Name, repo1.Name)
+
+		commit := func(parent, tree, message, date string) string {
+			when, err := time.Parse(time.RFC3339, date)
+			assert.NoError(t, err)
+			doerSig := user1.NewGitSig()
+			doerSig.When = when
+			return models.CreateTestCommit(t, path, models.TestCommitOptions{
+				Tree:      tree,
+				Parents:   []string{parent},
+				Message:   message,
+				Author:    doerSig,
+				Committer: doerSig,
+			})
+		}
+
+		// Create a branch from master removing all files and move master on, so that the branch has to be rebased
+		emptyTree, err := git.NewCommand("mktree").RunInDir(path)
+		assert.NoError(t, err)
+		authorDate := "2005-04-07T22:13:13Z"
+		models.UpdateTestRef(t, path, git.BranchPrefix+"dated", commit("master", strings.TrimSpace(emptyTree), "Dated commit", authorDate))
+		models.UpdateTestRef(t, path, git.BranchPrefix+"master", commit("master", "master^{tree}", "Move master", "2010-01-01T00:00:00Z"))
+
+		token := getTokenForLoggedInUser(t, session)
+		req := NewRequestWithJSON(t, http.MethodPost, fmt.Sprintf("/api/v1/repos/%s/%s/pulls?token=%s", "user1", "repo1", token), &api.CreatePullRequestOption{
+			Head:  "dated",
+			Base:  "master",
+			Title: "create a dated pr",
+		})
+		session.MakeRequest(t, req, 201)
+
+		gitRepo, err := git.OpenRepository(path)
+		assert.NoError(t, err)
+		defer gitRepo.Close()
+		pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{
+			HeadRepoID: repo1.ID,
+			BaseRepoID: repo1.ID,
+			HeadBranch: "dated",
+			BaseBranch: "master",
+		}).(*models.PullRequest)
+
+		// the rebase is committed with the identity of the server
+		for _, key := range []string{"GIT_COMMITTER_NAME", "GIT_COMMITTER_EMAIL"} {
+			if _, ok := os.LookupEnv(key); !ok {
+				assert.NoError(t, os.Setenv(key, "gitea"))
+				defer os.Unsetenv(key)
+			}
+		}
+		assert.NoError(t, pull.MergeWithOptions(pr, user1, gitRepo, &pull.MergeOptions{
+			Style:                 models.MergeStyleRebase,
+			PreserveCommitterDate: true,
+		}))
+
+		merged, err := gitRepo.GetBranchCommit("master")
+		assert.NoError(t, err)
+		assert.Equal(t, "Dated commit\n", merged.Message())
+		expected, err := time.Parse(time.RFC3339, authorDate)
+		assert.NoError(t, err)
+		assert.True(t, expected.Equal(merged.Author.When), "author date %v", merged.Author.When)
+		assert.True(t, expected.Equal(merged.Committer.When), "committer date %v", merged.Committer.When)
+	})
+}
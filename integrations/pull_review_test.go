@@ -5,7 +5,16 @@ package integrations
 
 import (
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestPullView_ReviewerMissed(t *testing.T) {
@@ -18,3 +27,38 @@ func TestPullView_ReviewerMissed(t *testing.T) {
 	req = NewRequest(t, "GET", "/user2/repo1/pulls/3")
 	session.MakeRequest(t, req, http.StatusOK)
 }
+
+func TestPullReviewedSincePush(t *testing.T) {
+	onGiteaRun(t, func(t *testing.T, u *url.URL) {
+		session := loginUser(t, "user1")
+		testRepoFork(t, session, "user2", "repo1", "user1", "repo1")
+		testEditFile(t, session, "user1", "repo1", "master", "README.md", "Hello, World (Edited)\n")
+		resp := testPullCreate(t, session, "user1", "repo1", "master", "This is a pull title")
+
+		elem := strings.Split(test.RedirectURL(resp), "/")
+		index, err := strconv.ParseInt(elem[4], 10, 64)
+		assert.NoError(t, err)
+		baseRepo := models.AssertExistsAndLoadBean(t, &models.Repository{OwnerName: "user2", Name: "repo1"}).(*models.Repository)
+		issue := models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: baseRepo.ID, Index: index}).(*models.Issue)
+		reviewer := models.AssertExistsAndLoadBean(t, &models.User{ID: 4}).(*models.User)
+		awaitingReview := func() int64 {
+			_, count, err := models.GetPullRequestsAwaitingReview(reviewer.ID, 1, 10)
+			assert.NoError(t, err)
+			return count
+		}
+
+		_, err = models.CreateReviewRequest(issue, reviewer)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 1, awaitingReview())
+		_, err = models.CreateReview(models.CreateReviewOptions{Type: models.ReviewTypeComment, Issue: issue, Reviewer: reviewer})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, awaitingReview())
+
+		// a push after the review asks for a review of the new commits
+		time.Sleep(time.Second)
+		testEditFile(t, session, "user1", "repo1", "master", "README.md", "Hello, World (Edited again)\n")
+		assert.Eventually(t, func() bool {
+			return awaitingReview() == 1
+		}, 5*time.Second, 100*time.Millisecond)
+	})
+}
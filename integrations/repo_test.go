@@ -7,13 +7,16 @@ package integrations
 import (
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
 	"testing"
 
+	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/setting"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/stretchr/testify/assert"
+	"github.com/unknwon/i18n"
 )
 
 func TestViewRepo(t *testing.T) {
@@ -119,3 +122,39 @@ func TestViewAsRepoAdmin(t *testing.T) {
 		assert.Equal(t, expectedNoDescription, noDescription.HasClass("no-description"))
 	}
 }
+
+func TestRepoSettingsDefaultMergeStyle(t *testing.T) {
+	defer prepareTestEnv(t)()
+
+	session := loginUser(t, "user2")
+	postSettings := func(defaultMergeStyle string) string {
+		csrf := GetCSRF(t, session, "/user2/repo1/settings")
+		req := NewRequestWithValues(t, "POST", "/user2/repo1/settings", map[string]string{
+			"_csrf":                     csrf,
+			"action":                    "advanced",
+			"enable_pulls":              "on",
+			"pulls_allow_merge":         "on",
+			"pulls_allow_squash":        "on",
+			"pulls_default_merge_style": defaultMergeStyle,
+		})
+		session.MakeRequest(t, req, http.StatusFound)
+		flashCookie := session.GetCookie("macaron_flash")
+		assert.NotNil(t, flashCookie)
+		return flashCookie.Value
+	}
+	defaultMergeStyle := func() models.MergeStyle {
+		repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+		unit, err := repo.GetUnit(models.UnitTypePullRequests)
+		assert.NoError(t, err)
+		return unit.PullRequestsConfig().DefaultMergeStyle
+	}
+
+	assert.EqualValues(t, url.QueryEscape("success="+url.QueryEscape(i18n.Tr("en", "repo.settings.update_settings_success"))), postSettings("squash"))
+	assert.Equal(t, models.MergeStyleSquash, defaultMergeStyle())
+
+	// styles which are unknown or not enabled are refused and leave the settings untouched
+	for _, style := range []string{"rebase", "fast-forward"} {
+		assert.EqualValues(t, url.QueryEscape("error="+url.QueryEscape(i18n.Tr("en", "repo.settings.pulls.default_merge_style_error"))), postSettings(style))
+		assert.Equal(t, models.MergeStyleSquash, defaultMergeStyle())
+	}
+}
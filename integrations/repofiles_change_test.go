@@ -0,0 +1,170 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"net/url"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/repofiles"
+	"code.gitea.io/gitea/modules/test"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func getChangeRepoFilesOptions(repo *models.Repository) *repofiles.ChangeFilesOptions {
+	return &repofiles.ChangeFilesOptions{
+		OldBranch: repo.DefaultBranch,
+		NewBranch: repo.DefaultBranch,
+		Message:   "Changes multiple files",
+		Files: []*repofiles.ChangeRepoFile{
+			{
+				Operation: repofiles.ChangeFileOperationCreate,
+				TreePath:  "docs/new_file.md",
+				Content:   "This is a new file",
+			},
+			{
+				Operation: repofiles.ChangeFileOperationUpdate,
+				TreePath:  "README.md",
+				Content:   "This is an updated README",
+				SHA:       "4b4851ad51df6a7d9f25c979345979eaeb5b349f",
+			},
+		},
+		Author: &repofiles.IdentityOptions{
+			Name:  "Bob Smith",
+			Email: "bob@smith.com",
+		},
+		Committer: nil,
+	}
+}
+
+func TestChangeRepoFiles(t *testing.T) {
+	onGiteaRun(t, testChangeRepoFiles)
+}
+
+func testChangeRepoFiles(t *testing.T, u *url.URL) {
+	// setup
+	models.PrepareTestEnv(t)
+	ctx := test.MockContext(t, "user2/repo1")
+	ctx.SetParams(":id", "1")
+	test.LoadRepo(t, ctx, 1)
+	test.LoadRepoCommit(t, ctx)
+	test.LoadUser(t, ctx, 2)
+	test.LoadGitRepo(t, ctx)
+	defer ctx.Repo.GitRepo.Close()
+	repo := ctx.Repo.Repository
+	doer := ctx.User
+	opts := getChangeRepoFilesOptions(repo)
+	var newFileSHA string
+
+	t.Run("Create and update in one commit", func(t *testing.T) {
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.NoError(t, err)
+		assert.NotNil(t, filesResponse)
+		assert.Len(t, filesResponse.Files, 2)
+		assert.EqualValues(t, "docs/new_file.md", filesResponse.Files[0].Path)
+		assert.EqualValues(t, "README.md", filesResponse.Files[1].Path)
+		assert.EqualValues(t, "Changes multiple files\n", filesResponse.Commit.Message)
+		assert.EqualValues(t, "Bob Smith", filesResponse.Commit.Author.Name)
+		assert.Len(t, filesResponse.Commit.Parents, 1)
+		newFileSHA = filesResponse.Files[0].SHA
+	})
+
+	t.Run("Stale SHA is rejected", func(t *testing.T) {
+		opts := getChangeRepoFilesOptions(repo)
+		opts.Files = opts.Files[1:]
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.Nil(t, filesResponse)
+		assert.True(t, models.IsErrSHADoesNotMatch(err))
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		opts := getChangeRepoFilesOptions(repo)
+		opts.Files = []*repofiles.ChangeRepoFile{
+			{
+				Operation: repofiles.ChangeFileOperationDelete,
+				TreePath:  "docs/new_file.md",
+				SHA:       newFileSHA,
+			},
+		}
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.NoError(t, err)
+		assert.Len(t, filesResponse.Files, 1)
+		assert.Nil(t, filesResponse.Files[0])
+	})
+}
+
+func TestChangeRepoFilesErrors(t *testing.T) {
+	// setup
+	models.PrepareTestEnv(t)
+	ctx := test.MockContext(t, "user2/repo1")
+	ctx.SetParams(":id", "1")
+	test.LoadRepo(t, ctx, 1)
+	test.LoadRepoCommit(t, ctx)
+	test.LoadUser(t, ctx, 2)
+	test.LoadGitRepo(t, ctx)
+	defer ctx.Repo.GitRepo.Close()
+
+	repo := ctx.Repo.Repository
+	doer := ctx.User
+
+	t.Run("Duplicated path", func(t *testing.T) {
+		opts := getChangeRepoFilesOptions(repo)
+		opts.Files[0].TreePath = "./README.md"
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.Nil(t, filesResponse)
+		assert.EqualError(t, err, "repository file path is changed more than once [path: README.md]")
+	})
+
+	t.Run("Bad SHA", func(t *testing.T) {
+		opts := getChangeRepoFilesOptions(repo)
+		opts.Files[1].SHA = "bad_sha"
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.Nil(t, filesResponse)
+		assert.EqualError(t, err, "sha does not match [given: bad_sha, expected: 4b4851ad51df6a7d9f25c979345979eaeb5b349f]")
+	})
+
+	t.Run("Missing SHA", func(t *testing.T) {
+		opts := getChangeRepoFilesOptions(repo)
+		opts.Files[1].SHA = ""
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.Nil(t, filesResponse)
+		assert.EqualError(t, err, "a SHA must be provided when updating or deleting a file [path: README.md]")
+
+		opts.Files = []*repofiles.ChangeRepoFile{
+			{
+				Operation: repofiles.ChangeFileOperationDelete,
+				TreePath:  "README.md",
+			},
+		}
+		filesResponse, err = repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.Nil(t, filesResponse)
+		assert.True(t, models.IsErrSHANotProvided(err))
+	})
+
+	t.Run("Create existing file", func(t *testing.T) {
+		opts := getChangeRepoFilesOptions(repo)
+		opts.Files[0].TreePath = "README.md"
+		opts.Files = opts.Files[:1]
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.Nil(t, filesResponse)
+		assert.EqualError(t, err, "repository file already exists [path: README.md]")
+	})
+
+	t.Run("Delete missing file", func(t *testing.T) {
+		opts := getChangeRepoFilesOptions(repo)
+		opts.Files = []*repofiles.ChangeRepoFile{
+			{
+				Operation: repofiles.ChangeFileOperationDelete,
+				TreePath:  "missing.md",
+				SHA:       "4b4851ad51df6a7d9f25c979345979eaeb5b349f",
+			},
+		}
+		filesResponse, err := repofiles.ChangeRepoFiles(repo, doer, opts)
+		assert.Nil(t, filesResponse)
+		assert.EqualError(t, err, "repository file does not exist [path: missing.md]")
+	})
+}
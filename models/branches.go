@@ -7,6 +7,7 @@ package models
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"code.gitea.io/gitea/modules/base"
@@ -15,6 +16,7 @@ import (
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
 
+	"github.com/gobwas/glob"
 	"github.com/unknwon/com"
 )
 
@@ -27,25 +29,29 @@ const (
 
 // ProtectedBranch struct
 type ProtectedBranch struct {
-	ID                        int64  `xorm:"pk autoincr"`
-	RepoID                    int64  `xorm:"UNIQUE(s)"`
-	BranchName                string `xorm:"UNIQUE(s)"`
-	CanPush                   bool   `xorm:"NOT NULL DEFAULT false"`
-	EnableWhitelist           bool
-	WhitelistUserIDs          []int64            `xorm:"JSON TEXT"`
-	WhitelistTeamIDs          []int64            `xorm:"JSON TEXT"`
-	EnableMergeWhitelist      bool               `xorm:"NOT NULL DEFAULT false"`
-	WhitelistDeployKeys       bool               `xorm:"NOT NULL DEFAULT false"`
-	MergeWhitelistUserIDs     []int64            `xorm:"JSON TEXT"`
-	MergeWhitelistTeamIDs     []int64            `xorm:"JSON TEXT"`
-	EnableStatusCheck         bool               `xorm:"NOT NULL DEFAULT false"`
-	StatusCheckContexts       []string           `xorm:"JSON TEXT"`
-	EnableApprovalsWhitelist  bool               `xorm:"NOT NULL DEFAULT false"`
-	ApprovalsWhitelistUserIDs []int64            `xorm:"JSON TEXT"`
-	ApprovalsWhitelistTeamIDs []int64            `xorm:"JSON TEXT"`
-	RequiredApprovals         int64              `xorm:"NOT NULL DEFAULT 0"`
-	CreatedUnix               timeutil.TimeStamp `xorm:"created"`
-	UpdatedUnix               timeutil.TimeStamp `xorm:"updated"`
+	ID                           int64  `xorm:"pk autoincr"`
+	RepoID                       int64  `xorm:"UNIQUE(s)"`
+	BranchName                   string `xorm:"UNIQUE(s)"`
+	CanPush                      bool   `xorm:"NOT NULL DEFAULT false"`
+	EnableWhitelist              bool
+	WhitelistUserIDs             []int64            `xorm:"JSON TEXT"`
+	WhitelistTeamIDs             []int64            `xorm:"JSON TEXT"`
+	EnableMergeWhitelist         bool               `xorm:"NOT NULL DEFAULT false"`
+	WhitelistDeployKeys          bool               `xorm:"NOT NULL DEFAULT false"`
+	MergeWhitelistUserIDs        []int64            `xorm:"JSON TEXT"`
+	MergeWhitelistTeamIDs        []int64            `xorm:"JSON TEXT"`
+	EnableStatusCheck            bool               `xorm:"NOT NULL DEFAULT false"`
+	StatusCheckContexts          []string           `xorm:"JSON TEXT"`
+	EnableApprovalsWhitelist     bool               `xorm:"NOT NULL DEFAULT false"`
+	ApprovalsWhitelistUserIDs    []int64            `xorm:"JSON TEXT"`
+	ApprovalsWhitelistTeamIDs    []int64            `xorm:"JSON TEXT"`
+	RequiredApprovals            int64              `xorm:"NOT NULL DEFAULT 0"`
+	DismissStaleApprovals        bool               `xorm:"NOT NULL DEFAULT false"`
+	ProtectedFilePatterns        string             `xorm:"TEXT"`
+	RequireLinearHistory         bool               `xorm:"NOT NULL DEFAULT false"`
+	RequireResolvedConversations bool               `xorm:"NOT NULL DEFAULT false"`
+	CreatedUnix                  timeutil.TimeStamp `xorm:"created"`
+	UpdatedUnix                  timeutil.TimeStamp `xorm:"updated"`
 }
 
 // IsProtected returns if the branch is protected
@@ -53,6 +59,11 @@ func (protectBranch *ProtectedBranch) IsProtected() bool {
 	return protectBranch.ID > 0
 }
 
+// IsMergeStyleAllowed returns false for the merge styles creating a merge commit if the branch requires a linear history
+func (protectBranch *ProtectedBranch) IsMergeStyleAllowed(style MergeStyle) bool {
+	return !protectBranch.RequireLinearHistory || (style != MergeStyleMerge && style != MergeStyleRebaseMerge)
+}
+
 // CanUserPush returns if some user could push to this protected branch
 func (protectBranch *ProtectedBranch) CanUserPush(userID int64) bool {
 	if !protectBranch.CanPush {
@@ -152,11 +163,13 @@ func (protectBranch *ProtectedBranch) HasEnoughApprovals(pr *PullRequest) bool {
 	return protectBranch.GetGrantedApprovalsCount(pr) >= protectBranch.RequiredApprovals
 }
 
-// GetGrantedApprovalsCount returns the number of granted approvals for pr. A granted approval must be authored by a user in an approval whitelist.
+// GetGrantedApprovalsCount returns the number of granted approvals for pr. A granted approval must be authored by a user in an approval whitelist
+// and must not have been dismissed as stale.
 func (protectBranch *ProtectedBranch) GetGrantedApprovalsCount(pr *PullRequest) int64 {
 	approvals, err := x.Where("issue_id = ?", pr.IssueID).
 		And("type = ?", ReviewTypeApprove).
 		And("official = ?", true).
+		And("stale = ?", false).
 		Count(new(Review))
 	if err != nil {
 		log.Error("GetGrantedApprovalsCount: %v", err)
@@ -166,6 +179,43 @@ func (protectBranch *ProtectedBranch) GetGrantedApprovalsCount(pr *PullRequest)
 	return approvals
 }
 
+// GetProtectedFilePatterns parses the semicolon separated ProtectedFilePatterns
+func (protectBranch *ProtectedBranch) GetProtectedFilePatterns() []glob.Glob {
+	extarr := make([]glob.Glob, 0, 10)
+	for _, expr := range strings.Split(protectBranch.ProtectedFilePatterns, ";") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		g, err := glob.Compile(expr, '/')
+		if err != nil {
+			log.Info("Invalid glob expression '%s' (skipped): %v", expr, err)
+			continue
+		}
+		extarr = append(extarr, g)
+	}
+	return extarr
+}
+
+// MatchProtectedFiles returns the files matching one of the protected file patterns
+func (protectBranch *ProtectedBranch) MatchProtectedFiles(files []string) []string {
+	patterns := protectBranch.GetProtectedFilePatterns()
+	if len(patterns) == 0 {
+		return nil
+	}
+
+	var protected []string
+	for _, file := range files {
+		for _, pattern := range patterns {
+			if pattern.Match(file) {
+				protected = append(protected, file)
+				break
+			}
+		}
+	}
+	return protected
+}
+
 // GetProtectedBranchByRepoID getting protected branch by repo ID
 func GetProtectedBranchByRepoID(repoID int64) ([]*ProtectedBranch, error) {
 	protectedBranches := make([]*ProtectedBranch, 0)
@@ -78,3 +78,13 @@ func getDeletedBranch(t *testing.T, branch *DeletedBranch) *DeletedBranch {
 
 	return deletedBranch
 }
+
+func TestProtectedBranch_MatchProtectedFiles(t *testing.T) {
+	protectBranch := &ProtectedBranch{ProtectedFilePatterns: "go.mod; .ci/** ;*.yml;"}
+	assert.Len(t, protectBranch.GetProtectedFilePatterns(), 3)
+	assert.Equal(t, []string{"go.mod", ".ci/build/run.sh", ".drone.yml"},
+		protectBranch.MatchProtectedFiles([]string{"go.mod", "main.go", ".ci/build/run.sh", ".drone.yml", "docs/config.yml", "vendor/x/go.mod"}))
+
+	protectBranch.ProtectedFilePatterns = ""
+	assert.Empty(t, protectBranch.MatchProtectedFiles([]string{"go.mod"}))
+}
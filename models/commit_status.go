@@ -9,6 +9,7 @@ import (
 	"crypto/sha1"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"code.gitea.io/gitea/modules/log"
@@ -188,6 +189,39 @@ func sortCommitStatusesSession(sess *xorm.Session, sortType string) {
 	}
 }
 
+// IsCommitStatusContextSuccess returns true if all required status check contexts succeed.
+func IsCommitStatusContextSuccess(commitStatuses []*CommitStatus, requiredContexts []string) bool {
+	// If no specific context is required, require that last commit status is a success
+	if len(requiredContexts) == 0 {
+		status := CalcCommitStatus(commitStatuses)
+		if status == nil || status.State != CommitStatusSuccess {
+			return false
+		}
+		return true
+	}
+
+	return len(GetMissingRequiredStatusContexts(commitStatuses, requiredContexts)) == 0
+}
+
+// GetMissingRequiredStatusContexts returns the required status check contexts
+// which are absent from the given statuses or are not successful.
+func GetMissingRequiredStatusContexts(commitStatuses []*CommitStatus, requiredContexts []string) []string {
+	var missing []string
+	for _, ctx := range requiredContexts {
+		var found bool
+		for _, commitStatus := range commitStatuses {
+			if commitStatus.Context == ctx {
+				found = commitStatus.State == CommitStatusSuccess
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, ctx)
+		}
+	}
+	return missing
+}
+
 // GetLatestCommitStatus returns all statuses with a unique context for a given commit.
 func GetLatestCommitStatus(repo *Repository, sha string, page int) ([]*CommitStatus, error) {
 	ids := make([]int64, 0, 10)
@@ -290,7 +324,80 @@ func NewCommitStatus(opts NewCommitStatusOptions) error {
 		return fmt.Errorf("Insert CommitStatus[%s, %s]: %v", repoPath, opts.SHA, err)
 	}
 
-	return sess.Commit()
+	if err := sess.Commit(); err != nil {
+		return err
+	}
+	invalidateCommitStatusCache(opts.Repo.ID, opts.SHA)
+	return nil
+}
+
+type commitStatusCacheKey struct {
+	repoID int64
+	sha    string
+}
+
+type commitStatusCacheItem struct {
+	status  *CommitStatus
+	expires time.Time
+}
+
+// commitStatusCache holds the combined commit status of recently shown commits
+var commitStatusCache = struct {
+	sync.Mutex
+	items map[commitStatusCacheKey]commitStatusCacheItem
+}{
+	items: make(map[commitStatusCacheKey]commitStatusCacheItem),
+}
+
+// getCachedCommitStatus returns the combined commit status of the commit from the cache, or computes
+// it by getFunc and caches it for setting.CacheService.CommitStatusTTL.
+func getCachedCommitStatus(repoID int64, sha string, getFunc func() (*CommitStatus, error)) (*CommitStatus, error) {
+	if setting.CacheService == nil || setting.CacheService.CommitStatusTTL <= 0 || setting.CacheService.CommitStatusMaxItems <= 0 {
+		return getFunc()
+	}
+
+	key := commitStatusCacheKey{repoID: repoID, sha: sha}
+	now := time.Now()
+	commitStatusCache.Lock()
+	item, ok := commitStatusCache.items[key]
+	commitStatusCache.Unlock()
+	if ok && now.Before(item.expires) {
+		return item.status, nil
+	}
+
+	status, err := getFunc()
+	if err != nil {
+		return nil, err
+	}
+
+	commitStatusCache.Lock()
+	defer commitStatusCache.Unlock()
+	if len(commitStatusCache.items) >= setting.CacheService.CommitStatusMaxItems {
+		for k, v := range commitStatusCache.items {
+			if !now.Before(v.expires) {
+				delete(commitStatusCache.items, k)
+			}
+		}
+		// still full of current items, so drop any of them
+		for k := range commitStatusCache.items {
+			if len(commitStatusCache.items) < setting.CacheService.CommitStatusMaxItems {
+				break
+			}
+			delete(commitStatusCache.items, k)
+		}
+	}
+	commitStatusCache.items[key] = commitStatusCacheItem{
+		status:  status,
+		expires: now.Add(setting.CacheService.CommitStatusTTL),
+	}
+	return status, nil
+}
+
+// invalidateCommitStatusCache removes the cached commit status of the commit
+func invalidateCommitStatusCache(repoID int64, sha string) {
+	commitStatusCache.Lock()
+	delete(commitStatusCache.items, commitStatusCacheKey{repoID: repoID, sha: sha})
+	commitStatusCache.Unlock()
 }
 
 // SignCommitWithStatuses represents a commit with validation of signature and status state.
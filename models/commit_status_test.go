@@ -6,6 +6,9 @@ package models
 
 import (
 	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -42,3 +45,84 @@ func TestGetCommitStatuses(t *testing.T) {
 	assert.Equal(t, CommitStatusError, statuses[4].State)
 	assert.Equal(t, "https://try.gitea.io/api/v1/repos/user2/repo1/statuses/1234123412341234123412341234123412341234", statuses[4].APIURL())
 }
+
+func TestGetMissingRequiredStatusContexts(t *testing.T) {
+	statuses := []*CommitStatus{
+		{Context: "ci/build", State: CommitStatusSuccess},
+		{Context: "ci/test", State: CommitStatusFailure},
+		{Context: "ci/lint", State: CommitStatusPending},
+	}
+
+	assert.Empty(t, GetMissingRequiredStatusContexts(statuses, nil))
+	assert.Empty(t, GetMissingRequiredStatusContexts(statuses, []string{"ci/build"}))
+	assert.Equal(t, []string{"ci/test", "ci/lint", "ci/deploy"},
+		GetMissingRequiredStatusContexts(statuses, []string{"ci/build", "ci/test", "ci/lint", "ci/deploy"}))
+
+	assert.True(t, IsCommitStatusContextSuccess(statuses, []string{"ci/build"}))
+	assert.False(t, IsCommitStatusContextSuccess(statuses, []string{"ci/build", "ci/deploy"}))
+	assert.False(t, IsCommitStatusContextSuccess(statuses, nil))
+}
+
+func TestGetCachedCommitStatus(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	defer func(cacheService *setting.Cache) {
+		setting.CacheService = cacheService
+	}(setting.CacheService)
+	setting.CacheService = &setting.Cache{
+		CommitStatusTTL:      time.Minute,
+		CommitStatusMaxItems: 2,
+	}
+
+	repo1 := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	user2 := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	const sha1 = "1234123412341234123412341234123412341234"
+
+	var calls int
+	getStatus := func() (*CommitStatus, error) {
+		calls++
+		statuses, err := GetLatestCommitStatus(repo1, sha1, 0)
+		if err != nil {
+			return nil, err
+		}
+		return CalcCommitStatus(statuses), nil
+	}
+
+	status, err := getCachedCommitStatus(repo1.ID, sha1, getStatus)
+	assert.NoError(t, err)
+	cached, err := getCachedCommitStatus(repo1.ID, sha1, getStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, status, cached)
+	assert.Equal(t, 1, calls)
+
+	// a new status of the commit invalidates the cached one
+	assert.NoError(t, NewCommitStatus(NewCommitStatusOptions{
+		Repo:    repo1,
+		Creator: user2,
+		SHA:     sha1,
+		CommitStatus: &CommitStatus{
+			State:   CommitStatusSuccess,
+			Context: "ci/cached",
+		},
+	}))
+	_, err = getCachedCommitStatus(repo1.ID, sha1, getStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	// the cache keeps at most CommitStatusMaxItems items
+	for _, sha := range []string{"a", "b", "c"} {
+		_, err = getCachedCommitStatus(repo1.ID, sha, func() (*CommitStatus, error) { return nil, nil })
+		assert.NoError(t, err)
+	}
+	assert.Len(t, commitStatusCache.items, 2)
+
+	// expired items are computed again, the eviction above might have kept sha1
+	invalidateCommitStatusCache(repo1.ID, sha1)
+	setting.CacheService.CommitStatusTTL = time.Nanosecond
+	_, err = getCachedCommitStatus(repo1.ID, sha1, getStatus)
+	assert.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = getCachedCommitStatus(repo1.ID, sha1, getStatus)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, calls)
+}
@@ -818,6 +818,25 @@ func (err ErrInvalidTagName) Error() string {
 	return fmt.Sprintf("release tag name is not valid [tag_name: %s]", err.TagName)
 }
 
+// ErrCommitAuthorNotAllowed represents an error that a commit pushed to a protected branch
+// is authored by an email address which does not belong to a verified account nor to an
+// allowed domain of the repository.
+type ErrCommitAuthorNotAllowed struct {
+	RepoID   int64
+	CommitID string
+	Email    string
+}
+
+// IsErrCommitAuthorNotAllowed checks if an error is an ErrCommitAuthorNotAllowed.
+func IsErrCommitAuthorNotAllowed(err error) bool {
+	_, ok := err.(ErrCommitAuthorNotAllowed)
+	return ok
+}
+
+func (err ErrCommitAuthorNotAllowed) Error() string {
+	return fmt.Sprintf("commit author is not allowed [repo_id: %d, commit_id: %s, email: %s]", err.RepoID, err.CommitID, err.Email)
+}
+
 // ErrRepoFileAlreadyExists represents a "RepoFileAlreadyExist" kind of error.
 type ErrRepoFileAlreadyExists struct {
 	Path string
@@ -833,6 +852,21 @@ func (err ErrRepoFileAlreadyExists) Error() string {
 	return fmt.Sprintf("repository file already exists [path: %s]", err.Path)
 }
 
+// ErrRepoFilePathDuplicated represents a "RepoFilePathDuplicated" kind of error.
+type ErrRepoFilePathDuplicated struct {
+	Path string
+}
+
+// IsErrRepoFilePathDuplicated checks if an error is a ErrRepoFilePathDuplicated.
+func IsErrRepoFilePathDuplicated(err error) bool {
+	_, ok := err.(ErrRepoFilePathDuplicated)
+	return ok
+}
+
+func (err ErrRepoFilePathDuplicated) Error() string {
+	return fmt.Sprintf("repository file path is changed more than once [path: %s]", err.Path)
+}
+
 // ErrRepoFileDoesNotExist represents a "RepoFileDoesNotExist" kind of error.
 type ErrRepoFileDoesNotExist struct {
 	Path string
@@ -984,6 +1018,89 @@ func (err ErrNotAllowedToMerge) Error() string {
 	return fmt.Sprintf("not allowed to merge [reason: %s]", err.Reason)
 }
 
+// ErrMergeOnHold represents an error that a pull request can not be merged because it is on hold.
+type ErrMergeOnHold struct {
+	ID     int64
+	Reason string
+}
+
+// IsErrMergeOnHold checks if an error is an ErrMergeOnHold.
+func IsErrMergeOnHold(err error) bool {
+	_, ok := err.(ErrMergeOnHold)
+	return ok
+}
+
+func (err ErrMergeOnHold) Error() string {
+	return fmt.Sprintf("pull request is on hold [id: %d, reason: %s]", err.ID, err.Reason)
+}
+
+// ErrProtectedFilesChanged represents an error that a pull request changing protected files can not be merged without official approval.
+type ErrProtectedFilesChanged struct {
+	ID    int64
+	Files []string
+}
+
+// IsErrProtectedFilesChanged checks if an error is an ErrProtectedFilesChanged.
+func IsErrProtectedFilesChanged(err error) bool {
+	_, ok := err.(ErrProtectedFilesChanged)
+	return ok
+}
+
+func (err ErrProtectedFilesChanged) Error() string {
+	return fmt.Sprintf("pull request changes protected files without official approval [id: %d, files: %v]", err.ID, err.Files)
+}
+
+// ErrLinearHistoryRequired represents an error that a pull request can not be merged with a merge commit
+// because the protection of the base branch requires a linear history.
+type ErrLinearHistoryRequired struct {
+	BranchName string
+	Style      MergeStyle
+}
+
+// IsErrLinearHistoryRequired checks if an error is an ErrLinearHistoryRequired.
+func IsErrLinearHistoryRequired(err error) bool {
+	_, ok := err.(ErrLinearHistoryRequired)
+	return ok
+}
+
+func (err ErrLinearHistoryRequired) Error() string {
+	return fmt.Sprintf("branch requires a linear history [branch: %s, style: %s]", err.BranchName, err.Style)
+}
+
+// ErrUnresolvedConversations represents an error that a pull request can not be merged
+// because the protection of the base branch requires all review conversations to be resolved.
+type ErrUnresolvedConversations struct {
+	ID    int64
+	Count int
+}
+
+// IsErrUnresolvedConversations checks if an error is an ErrUnresolvedConversations.
+func IsErrUnresolvedConversations(err error) bool {
+	_, ok := err.(ErrUnresolvedConversations)
+	return ok
+}
+
+func (err ErrUnresolvedConversations) Error() string {
+	return fmt.Sprintf("pull request has unresolved conversations [id: %d, count: %d]", err.ID, err.Count)
+}
+
+// ErrRequiredStatusMissing represents an error that a pull request can not be merged
+// because required status check contexts are absent or not successful.
+type ErrRequiredStatusMissing struct {
+	ID       int64
+	Contexts []string
+}
+
+// IsErrRequiredStatusMissing checks if an error is an ErrRequiredStatusMissing.
+func IsErrRequiredStatusMissing(err error) bool {
+	_, ok := err.(ErrRequiredStatusMissing)
+	return ok
+}
+
+func (err ErrRequiredStatusMissing) Error() string {
+	return fmt.Sprintf("required status checks are missing or not successful [id: %d, contexts: %v]", err.ID, err.Contexts)
+}
+
 // ErrTagAlreadyExists represents an error that tag with such name already exists.
 type ErrTagAlreadyExists struct {
 	TagName string
@@ -1060,6 +1177,21 @@ func (err ErrSHAOrCommitIDNotProvided) Error() string {
 	return fmt.Sprintf("a SHA or commmit ID must be proved when updating a file")
 }
 
+// ErrSHANotProvided represents a "SHANotProvided" kind of error.
+type ErrSHANotProvided struct {
+	Path string
+}
+
+// IsErrSHANotProvided checks if an error is a ErrSHANotProvided.
+func IsErrSHANotProvided(err error) bool {
+	_, ok := err.(ErrSHANotProvided)
+	return ok
+}
+
+func (err ErrSHANotProvided) Error() string {
+	return fmt.Sprintf("a SHA must be provided when updating or deleting a file [path: %s]", err.Path)
+}
+
 //  __      __      ___.   .__                   __
 // /  \    /  \ ____\_ |__ |  |__   ____   ____ |  | __
 // \   \/\/   // __ \| __ \|  |  \ /  _ \ /  _ \|  |/ /
@@ -1269,6 +1401,37 @@ func (err ErrPullRequestHeadRepoMissing) Error() string {
 		err.ID, err.HeadRepoID)
 }
 
+// ErrPullRequestRefNotExist represents a "PullRequestRefNotExist" kind of error.
+type ErrPullRequestRefNotExist struct {
+	ID  int64
+	Ref string
+}
+
+// IsErrPullRequestRefNotExist checks if an error is a ErrPullRequestRefNotExist.
+func IsErrPullRequestRefNotExist(err error) bool {
+	_, ok := err.(ErrPullRequestRefNotExist)
+	return ok
+}
+
+func (err ErrPullRequestRefNotExist) Error() string {
+	return fmt.Sprintf("pull request ref does not exist [id: %d, ref: %s]", err.ID, err.Ref)
+}
+
+// ErrPullRequestNotImported represents a "ErrPullRequestNotImported" error
+type ErrPullRequestNotImported struct {
+	ID int64
+}
+
+// IsErrPullRequestNotImported checks if an error is a ErrPullRequestNotImported.
+func IsErrPullRequestNotImported(err error) bool {
+	_, ok := err.(ErrPullRequestNotImported)
+	return ok
+}
+
+func (err ErrPullRequestNotImported) Error() string {
+	return fmt.Sprintf("pull request was not imported [id: %d]", err.ID)
+}
+
 // ErrInvalidMergeStyle represents an error if merging with disabled merge strategy
 type ErrInvalidMergeStyle struct {
 	ID    int64
@@ -1322,6 +1485,74 @@ func (err ErrMergeUnrelatedHistories) Error() string {
 	return fmt.Sprintf("Merge UnrelatedHistories Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
 }
 
+// ErrInvalidMergeUpToCommit represents an error if the commit to merge up to is not part of the pull request
+type ErrInvalidMergeUpToCommit struct {
+	ID        int64
+	CommitSHA string
+}
+
+// IsErrInvalidMergeUpToCommit checks if an error is a ErrInvalidMergeUpToCommit.
+func IsErrInvalidMergeUpToCommit(err error) bool {
+	_, ok := err.(ErrInvalidMergeUpToCommit)
+	return ok
+}
+
+func (err ErrInvalidMergeUpToCommit) Error() string {
+	return fmt.Sprintf("commit is not part of the pull request [pull_id: %d, sha: %s]", err.ID, err.CommitSHA)
+}
+
+// ErrInvalidMergeTrailer represents an error if a commit message trailer to add on merge is malformed
+type ErrInvalidMergeTrailer struct {
+	Trailer string
+}
+
+// IsErrInvalidMergeTrailer checks if an error is a ErrInvalidMergeTrailer.
+func IsErrInvalidMergeTrailer(err error) bool {
+	_, ok := err.(ErrInvalidMergeTrailer)
+	return ok
+}
+
+func (err ErrInvalidMergeTrailer) Error() string {
+	return fmt.Sprintf("invalid commit message trailer [trailer: %s]", err.Trailer)
+}
+
+// ErrInvalidDeploymentState represents an error if a pull request deployment can not change into a state
+type ErrInvalidDeploymentState struct {
+	Environment string
+	From        DeploymentState
+	To          DeploymentState
+}
+
+// IsErrInvalidDeploymentState checks if an error is a ErrInvalidDeploymentState.
+func IsErrInvalidDeploymentState(err error) bool {
+	_, ok := err.(ErrInvalidDeploymentState)
+	return ok
+}
+
+func (err ErrInvalidDeploymentState) Error() string {
+	return fmt.Sprintf("invalid deployment state [environment: %s, from: %s, to: %s]", err.Environment, err.From, err.To)
+}
+
+// ErrHeadBranchDeletionFailed represents an error if the head branch could not be deleted
+// after the pull request has been merged. The merge itself has succeeded.
+// HasNewCommits is set if the branch was kept because it has moved past the merged commit.
+type ErrHeadBranchDeletionFailed struct {
+	ID            int64
+	Branch        string
+	HasNewCommits bool
+	Err           error
+}
+
+// IsErrHeadBranchDeletionFailed checks if an error is a ErrHeadBranchDeletionFailed.
+func IsErrHeadBranchDeletionFailed(err error) bool {
+	_, ok := err.(ErrHeadBranchDeletionFailed)
+	return ok
+}
+
+func (err ErrHeadBranchDeletionFailed) Error() string {
+	return fmt.Sprintf("pull request merged but head branch could not be deleted [pull_id: %d, branch: %s]: %v", err.ID, err.Branch, err.Err)
+}
+
 // ErrMergePushOutOfDate represents an error if merging fails due to unrelated histories
 type ErrMergePushOutOfDate struct {
 	Style  MergeStyle
@@ -1340,6 +1571,22 @@ func (err ErrMergePushOutOfDate) Error() string {
 	return fmt.Sprintf("Merge PushOutOfDate Error: %v: %s\n%s", err.Err, err.StdErr, err.StdOut)
 }
 
+// ErrMergeEmptyDiff represents an error if the pull request to merge does not change anything
+type ErrMergeEmptyDiff struct {
+	ID    int64
+	Style MergeStyle
+}
+
+// IsErrMergeEmptyDiff checks if an error is a ErrMergeEmptyDiff.
+func IsErrMergeEmptyDiff(err error) bool {
+	_, ok := err.(ErrMergeEmptyDiff)
+	return ok
+}
+
+func (err ErrMergeEmptyDiff) Error() string {
+	return fmt.Sprintf("pull request does not change anything [pull_id: %d, style: %s]", err.ID, err.Style)
+}
+
 // ErrRebaseConflicts represents an error if rebase fails with a conflict
 type ErrRebaseConflicts struct {
 	Style     MergeStyle
@@ -1381,6 +1628,71 @@ func (err ErrPullRequestHasMerged) Error() string {
 		err.ID, err.IssueID, err.HeadRepoID, err.BaseRepoID, err.HeadBranch, err.BaseBranch)
 }
 
+// ErrPullRequestNotMerged represents a "PullRequestNotMerged"-error
+type ErrPullRequestNotMerged struct {
+	ID int64
+}
+
+// IsErrPullRequestNotMerged checks if an error is a ErrPullRequestNotMerged.
+func IsErrPullRequestNotMerged(err error) bool {
+	_, ok := err.(ErrPullRequestNotMerged)
+	return ok
+}
+
+func (err ErrPullRequestNotMerged) Error() string {
+	return fmt.Sprintf("pull request has not been merged [id: %d]", err.ID)
+}
+
+// ErrQuickConflictCheckUnsupported represents an error if the conflicts of a pull request can't be
+// checked without a temporary repository
+type ErrQuickConflictCheckUnsupported struct {
+	ID     int64
+	Reason string
+}
+
+// IsErrQuickConflictCheckUnsupported checks if an error is a ErrQuickConflictCheckUnsupported.
+func IsErrQuickConflictCheckUnsupported(err error) bool {
+	_, ok := err.(ErrQuickConflictCheckUnsupported)
+	return ok
+}
+
+func (err ErrQuickConflictCheckUnsupported) Error() string {
+	return fmt.Sprintf("quick conflict check is not supported [pull_id: %d]: %s", err.ID, err.Reason)
+}
+
+// ErrPullRequestMergedCommitNotExist represents an error if the merged commit of a pull request is unknown
+// or does not exist anymore in the base repository
+type ErrPullRequestMergedCommitNotExist struct {
+	ID       int64
+	CommitID string
+}
+
+// IsErrPullRequestMergedCommitNotExist checks if an error is a ErrPullRequestMergedCommitNotExist.
+func IsErrPullRequestMergedCommitNotExist(err error) bool {
+	_, ok := err.(ErrPullRequestMergedCommitNotExist)
+	return ok
+}
+
+func (err ErrPullRequestMergedCommitNotExist) Error() string {
+	return fmt.Sprintf("merged commit of pull request does not exist [id: %d, commit_id: %s]", err.ID, err.CommitID)
+}
+
+// ErrPullRequestBranchPointNotExist represents an error if the head branch of a pull request
+// does not share any commit with the base branch
+type ErrPullRequestBranchPointNotExist struct {
+	ID int64
+}
+
+// IsErrPullRequestBranchPointNotExist checks if an error is a ErrPullRequestBranchPointNotExist.
+func IsErrPullRequestBranchPointNotExist(err error) bool {
+	_, ok := err.(ErrPullRequestBranchPointNotExist)
+	return ok
+}
+
+func (err ErrPullRequestBranchPointNotExist) Error() string {
+	return fmt.Sprintf("head branch of pull request was not branched off the base branch [id: %d]", err.ID)
+}
+
 // _________                                       __
 // \_   ___ \  ____   _____   _____   ____   _____/  |_
 // /    \  \/ /  _ \ /     \ /     \_/ __ \ /    \   __\
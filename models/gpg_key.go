@@ -364,6 +364,7 @@ func DeleteGPGKey(doer *User, id int64) (err error) {
 type CommitVerification struct {
 	Verified       bool
 	Warning        bool
+	UnlistedEmail  bool
 	Reason         string
 	SigningUser    *User
 	CommittingUser *User
@@ -386,6 +387,9 @@ const (
 	BadDefaultSignature = "gpg.error.probable_bad_default_signature"
 	// NoKeyFound is used as the reason when no key can be found to verify the signature.
 	NoKeyFound = "gpg.error.no_gpg_keys_found"
+	// UnlistedEmail is used as the reason when the signature is verified by a key of a user
+	// but the signing email is not an activated email address of that user.
+	UnlistedEmail = "gpg.error.unlisted_email"
 )
 
 func readerFromBase64(s string) (io.Reader, error) {
@@ -458,6 +462,23 @@ func hashAndVerify(sig *packet.Signature, payload string, k *GPGKey, committer,
 	}
 
 	if err := verifySign(sig, hash, k); err == nil {
+		if signer.ID != 0 {
+			activated, err := IsEmailActivatedForUser(signer.ID, email)
+			if err != nil {
+				log.Error("IsEmailActivatedForUser: %v", err)
+			}
+			if !activated {
+				return &CommitVerification{ //Signature is good but the email is not (or no longer) an activated address of the signer
+					CommittingUser: committer,
+					Verified:       false,
+					UnlistedEmail:  true,
+					Reason:         UnlistedEmail,
+					SigningUser:    signer,
+					SigningKey:     k,
+					SigningEmail:   email,
+				}
+			}
+		}
 		return &CommitVerification{ //Everything is ok
 			CommittingUser: committer,
 			Verified:       true,
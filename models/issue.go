@@ -63,6 +63,9 @@ type Issue struct {
 	TotalTrackedTime int64         `xorm:"-"`
 	Assignees        []*User       `xorm:"-"`
 
+	// ReactionTotal is the number of reactions on the issue itself, nil until it's loaded
+	ReactionTotal *int64 `xorm:"-"`
+
 	// IsLocked limits commenting abilities to users on an issue
 	// with write access
 	IsLocked bool `xorm:"NOT NULL DEFAULT false"`
@@ -230,6 +233,9 @@ func (issue *Issue) loadReactions(e Engine) (err error) {
 	}
 	// Add reactions either to issue or comment
 	for _, react := range reactions {
+		if react.ReviewID != 0 {
+			continue
+		}
 		if react.CommentID == 0 {
 			issue.Reactions = append(issue.Reactions, react)
 		} else if comment, ok := comments[react.CommentID]; ok {
@@ -413,6 +419,13 @@ func (issue *Issue) apiFormat(e Engine) *api.Issue {
 		apiIssue.Deadline = issue.DeadlineUnix.AsTimePtr()
 	}
 
+	if err := issue.loadReactionTotal(e); err != nil {
+		log.Error("loadReactionTotal[%d]: %v", issue.ID, err)
+	}
+	if issue.ReactionTotal != nil {
+		apiIssue.Reactions = *issue.ReactionTotal
+	}
+
 	return apiIssue
 }
 
@@ -1065,6 +1078,19 @@ func GetIssueIDsByRepoID(repoID int64) ([]int64, error) {
 	return getIssueIDsByRepoID(x, repoID)
 }
 
+// GetIssueIDsByIndex returns the ids of all issues and pull requests of a repository by their index
+func GetIssueIDsByIndex(repoID int64) (map[int64]int64, error) {
+	var issues = make([]*Issue, 0, 10)
+	if err := x.Cols("id", "`index`").Where("repo_id = ?", repoID).Find(&issues); err != nil {
+		return nil, err
+	}
+	var ids = make(map[int64]int64, len(issues))
+	for _, issue := range issues {
+		ids[issue.Index] = issue.ID
+	}
+	return ids, nil
+}
+
 // GetIssuesByIDs return issues with the given IDs.
 func GetIssuesByIDs(issueIDs []int64) ([]*Issue, error) {
 	return getIssuesByIDs(x, issueIDs)
@@ -86,6 +86,12 @@ const (
 	CommentTypeChangeTargetBranch
 	// Delete time manual for time tracking
 	CommentTypeDeleteTimeManual
+	// Pull request merged
+	CommentTypeMergedPR
+	// Pull request marked as ready for review
+	CommentTypeReadyForReview
+	// Pull request merged by an admin overriding the branch protection
+	CommentTypeMergeOverride
 )
 
 // CommentTag defines comment tag type
@@ -146,9 +152,10 @@ type Comment struct {
 	// For view issue page.
 	ShowTag CommentTag `xorm:"-"`
 
-	Review      *Review `xorm:"-"`
-	ReviewID    int64   `xorm:"index"`
-	Invalidated bool
+	Review        *Review `xorm:"-"`
+	ReviewID      int64   `xorm:"index"`
+	Invalidated   bool
+	ResolveDoerID int64 `xorm:"index"`
 
 	// Reference an issue or pull from another comment, issue or PR
 	// All information is about the origin of the reference
@@ -289,6 +296,24 @@ func (c *Comment) APIFormat() *api.Comment {
 	}
 }
 
+// IsResolved returns true if the code comment has been marked as resolved.
+func (c *Comment) IsResolved() bool {
+	return c.ResolveDoerID != 0
+}
+
+// SetResolved marks the code comment as resolved by doer, or as unresolved again.
+func (c *Comment) SetResolved(doer *User, resolved bool) error {
+	if c.Type != CommentTypeCode {
+		return fmt.Errorf("comment %d is not a code comment", c.ID)
+	}
+	c.ResolveDoerID = 0
+	if resolved {
+		c.ResolveDoerID = doer.ID
+	}
+	_, err := x.ID(c.ID).Cols("resolve_doer_id").NoAutoTime().Update(c)
+	return err
+}
+
 // CommentHashTag returns unique hash tag for comment id.
 func CommentHashTag(id int64) string {
 	return fmt.Sprintf("issuecomment-%d", id)
@@ -749,8 +774,12 @@ func CreateRefComment(doer *User, repo *Repository, issue *Issue, content, commi
 
 // GetCommentByID returns the comment by given ID.
 func GetCommentByID(id int64) (*Comment, error) {
+	return getCommentByID(x, id)
+}
+
+func getCommentByID(e Engine, id int64) (*Comment, error) {
 	c := new(Comment)
-	has, err := x.ID(id).Get(c)
+	has, err := e.ID(id).Get(c)
 	if err != nil {
 		return nil, err
 	} else if !has {
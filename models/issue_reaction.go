@@ -7,20 +7,24 @@ package models
 import (
 	"bytes"
 	"fmt"
+	"strings"
+	"time"
 
 	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/timeutil"
 
 	"xorm.io/builder"
 	"xorm.io/xorm"
 )
 
-// Reaction represents a reactions on issues and comments.
+// Reaction represents a reactions on issues, comments and reviews.
 type Reaction struct {
 	ID          int64              `xorm:"pk autoincr"`
 	Type        string             `xorm:"INDEX UNIQUE(s) NOT NULL"`
 	IssueID     int64              `xorm:"INDEX UNIQUE(s) NOT NULL"`
 	CommentID   int64              `xorm:"INDEX UNIQUE(s)"`
+	ReviewID    int64              `xorm:"INDEX UNIQUE(s) NOT NULL DEFAULT 0"`
 	UserID      int64              `xorm:"INDEX UNIQUE(s) NOT NULL"`
 	User        *User              `xorm:"-"`
 	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
@@ -30,6 +34,7 @@ type Reaction struct {
 type FindReactionsOptions struct {
 	IssueID   int64
 	CommentID int64
+	ReviewID  int64
 }
 
 func (opts *FindReactionsOptions) toConds() builder.Cond {
@@ -46,23 +51,118 @@ func (opts *FindReactionsOptions) toConds() builder.Cond {
 	} else if opts.CommentID == -1 {
 		cond = cond.And(builder.Eq{"reaction.comment_id": 0})
 	}
+	//If ReviewID is > 0 only reactions of that review are selected,
+	//reactions of reviews are never part of an explicit Issue Reactions search
+	if opts.ReviewID > 0 {
+		cond = cond.And(builder.Eq{"reaction.review_id": opts.ReviewID})
+	} else if opts.CommentID == -1 || opts.ReviewID == -1 {
+		cond = cond.And(builder.Eq{"reaction.review_id": 0})
+	}
 
 	return cond
 }
 
-// FindCommentReactions returns a ReactionList of all reactions from an comment
-func FindCommentReactions(comment *Comment) (ReactionList, error) {
-	return findReactions(x, FindReactionsOptions{
+// FindCommentReactions returns a ReactionList of all reactions from an comment, with their users
+// as seen by viewer, see ReactionList.HidePrivateUsers.
+func FindCommentReactions(comment *Comment, viewer *User) (ReactionList, error) {
+	return findVisibleReactions(x, FindReactionsOptions{
 		IssueID:   comment.IssueID,
-		CommentID: comment.ID})
+		CommentID: comment.ID,
+	}, viewer)
+}
+
+// ReactionUsers represents the users who reacted with the same type of reaction
+type ReactionUsers struct {
+	// Users contains at most setting.UI.ReactionMaxUserNum users in the order they reacted
+	Users []*User
+	// MoreCount is the number of users not contained in Users
+	MoreCount int
+}
+
+// FindCommentReactionsGrouped returns the users who reacted to the comment grouped by the type
+// of reaction as seen by viewer, e.g. for showing "user1, user2 and 3 more" per type.
+func FindCommentReactionsGrouped(comment *Comment, viewer *User) (map[string]*ReactionUsers, error) {
+	reactions, err := FindCommentReactions(comment, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string]*ReactionUsers)
+	for tp, list := range reactions.GroupByType() {
+		shown := list
+		if len(shown) > setting.UI.ReactionMaxUserNum {
+			shown = shown[:setting.UI.ReactionMaxUserNum]
+		}
+		users := make([]*User, len(shown))
+		for i, reaction := range shown {
+			users[i] = reaction.User
+		}
+		grouped[tp] = &ReactionUsers{
+			Users:     users,
+			MoreCount: list.GetMoreUserCount(),
+		}
+	}
+	return grouped, nil
 }
 
-// FindIssueReactions returns a ReactionList of all reactions from an issue
-func FindIssueReactions(issue *Issue) (ReactionList, error) {
-	return findReactions(x, FindReactionsOptions{
+// FindIssueReactions returns a ReactionList of all reactions from an issue, with their users
+// as seen by viewer, see ReactionList.HidePrivateUsers.
+func FindIssueReactions(issue *Issue, viewer *User) (ReactionList, error) {
+	return findVisibleReactions(x, FindReactionsOptions{
 		IssueID:   issue.ID,
 		CommentID: -1,
-	})
+	}, viewer)
+}
+
+// FindReviewReactions returns a ReactionList of all reactions from a review, with their users
+// as seen by viewer, see ReactionList.HidePrivateUsers.
+func FindReviewReactions(review *Review, viewer *User) (ReactionList, error) {
+	return findVisibleReactions(x, FindReactionsOptions{
+		IssueID:  review.IssueID,
+		ReviewID: review.ID,
+	}, viewer)
+}
+
+func findVisibleReactions(e Engine, opts FindReactionsOptions, viewer *User) (ReactionList, error) {
+	reactions, err := findReactions(e, opts)
+	if err != nil {
+		return nil, err
+	}
+	list := ReactionList(reactions)
+	if _, err = list.loadUsers(e); err != nil {
+		return nil, err
+	}
+	list.HidePrivateUsers(viewer)
+	return list, nil
+}
+
+// GetReactionTotal returns the number of reactions on the issue itself,
+// without the reactions on its comments.
+func (issue *Issue) GetReactionTotal() (int64, error) {
+	return issue.getReactionTotal(x)
+}
+
+func (issue *Issue) getReactionTotal(e Engine) (int64, error) {
+	opts := FindReactionsOptions{
+		IssueID:   issue.ID,
+		CommentID: -1,
+	}
+	return e.Where(opts.toConds()).
+		In("reaction.`type`", setting.UI.Reactions).
+		Count(new(Reaction))
+}
+
+// loadReactionTotal sets ReactionTotal, unless it was loaded for a whole IssueList already
+func (issue *Issue) loadReactionTotal(e Engine) error {
+	if issue.ReactionTotal != nil {
+		return nil
+	}
+	total, err := issue.getReactionTotal(e)
+	if err != nil {
+		return err
+	}
+	issue.ReactionTotal = &total
+	return nil
 }
 
 func findReactions(e Engine, opts FindReactionsOptions) ([]*Reaction, error) {
@@ -83,6 +183,9 @@ func createReaction(e *xorm.Session, opts *ReactionOptions) (*Reaction, error) {
 	if opts.Comment != nil {
 		reaction.CommentID = opts.Comment.ID
 	}
+	if opts.Review != nil {
+		reaction.ReviewID = opts.Review.ID
+	}
 	if _, err := e.Insert(reaction); err != nil {
 		return nil, err
 	}
@@ -90,19 +193,199 @@ func createReaction(e *xorm.Session, opts *ReactionOptions) (*Reaction, error) {
 	return reaction, nil
 }
 
+func getReaction(e Engine, opts *ReactionOptions) (*Reaction, bool, error) {
+	var commentID, reviewID int64
+	if opts.Comment != nil {
+		commentID = opts.Comment.ID
+	}
+	if opts.Review != nil {
+		reviewID = opts.Review.ID
+	}
+	reaction := new(Reaction)
+	has, err := e.Where("`type` = ? AND issue_id = ? AND comment_id = ? AND review_id = ? AND user_id = ?",
+		opts.Type, opts.Issue.ID, commentID, reviewID, opts.Doer.ID).Get(reaction)
+	return reaction, has, err
+}
+
+// DeduplicateReactions deletes identical reactions of the same user on the same issue, comment or review,
+// keeping the earliest one. It returns the number of deleted reactions.
+func DeduplicateReactions() (int64, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return 0, err
+	}
+
+	type reactionGroup struct {
+		Type      string
+		IssueID   int64
+		CommentID int64
+		ReviewID  int64
+		UserID    int64
+		KeepID    int64
+	}
+	groups := make([]*reactionGroup, 0, 10)
+	if err := sess.Table("reaction").
+		Select("`type`, issue_id, COALESCE(comment_id, 0) AS comment_id, review_id, user_id, MIN(id) AS keep_id").
+		GroupBy("`type`, issue_id, COALESCE(comment_id, 0), review_id, user_id").
+		Having("COUNT(*) > 1").
+		Find(&groups); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, group := range groups {
+		cond := builder.Eq{
+			"`type`":    group.Type,
+			"issue_id":  group.IssueID,
+			"review_id": group.ReviewID,
+			"user_id":   group.UserID,
+		}.And(builder.Neq{"id": group.KeepID})
+		if group.CommentID == 0 {
+			cond = cond.And(builder.Eq{"comment_id": 0}.Or(builder.IsNull{"comment_id"}))
+		} else {
+			cond = cond.And(builder.Eq{"comment_id": group.CommentID})
+		}
+		count, err := sess.Where(cond).Delete(new(Reaction))
+		if err != nil {
+			return 0, err
+		}
+		deleted += count
+	}
+
+	// NULL comment IDs are not covered by the unique index
+	if _, err := sess.Exec("UPDATE reaction SET comment_id = 0 WHERE comment_id IS NULL"); err != nil {
+		return 0, err
+	}
+
+	if err := sess.Commit(); err != nil {
+		return 0, err
+	}
+	return deleted, nil
+}
+
+// reactionAliases maps the GitHub-style aliases and emoji clients commonly
+// send to the canonical reaction content stored in the database.
+var reactionAliases = map[string]string{
+	"+1":          "+1",
+	"thumbsup":    "+1",
+	"thumbs_up":   "+1",
+	"👍":           "+1",
+	"-1":          "-1",
+	"thumbsdown":  "-1",
+	"thumbs_down": "-1",
+	"👎":           "-1",
+	"laugh":       "laugh",
+	"smile":       "laugh",
+	"laughing":    "laugh",
+	"😄":           "laugh",
+	"hooray":      "hooray",
+	"tada":        "hooray",
+	"🎉":           "hooray",
+	"confused":    "confused",
+	"😕":           "confused",
+	"heart":       "heart",
+	"❤":           "heart",
+	"❤️":          "heart",
+	"rocket":      "rocket",
+	"🚀":           "rocket",
+	"eyes":        "eyes",
+	"👀":           "eyes",
+}
+
+// NormalizeReactionContent maps a reaction alias (e.g. ":+1:", "thumbsup" or "👍")
+// to its canonical stored form and reports whether the content was recognized.
+func NormalizeReactionContent(s string) (string, bool) {
+	content := strings.ToLower(strings.TrimSpace(s))
+	if len(content) > 2 && strings.HasPrefix(content, ":") && strings.HasSuffix(content, ":") {
+		content = content[1 : len(content)-1]
+	}
+	if canonical, ok := reactionAliases[content]; ok {
+		return canonical, true
+	}
+	// Instance specific reactions are stored as configured
+	if setting.UI.ReactionsMap[content] {
+		return content, true
+	}
+	return s, false
+}
+
+// CanReadReactions returns true if the user is allowed to see the reactions on the issues
+// and pull requests of the repository. Anonymous users are never allowed.
+func CanReadReactions(doer *User, repo *Repository) (bool, error) {
+	if doer == nil {
+		return false, nil
+	}
+
+	perm, err := GetUserRepoPermission(repo, doer)
+	if err != nil {
+		return false, err
+	}
+	return perm.CanRead(UnitTypeIssues) || perm.CanRead(UnitTypePullRequests), nil
+}
+
+// CanReactOn returns true if the user is allowed to add or remove reactions on the issue,
+// its comments and reviews. Reacting on a locked issue requires write access.
+func CanReactOn(doer *User, issue *Issue) (bool, error) {
+	if doer == nil {
+		return false, nil
+	}
+
+	if err := issue.LoadRepo(); err != nil {
+		return false, err
+	}
+	perm, err := GetUserRepoPermission(issue.Repo, doer)
+	if err != nil {
+		return false, err
+	}
+	if !perm.CanReadIssuesOrPulls(issue.IsPull) {
+		return false, nil
+	}
+	return !issue.IsLocked || perm.CanWriteIssuesOrPulls(issue.IsPull), nil
+}
+
+// IsReactionAllowed returns true if the canonical reaction content may be used in the repository.
+// It must be a reaction of the instance and, if the repository restricts its reactions, one of those.
+func (repo *Repository) IsReactionAllowed(content string) bool {
+	if !setting.UI.ReactionsMap[content] {
+		return false
+	}
+
+	u, err := repo.GetUnit(UnitTypeIssues)
+	if err != nil {
+		return true
+	}
+	allowed := u.IssuesConfig().AllowedReactions
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, reaction := range allowed {
+		if reaction == content {
+			return true
+		}
+	}
+	return false
+}
+
 // ReactionOptions defines options for creating or deleting reactions
 type ReactionOptions struct {
 	Type    string
 	Doer    *User
 	Issue   *Issue
 	Comment *Comment
+	Review  *Review
 }
 
-// CreateReaction creates reaction for issue or comment.
+// CreateReaction creates reaction for issue, comment or review.
 func CreateReaction(opts *ReactionOptions) (reaction *Reaction, err error) {
-	if !setting.UI.ReactionsMap[opts.Type] {
+	if err = opts.Issue.LoadRepo(); err != nil {
+		return nil, err
+	}
+	content, ok := NormalizeReactionContent(opts.Type)
+	if !ok || !opts.Issue.Repo.IsReactionAllowed(content) {
 		return nil, ErrForbiddenIssueReaction{opts.Type}
 	}
+	opts.Type = content
 
 	sess := x.NewSession()
 	defer sess.Close()
@@ -112,6 +395,14 @@ func CreateReaction(opts *ReactionOptions) (reaction *Reaction, err error) {
 
 	reaction, err = createReaction(sess, opts)
 	if err != nil {
+		// a repeated submit of the same reaction violates the unique index,
+		// which only means that the user has already reacted
+		if errRollback := sess.Rollback(); errRollback != nil {
+			return nil, err
+		}
+		if existing, has, errGet := getReaction(x, opts); errGet == nil && has {
+			return existing, nil
+		}
 		return nil, err
 	}
 
@@ -140,21 +431,113 @@ func CreateCommentReaction(doer *User, issue *Issue, comment *Comment, content s
 	})
 }
 
+// CreateReactionsWithTime creates the reactions in one transaction, keeping their CreatedUnix,
+// e.g. when importing reactions of another instance. The content of each reaction is normalized
+// and has to be allowed in the repository of its issue, and its comment or review has to belong
+// to its issue. Reactions which exist already are skipped.
+func CreateReactionsWithTime(reactions []*Reaction) error {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	issues := make(map[int64]*Issue)
+	for _, reaction := range reactions {
+		opts, err := getReactionOptions(sess, issues, reaction)
+		if err != nil {
+			return err
+		}
+
+		// the same reaction may also be given more than once in reactions
+		if _, has, err := getReaction(sess, opts); err != nil {
+			return err
+		} else if has {
+			continue
+		}
+
+		reaction.Type = opts.Type
+		if reaction.CreatedUnix == 0 {
+			reaction.CreatedUnix = timeutil.TimeStampNow()
+		}
+		if _, err := sess.NoAutoTime().Insert(reaction); err != nil {
+			return err
+		}
+	}
+
+	return sess.Commit()
+}
+
+// getReactionOptions validates the target of the reaction and returns it as ReactionOptions
+func getReactionOptions(e Engine, issues map[int64]*Issue, reaction *Reaction) (*ReactionOptions, error) {
+	issue, ok := issues[reaction.IssueID]
+	if !ok {
+		var err error
+		if issue, err = getIssueByID(e, reaction.IssueID); err != nil {
+			return nil, err
+		}
+		if err = issue.loadRepo(e); err != nil {
+			return nil, err
+		}
+		issues[issue.ID] = issue
+	}
+
+	content, ok := NormalizeReactionContent(reaction.Type)
+	if !ok || !issue.Repo.IsReactionAllowed(content) {
+		return nil, ErrForbiddenIssueReaction{reaction.Type}
+	}
+
+	doer, err := getUserByID(e, reaction.UserID)
+	if err != nil {
+		return nil, err
+	}
+	opts := &ReactionOptions{
+		Type:  content,
+		Doer:  doer,
+		Issue: issue,
+	}
+
+	if reaction.CommentID > 0 {
+		if opts.Comment, err = getCommentByID(e, reaction.CommentID); err != nil {
+			return nil, err
+		} else if opts.Comment.IssueID != issue.ID {
+			return nil, ErrCommentNotExist{reaction.CommentID, issue.ID}
+		}
+	}
+	if reaction.ReviewID > 0 {
+		if opts.Review, err = getReviewByID(e, reaction.ReviewID); err != nil {
+			return nil, err
+		} else if opts.Review.IssueID != issue.ID {
+			return nil, ErrReviewNotExist{reaction.ReviewID}
+		}
+	}
+	return opts, nil
+}
+
 func deleteReaction(e *xorm.Session, opts *ReactionOptions) error {
 	reaction := &Reaction{
 		Type:    opts.Type,
 		UserID:  opts.Doer.ID,
 		IssueID: opts.Issue.ID,
 	}
+	var commentID, reviewID int64
 	if opts.Comment != nil {
-		reaction.CommentID = opts.Comment.ID
+		commentID = opts.Comment.ID
+	}
+	if opts.Review != nil {
+		reviewID = opts.Review.ID
 	}
-	_, err := e.Delete(reaction)
+	// Zero IDs are ignored by a bean based delete, so restrict them explicitly
+	_, err := e.Where("comment_id = ? AND review_id = ?", commentID, reviewID).Delete(reaction)
 	return err
 }
 
-// DeleteReaction deletes reaction for issue or comment.
+// DeleteReaction deletes reaction for issue, comment or review.
 func DeleteReaction(opts *ReactionOptions) error {
+	if content, ok := NormalizeReactionContent(opts.Type); ok {
+		opts.Type = content
+	}
+
 	sess := x.NewSession()
 	defer sess.Close()
 	if err := sess.Begin(); err != nil {
@@ -177,6 +560,68 @@ func DeleteIssueReaction(doer *User, issue *Issue, content string) error {
 	})
 }
 
+// SetIssueReactions replaces the reactions of doer on issue by exactly the given contents
+// in a single transaction, and returns the resulting reactions of doer.
+// All contents are validated first, so a single forbidden reaction rejects all of them.
+func SetIssueReactions(doer *User, issue *Issue, contents []string) (ReactionList, error) {
+	if err := issue.LoadRepo(); err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool, len(contents))
+	for _, c := range contents {
+		content, ok := NormalizeReactionContent(c)
+		if !ok || !issue.Repo.IsReactionAllowed(content) {
+			return nil, ErrForbiddenIssueReaction{c}
+		}
+		wanted[content] = true
+	}
+
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	existing := make([]*Reaction, 0, len(wanted))
+	if err := sess.Where("issue_id = ? AND comment_id = ? AND review_id = ? AND user_id = ?", issue.ID, 0, 0, doer.ID).
+		Find(&existing); err != nil {
+		return nil, err
+	}
+
+	for _, reaction := range existing {
+		if wanted[reaction.Type] {
+			delete(wanted, reaction.Type)
+			continue
+		}
+		if _, err := sess.ID(reaction.ID).Delete(new(Reaction)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, content := range setting.UI.Reactions {
+		if !wanted[content] {
+			continue
+		}
+		if _, err := createReaction(sess, &ReactionOptions{
+			Type:  content,
+			Doer:  doer,
+			Issue: issue,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	reactions := make(ReactionList, 0, len(contents))
+	if err := sess.Where("issue_id = ? AND comment_id = ? AND review_id = ? AND user_id = ?", issue.ID, 0, 0, doer.ID).
+		Asc("created_unix", "id").
+		Find(&reactions); err != nil {
+		return nil, err
+	}
+
+	return reactions, sess.Commit()
+}
+
 // DeleteCommentReaction deletes a reaction on comment.
 func DeleteCommentReaction(doer *User, issue *Issue, comment *Comment, content string) error {
 	return DeleteReaction(&ReactionOptions{
@@ -187,6 +632,85 @@ func DeleteCommentReaction(doer *User, issue *Issue, comment *Comment, content s
 	})
 }
 
+// CreateReviewReaction creates a reaction on review.
+func CreateReviewReaction(doer *User, review *Review, content string) (*Reaction, error) {
+	if review.Issue == nil {
+		if err := review.loadIssue(x); err != nil {
+			return nil, err
+		}
+	}
+	return CreateReaction(&ReactionOptions{
+		Type:   content,
+		Doer:   doer,
+		Issue:  review.Issue,
+		Review: review,
+	})
+}
+
+// DeleteReviewReaction deletes a reaction on review.
+func DeleteReviewReaction(doer *User, review *Review, content string) error {
+	if review.Issue == nil {
+		if err := review.loadIssue(x); err != nil {
+			return err
+		}
+	}
+	return DeleteReaction(&ReactionOptions{
+		Type:   content,
+		Doer:   doer,
+		Issue:  review.Issue,
+		Review: review,
+	})
+}
+
+// ReactionDump is the exported form of a reaction. It refers to the reacting user by the name
+// and ID of the user, so that it can be mapped to a user of another instance.
+type ReactionDump struct {
+	Type     string
+	UserID   int64
+	UserName string
+	Created  time.Time
+}
+
+// APIFormat converts a ReactionDump to an api.ReactionDump
+func (dump *ReactionDump) APIFormat() *api.ReactionDump {
+	return &api.ReactionDump{
+		UserID:   dump.UserID,
+		UserName: dump.UserName,
+		Reaction: dump.Type,
+		Created:  dump.Created,
+	}
+}
+
+// DumpReactions returns every stored reaction of the issue itself, i.e. without the reactions of
+// its comments and reviews, in the order they were added. Unlike FindIssueReactions it keeps
+// reactions of types which are not allowed anymore and the users who keep their reactions private.
+func (issue *Issue) DumpReactions() ([]*ReactionDump, error) {
+	opts := FindReactionsOptions{
+		IssueID:   issue.ID,
+		CommentID: -1,
+	}
+	reactions := make([]*Reaction, 0, 10)
+	if err := x.Where(opts.toConds()).
+		Asc("reaction.created_unix", "reaction.id").
+		Find(&reactions); err != nil {
+		return nil, err
+	}
+	if _, err := ReactionList(reactions).LoadUsers(); err != nil {
+		return nil, err
+	}
+
+	dumps := make([]*ReactionDump, len(reactions))
+	for i, reaction := range reactions {
+		dumps[i] = &ReactionDump{
+			Type:     reaction.Type,
+			UserID:   reaction.UserID,
+			UserName: reaction.User.Name,
+			Created:  reaction.CreatedUnix.AsTime(),
+		}
+	}
+	return dumps, nil
+}
+
 // LoadUser load user of reaction
 func (r *Reaction) LoadUser() (*User, error) {
 	if r.User != nil {
@@ -280,6 +804,22 @@ func (list ReactionList) GetFirstUsers() string {
 	return buffer.String()
 }
 
+// HidePrivateUsers replaces the users of the reactions by users who keep their reactions
+// private with the placeholder of NewPrivateReactionUser, unless viewer is an admin or the
+// user themselves. The reactions are still listed, so they are counted as before.
+// The users of the reactions have to be loaded already.
+func (list ReactionList) HidePrivateUsers(viewer *User) {
+	for _, reaction := range list {
+		if reaction.User == nil || !reaction.User.KeepReactionsPrivate {
+			continue
+		}
+		if viewer != nil && (viewer.IsAdmin || viewer.ID == reaction.UserID) {
+			continue
+		}
+		reaction.User = NewPrivateReactionUser()
+	}
+}
+
 // GetMoreUserCount returns count of not shown users in reaction tooltip
 func (list ReactionList) GetMoreUserCount() int {
 	if len(list) <= setting.UI.ReactionMaxUserNum {
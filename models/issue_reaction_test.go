@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -44,15 +45,128 @@ func TestIssueAddDuplicateReaction(t *testing.T) {
 
 	addReaction(t, user1, issue1, nil, "heart")
 
+	existing := AssertExistsAndLoadBean(t, &Reaction{Type: "heart", UserID: user1.ID, IssueID: issue1.ID}).(*Reaction)
+
+	// reacting again is a no-op
 	reaction, err := CreateReaction(&ReactionOptions{
 		Doer:  user1,
 		Issue: issue1,
 		Type:  "heart",
 	})
-	assert.Error(t, err)
+	assert.NoError(t, err)
+	if assert.NotNil(t, reaction) {
+		assert.Equal(t, existing.ID, reaction.ID)
+	}
+
+	count, err := x.Where("`type` = ? AND user_id = ? AND issue_id = ?", "heart", user1.ID, issue1.ID).Count(new(Reaction))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+}
+
+func TestDeduplicateReactions(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// the unique index does not cover NULL comment IDs, so duplicates can only exist with those
+	for i := 0; i < 3; i++ {
+		_, err := x.Exec("INSERT INTO reaction (`type`, issue_id, comment_id, review_id, user_id, created_unix) VALUES (?, ?, NULL, 0, ?, ?)",
+			"heart", 1, 1, 100+i)
+		assert.NoError(t, err)
+	}
+	var first Reaction
+	has, err := x.Where("`type` = ? AND issue_id = ? AND user_id = ?", "heart", 1, 1).Asc("id").Get(&first)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	deleted, err := DeduplicateReactions()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, deleted)
+
+	reaction := AssertExistsAndLoadBean(t, &Reaction{Type: "heart", IssueID: 1, UserID: 1}).(*Reaction)
+	assert.Equal(t, first.ID, reaction.ID)
+	assert.EqualValues(t, 0, reaction.CommentID)
+
+	deleted, err = DeduplicateReactions()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, deleted)
+}
+
+func TestCreateReactionsWithTime(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	reactions := []*Reaction{
+		{Type: "heart", IssueID: 1, UserID: 1, CreatedUnix: 946684800},
+		{Type: ":+1:", IssueID: 1, CommentID: 2, UserID: 3, CreatedUnix: 946684801},
+		{Type: "heart", IssueID: 1, UserID: 1, CreatedUnix: 946684802},
+		{Type: "eyes", IssueID: 1, UserID: 2, CreatedUnix: 946684803},
+	}
+	assert.NoError(t, CreateReactionsWithTime(reactions))
+
+	reaction := AssertExistsAndLoadBean(t, &Reaction{Type: "heart", IssueID: 1, UserID: 1}).(*Reaction)
+	assert.EqualValues(t, 946684800, reaction.CreatedUnix)
+	reaction = AssertExistsAndLoadBean(t, &Reaction{Type: "+1", IssueID: 1, CommentID: 2, UserID: 3}).(*Reaction)
+	assert.EqualValues(t, 946684801, reaction.CreatedUnix)
+	AssertCount(t, &Reaction{Type: "heart", IssueID: 1, UserID: 1}, 1)
+	reaction = AssertExistsAndLoadBean(t, &Reaction{Type: "eyes", IssueID: 1, UserID: 2}).(*Reaction)
+	assert.EqualValues(t, 1573248003, reaction.CreatedUnix)
+
+	// invalid reactions roll back all reactions
+	err := CreateReactionsWithTime([]*Reaction{
+		{Type: "laugh", IssueID: 1, UserID: 4, CreatedUnix: 946684804},
+		{Type: "zzz", IssueID: 1, UserID: 4, CreatedUnix: 946684805},
+	})
+	assert.True(t, IsErrForbiddenIssueReaction(err))
+	AssertNotExistsBean(t, &Reaction{Type: "laugh", IssueID: 1, UserID: 4})
+
+	err = CreateReactionsWithTime([]*Reaction{{Type: "laugh", IssueID: 1, CommentID: 4, UserID: 4}})
+	assert.True(t, IsErrCommentNotExist(err))
+	AssertNotExistsBean(t, &Reaction{Type: "laugh", IssueID: 1, UserID: 4})
+}
+
+func TestIssueAddAliasReaction(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	user1 := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+
+	issue1 := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+
+	addReaction(t, user1, issue1, nil, ":+1:")
+	existing := AssertExistsAndLoadBean(t, &Reaction{Type: "+1", UserID: user1.ID, IssueID: issue1.ID}).(*Reaction)
+
+	// The same reaction sent as emoji is a duplicate
+	reaction, err := CreateIssueReaction(user1, issue1, "👍")
+	assert.NoError(t, err)
+	if assert.NotNil(t, reaction) {
+		assert.Equal(t, existing.ID, reaction.ID)
+	}
+
+	reaction, err = CreateIssueReaction(user1, issue1, "not-a-reaction")
+	assert.True(t, IsErrForbiddenIssueReaction(err))
 	assert.Nil(t, reaction)
+}
 
-	AssertExistsAndLoadBean(t, &Reaction{Type: "heart", UserID: user1.ID, IssueID: issue1.ID})
+func TestNormalizeReactionContent(t *testing.T) {
+	for _, test := range []struct {
+		input      string
+		expected   string
+		recognized bool
+	}{
+		{"+1", "+1", true},
+		{":+1:", "+1", true},
+		{"thumbsup", "+1", true},
+		{"👍", "+1", true},
+		{":-1:", "-1", true},
+		{"👎", "-1", true},
+		{":tada:", "hooray", true},
+		{"Heart", "heart", true},
+		{"❤️", "heart", true},
+		{"eyes", "eyes", true},
+		{"unknown", "unknown", false},
+		{"::", "::", false},
+	} {
+		content, ok := NormalizeReactionContent(test.input)
+		assert.Equal(t, test.recognized, ok, test.input)
+		assert.Equal(t, test.expected, content, test.input)
+	}
 }
 
 func TestIssueDeleteReaction(t *testing.T) {
@@ -108,6 +222,35 @@ func TestIssueReactionCount(t *testing.T) {
 	assert.Len(t, reactions["-1"], 1)
 }
 
+func TestSetIssueReactions(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	user2 := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	issue1 := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+
+	reactions, err := SetIssueReactions(user2, issue1, []string{"heart", "👀", ":heart:"})
+	assert.NoError(t, err)
+	if assert.Len(t, reactions, 2) {
+		assert.Equal(t, "eyes", reactions[0].Type)
+		assert.Equal(t, "heart", reactions[1].Type)
+	}
+	// reactions not in the set are removed, comment reactions are kept
+	AssertNotExistsBean(t, &Reaction{ID: 1})
+	AssertExistsAndLoadBean(t, &Reaction{ID: 3})
+	AssertExistsAndLoadBean(t, &Reaction{ID: 4})
+
+	// a single forbidden reaction rejects the whole set
+	_, err = SetIssueReactions(user2, issue1, []string{"rocket", "zzz"})
+	assert.True(t, IsErrForbiddenIssueReaction(err))
+	AssertNotExistsBean(t, &Reaction{Type: "rocket", UserID: user2.ID, IssueID: issue1.ID})
+
+	reactions, err = SetIssueReactions(user2, issue1, nil)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 0)
+	AssertNotExistsBean(t, &Reaction{ID: 3})
+	AssertExistsAndLoadBean(t, &Reaction{ID: 4})
+}
+
 func TestIssueCommentAddReaction(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 
@@ -150,6 +293,57 @@ func TestIssueCommentDeleteReaction(t *testing.T) {
 	assert.Len(t, reactions["+1"], 1)
 }
 
+func TestFindCommentReactionsGrouped(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	setting.UI.ReactionMaxUserNum = 2
+
+	user1 := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	user2 := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	user3 := AssertExistsAndLoadBean(t, &User{ID: 3}).(*User)
+	user4 := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+
+	issue1 := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+
+	comment1 := AssertExistsAndLoadBean(t, &Comment{ID: 1}).(*Comment)
+
+	addReaction(t, user1, issue1, comment1, "heart")
+	addReaction(t, user2, issue1, comment1, "heart")
+	addReaction(t, user3, issue1, comment1, "heart")
+	addReaction(t, user4, issue1, comment1, "+1")
+
+	grouped, err := FindCommentReactionsGrouped(comment1, nil)
+	assert.NoError(t, err)
+	assert.Len(t, grouped, 2)
+	if assert.Contains(t, grouped, "heart") {
+		assert.Len(t, grouped["heart"].Users, 2)
+		assert.Equal(t, user1.ID, grouped["heart"].Users[0].ID)
+		assert.Equal(t, user2.ID, grouped["heart"].Users[1].ID)
+		assert.Equal(t, 1, grouped["heart"].MoreCount)
+	}
+	if assert.Contains(t, grouped, "+1") {
+		assert.Len(t, grouped["+1"].Users, 1)
+		assert.Equal(t, user4.Name, grouped["+1"].Users[0].Name)
+		assert.Equal(t, 0, grouped["+1"].MoreCount)
+	}
+
+	// users who keep their reactions private are only shown to themselves
+	user2.KeepReactionsPrivate = true
+	assert.NoError(t, UpdateUser(user2))
+	grouped, err = FindCommentReactionsGrouped(comment1, user4)
+	assert.NoError(t, err)
+	assert.Equal(t, NewPrivateReactionUser().Name, grouped["heart"].Users[1].Name)
+	grouped, err = FindCommentReactionsGrouped(comment1, user2)
+	assert.NoError(t, err)
+	assert.Equal(t, user2.Name, grouped["heart"].Users[1].Name)
+
+	// comment without reactions
+	comment3 := AssertExistsAndLoadBean(t, &Comment{ID: 3}).(*Comment)
+	grouped, err = FindCommentReactionsGrouped(comment3, nil)
+	assert.NoError(t, err)
+	assert.Len(t, grouped, 0)
+}
+
 func TestIssueCommentReactionCount(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 
@@ -164,3 +358,260 @@ func TestIssueCommentReactionCount(t *testing.T) {
 
 	AssertNotExistsBean(t, &Reaction{Type: "heart", UserID: user1.ID, IssueID: issue1.ID, CommentID: comment1.ID})
 }
+
+func TestIssue_GetReactionTotal(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	issue1 := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+	issue2 := AssertExistsAndLoadBean(t, &Issue{ID: 2}).(*Issue)
+
+	// reactions of a type which is not allowed anymore and comment reactions are not counted
+	total, err := issue1.GetReactionTotal()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+	assert.EqualValues(t, 1, issue1.APIFormat().Reactions)
+
+	total, err = issue2.GetReactionTotal()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, total)
+}
+
+func TestIssueList_LoadReactionTotals(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	issues := IssueList{
+		AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue),
+		AssertExistsAndLoadBean(t, &Issue{ID: 2}).(*Issue),
+	}
+	assert.NoError(t, issues.LoadReactionTotals())
+	assert.EqualValues(t, 1, *issues[0].ReactionTotal)
+	assert.EqualValues(t, 0, *issues[1].ReactionTotal)
+
+	// the loaded totals are used as they are
+	*issues[1].ReactionTotal = 3
+	assert.EqualValues(t, 3, issues[1].APIFormat().Reactions)
+}
+
+func TestIssue_DumpReactions(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	issue1 := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+	user1 := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	user1.KeepReactionsPrivate = true
+	assert.NoError(t, UpdateUserCols(user1, "keep_reactions_private"))
+	addReaction(t, user1, issue1, nil, "heart")
+
+	// comment reactions are not dumped, but reactions of a type which is not allowed anymore and
+	// private reaction users are
+	dumps, err := issue1.DumpReactions()
+	assert.NoError(t, err)
+	if assert.Len(t, dumps, 4) {
+		assert.Equal(t, &ReactionDump{
+			Type:     "zzz",
+			UserID:   2,
+			UserName: "user2",
+			Created:  timeutil.TimeStamp(1573248001).AsTime(),
+		}, dumps[0])
+		assert.Equal(t, "zzz", dumps[1].Type)
+		assert.Equal(t, "user1", dumps[1].UserName)
+		assert.Equal(t, "eyes", dumps[2].Type)
+		assert.Equal(t, "heart", dumps[3].Type)
+		assert.EqualValues(t, 1, dumps[3].UserID)
+		assert.Equal(t, "user1", dumps[3].UserName)
+	}
+
+	issue2 := AssertExistsAndLoadBean(t, &Issue{ID: 2}).(*Issue)
+	dumps, err = issue2.DumpReactions()
+	assert.NoError(t, err)
+	assert.Empty(t, dumps)
+}
+
+func TestIssue_DumpReactionsRoundTrip(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	issue1 := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+	issue2 := AssertExistsAndLoadBean(t, &Issue{ID: 2}).(*Issue)
+	user1 := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	addReaction(t, user1, issue1, nil, "heart")
+	_, err := x.Delete(&Reaction{Type: "zzz"})
+	assert.NoError(t, err)
+
+	dumps, err := issue1.DumpReactions()
+	assert.NoError(t, err)
+	assert.Len(t, dumps, 2)
+
+	// import the dump into another issue the way a migration does, mapping the users by name
+	reactions := make([]*Reaction, 0, len(dumps))
+	for _, dump := range dumps {
+		user, err := GetUserByName(dump.UserName)
+		assert.NoError(t, err)
+		reactions = append(reactions, &Reaction{
+			Type:        dump.Type,
+			IssueID:     issue2.ID,
+			UserID:      user.ID,
+			CreatedUnix: timeutil.TimeStamp(dump.Created.Unix()),
+		})
+	}
+	assert.NoError(t, CreateReactionsWithTime(reactions))
+
+	imported, err := issue2.DumpReactions()
+	assert.NoError(t, err)
+	assert.Equal(t, dumps, imported)
+}
+
+func TestReviewAddAndDeleteReaction(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	user1 := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	review1 := AssertExistsAndLoadBean(t, &Review{ID: 1}).(*Review)
+	issue2 := AssertExistsAndLoadBean(t, &Issue{ID: review1.IssueID}).(*Issue)
+
+	// the same reaction on the issue and on one of its reviews do not conflict
+	addReaction(t, user1, issue2, nil, "heart")
+	reaction, err := CreateReviewReaction(user1, review1, "heart")
+	assert.NoError(t, err)
+	assert.EqualValues(t, review1.ID, reaction.ReviewID)
+
+	reaction, err = CreateReviewReaction(user1, review1, "not-a-reaction")
+	assert.True(t, IsErrForbiddenIssueReaction(err))
+	assert.Nil(t, reaction)
+
+	reactions, err := FindReviewReactions(review1, nil)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 1)
+
+	// review reactions are not part of the issue reactions
+	reactions, err = FindIssueReactions(issue2, nil)
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 1)
+	total, err := issue2.GetReactionTotal()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, total)
+
+	assert.NoError(t, DeleteReviewReaction(user1, review1, "heart"))
+	AssertNotExistsBean(t, &Reaction{Type: "heart", UserID: user1.ID, ReviewID: review1.ID})
+	AssertExistsAndLoadBean(t, &Reaction{Type: "heart", UserID: user1.ID, IssueID: issue2.ID}, "review_id = 0")
+}
+
+func TestCanReadReactions(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	publicRepo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	privateRepo := AssertExistsAndLoadBean(t, &Repository{ID: 3}).(*Repository)
+	admin := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	member := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	nonMember := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+
+	for _, test := range []struct {
+		doer     *User
+		repo     *Repository
+		expected bool
+	}{
+		{nil, publicRepo, false},
+		{nil, privateRepo, false},
+		{nonMember, publicRepo, true},
+		{nonMember, privateRepo, false},
+		{member, privateRepo, true},
+		{admin, privateRepo, true},
+	} {
+		canRead, err := CanReadReactions(test.doer, test.repo)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, canRead, "doer: %v, repo: %d", test.doer, test.repo.ID)
+	}
+}
+
+func TestCanReactOn(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	publicIssue := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+	privateIssue := AssertExistsAndLoadBean(t, &Issue{ID: 6}).(*Issue)
+	admin := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	member := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	nonMember := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+
+	check := func(doer *User, issue *Issue, expected bool) {
+		canReact, err := CanReactOn(doer, issue)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, canReact, "doer: %v, issue: %d, locked: %v", doer, issue.ID, issue.IsLocked)
+	}
+
+	check(nil, publicIssue, false)
+	check(nonMember, publicIssue, true)
+	check(nonMember, privateIssue, false)
+	check(member, privateIssue, true)
+	check(admin, privateIssue, true)
+
+	// locked issues need write access
+	publicIssue.IsLocked = true
+	check(nonMember, publicIssue, false)
+	check(member, publicIssue, true)
+	check(admin, publicIssue, true)
+}
+
+func TestRepository_IsReactionAllowed(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	setAllowedReactions := func(allowed []string) *Repository {
+		repo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+		unit, err := repo.GetUnit(UnitTypeIssues)
+		assert.NoError(t, err)
+		unit.IssuesConfig().AllowedReactions = allowed
+		_, err = x.ID(unit.ID).Cols("config").Update(unit)
+		assert.NoError(t, err)
+		return AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	}
+
+	// an empty allowlist inherits the reactions of the instance
+	repo1 := setAllowedReactions(nil)
+	for _, content := range setting.UI.Reactions {
+		assert.True(t, repo1.IsReactionAllowed(content), content)
+	}
+	assert.False(t, repo1.IsReactionAllowed("zzz"))
+
+	// the repository can only restrict the reactions of the instance
+	repo1 = setAllowedReactions([]string{"heart", "zzz"})
+	assert.True(t, repo1.IsReactionAllowed("heart"))
+	assert.False(t, repo1.IsReactionAllowed("laugh"))
+	assert.False(t, repo1.IsReactionAllowed("zzz"))
+
+	user2 := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	issue1 := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+	_, err := CreateIssueReaction(user2, issue1, "laugh")
+	assert.True(t, IsErrForbiddenIssueReaction(err))
+	_, err = SetIssueReactions(user2, issue1, []string{"heart", "laugh"})
+	assert.True(t, IsErrForbiddenIssueReaction(err))
+	addReaction(t, user2, issue1, nil, ":heart:")
+	AssertExistsAndLoadBean(t, &Reaction{Type: "heart", UserID: user2.ID, IssueID: issue1.ID})
+}
+
+func TestReactionList_HidePrivateUsers(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	admin := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	user2 := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	user4 := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	issue := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+
+	user2.KeepReactionsPrivate = true
+	assert.NoError(t, UpdateUser(user2))
+	addReaction(t, user2, issue, nil, "heart")
+	addReaction(t, user4, issue, nil, "heart")
+
+	userNames := func(viewer *User) []string {
+		reactions, err := FindIssueReactions(issue, viewer)
+		assert.NoError(t, err)
+		assert.Len(t, reactions, 3)
+		names := make([]string, len(reactions))
+		for i, reaction := range reactions {
+			names[i] = reaction.User.Name
+		}
+		return names
+	}
+
+	anonymous := NewPrivateReactionUser().Name
+	// user2 already reacted to issue1 (see fixtures/reaction.yml)
+	assert.Equal(t, []string{anonymous, anonymous, user4.Name}, userNames(nil))
+	assert.Equal(t, []string{anonymous, anonymous, user4.Name}, userNames(user4))
+	assert.Equal(t, []string{user2.Name, user2.Name, user4.Name}, userNames(user2))
+	assert.Equal(t, []string{user2.Name, user2.Name, user4.Name}, userNames(admin))
+}
@@ -288,6 +288,54 @@ var migrations = []Migration{
 	NewMigration("add user_id prefix to existing user avatar name", renameExistingUserAvatarName),
 	// v116 -> v117
 	NewMigration("Extend TrackedTimes", extendTrackedTimes),
+	// v117 -> v118
+	NewMigration("Add dismiss stale approvals to protected branch and stale to review", addDismissStaleApprovals),
+	// v118 -> v119
+	NewMigration("Add bounce tracking to email addresses", addEmailBounceTracking),
+	// v119 -> v120
+	NewMigration("Add verification sent time to email addresses", addEmailVerificationSentUnix),
+	// v120 -> v121
+	NewMigration("Add created unix to email address", addEmailAddressCreatedUnix),
+	// v121 -> v122
+	NewMigration("Add is shallow to repository", addIsShallowToRepository),
+	// v122 -> v123
+	NewMigration("Add resolve doer to code comments", addResolveDoerIDToComment),
+	// v123 -> v124
+	NewMigration("Add merge hold to pull requests", addMergeHoldToPullRequest),
+	// v124 -> v125
+	NewMigration("Add canonical email to email address", addCanonicalEmailToEmailAddress),
+	// v125 -> v126
+	NewMigration("Add protected file patterns to protected branch", addProtectedFilePatterns),
+	// v126 -> v127
+	NewMigration("Add review id to reaction", addReviewIDToReaction),
+	// v127 -> v128
+	NewMigration("Add last tested commits to pull request", addLastTestedSHAsToPullRequest),
+	// v128 -> v129
+	NewMigration("Add result to task", addResultToTask),
+	// v129 -> v130
+	NewMigration("Add warnings to task", addWarningsToTask),
+	// v130 -> v131
+	NewMigration("Add commit id to review", addCommitIDToReview),
+	// v131 -> v132
+	NewMigration("Add pull request deployment table", addPullRequestDeploymentTable),
+	// v132 -> v133
+	NewMigration("Add require linear history to protected branch", addRequireLinearHistoryToProtectedBranch),
+	// v133 -> v134
+	NewMigration("Add binary conflicted files to pull request", addBinaryConflictedFilesToPullRequest),
+	// v134 -> v135
+	NewMigration("Add keep reactions private to user", addKeepReactionsPrivateToUser),
+	// v135 -> v136
+	NewMigration("Deduplicate reactions and enforce their unique index", deduplicateReactions),
+	// v136 -> v137
+	NewMigration("Add require resolved conversations to protected branch", addRequireResolvedConversationsToProtectedBranch),
+	// v137 -> v138
+	NewMigration("Add require verified commit authors to repository", addRequireVerifiedCommitAuthorsToRepository),
+	// v138 -> v139
+	NewMigration("Add notify on reactions to user", addNotifyOnReactionsToUser),
+	// v139 -> v140
+	NewMigration("Add checkpoint to task", addCheckpointToTask),
+	// v140 -> v141
+	NewMigration("Add head updated time to pull request", addHeadUpdatedUnixToPullRequest),
 }
 
 // Migrate database to current version
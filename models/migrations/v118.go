@@ -0,0 +1,20 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addEmailBounceTracking(x *xorm.Engine) error {
+	type EmailAddress struct {
+		BounceCount int                `xorm:"NOT NULL DEFAULT 0"`
+		LastBounce  timeutil.TimeStamp `xorm:"INDEX"`
+	}
+
+	return x.Sync2(new(EmailAddress))
+}
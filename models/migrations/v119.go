@@ -0,0 +1,19 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addEmailVerificationSentUnix(x *xorm.Engine) error {
+	type EmailAddress struct {
+		VerificationSentUnix timeutil.TimeStamp
+	}
+
+	return x.Sync2(new(EmailAddress))
+}
@@ -0,0 +1,26 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addEmailAddressCreatedUnix(x *xorm.Engine) error {
+	type EmailAddress struct {
+		CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+	}
+
+	if err := x.Sync2(new(EmailAddress)); err != nil {
+		return err
+	}
+
+	// The age of existing email addresses is unknown, so count them as added now
+	// rather than making them immediately eligible for cleanup.
+	_, err := x.Exec("UPDATE email_address SET created_unix = ? WHERE created_unix IS NULL OR created_unix = 0", timeutil.TimeStampNow())
+	return err
+}
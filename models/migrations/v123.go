@@ -0,0 +1,19 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addMergeHoldToPullRequest(x *xorm.Engine) error {
+	type PullRequest struct {
+		IsMergeOnHold   bool   `xorm:"NOT NULL DEFAULT false"`
+		MergeHoldReason string `xorm:"TEXT"`
+		MergeHoldUserID int64
+	}
+
+	return x.Sync2(new(PullRequest))
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"strings"
+
+	"xorm.io/xorm"
+)
+
+func addCanonicalEmailToEmailAddress(x *xorm.Engine) error {
+	type EmailAddress struct {
+		ID             int64  `xorm:"pk autoincr"`
+		Email          string `xorm:"UNIQUE NOT NULL"`
+		CanonicalEmail string `xorm:"UNIQUE"`
+	}
+	type EmailAddressWithoutIndex struct {
+		ID             int64  `xorm:"pk autoincr"`
+		Email          string `xorm:"UNIQUE NOT NULL"`
+		CanonicalEmail string
+	}
+
+	// Add the column without its unique index first, existing addresses may share a canonical form
+	if err := x.Table("email_address").Sync2(new(EmailAddressWithoutIndex)); err != nil {
+		return err
+	}
+
+	var taken = make(map[string]bool)
+	var last int
+	const batchSize = 50
+	for {
+		var results = make([]EmailAddressWithoutIndex, 0, batchSize)
+		err := x.Table("email_address").OrderBy("id").
+			Limit(batchSize, last).
+			Find(&results)
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			break
+		}
+		last += len(results)
+
+		for _, res := range results {
+			// same as models.CanonicalEmail
+			canonical := strings.ToLower(strings.TrimSpace(res.Email))
+			if at := strings.LastIndex(canonical, "@"); at >= 0 {
+				local, domain := canonical[:at], canonical[at:]
+				if plus := strings.Index(local, "+"); plus > 0 {
+					local = local[:plus]
+				}
+				canonical = local + domain
+			}
+			// the first address keeps the canonical form, later ones fall back to the address itself
+			if taken[canonical] {
+				canonical = strings.ToLower(strings.TrimSpace(res.Email))
+				if taken[canonical] {
+					canonical = res.Email
+				}
+			}
+			taken[canonical] = true
+			if _, err = x.Exec("UPDATE email_address SET canonical_email = ? WHERE id = ?", canonical, res.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return x.Sync2(new(EmailAddress))
+}
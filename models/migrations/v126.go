@@ -0,0 +1,26 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addReviewIDToReaction(x *xorm.Engine) error {
+	// Reaction see models/issue_reaction.go
+	type Reaction struct {
+		ID          int64              `xorm:"pk autoincr"`
+		Type        string             `xorm:"INDEX UNIQUE(s) NOT NULL"`
+		IssueID     int64              `xorm:"INDEX UNIQUE(s) NOT NULL"`
+		CommentID   int64              `xorm:"INDEX UNIQUE(s)"`
+		ReviewID    int64              `xorm:"INDEX UNIQUE(s) NOT NULL DEFAULT 0"`
+		UserID      int64              `xorm:"INDEX UNIQUE(s) NOT NULL"`
+		CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+	}
+
+	return x.Sync2(new(Reaction))
+}
@@ -0,0 +1,18 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addLastTestedSHAsToPullRequest(x *xorm.Engine) error {
+	type PullRequest struct {
+		LastTestedHeadSHA string `xorm:"VARCHAR(40)"`
+		LastTestedBaseSHA string `xorm:"VARCHAR(40)"`
+	}
+
+	return x.Sync2(new(PullRequest))
+}
@@ -0,0 +1,28 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/xorm"
+)
+
+func addPullRequestDeploymentTable(x *xorm.Engine) error {
+	type PullRequestDeployment struct {
+		ID          int64  `xorm:"pk autoincr"`
+		RepoID      int64  `xorm:"INDEX"`
+		PullID      int64  `xorm:"INDEX"`
+		Environment string `xorm:"VARCHAR(255)"`
+		URL         string `xorm:"TEXT"`
+		State       string `xorm:"VARCHAR(7) NOT NULL"`
+		CreatorID   int64
+
+		CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+		UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
+	}
+
+	return x.Sync2(new(PullRequestDeployment))
+}
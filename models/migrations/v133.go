@@ -0,0 +1,17 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"xorm.io/xorm"
+)
+
+func addBinaryConflictedFilesToPullRequest(x *xorm.Engine) error {
+	type PullRequest struct {
+		BinaryConflictedFiles []string `xorm:"TEXT JSON"`
+	}
+
+	return x.Sync2(new(PullRequest))
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"fmt"
+
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
+	"xorm.io/xorm"
+)
+
+func deduplicateReactions(x *xorm.Engine) error {
+	// Reaction see models/issue_reaction.go
+	type Reaction struct {
+		ID          int64              `xorm:"pk autoincr"`
+		Type        string             `xorm:"INDEX UNIQUE(s) NOT NULL"`
+		IssueID     int64              `xorm:"INDEX UNIQUE(s) NOT NULL"`
+		CommentID   int64              `xorm:"INDEX UNIQUE(s)"`
+		ReviewID    int64              `xorm:"INDEX UNIQUE(s) NOT NULL DEFAULT 0"`
+		UserID      int64              `xorm:"INDEX UNIQUE(s) NOT NULL"`
+		CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+	}
+
+	type reactionGroup struct {
+		Type      string
+		IssueID   int64
+		CommentID int64
+		ReviewID  int64
+		UserID    int64
+		KeepID    int64
+	}
+
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	groups := make([]*reactionGroup, 0, 10)
+	if err := sess.Table("reaction").
+		Select("`type`, issue_id, COALESCE(comment_id, 0) AS comment_id, review_id, user_id, MIN(id) AS keep_id").
+		GroupBy("`type`, issue_id, COALESCE(comment_id, 0), review_id, user_id").
+		Having("COUNT(*) > 1").
+		Find(&groups); err != nil {
+		return fmt.Errorf("find duplicate reactions: %v", err)
+	}
+
+	for _, group := range groups {
+		cond := builder.Eq{
+			"`type`":    group.Type,
+			"issue_id":  group.IssueID,
+			"review_id": group.ReviewID,
+			"user_id":   group.UserID,
+		}.And(builder.Neq{"id": group.KeepID})
+		if group.CommentID == 0 {
+			cond = cond.And(builder.Eq{"comment_id": 0}.Or(builder.IsNull{"comment_id"}))
+		} else {
+			cond = cond.And(builder.Eq{"comment_id": group.CommentID})
+		}
+		if _, err := sess.Where(cond).Delete(new(Reaction)); err != nil {
+			return fmt.Errorf("delete duplicate reactions: %v", err)
+		}
+	}
+
+	// NULL comment IDs are not covered by the unique index
+	if _, err := sess.Exec("UPDATE reaction SET comment_id = 0 WHERE comment_id IS NULL"); err != nil {
+		return err
+	}
+	if err := sess.Commit(); err != nil {
+		return err
+	}
+
+	// (Re)creates the unique index in case it is missing or outdated
+	return x.Sync2(new(Reaction))
+}
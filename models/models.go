@@ -114,6 +114,7 @@ func init() {
 		new(OAuth2AuthorizationCode),
 		new(OAuth2Grant),
 		new(Task),
+		new(PullRequestDeployment),
 	)
 
 	gonicNames := []string{"SSL", "UID"}
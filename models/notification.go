@@ -7,6 +7,7 @@ package models
 import (
 	"fmt"
 
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/timeutil"
 )
 
@@ -147,6 +148,91 @@ func createOrUpdateIssueNotifications(e Engine, issueID, commentID int64, notifi
 	return nil
 }
 
+// CreateOrUpdateReactionNotification notifies the author of the reacted issue or comment
+// if he/she opted in to reaction notifications. Reactions are coalesced into a notification
+// which is still unread and was updated within the reaction notification window.
+func CreateOrUpdateReactionNotification(issueID, commentID, reactorID int64) error {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	if err := createOrUpdateReactionNotification(sess, issueID, commentID, reactorID); err != nil {
+		return err
+	}
+
+	return sess.Commit()
+}
+
+func createOrUpdateReactionNotification(e Engine, issueID, commentID, reactorID int64) error {
+	issue, err := getIssueByID(e, issueID)
+	if err != nil {
+		return err
+	}
+
+	authorID := issue.PosterID
+	if commentID > 0 {
+		comment, err := getCommentByID(e, commentID)
+		if err != nil {
+			return err
+		}
+		authorID = comment.PosterID
+	}
+
+	// do not send notification for the own reactions or to ghost users
+	if authorID <= 0 || authorID == reactorID {
+		return nil
+	}
+
+	author, err := getUserByID(e, authorID)
+	if err != nil {
+		if IsErrUserNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if !author.IsActive || !author.NotifyOnReactions {
+		return nil
+	}
+
+	// ignore if user unwatched the issue
+	unwatched, err := e.
+		Where("user_id = ?", authorID).
+		And("issue_id = ?", issueID).
+		And("is_watching = ?", false).
+		Exist(new(IssueWatch))
+	if err != nil {
+		return err
+	} else if unwatched {
+		return nil
+	}
+
+	if err = issue.loadRepo(e); err != nil {
+		return err
+	}
+	if issue.IsPull && !issue.Repo.checkUnitUser(e, authorID, author.IsAdmin, UnitTypePullRequests) {
+		return nil
+	}
+	if !issue.IsPull && !issue.Repo.checkUnitUser(e, authorID, author.IsAdmin, UnitTypeIssues) {
+		return nil
+	}
+
+	notification, err := getIssueNotification(e, authorID, issueID)
+	if err != nil {
+		return err
+	}
+	if notification.ID == 0 {
+		return createIssueNotification(e, authorID, issue, commentID, reactorID)
+	}
+
+	window := timeutil.TimeStamp(setting.Service.ReactionNotificationWindow.Seconds())
+	if notification.Status == NotificationStatusUnread && notification.UpdatedUnix+window >= timeutil.TimeStampNow() {
+		return nil
+	}
+	return updateIssueNotification(e, authorID, issueID, commentID, reactorID)
+}
+
 func getNotificationsByIssueID(e Engine, issueID int64) (notifications []*Notification, err error) {
 	err = e.
 		Where("issue_id = ?", issueID).
@@ -193,13 +279,13 @@ func updateIssueNotification(e Engine, userID, issueID, commentID, updatedByID i
 	// NOTICE: Only update comment id when the before notification on this issue is read, otherwise you may miss some old comments.
 	// But we need update update_by so that the notification will be reorder
 	var cols []string
+	notification.UpdatedBy = updatedByID
 	if notification.Status == NotificationStatusRead {
 		notification.Status = NotificationStatusUnread
 		notification.CommentID = commentID
-		cols = []string{"status", "update_by", "comment_id"}
+		cols = []string{"status", "updated_by", "comment_id"}
 	} else {
-		notification.UpdatedBy = updatedByID
-		cols = []string{"update_by"}
+		cols = []string{"updated_by"}
 	}
 
 	_, err = e.ID(notification.ID).Cols(cols...).Update(notification)
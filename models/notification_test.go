@@ -25,6 +25,65 @@ func TestCreateOrUpdateIssueNotifications(t *testing.T) {
 	assert.Equal(t, NotificationStatusUnread, notf.Status)
 }
 
+func TestCreateOrUpdateReactionNotification(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	issue := AssertExistsAndLoadBean(t, &Issue{ID: 1}).(*Issue)
+
+	// the poster of issue 1 did not opt in to reaction notifications
+	assert.NoError(t, CreateOrUpdateReactionNotification(issue.ID, 0, 4))
+	notf := AssertExistsAndLoadBean(t, &Notification{ID: 1}).(*Notification)
+	assert.EqualValues(t, 2, notf.UpdatedBy)
+
+	poster := AssertExistsAndLoadBean(t, &User{ID: issue.PosterID}).(*User)
+	assert.NoError(t, poster.SetNotifyOnReactions(true))
+
+	// own reactions are never notified
+	assert.NoError(t, CreateOrUpdateReactionNotification(issue.ID, 0, poster.ID))
+	notf = AssertExistsAndLoadBean(t, &Notification{ID: 1}).(*Notification)
+	assert.EqualValues(t, 2, notf.UpdatedBy)
+
+	// the unread notification was updated long ago, so it is bumped
+	assert.NoError(t, CreateOrUpdateReactionNotification(issue.ID, 0, 4))
+	notf = AssertExistsAndLoadBean(t, &Notification{ID: 1}).(*Notification)
+	assert.EqualValues(t, 4, notf.UpdatedBy)
+
+	// further reactions within the window are coalesced
+	assert.NoError(t, CreateOrUpdateReactionNotification(issue.ID, 0, 5))
+	notf = AssertExistsAndLoadBean(t, &Notification{ID: 1}).(*Notification)
+	assert.EqualValues(t, 4, notf.UpdatedBy)
+
+	// the poster of comment 3 muted the issue
+	commenter := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+	assert.NoError(t, commenter.SetNotifyOnReactions(true))
+	assert.NoError(t, CreateOrUpdateIssueWatch(commenter.ID, issue.ID, false))
+	assert.NoError(t, CreateOrUpdateReactionNotification(issue.ID, 3, 2))
+	AssertNotExistsBean(t, &Notification{UserID: commenter.ID, IssueID: issue.ID})
+
+	_, err := x.Delete(&IssueWatch{UserID: commenter.ID, IssueID: issue.ID})
+	assert.NoError(t, err)
+	assert.NoError(t, CreateOrUpdateReactionNotification(issue.ID, 3, 2))
+	notf = AssertExistsAndLoadBean(t, &Notification{UserID: commenter.ID, IssueID: issue.ID}).(*Notification)
+	assert.Equal(t, NotificationStatusUnread, notf.Status)
+	assert.EqualValues(t, 3, notf.CommentID)
+	assert.EqualValues(t, 2, notf.UpdatedBy)
+}
+
+func TestUpdateIssueNotification(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// unread notification of user 1 on issue 1
+	assert.NoError(t, updateIssueNotification(x, 1, 1, 0, 4))
+	notf := AssertExistsAndLoadBean(t, &Notification{ID: 1}).(*Notification)
+	assert.Equal(t, NotificationStatusUnread, notf.Status)
+	assert.EqualValues(t, 4, notf.UpdatedBy)
+
+	// read notification of user 2 on issue 2
+	assert.NoError(t, updateIssueNotification(x, 2, 2, 0, 4))
+	notf = AssertExistsAndLoadBean(t, &Notification{ID: 2}).(*Notification)
+	assert.Equal(t, NotificationStatusUnread, notf.Status)
+	assert.EqualValues(t, 4, notf.UpdatedBy)
+}
+
 func TestNotificationsForUser(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	user := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
@@ -6,14 +6,22 @@
 package models
 
 import (
+	"bytes"
 	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/references"
 	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
+
+	"github.com/mcuadros/go-version"
 )
 
 // PullRequestType defines pull request type
@@ -38,10 +46,12 @@ const (
 
 // PullRequest represents relation between pull request and repositories.
 type PullRequest struct {
-	ID              int64 `xorm:"pk autoincr"`
-	Type            PullRequestType
-	Status          PullRequestStatus
-	ConflictedFiles []string `xorm:"TEXT JSON"`
+	ID                    int64 `xorm:"pk autoincr"`
+	Type                  PullRequestType
+	Status                PullRequestStatus
+	ConflictedFiles       []string `xorm:"TEXT JSON"`
+	BinaryConflictedFiles []string `xorm:"TEXT JSON"`
+	ChangedProtectedFiles []string `xorm:"TEXT JSON"`
 
 	IssueID int64  `xorm:"INDEX"`
 	Issue   *Issue `xorm:"-"`
@@ -56,11 +66,26 @@ type PullRequest struct {
 	ProtectedBranch *ProtectedBranch `xorm:"-"`
 	MergeBase       string           `xorm:"VARCHAR(40)"`
 
+	// Head and base commits the current Status has been tested against
+	LastTestedHeadSHA string `xorm:"VARCHAR(40)"`
+	LastTestedBaseSHA string `xorm:"VARCHAR(40)"`
+
+	// Time of the last push to the head branch, 0 if it is unknown
+	HeadUpdatedUnix timeutil.TimeStamp `xorm:"NOT NULL DEFAULT 0"`
+
 	HasMerged      bool               `xorm:"INDEX"`
 	MergedCommitID string             `xorm:"VARCHAR(40)"`
 	MergerID       int64              `xorm:"INDEX"`
 	Merger         *User              `xorm:"-"`
 	MergedUnix     timeutil.TimeStamp `xorm:"updated INDEX"`
+
+	IsMergeOnHold   bool   `xorm:"NOT NULL DEFAULT false"`
+	MergeHoldReason string `xorm:"TEXT"`
+	MergeHoldUserID int64
+
+	// UnresolvedReviewThreads is the number of review threads which are neither resolved nor outdated,
+	// nil until it's loaded
+	UnresolvedReviewThreads *int `xorm:"-"`
 }
 
 // MustHeadUserName returns the HeadRepo's username if failed return blank
@@ -164,6 +189,154 @@ func (pr *PullRequest) loadProtectedBranch(e Engine) (err error) {
 	return
 }
 
+// IsBlockedByChangedProtectedFiles returns true if the pull request changes protected files of the base branch
+// and has not been approved by an official reviewer yet.
+func (pr *PullRequest) IsBlockedByChangedProtectedFiles() bool {
+	if len(pr.ChangedProtectedFiles) == 0 {
+		return false
+	}
+	if pr.ProtectedBranch == nil {
+		if err := pr.LoadProtectedBranch(); err != nil {
+			log.Error("LoadProtectedBranch: %v", err)
+			return true
+		}
+	}
+	if pr.ProtectedBranch == nil {
+		return false
+	}
+	return pr.ProtectedBranch.GetGrantedApprovalsCount(pr) == 0
+}
+
+// CountBlockingUnresolvedConversations returns the number of unresolved review threads if the protection
+// of the base branch requires all conversations to be resolved, and 0 otherwise.
+func (pr *PullRequest) CountBlockingUnresolvedConversations() (int, error) {
+	if pr.ProtectedBranch == nil {
+		if err := pr.LoadProtectedBranch(); err != nil {
+			return 0, err
+		}
+	}
+	if pr.ProtectedBranch == nil || !pr.ProtectedBranch.RequireResolvedConversations {
+		return 0, nil
+	}
+	return pr.CountUnresolvedReviewThreads()
+}
+
+// HasEnoughApprovals returns true if the base branch is protected and the pull request
+// has at least the number of official approvals required by the protected branch.
+func (pr *PullRequest) HasEnoughApprovals() bool {
+	if pr.ProtectedBranch == nil {
+		if err := pr.LoadProtectedBranch(); err != nil {
+			log.Error("LoadProtectedBranch: %v", err)
+			return false
+		}
+		if pr.ProtectedBranch == nil {
+			return false
+		}
+	}
+	return pr.ProtectedBranch.HasEnoughApprovals(pr)
+}
+
+// HasTooFewReviews returns true if the protected base branch requires approvals
+// but the pull request has not been reviewed at all yet.
+func (pr *PullRequest) HasTooFewReviews() bool {
+	if pr.ProtectedBranch == nil {
+		if err := pr.LoadProtectedBranch(); err != nil {
+			log.Error("LoadProtectedBranch: %v", err)
+			return false
+		}
+		if pr.ProtectedBranch == nil {
+			return false
+		}
+	}
+	if pr.ProtectedBranch.RequiredApprovals == 0 {
+		return false
+	}
+
+	reviews, err := x.Where("issue_id = ?", pr.IssueID).
+		NotIn("type", ReviewTypePending, ReviewTypeRequest).
+		Count(new(Review))
+	if err != nil {
+		log.Error("HasTooFewReviews: %v", err)
+		return false
+	}
+	return reviews == 0
+}
+
+// GetParticipants returns everyone involved in the pull request: the poster, the assignees,
+// the reviewers (including requested ones) and the commenters, each listed once.
+// Users who unsubscribed from the pull request are left out, and so are deleted users
+// whose IDs are still referenced by the pull request.
+func (pr *PullRequest) GetParticipants() ([]*User, error) {
+	return pr.getParticipants(x)
+}
+
+func (pr *PullRequest) getParticipants(e Engine) ([]*User, error) {
+	if err := pr.loadIssue(e); err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]int64, 1, 10)
+	userIDs[0] = pr.Issue.PosterID
+
+	var ids []int64
+	if err := e.Table("issue_assignees").
+		Cols("assignee_id").
+		Where("issue_id = ?", pr.IssueID).
+		Find(&ids); err != nil {
+		return nil, fmt.Errorf("get assignee IDs: %v", err)
+	}
+	userIDs = append(userIDs, ids...)
+
+	ids = ids[:0]
+	if err := e.Table("review").
+		Cols("reviewer_id").
+		Where("issue_id = ?", pr.IssueID).
+		And("type <> ?", ReviewTypePending).
+		Find(&ids); err != nil {
+		return nil, fmt.Errorf("get reviewer IDs: %v", err)
+	}
+	userIDs = append(userIDs, ids...)
+
+	ids = ids[:0]
+	if err := e.Table("comment").
+		Cols("poster_id").
+		Where("issue_id = ?", pr.IssueID).
+		And("type in (?,?,?)", CommentTypeComment, CommentTypeCode, CommentTypeReview).
+		Find(&ids); err != nil {
+		return nil, fmt.Errorf("get commenter IDs: %v", err)
+	}
+	userIDs = append(userIDs, ids...)
+
+	ids = ids[:0]
+	if err := e.Table("issue_watch").
+		Cols("user_id").
+		Where("issue_id = ?", pr.IssueID).
+		And("is_watching = ?", false).
+		Find(&ids); err != nil {
+		return nil, fmt.Errorf("get unwatcher IDs: %v", err)
+	}
+	muted := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		muted[id] = true
+	}
+
+	participantIDs := make([]int64, 0, len(userIDs))
+	for _, id := range userIDs {
+		if muted[id] {
+			continue
+		}
+		// Marking the ID as muted also skips later duplicates
+		muted[id] = true
+		participantIDs = append(participantIDs, id)
+	}
+
+	users := make([]*User, 0, len(participantIDs))
+	if len(participantIDs) == 0 {
+		return users, nil
+	}
+	return users, e.In("id", participantIDs).Asc("id").Find(&users)
+}
+
 // GetDefaultMergeMessage returns default message used when merging pull request
 func (pr *PullRequest) GetDefaultMergeMessage() string {
 	if pr.HeadRepo == nil {
@@ -177,8 +350,15 @@ func (pr *PullRequest) GetDefaultMergeMessage() string {
 	return fmt.Sprintf("Merge branch '%s' of %s/%s into %s", pr.HeadBranch, pr.MustHeadUserName(), pr.HeadRepo.Name, pr.BaseBranch)
 }
 
-// GetDefaultSquashMessage returns default message used when squash and merging pull request
+// GetDefaultSquashMessage returns default message used when squash and merging pull request.
+// It is the same as GetDefaultSquashSubject and kept for compatibility.
 func (pr *PullRequest) GetDefaultSquashMessage() string {
+	return pr.GetDefaultSquashSubject()
+}
+
+// GetDefaultSquashSubject returns the default subject, i.e. the first line, of the commit
+// created when squash merging the pull request
+func (pr *PullRequest) GetDefaultSquashSubject() string {
 	if err := pr.LoadIssue(); err != nil {
 		log.Error("LoadIssue: %v", err)
 		return ""
@@ -186,19 +366,612 @@ func (pr *PullRequest) GetDefaultSquashMessage() string {
 	return fmt.Sprintf("%s (#%d)", pr.Issue.Title, pr.Issue.Index)
 }
 
+// GetDefaultSquashBody returns the default body of the commit created when squash merging
+// the pull request, which is the description of the pull request
+func (pr *PullRequest) GetDefaultSquashBody() string {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return ""
+	}
+	return strings.TrimSpace(pr.Issue.Content)
+}
+
+// GetSquashPreview returns the number of commits that will be collapsed into one
+// when the pull request is squash merged, along with the default squash message.
+func (pr *PullRequest) GetSquashPreview() (commitCount int, message string, err error) {
+	if err = pr.GetBaseRepo(); err != nil {
+		return 0, "", err
+	}
+	baseGitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return 0, "", err
+	}
+	defer baseGitRepo.Close()
+
+	mergeBase := pr.MergeBase
+	if len(mergeBase) == 0 {
+		mergeBase = git.BranchPrefix + pr.BaseBranch
+	}
+	count, err := baseGitRepo.CommitsCountBetween(mergeBase, pr.GetGitRefName())
+	if err != nil {
+		return 0, "", fmt.Errorf("CommitsCountBetween: %v", err)
+	}
+	return int(count), pr.GetDefaultSquashMessage(), nil
+}
+
+// mergeTreeGitVersionRequired is the first git version whose merge-tree can write the merge result
+const mergeTreeGitVersionRequired = "2.38.0"
+
+// QuickConflictCheck checks whether the pull request merges cleanly into its base branch by a
+// three-way merge with git merge-tree in the base repository, which needs neither a temporary
+// clone nor a worktree. pr.MergeBase and pr.ConflictedFiles are updated, and true is returned
+// if there are conflicts. ErrQuickConflictCheckUnsupported is returned if the check is not
+// possible, e.g. because git is too old, in which case a full patch test is needed.
+func (pr *PullRequest) QuickConflictCheck() (bool, error) {
+	binVersion, err := git.BinVersion()
+	if err != nil {
+		return false, err
+	}
+	if version.Compare(binVersion, mergeTreeGitVersionRequired, "<") {
+		return false, ErrQuickConflictCheckUnsupported{ID: pr.ID, Reason: "git " + binVersion + " does not support merge-tree --write-tree"}
+	}
+
+	if err := pr.LoadBaseRepo(); err != nil {
+		return false, err
+	}
+	repoPath := pr.BaseRepo.RepoPath()
+	baseRef := git.BranchPrefix + pr.BaseBranch
+	headRef := pr.GetGitRefName()
+
+	mergeBase, err := git.NewCommand("merge-base", "--", baseRef, headRef).RunInDir(repoPath)
+	if err != nil {
+		// merge-tree refuses to merge unrelated histories
+		return false, ErrQuickConflictCheckUnsupported{ID: pr.ID, Reason: "no merge base"}
+	}
+	pr.MergeBase = strings.TrimSpace(mergeBase)
+
+	var conflict bool
+	stdout := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	if err := git.NewCommand("merge-tree", "--write-tree", "--name-only", "--no-messages", "-z", baseRef, headRef).
+		RunInDirPipeline(repoPath, stdout, stderr); err != nil {
+		// merge-tree exits with 1 if there are conflicts
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return false, fmt.Errorf("git merge-tree: %v - %s", err, stderr)
+		}
+		conflict = true
+	}
+
+	// the output is the ID of the merged tree followed by the conflicted files
+	pr.ConflictedFiles = []string{}
+	fields := strings.Split(stdout.String(), "\x00")
+	for _, name := range fields[1:] {
+		if len(name) == 0 {
+			continue
+		}
+		pr.ConflictedFiles = append(pr.ConflictedFiles, name)
+		// only list 10 conflicted files
+		if len(pr.ConflictedFiles) >= 10 {
+			break
+		}
+	}
+	if err := pr.ClassifyConflictedFiles(repoPath, baseRef, headRef); err != nil {
+		return false, err
+	}
+	return conflict, nil
+}
+
+// ClassifyConflictedFiles records in pr.BinaryConflictedFiles which of pr.ConflictedFiles are
+// binary, on either side of the merge, according to git. Binary conflicts can't be resolved
+// by editing the file, so they have to be resolved locally.
+// baseRef and headRef are the base and the head of the pull request in the repository at repoPath.
+func (pr *PullRequest) ClassifyConflictedFiles(repoPath, baseRef, headRef string) error {
+	pr.BinaryConflictedFiles = []string{}
+	if len(pr.ConflictedFiles) == 0 {
+		return nil
+	}
+
+	mergeBase := pr.MergeBase
+	if len(mergeBase) == 0 {
+		mergeBase = baseRef
+	}
+	binary := make(map[string]bool, len(pr.ConflictedFiles))
+	for _, ref := range []string{baseRef, headRef} {
+		args := append([]string{"diff", "--numstat", "--no-renames", "-z", mergeBase, ref, "--"}, pr.ConflictedFiles...)
+		stdout, err := git.NewCommand(args...).RunInDir(repoPath)
+		if err != nil {
+			return fmt.Errorf("git diff --numstat [%s]: %v", repoPath, err)
+		}
+		// every entry is "added\tdeleted\tpath", git counts no lines but "-" for binary files
+		for _, entry := range strings.Split(stdout, "\x00") {
+			if strings.HasPrefix(entry, "-\t-\t") {
+				binary[entry[len("-\t-\t"):]] = true
+			}
+		}
+	}
+
+	for _, name := range pr.ConflictedFiles {
+		if binary[name] {
+			pr.BinaryConflictedFiles = append(pr.BinaryConflictedFiles, name)
+		}
+	}
+	return nil
+}
+
+// GetConflictedFileTypes returns whether each of the conflicted files of the pull request is binary.
+// Pull requests which were tested before binary files have been told apart are classified on demand.
+func (pr *PullRequest) GetConflictedFileTypes() (map[string]bool, error) {
+	if pr.BinaryConflictedFiles == nil && len(pr.ConflictedFiles) > 0 {
+		if err := pr.LoadBaseRepo(); err != nil {
+			return nil, err
+		}
+		if err := pr.ClassifyConflictedFiles(pr.BaseRepo.RepoPath(), git.BranchPrefix+pr.BaseBranch, pr.GetGitRefName()); err != nil {
+			return nil, err
+		}
+	}
+
+	types := make(map[string]bool, len(pr.ConflictedFiles))
+	for _, name := range pr.ConflictedFiles {
+		types[name] = false
+	}
+	for _, name := range pr.BinaryConflictedFiles {
+		types[name] = true
+	}
+	return types, nil
+}
+
+// GetMergedCommit returns the commit the pull request has been merged with from the base repository
+func (pr *PullRequest) GetMergedCommit() (*git.Commit, error) {
+	if !pr.HasMerged {
+		return nil, ErrPullRequestNotMerged{ID: pr.ID}
+	}
+	if len(pr.MergedCommitID) == 0 {
+		return nil, ErrPullRequestMergedCommitNotExist{ID: pr.ID}
+	}
+
+	if err := pr.LoadBaseRepo(); err != nil {
+		return nil, err
+	}
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return nil, fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+
+	// the history of the base branch may have been rewritten since the merge
+	if !gitRepo.IsCommitExist(pr.MergedCommitID) {
+		return nil, ErrPullRequestMergedCommitNotExist{ID: pr.ID, CommitID: pr.MergedCommitID}
+	}
+
+	commit, err := gitRepo.GetCommit(pr.MergedCommitID)
+	if err != nil {
+		return nil, fmt.Errorf("GetCommit: %v", err)
+	}
+	return commit, nil
+}
+
+// GetMergeCommitParents returns the IDs of the parents of the commit the pull request has been
+// merged with, to verify how it was merged. A merge commit has the previous head of the base
+// branch as first and the head of the pull request as second parent. Squashed and rebased pull
+// requests have a single parent, which for rebases is the previous rebased commit. The merged
+// commit missing from the base repository hints at a rewritten history of the base branch.
+func (pr *PullRequest) GetMergeCommitParents() ([]string, error) {
+	commit, err := pr.GetMergedCommit()
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make([]string, commit.ParentCount())
+	for i := range parents {
+		id, err := commit.ParentID(i)
+		if err != nil {
+			return nil, fmt.Errorf("ParentID: %v", err)
+		}
+		parents[i] = id.String()
+	}
+	return parents, nil
+}
+
+// GetBranchPoint returns the commit the head branch of the pull request was
+// branched off the base branch: following the first parents from the head
+// commit, it is the first commit which is also on the base branch.
+//
+// This differs from the merge base when the base branch has been merged into
+// the head branch after it was created: the merge base is then the merged base
+// commit, while the branch point stays the commit the head branch started from.
+// Callers which want the best common ancestor should use MergeBase instead.
+// The head of a merged pull request is on the base branch, so for those the
+// merge base recorded before the merge is returned.
+func (pr *PullRequest) GetBranchPoint() (*git.Commit, error) {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return nil, err
+	}
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return nil, fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+
+	if pr.HasMerged && len(pr.MergeBase) > 0 {
+		return gitRepo.GetCommit(pr.MergeBase)
+	}
+
+	stdout, err := git.NewCommand("rev-list", "--first-parent", pr.GetGitRefName(),
+		"--not", git.BranchPrefix+pr.BaseBranch, "--").RunInDir(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return nil, fmt.Errorf("git rev-list: %v", err)
+	}
+
+	// the head commit is on the base branch already
+	commitIDs := strings.Fields(stdout)
+	if len(commitIDs) == 0 {
+		headCommitID, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+		if err != nil {
+			return nil, fmt.Errorf("GetRefCommitID: %v", err)
+		}
+		return gitRepo.GetCommit(headCommitID)
+	}
+
+	oldest, err := gitRepo.GetCommit(commitIDs[len(commitIDs)-1])
+	if err != nil {
+		return nil, fmt.Errorf("GetCommit: %v", err)
+	}
+	if oldest.ParentCount() == 0 {
+		return nil, ErrPullRequestBranchPointNotExist{ID: pr.ID}
+	}
+	return oldest.Parent(0)
+}
+
+// GetEffectiveMergeBase returns the merge base of the pull request to compare its head against.
+//
+// Without recompute the merge base stored by the last patch test is returned, which keeps
+// the commits and changes shown for the pull request stable between two pushes. It may be
+// stale once the base branch has been merged into the head branch or the base branch was
+// rewritten. With recompute the merge base is calculated against the current tip of the
+// base branch and saved if it has changed, so only callers which need the current state,
+// e.g. to show how far the head has diverged from the base, should recompute. Merged pull
+// requests always use the stored merge base, as their head is part of the base branch.
+func (pr *PullRequest) GetEffectiveMergeBase(recompute bool) (string, error) {
+	if len(pr.MergeBase) > 0 && (!recompute || pr.HasMerged) {
+		return pr.MergeBase, nil
+	}
+
+	if err := pr.LoadBaseRepo(); err != nil {
+		return "", err
+	}
+	stdout, err := git.NewCommand("merge-base", "--", git.BranchPrefix+pr.BaseBranch, pr.GetGitRefName()).
+		RunInDir(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return "", fmt.Errorf("git merge-base: %v", err)
+	}
+
+	mergeBase := strings.TrimSpace(stdout)
+	if mergeBase != pr.MergeBase {
+		pr.MergeBase = mergeBase
+		if err := pr.UpdateCols("merge_base"); err != nil {
+			return "", fmt.Errorf("UpdateCols: %v", err)
+		}
+	}
+	return mergeBase, nil
+}
+
+// countCommitsBetween returns the number of commits reachable from to but not from from
+func countCommitsBetween(repoPath, from, to string) (int, error) {
+	stdout, err := git.NewCommand("rev-list", "--count", from+".."+to, "--").RunInDir(repoPath)
+	if err != nil {
+		return 0, fmt.Errorf("git rev-list: %v", err)
+	}
+	return strconv.Atoi(strings.TrimSpace(stdout))
+}
+
+// GetCommitDivergence returns the number of commits the head of the pull request is ahead of
+// its merge base and the number of commits the base branch has advanced since the merge base.
+// See GetEffectiveMergeBase for the meaning of recompute.
+func (pr *PullRequest) GetCommitDivergence(recompute bool) (git.DivergeObject, error) {
+	mergeBase, err := pr.GetEffectiveMergeBase(recompute)
+	if err != nil {
+		return git.DivergeObject{}, err
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return git.DivergeObject{}, err
+	}
+
+	repoPath := pr.BaseRepo.RepoPath()
+	ahead, err := countCommitsBetween(repoPath, mergeBase, pr.GetGitRefName())
+	if err != nil {
+		return git.DivergeObject{}, err
+	}
+	behind, err := countCommitsBetween(repoPath, mergeBase, git.BranchPrefix+pr.BaseBranch)
+	if err != nil {
+		return git.DivergeObject{}, err
+	}
+	return git.DivergeObject{Ahead: ahead, Behind: behind}, nil
+}
+
+// IsBaseBranchUpdatedSinceMergeBase returns true if the base branch has commits which are not
+// in the stored merge base of the pull request, i.e. the base has moved on since the last
+// patch test.
+func (pr *PullRequest) IsBaseBranchUpdatedSinceMergeBase() (bool, error) {
+	divergence, err := pr.GetCommitDivergence(false)
+	if err != nil {
+		return false, err
+	}
+	return divergence.Behind > 0, nil
+}
+
+// HeadChangedSince returns true if the head branch of the pull request was
+// rewritten since it pointed to the given commit, e.g. by a force-push.
+// Commits which were only appended to the branch are not counted as a change.
+func (pr *PullRequest) HeadChangedSince(sha string) (bool, error) {
+	if err := pr.LoadHeadRepo(); err != nil {
+		return false, err
+	}
+	headRepoPath := pr.HeadRepo.RepoPath()
+	gitRepo, err := git.OpenRepository(headRepoPath)
+	if err != nil {
+		return false, fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+
+	headCommitID, err := gitRepo.GetBranchCommitID(pr.HeadBranch)
+	if err != nil {
+		return false, fmt.Errorf("GetBranchCommitID: %v", err)
+	}
+	if headCommitID == sha {
+		return false, nil
+	}
+	if !gitRepo.IsCommitExist(sha) {
+		return true, nil
+	}
+
+	isAncestor, err := gitRepo.IsAncestor(sha, headCommitID)
+	if err != nil {
+		// Without the history the head has to be assumed to be changed
+		if git.IsErrShallowHistory(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return !isAncestor, nil
+}
+
+// GetClosingIssues returns the issues which are closed by closing keywords in the description
+// or in the commit messages of the pull request, e.g. "fixes #1". Issues of other repositories
+// are only returned if closing across repositories is enabled.
+func (pr *PullRequest) GetClosingIssues() ([]*Issue, error) {
+	if err := pr.LoadIssue(); err != nil {
+		return nil, err
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return nil, err
+	}
+	if err := pr.BaseRepo.GetOwner(); err != nil {
+		return nil, err
+	}
+
+	baseGitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+	defer baseGitRepo.Close()
+
+	mergeBase := pr.MergeBase
+	if len(mergeBase) == 0 {
+		mergeBase = git.BranchPrefix + pr.BaseBranch
+	}
+	commits, err := baseGitRepo.GetCommits(mergeBase, pr.GetGitRefName(), false)
+	if err != nil {
+		return nil, fmt.Errorf("GetCommits: %v", err)
+	}
+
+	contents := make([]string, 0, len(commits)+1)
+	contents = append(contents, pr.Issue.Content)
+	// Oldest commits first
+	for i := len(commits) - 1; i >= 0; i-- {
+		contents = append(contents, commits[i].Message())
+	}
+
+	issues := make([]*Issue, 0, 5)
+	seen := make(map[int64]bool)
+	for _, content := range contents {
+		for _, ref := range references.FindAllIssueReferences(content) {
+			if ref.Action != references.XRefActionCloses {
+				continue
+			}
+
+			repo := pr.BaseRepo
+			if len(ref.Owner) > 0 && !(strings.EqualFold(ref.Owner, pr.BaseRepo.Owner.Name) && strings.EqualFold(ref.Name, pr.BaseRepo.Name)) {
+				if !setting.Repository.PullRequest.EnableCrossRepoClosing {
+					continue
+				}
+				if repo, err = GetRepositoryByOwnerAndName(ref.Owner, ref.Name); err != nil {
+					if IsErrRepoNotExist(err) {
+						continue
+					}
+					return nil, err
+				}
+			}
+
+			issue, err := GetIssueByIndex(repo.ID, ref.Index)
+			if err != nil {
+				if IsErrIssueNotExist(err) {
+					continue
+				}
+				return nil, err
+			}
+			// Only issues can be closed by a pull request
+			if issue.IsPull || seen[issue.ID] {
+				continue
+			}
+			seen[issue.ID] = true
+			issue.Repo = repo
+			issues = append(issues, issue)
+		}
+	}
+	return issues, nil
+}
+
+// revertPattern matches the line git revert adds to the message of a revert commit
+var revertPattern = regexp.MustCompile(`(?m)^This reverts commit ([0-9a-fA-F]{7,40})\b`)
+
+// IsRevertOf returns the commit the pull request reverts if all of its commits are reverts made by git revert,
+// and nil otherwise. If several commits are reverted, the one reverted by the oldest commit of the pull request
+// is returned. Reverted commits which do not exist in the base repository do not count as reverts.
+func (pr *PullRequest) IsRevertOf() (*git.Commit, error) {
+	if err := pr.GetBaseRepo(); err != nil {
+		return nil, err
+	}
+
+	baseGitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+	defer baseGitRepo.Close()
+
+	mergeBase := pr.MergeBase
+	if len(mergeBase) == 0 {
+		mergeBase = git.BranchPrefix + pr.BaseBranch
+	}
+	commits, err := baseGitRepo.GetCommits(mergeBase, pr.GetGitRefName(), false)
+	if err != nil {
+		return nil, fmt.Errorf("GetCommits: %v", err)
+	}
+	if len(commits) == 0 {
+		return nil, nil
+	}
+
+	var reverted *git.Commit
+	// Oldest commits first
+	for i := len(commits) - 1; i >= 0; i-- {
+		m := revertPattern.FindStringSubmatch(commits[i].Message())
+		if m == nil {
+			return nil, nil
+		}
+		id, err := baseGitRepo.ConvertToSHA1(m[1])
+		if err != nil {
+			if git.IsErrNotExist(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		if !baseGitRepo.IsCommitExist(id.String()) {
+			return nil, nil
+		}
+		commit, err := baseGitRepo.GetCommit(id.String())
+		if err != nil {
+			return nil, err
+		}
+		if reverted == nil {
+			reverted = commit
+		}
+	}
+	return reverted, nil
+}
+
+// GetDiffTrees returns the IDs of the trees of the base branch, the head and their merge base, which
+// allow a three-way diff of the pull request. All of them are resolved in the base repository using the
+// head ref pushed there, so they are available for pull requests from forks which were deleted as well.
+// It returns ErrPullRequestRefNotExist if the base branch or the head ref is missing, or if they have no
+// merge base.
+func (pr *PullRequest) GetDiffTrees() (baseTree, headTree, mergeBaseTree string, err error) {
+	if err = pr.GetBaseRepo(); err != nil {
+		return "", "", "", err
+	}
+
+	baseGitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return "", "", "", err
+	}
+	defer baseGitRepo.Close()
+
+	getCommit := func(ref string) (*git.Commit, error) {
+		commit, err := baseGitRepo.GetCommit(ref)
+		if git.IsErrNotExist(err) {
+			return nil, ErrPullRequestRefNotExist{ID: pr.ID, Ref: ref}
+		}
+		return commit, err
+	}
+
+	baseCommit, err := getCommit(git.BranchPrefix + pr.BaseBranch)
+	if err != nil {
+		return "", "", "", err
+	}
+	headCommit, err := getCommit(pr.GetGitRefName())
+	if err != nil {
+		return "", "", "", err
+	}
+
+	mergeBase, err := git.NewCommand("merge-base", "--", baseCommit.ID.String(), headCommit.ID.String()).RunInDir(baseGitRepo.Path)
+	if err != nil {
+		// both commits exist, so merge-base only fails if they are unrelated
+		log.Trace("merge-base of %s and %s: %v", baseCommit.ID, headCommit.ID, err)
+		return "", "", "", ErrPullRequestRefNotExist{ID: pr.ID, Ref: "merge base"}
+	}
+	mergeBaseCommit, err := getCommit(strings.TrimSpace(mergeBase))
+	if err != nil {
+		return "", "", "", err
+	}
+
+	return baseCommit.Tree.ID.String(), headCommit.Tree.ID.String(), mergeBaseCommit.Tree.ID.String(), nil
+}
+
+// GetDefaultMessage returns default message used when merging pull request with the given merge style
+func (pr *PullRequest) GetDefaultMessage(mergeStyle MergeStyle) string {
+	switch mergeStyle {
+	case MergeStyleMerge, MergeStyleRebaseMerge:
+		return pr.GetDefaultMergeMessage()
+	case MergeStyleSquash:
+		return pr.GetDefaultSquashMessage()
+	}
+	return ""
+}
+
 // GetGitRefName returns git ref for hidden pull request branch
 func (pr *PullRequest) GetGitRefName() string {
 	return fmt.Sprintf("refs/pull/%d/head", pr.Index)
 }
 
+// GetHeadBranchHTMLURL returns the web URL of the head branch, which lives in the
+// fork for pull requests from forks. It returns an empty string if the head
+// repository can't be loaded.
+func (pr *PullRequest) GetHeadBranchHTMLURL() string {
+	if err := pr.LoadHeadRepo(); err != nil {
+		return ""
+	}
+	return pr.HeadRepo.HTMLURL() + "/src/branch/" + util.PathEscapeSegments(pr.HeadBranch)
+}
+
+// GetBaseBranchHTMLURL returns the web URL of the base branch. It returns an
+// empty string if the base repository can't be loaded.
+func (pr *PullRequest) GetBaseBranchHTMLURL() string {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return ""
+	}
+	return pr.BaseRepo.HTMLURL() + "/src/branch/" + util.PathEscapeSegments(pr.BaseBranch)
+}
+
 // APIFormat assumes following fields have been assigned with valid values:
 // Required - Issue
 // Optional - Merger
+//
+// Without a doer, Mergeable only reports whether the pull request is free of conflicts, not a work in
+// progress and not on hold. Whether the protection of the base branch allows merging depends on who merges
+// (merge whitelists, write access), and this format is also used for webhook payloads which are not sent
+// on behalf of a user, so it can not be taken into account. Use APIFormatForDoer to include it.
 func (pr *PullRequest) APIFormat() *api.PullRequest {
-	return pr.apiFormat(x)
+	return pr.apiFormat(x, nil)
+}
+
+// APIFormatForDoer is like APIFormat, but Mergeable reports whether doer can merge the pull request
+// right now, including the required approvals and status checks of the base branch protection.
+// The API uses it for all pull requests it returns, so that Mergeable means the same everywhere.
+// Merged and closed pull requests keep the conflict based Mergeable, as they can't be merged by anyone.
+// If the protection can't be evaluated, the conflict based Mergeable is reported as well.
+func (pr *PullRequest) APIFormatForDoer(doer *User) *api.PullRequest {
+	return pr.apiFormat(x, doer)
 }
 
-func (pr *PullRequest) apiFormat(e Engine) *api.PullRequest {
+func (pr *PullRequest) apiFormat(e Engine, doer *User) *api.PullRequest {
 	var (
 		baseBranch *git.Branch
 		headBranch *git.Branch
@@ -312,8 +1085,23 @@ func (pr *PullRequest) apiFormat(e Engine) *api.PullRequest {
 	}
 
 	if pr.Status != PullRequestStatusChecking {
-		mergeable := pr.Status != PullRequestStatusConflict && !pr.IsWorkInProgress()
-		apiPullRequest.Mergeable = mergeable
+		apiPullRequest.Mergeable = pr.Status != PullRequestStatusConflict && !pr.IsWorkInProgress() && !pr.IsMergeOnHold
+		if doer != nil && !pr.HasMerged && !pr.Issue.IsClosed {
+			if mergeable, err := pr.ComputeMergeable(doer); err != nil {
+				log.Error("ComputeMergeable[%d]: %v", pr.ID, err)
+			} else {
+				apiPullRequest.Mergeable = mergeable
+			}
+		}
+	}
+	apiPullRequest.IsMergeOnHold = pr.IsMergeOnHold
+	if pr.IsMergeOnHold {
+		apiPullRequest.MergeHoldReason = pr.MergeHoldReason
+	}
+	if err := pr.loadUnresolvedReviewThreads(e); err != nil {
+		log.Error("loadUnresolvedReviewThreads[%d]: %v", pr.ID, err)
+	} else {
+		apiPullRequest.UnresolvedReviewThreads = *pr.UnresolvedReviewThreads
 	}
 	if pr.HasMerged {
 		apiPullRequest.Merged = pr.MergedUnix.AsTimePtr()
@@ -324,6 +1112,26 @@ func (pr *PullRequest) apiFormat(e Engine) *api.PullRequest {
 	return apiPullRequest
 }
 
+// APIFormatWithDeployments returns the API format of the pull request for doer including its latest deployment per environment
+func (pr *PullRequest) APIFormatWithDeployments(doer *User) *api.PullRequest {
+	apiPullRequest := pr.apiFormat(x, doer)
+	if apiPullRequest == nil {
+		return nil
+	}
+
+	deployments, err := getLatestPullRequestDeployments(x, pr.ID)
+	if err != nil {
+		// The pull request itself is still returned, only without its deployments
+		log.Error("getLatestPullRequestDeployments[%d]: %v", pr.ID, err)
+		return apiPullRequest
+	}
+	apiPullRequest.Deployments = make([]*api.PullRequestDeployment, 0, len(deployments))
+	for _, deployment := range deployments {
+		apiPullRequest.Deployments = append(apiPullRequest.Deployments, deployment.APIFormat())
+	}
+	return apiPullRequest
+}
+
 func (pr *PullRequest) getHeadRepo(e Engine) (err error) {
 	pr.HeadRepo, err = getRepositoryByID(e, pr.HeadRepoID)
 	if err != nil && !IsErrRepoNotExist(err) {
@@ -357,40 +1165,87 @@ func (pr *PullRequest) IsChecking() bool {
 
 // CanAutoMerge returns true if this pull request can be merged automatically.
 func (pr *PullRequest) CanAutoMerge() bool {
-	return pr.Status == PullRequestStatusMergeable
+	return pr.Status == PullRequestStatusMergeable && !pr.IsMergeOnHold
 }
 
-// GetLastCommitStatus returns the last commit status for this pull request.
+// SetMergeHold puts the pull request on hold so that it can not be merged
+// until the hold is cleared again, e.g. while an external CI is running.
+func (pr *PullRequest) SetMergeHold(doer *User, reason string) error {
+	pr.IsMergeOnHold = true
+	pr.MergeHoldReason = reason
+	pr.MergeHoldUserID = doer.ID
+	_, err := x.ID(pr.ID).Cols("is_merge_on_hold", "merge_hold_reason", "merge_hold_user_id").Update(pr)
+	return err
+}
+
+// ClearMergeHold releases a hold previously set by SetMergeHold.
+func (pr *PullRequest) ClearMergeHold() error {
+	pr.IsMergeOnHold = false
+	pr.MergeHoldReason = ""
+	pr.MergeHoldUserID = 0
+	_, err := x.ID(pr.ID).Cols("is_merge_on_hold", "merge_hold_reason", "merge_hold_user_id").Update(pr)
+	return err
+}
+
+// GetLastCommitStatus returns the last commit status for this pull request. The result is cached
+// for a short time, see setting.CacheService.CommitStatusTTL, so that e.g. lists of pull requests
+// don't compute it again. Decisions which must not use a stale status, like whether the pull
+// request may be merged, have to use GetLastCommitStatuses instead.
 func (pr *PullRequest) GetLastCommitStatus() (status *CommitStatus, err error) {
-	if err = pr.GetHeadRepo(); err != nil {
+	lastCommitID, err := pr.getHeadCommitID()
+	if err != nil {
+		return nil, err
+	}
+	if err = pr.LoadBaseRepo(); err != nil {
 		return nil, err
 	}
 
+	return getCachedCommitStatus(pr.BaseRepoID, lastCommitID, func() (*CommitStatus, error) {
+		statusList, err := GetLatestCommitStatus(pr.BaseRepo, lastCommitID, 0)
+		if err != nil {
+			return nil, err
+		}
+		return CalcCommitStatus(statusList), nil
+	})
+}
+
+// GetLastCommitStatuses returns the latest commit status of every context
+// reported for the head commit of this pull request.
+func (pr *PullRequest) GetLastCommitStatuses() ([]*CommitStatus, error) {
+	return pr.getHeadCommitStatuses()
+}
+
+// getHeadCommitID returns the ID of the commit the head branch of this pull request points to.
+func (pr *PullRequest) getHeadCommitID() (string, error) {
+	if err := pr.GetHeadRepo(); err != nil {
+		return "", err
+	}
+
 	if pr.HeadRepo == nil {
-		return nil, ErrPullRequestHeadRepoMissing{pr.ID, pr.HeadRepoID}
+		return "", ErrPullRequestHeadRepoMissing{pr.ID, pr.HeadRepoID}
 	}
 
 	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer headGitRepo.Close()
 
-	lastCommitID, err := headGitRepo.GetBranchCommitID(pr.HeadBranch)
-	if err != nil {
-		return nil, err
-	}
+	return headGitRepo.GetBranchCommitID(pr.HeadBranch)
+}
 
-	err = pr.LoadBaseRepo()
+// getHeadCommitStatuses returns the latest commit statuses of the head commit of this pull request.
+func (pr *PullRequest) getHeadCommitStatuses() ([]*CommitStatus, error) {
+	lastCommitID, err := pr.getHeadCommitID()
 	if err != nil {
 		return nil, err
 	}
 
-	statusList, err := GetLatestCommitStatus(pr.BaseRepo, lastCommitID, 0)
-	if err != nil {
+	if err = pr.LoadBaseRepo(); err != nil {
 		return nil, err
 	}
-	return CalcCommitStatus(statusList), nil
+
+	return GetLatestCommitStatus(pr.BaseRepo, lastCommitID, 0)
 }
 
 // MergeStyle represents the approach to merge commits into base branch.
@@ -409,29 +1264,332 @@ const (
 
 // CheckUserAllowedToMerge checks whether the user is allowed to merge
 func (pr *PullRequest) CheckUserAllowedToMerge(doer *User) (err error) {
+	_, err = pr.CheckUserAllowedToForceMerge(doer, false, "")
+	return err
+}
+
+// CheckUserAllowedToForceMerge checks whether the user is allowed to merge like CheckUserAllowedToMerge.
+// If force is set, admins of the base repository may override the protection of the base branch
+// by giving a reason, e.g. in an emergency. It returns whether the protection has been overridden.
+func (pr *PullRequest) CheckUserAllowedToForceMerge(doer *User, force bool, reason string) (overridden bool, err error) {
 	if doer == nil {
-		return ErrNotAllowedToMerge{
+		return false, ErrNotAllowedToMerge{
 			"Not signed in",
 		}
 	}
 
 	if pr.BaseRepo == nil {
 		if err = pr.GetBaseRepo(); err != nil {
-			return fmt.Errorf("GetBaseRepo: %v", err)
+			return false, fmt.Errorf("GetBaseRepo: %v", err)
 		}
 	}
 
-	if protected, err := pr.BaseRepo.IsProtectedBranchForMerging(pr, pr.BaseBranch, doer); err != nil {
-		return fmt.Errorf("IsProtectedBranch: %v", err)
+	protected, err := pr.BaseRepo.IsProtectedBranchForMerging(pr, pr.BaseBranch, doer)
+	if err != nil {
+		return false, fmt.Errorf("IsProtectedBranch: %v", err)
+	}
+	protectedFilesChanged := pr.IsBlockedByChangedProtectedFiles()
+	unresolvedConversations, err := pr.CountBlockingUnresolvedConversations()
+	if err != nil {
+		return false, fmt.Errorf("CountBlockingUnresolvedConversations: %v", err)
+	}
+
+	if force && (protected || protectedFilesChanged || unresolvedConversations > 0) {
+		if len(strings.TrimSpace(reason)) == 0 {
+			return false, ErrNotAllowedToMerge{
+				"A reason is required to override the branch protection",
+			}
+		}
+		perm, err := GetUserRepoPermission(pr.BaseRepo, doer)
+		if err != nil {
+			return false, err
+		}
+		if !perm.IsAdmin() {
+			return false, ErrNotAllowedToMerge{
+				"Only repository admins can override the branch protection",
+			}
+		}
+		overridden = true
 	} else if protected {
-		return ErrNotAllowedToMerge{
+		return false, ErrNotAllowedToMerge{
 			"The branch is protected",
 		}
+	} else if protectedFilesChanged {
+		return false, ErrProtectedFilesChanged{
+			ID:    pr.ID,
+			Files: pr.ChangedProtectedFiles,
+		}
+	} else if unresolvedConversations > 0 {
+		return false, ErrUnresolvedConversations{
+			ID:    pr.ID,
+			Count: unresolvedConversations,
+		}
+	}
+
+	if pr.IsMergeOnHold {
+		return false, ErrMergeOnHold{
+			ID:     pr.ID,
+			Reason: pr.MergeHoldReason,
+		}
 	}
 
+	return overridden, nil
+}
+
+// AvailableMergeStyles returns the merge styles allowed for the base repository of the pull request,
+// or none if doer is not allowed to merge it. Whether the pull request can actually be merged with
+// the returned styles depends on its commits and has to be checked separately.
+func (pr *PullRequest) AvailableMergeStyles(doer *User) ([]MergeStyle, error) {
+	if err := pr.LoadIssue(); err != nil {
+		return nil, err
+	}
+	if pr.HasMerged || pr.Issue.IsClosed {
+		return []MergeStyle{}, nil
+	}
+
+	if err := pr.CheckUserAllowedToMerge(doer); err != nil {
+		if IsErrNotAllowedToMerge(err) || IsErrProtectedFilesChanged(err) || IsErrMergeOnHold(err) || IsErrUnresolvedConversations(err) {
+			return []MergeStyle{}, nil
+		}
+		return nil, err
+	}
+
+	perm, err := GetUserRepoPermission(pr.BaseRepo, doer)
+	if err != nil {
+		return nil, err
+	}
+	if !perm.CanWrite(UnitTypePullRequests) {
+		return []MergeStyle{}, nil
+	}
+
+	return pr.AllowedMergeStyles()
+}
+
+// AllowedMergeStyles returns the merge styles allowed by the config of the base repository
+// which the protection of the base branch does not forbid.
+func (pr *PullRequest) AllowedMergeStyles() ([]MergeStyle, error) {
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return nil, err
+	}
+	prUnit, err := pr.BaseRepo.GetUnit(UnitTypePullRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	styles := prUnit.PullRequestsConfig().AllowedMergeStyles()
+	if pr.ProtectedBranch == nil {
+		return styles, nil
+	}
+	allowed := make([]MergeStyle, 0, len(styles))
+	for _, style := range styles {
+		if pr.ProtectedBranch.IsMergeStyleAllowed(style) {
+			allowed = append(allowed, style)
+		}
+	}
+	return allowed, nil
+}
+
+// CheckMergeStyleAllowed returns ErrLinearHistoryRequired if the protection of the base branch forbids
+// merging with the merge style. Unlike the other protections, it can not be overridden by a forced
+// merge as another merge style can be used instead.
+func (pr *PullRequest) CheckMergeStyleAllowed(style MergeStyle) error {
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return err
+	}
+	if pr.ProtectedBranch != nil && !pr.ProtectedBranch.IsMergeStyleAllowed(style) {
+		return ErrLinearHistoryRequired{BranchName: pr.BaseBranch, Style: style}
+	}
+	return nil
+}
+
+// SetPoster changes the poster of the issue of an imported pull request to the given user,
+// e.g. when the user mapping of a migration got fixed after the import.
+func (pr *PullRequest) SetPoster(userID int64) error {
+	return pr.setPoster(userID, false)
+}
+
+// ForceSetPoster changes the poster of the issue of the pull request to the given user,
+// even if the pull request was not imported. It is meant to be used by site administrators only.
+func (pr *PullRequest) ForceSetPoster(userID int64) error {
+	return pr.setPoster(userID, true)
+}
+
+func (pr *PullRequest) setPoster(userID int64, force bool) (err error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+
+	if err = pr.loadIssue(sess); err != nil {
+		return fmt.Errorf("loadIssue: %v", err)
+	}
+	if err = pr.Issue.loadRepo(sess); err != nil {
+		return fmt.Errorf("loadRepo: %v", err)
+	}
+	if !force && pr.Issue.OriginalAuthorID == 0 && pr.Issue.Repo.OriginalServiceType == api.NotMigrated {
+		return ErrPullRequestNotImported{pr.ID}
+	}
+
+	poster, err := getUserByID(sess, userID)
+	if err != nil {
+		return err
+	}
+
+	pr.Issue.PosterID = poster.ID
+	pr.Issue.OriginalAuthor = ""
+	pr.Issue.OriginalAuthorID = 0
+	if _, err = sess.ID(pr.Issue.ID).Cols("poster_id", "original_author", "original_author_id").Update(pr.Issue); err != nil {
+		return fmt.Errorf("update issue poster: %v", err)
+	}
+
+	if err = sess.Commit(); err != nil {
+		return err
+	}
+	pr.Issue.Poster = poster
 	return nil
 }
 
+// MergeBlockerType defines the kind of reason preventing a pull request from being merged
+type MergeBlockerType int
+
+// Enumerate all the merge blocker types
+const (
+	MergeBlockerNotAllowed              MergeBlockerType = iota + 1 // 1 doer is not allowed to merge
+	MergeBlockerClosed                                              // 2 pull request is already merged or closed
+	MergeBlockerWorkInProgress                                      // 3 pull request is marked as work in progress
+	MergeBlockerChecking                                            // 4 mergeability is still being checked
+	MergeBlockerConflicts                                           // 5 pull request has conflicts with the base branch
+	MergeBlockerApprovals                                           // 6 not enough official approvals
+	MergeBlockerStatusCheck                                         // 7 required status checks are not successful
+	MergeBlockerNoMergeStyle                                        // 8 no merge style is allowed for the repository
+	MergeBlockerOnHold                                              // 9 pull request is on hold
+	MergeBlockerProtectedFiles                                      // 10 protected files are changed without official approval
+	MergeBlockerUnresolvedConversations                             // 11 review conversations are not resolved
+)
+
+// MergeBlocker represents a reason why a pull request can not be merged
+type MergeBlocker struct {
+	Type    MergeBlockerType
+	Message string
+}
+
+// GetMergeBlockers returns every reason why the pull request can not be merged by doer.
+// An empty list means the pull request can be merged.
+func (pr *PullRequest) GetMergeBlockers(doer *User) ([]MergeBlocker, error) {
+	if err := pr.LoadIssue(); err != nil {
+		return nil, fmt.Errorf("LoadIssue: %v", err)
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return nil, fmt.Errorf("LoadBaseRepo: %v", err)
+	}
+
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return nil, fmt.Errorf("LoadProtectedBranch: %v", err)
+	}
+
+	var blockers []MergeBlocker
+	addBlocker := func(tp MergeBlockerType, message string) {
+		blockers = append(blockers, MergeBlocker{Type: tp, Message: message})
+	}
+
+	if doer == nil {
+		addBlocker(MergeBlockerNotAllowed, "Not signed in")
+	} else {
+		perm, err := GetUserRepoPermission(pr.BaseRepo, doer)
+		if err != nil {
+			return nil, fmt.Errorf("GetUserRepoPermission: %v", err)
+		}
+		if !perm.CanWrite(UnitTypeCode) {
+			addBlocker(MergeBlockerNotAllowed, "No write permission to the base repository")
+		} else if pr.ProtectedBranch != nil && !pr.ProtectedBranch.CanUserMerge(doer.ID) {
+			addBlocker(MergeBlockerNotAllowed, "Not allowed to merge into the protected branch")
+		}
+	}
+
+	if pr.HasMerged || pr.Issue.IsClosed {
+		addBlocker(MergeBlockerClosed, "The pull request is already merged or closed")
+	}
+
+	if pr.IsWorkInProgress() {
+		addBlocker(MergeBlockerWorkInProgress, "The pull request is marked as work in progress")
+	}
+
+	if pr.IsChecking() {
+		addBlocker(MergeBlockerChecking, "The pull request is still being checked for conflicts")
+	} else if pr.Status != PullRequestStatusMergeable {
+		addBlocker(MergeBlockerConflicts, "The pull request has conflicts with the base branch")
+	}
+
+	if pr.IsMergeOnHold {
+		if len(pr.MergeHoldReason) > 0 {
+			addBlocker(MergeBlockerOnHold, "The pull request is on hold: "+pr.MergeHoldReason)
+		} else {
+			addBlocker(MergeBlockerOnHold, "The pull request is on hold")
+		}
+	}
+
+	if pr.ProtectedBranch != nil {
+		if !pr.HasEnoughApprovals() {
+			addBlocker(MergeBlockerApprovals, fmt.Sprintf("The pull request requires %d official approvals", pr.ProtectedBranch.RequiredApprovals))
+		}
+
+		if pr.IsBlockedByChangedProtectedFiles() {
+			addBlocker(MergeBlockerProtectedFiles, "Changed protected files require an official approval: "+strings.Join(pr.ChangedProtectedFiles, ", "))
+		}
+
+		if pr.ProtectedBranch.RequireResolvedConversations {
+			count, err := pr.CountUnresolvedReviewThreads()
+			if err != nil {
+				return nil, fmt.Errorf("CountUnresolvedReviewThreads: %v", err)
+			}
+			if count > 0 {
+				addBlocker(MergeBlockerUnresolvedConversations, fmt.Sprintf("The pull request has %d unresolved conversations", count))
+			}
+		}
+
+		if pr.ProtectedBranch.EnableStatusCheck {
+			statuses, err := pr.getHeadCommitStatuses()
+			if err != nil {
+				return nil, fmt.Errorf("getHeadCommitStatuses: %v", err)
+			}
+			if !IsCommitStatusContextSuccess(statuses, pr.ProtectedBranch.StatusCheckContexts) {
+				message := "Required status checks are not successful"
+				if missing := GetMissingRequiredStatusContexts(statuses, pr.ProtectedBranch.StatusCheckContexts); len(missing) > 0 {
+					message += ": " + strings.Join(missing, ", ")
+				}
+				addBlocker(MergeBlockerStatusCheck, message)
+			}
+		}
+	}
+
+	styles, err := pr.AllowedMergeStyles()
+	if err != nil {
+		return nil, fmt.Errorf("AllowedMergeStyles: %v", err)
+	}
+	if len(styles) == 0 {
+		if pr.ProtectedBranch != nil && pr.ProtectedBranch.RequireLinearHistory {
+			addBlocker(MergeBlockerNoMergeStyle, "No merge style without a merge commit is allowed for this repository, but the branch requires a linear history")
+		} else {
+			addBlocker(MergeBlockerNoMergeStyle, "No merge style is allowed for this repository")
+		}
+	}
+
+	return blockers, nil
+}
+
+// ComputeMergeable returns true if doer can merge the pull request right now. Unlike the conflict
+// check, it takes the protection of the base branch into account: the merge whitelist, the required
+// official approvals, the required status checks and changed protected files. The protection is always
+// the one of the base repository, which also applies to pull requests from forks.
+func (pr *PullRequest) ComputeMergeable(doer *User) (bool, error) {
+	blockers, err := pr.GetMergeBlockers(doer)
+	if err != nil {
+		return false, err
+	}
+	return len(blockers) == 0, nil
+}
+
 // SetMerged sets a pull request to merged and closes the corresponding issue
 func (pr *PullRequest) SetMerged() (err error) {
 	if pr.HasMerged {
@@ -460,11 +1618,27 @@ func (pr *PullRequest) SetMerged() (err error) {
 		return err
 	}
 
+	// Only the genuine transition from unmerged to merged may proceed,
+	// a concurrent merge would otherwise record the merge twice.
+	affected, err := sess.ID(pr.ID).Where("has_merged = ?", false).Cols("has_merged, status, merged_commit_id, merger_id, merged_unix").Update(pr)
+	if err != nil {
+		return fmt.Errorf("update pull request: %v", err)
+	} else if affected == 0 {
+		return fmt.Errorf("PullRequest[%d] already merged", pr.Index)
+	}
+
 	if _, err = pr.Issue.changeStatus(sess, pr.Merger, true); err != nil {
 		return fmt.Errorf("Issue.changeStatus: %v", err)
 	}
-	if _, err = sess.ID(pr.ID).Cols("has_merged, status, merged_commit_id, merger_id, merged_unix").Update(pr); err != nil {
-		return fmt.Errorf("update pull request: %v", err)
+
+	if _, err = createComment(sess, &CreateCommentOptions{
+		Type:      CommentTypeMergedPR,
+		Doer:      pr.Merger,
+		Repo:      pr.Issue.Repo,
+		Issue:     pr.Issue,
+		CommitSHA: pr.MergedCommitID,
+	}); err != nil {
+		return fmt.Errorf("createComment: %v", err)
 	}
 
 	if err = sess.Commit(); err != nil {
@@ -516,6 +1690,7 @@ func newPullRequestAttempt(repo *Repository, pull *Issue, labelIDs []int64, uuid
 	pr.BaseRepo = repo
 
 	pr.IssueID = pull.ID
+	pr.HeadUpdatedUnix = timeutil.TimeStampNow()
 	if _, err = sess.Insert(pr); err != nil {
 		return fmt.Errorf("insert pull repo: %v", err)
 	}
@@ -665,6 +1840,40 @@ func (pr *PullRequest) GetWorkInProgressPrefix() string {
 	return ""
 }
 
+// SetReadyForReview removes the work in progress prefix from the title of the pull request
+// and records the transition on its timeline.
+func (pr *PullRequest) SetReadyForReview(doer *User) (err error) {
+	prefix := pr.GetWorkInProgressPrefix()
+	if len(prefix) == 0 {
+		return nil
+	}
+	pr.Issue.Title = strings.TrimSpace(pr.Issue.Title[len(prefix):])
+
+	sess := x.NewSession()
+	defer sess.Close()
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+
+	if err = updateIssueCols(sess, pr.Issue, "name"); err != nil {
+		return fmt.Errorf("updateIssueCols: %v", err)
+	}
+	if err = pr.Issue.loadRepo(sess); err != nil {
+		return fmt.Errorf("loadRepo: %v", err)
+	}
+
+	if _, err = createComment(sess, &CreateCommentOptions{
+		Type:  CommentTypeReadyForReview,
+		Doer:  doer,
+		Repo:  pr.Issue.Repo,
+		Issue: pr.Issue,
+	}); err != nil {
+		return fmt.Errorf("createComment: %v", err)
+	}
+
+	return sess.Commit()
+}
+
 // IsHeadEqualWithBranch returns if the commits of branchName are available in pull request head
 func (pr *PullRequest) IsHeadEqualWithBranch(branchName string) (bool, error) {
 	var err error
@@ -0,0 +1,195 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"strings"
+
+	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/timeutil"
+)
+
+// DeploymentState holds the state of a pull request deployment
+type DeploymentState string
+
+const (
+	// DeploymentStatePending is for a deployment which is in progress
+	DeploymentStatePending DeploymentState = "pending"
+	// DeploymentStateSuccess is for a deployment which is available
+	DeploymentStateSuccess DeploymentState = "success"
+	// DeploymentStateFailure is for a deployment which failed
+	DeploymentStateFailure DeploymentState = "failure"
+)
+
+// IsValid returns true if the state is a known deployment state
+func (state DeploymentState) IsValid() bool {
+	switch state {
+	case DeploymentStatePending, DeploymentStateSuccess, DeploymentStateFailure:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo returns true if a deployment in this state may be followed by one in the next state.
+// A finished deployment must be started again as pending before it can finish once more.
+func (state DeploymentState) CanTransitionTo(next DeploymentState) bool {
+	switch state {
+	case "", DeploymentStatePending:
+		return next.IsValid()
+	case DeploymentStateSuccess, DeploymentStateFailure:
+		return next == DeploymentStatePending
+	}
+	return false
+}
+
+// PullRequestDeployment represents the state of a deployment of a pull request to an environment,
+// e.g. a preview created by CI. Unlike commit statuses, deployments belong to the pull request
+// and are kept when its head changes.
+type PullRequestDeployment struct {
+	ID          int64           `xorm:"pk autoincr"`
+	RepoID      int64           `xorm:"INDEX"`
+	PullID      int64           `xorm:"INDEX"`
+	Environment string          `xorm:"VARCHAR(255)"`
+	URL         string          `xorm:"TEXT"`
+	State       DeploymentState `xorm:"VARCHAR(7) NOT NULL"`
+	CreatorID   int64
+	Creator     *User `xorm:"-"`
+
+	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
+	UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
+}
+
+func (deployment *PullRequestDeployment) loadCreator(e Engine) (err error) {
+	if deployment.Creator != nil || deployment.CreatorID == 0 {
+		return nil
+	}
+	deployment.Creator, err = getUserByID(e, deployment.CreatorID)
+	if IsErrUserNotExist(err) {
+		deployment.Creator = NewGhostUser()
+		return nil
+	}
+	return err
+}
+
+// APIFormat assumes some fields assigned with values:
+// Required - Creator
+func (deployment *PullRequestDeployment) APIFormat() *api.PullRequestDeployment {
+	apiDeployment := &api.PullRequestDeployment{
+		ID:          deployment.ID,
+		Environment: deployment.Environment,
+		URL:         deployment.URL,
+		State:       string(deployment.State),
+		Created:     deployment.CreatedUnix.AsTime(),
+		Updated:     deployment.UpdatedUnix.AsTime(),
+	}
+	if deployment.Creator != nil {
+		apiDeployment.Creator = deployment.Creator.APIFormat()
+	}
+	return apiDeployment
+}
+
+// CreatePullRequestDeploymentOptions holds the information of a new pull request deployment
+type CreatePullRequestDeploymentOptions struct {
+	PullRequest *PullRequest
+	Creator     *User
+	Environment string
+	URL         string
+	State       DeploymentState
+}
+
+// CreatePullRequestDeployment records a new state of the deployment of a pull request to an environment.
+// It returns ErrInvalidDeploymentState if the latest deployment to the environment can not change into the state.
+func CreatePullRequestDeployment(opts CreatePullRequestDeploymentOptions) (*PullRequestDeployment, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	environment := strings.TrimSpace(opts.Environment)
+	latest, err := getLatestPullRequestDeployment(sess, opts.PullRequest.ID, environment)
+	if err != nil {
+		return nil, err
+	}
+	var current DeploymentState
+	if latest != nil {
+		current = latest.State
+	}
+	if len(environment) == 0 || !current.CanTransitionTo(opts.State) {
+		return nil, ErrInvalidDeploymentState{
+			Environment: environment,
+			From:        current,
+			To:          opts.State,
+		}
+	}
+
+	deployment := &PullRequestDeployment{
+		RepoID:      opts.PullRequest.BaseRepoID,
+		PullID:      opts.PullRequest.ID,
+		Environment: environment,
+		URL:         strings.TrimSpace(opts.URL),
+		State:       opts.State,
+		CreatorID:   opts.Creator.ID,
+		Creator:     opts.Creator,
+	}
+	if _, err = sess.Insert(deployment); err != nil {
+		return nil, err
+	}
+	return deployment, sess.Commit()
+}
+
+func getLatestPullRequestDeployment(e Engine, pullID int64, environment string) (*PullRequestDeployment, error) {
+	deployment := new(PullRequestDeployment)
+	has, err := e.Where("pull_id = ? AND environment = ?", pullID, environment).
+		Desc("id").
+		Get(deployment)
+	if err != nil || !has {
+		return nil, err
+	}
+	return deployment, nil
+}
+
+func getPullRequestDeployments(e Engine, pullID int64) ([]*PullRequestDeployment, error) {
+	deployments := make([]*PullRequestDeployment, 0, 5)
+	if err := e.Where("pull_id = ?", pullID).
+		Desc("id").
+		Find(&deployments); err != nil {
+		return nil, err
+	}
+	for _, deployment := range deployments {
+		if err := deployment.loadCreator(e); err != nil {
+			return nil, err
+		}
+	}
+	return deployments, nil
+}
+
+// GetPullRequestDeployments returns all deployment states of a pull request, newest first
+func GetPullRequestDeployments(pullID int64) ([]*PullRequestDeployment, error) {
+	return getPullRequestDeployments(x, pullID)
+}
+
+func getLatestPullRequestDeployments(e Engine, pullID int64) ([]*PullRequestDeployment, error) {
+	deployments, err := getPullRequestDeployments(e, pullID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(deployments))
+	latest := make([]*PullRequestDeployment, 0, len(deployments))
+	for _, deployment := range deployments {
+		if seen[deployment.Environment] {
+			continue
+		}
+		seen[deployment.Environment] = true
+		latest = append(latest, deployment)
+	}
+	return latest, nil
+}
+
+// GetLatestPullRequestDeployments returns the latest deployment state of a pull request per environment, newest first
+func GetLatestPullRequestDeployments(pullID int64) ([]*PullRequestDeployment, error) {
+	return getLatestPullRequestDeployments(x, pullID)
+}
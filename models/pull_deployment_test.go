@@ -0,0 +1,75 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeploymentState_CanTransitionTo(t *testing.T) {
+	var none DeploymentState
+	assert.True(t, none.CanTransitionTo(DeploymentStateSuccess))
+	assert.False(t, none.CanTransitionTo("unknown"))
+	assert.True(t, DeploymentStatePending.CanTransitionTo(DeploymentStateFailure))
+	assert.True(t, DeploymentStatePending.CanTransitionTo(DeploymentStatePending))
+	assert.True(t, DeploymentStateSuccess.CanTransitionTo(DeploymentStatePending))
+	assert.False(t, DeploymentStateSuccess.CanTransitionTo(DeploymentStateFailure))
+	assert.False(t, DeploymentStateFailure.CanTransitionTo(DeploymentStateSuccess))
+}
+
+func TestCreatePullRequestDeployment(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	create := func(environment string, state DeploymentState) (*PullRequestDeployment, error) {
+		return CreatePullRequestDeployment(CreatePullRequestDeploymentOptions{
+			PullRequest: pr,
+			Creator:     doer,
+			Environment: environment,
+			URL:         "https://" + environment + ".example.com",
+			State:       state,
+		})
+	}
+
+	deployment, err := create("preview", DeploymentStatePending)
+	assert.NoError(t, err)
+	assert.EqualValues(t, pr.BaseRepoID, deployment.RepoID)
+	_, err = create("preview", DeploymentStateSuccess)
+	assert.NoError(t, err)
+	_, err = create("preview", DeploymentStateFailure)
+	assert.True(t, IsErrInvalidDeploymentState(err))
+	_, err = create("staging", DeploymentStateFailure)
+	assert.NoError(t, err)
+	_, err = create(" ", DeploymentStatePending)
+	assert.True(t, IsErrInvalidDeploymentState(err))
+
+	deployments, err := GetPullRequestDeployments(pr.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, deployments, 3) {
+		assert.Equal(t, "staging", deployments[0].Environment)
+		assert.Equal(t, DeploymentStateSuccess, deployments[1].State)
+		assert.Equal(t, doer.ID, deployments[2].Creator.ID)
+	}
+
+	latest, err := GetLatestPullRequestDeployments(pr.ID)
+	assert.NoError(t, err)
+	if assert.Len(t, latest, 2) {
+		assert.Equal(t, DeploymentStateFailure, latest[0].State)
+		assert.Equal(t, "preview", latest[1].Environment)
+		assert.Equal(t, DeploymentStateSuccess, latest[1].State)
+	}
+
+	assert.NoError(t, pr.LoadAttributes())
+	assert.NoError(t, pr.LoadIssue())
+	apiPullRequest := pr.APIFormatWithDeployments(nil)
+	if assert.NotNil(t, apiPullRequest) && assert.Len(t, apiPullRequest.Deployments, 2) {
+		assert.Equal(t, "https://preview.example.com", apiPullRequest.Deployments[1].URL)
+	}
+	assert.Nil(t, pr.APIFormat().Deployments)
+}
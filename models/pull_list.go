@@ -6,11 +6,15 @@ package models
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/timeutil"
 
+	"xorm.io/builder"
 	"xorm.io/xorm"
 )
 
@@ -68,6 +72,57 @@ func GetUnmergedPullRequestsByBaseInfo(repoID int64, branch string) ([]*PullRequ
 		Find(&prs)
 }
 
+// GetUnmergedPullRequestsByBaseRepo returns all open pull requests into any branch of the repository
+func GetUnmergedPullRequestsByBaseRepo(repoID int64) ([]*PullRequest, error) {
+	prs := make([]*PullRequest, 0, 10)
+	return prs, x.
+		Where("base_repo_id=? AND has_merged=? AND issue.is_closed=?",
+			repoID, false, false).
+		Join("INNER", "issue", "issue.id=pull_request.issue_id").
+		Find(&prs)
+}
+
+// FindDuplicateOpenPullRequests returns the open pull requests sharing the same head and base
+// with an older open pull request. The oldest pull request of every group is kept out of the
+// result so that the returned ones can be cleaned up.
+func FindDuplicateOpenPullRequests() ([]*PullRequest, error) {
+	type prTarget struct {
+		HeadRepoID int64
+		HeadBranch string
+		BaseRepoID int64
+		BaseBranch string
+	}
+
+	targets := make([]*prTarget, 0, 10)
+	if err := x.Table("pull_request").
+		Join("INNER", "issue", "issue.id = pull_request.issue_id").
+		Where("pull_request.has_merged = ? AND issue.is_closed = ?", false, false).
+		Select("pull_request.head_repo_id, pull_request.head_branch, pull_request.base_repo_id, pull_request.base_branch").
+		GroupBy("pull_request.head_repo_id, pull_request.head_branch, pull_request.base_repo_id, pull_request.base_branch").
+		Having("count(*) > 1").
+		Find(&targets); err != nil {
+		return nil, fmt.Errorf("find duplicate targets: %v", err)
+	}
+
+	duplicates := make([]*PullRequest, 0, len(targets))
+	for _, target := range targets {
+		prs := make([]*PullRequest, 0, 2)
+		if err := x.
+			Where("pull_request.head_repo_id = ? AND pull_request.head_branch = ? AND pull_request.base_repo_id = ? AND pull_request.base_branch = ?",
+				target.HeadRepoID, target.HeadBranch, target.BaseRepoID, target.BaseBranch).
+			And("pull_request.has_merged = ? AND issue.is_closed = ?", false, false).
+			Join("INNER", "issue", "issue.id = pull_request.issue_id").
+			Asc("pull_request.id").
+			Find(&prs); err != nil {
+			return nil, fmt.Errorf("find duplicate pull requests: %v", err)
+		}
+		if len(prs) > 1 {
+			duplicates = append(duplicates, prs[1:]...)
+		}
+	}
+	return duplicates, nil
+}
+
 // GetPullRequestIDsByCheckStatus returns all pull requests according the special checking status.
 func GetPullRequestIDsByCheckStatus(status PullRequestStatus) ([]int64, error) {
 	prs := make([]int64, 0, 10)
@@ -105,6 +160,168 @@ func PullRequests(baseRepoID int64, opts *PullRequestsOptions) ([]*PullRequest,
 	return prs, maxResults, findSession.Find(&prs)
 }
 
+func pullRequestsAwaitingReviewCond(userID int64) builder.Cond {
+	return builder.And(
+		builder.Eq{"issue.is_closed": false, "pull_request.has_merged": false},
+		builder.In("issue.id", builder.Select("issue_id").From("review").
+			Where(builder.Eq{"reviewer_id": userID, "type": ReviewTypeRequest})),
+		builder.NotIn("issue.id", reviewedSinceRequestCond(userID)),
+		builder.In("issue.repo_id", builder.Select("`repository`.id").From("repository").
+			Where(accessibleRepositoryCondition(userID))),
+	)
+}
+
+// GetPullRequestsAwaitingReview returns the open pull requests across all repositories the user can access
+// for which a review of the user has been requested and which the user has not reviewed since the request
+// and the last push to the head branch. Approvals which have been dismissed as stale don't count as review.
+func GetPullRequestsAwaitingReview(userID int64, page, pageSize int) ([]*PullRequest, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = ItemsPerPage
+	}
+
+	cond := pullRequestsAwaitingReviewCond(userID)
+	count, err := x.Join("INNER", "issue", "pull_request.issue_id = issue.id").
+		Where(cond).
+		Count(new(PullRequest))
+	if err != nil {
+		return nil, 0, fmt.Errorf("Count: %v", err)
+	}
+
+	prs := make([]*PullRequest, 0, pageSize)
+	if err = x.Join("INNER", "issue", "pull_request.issue_id = issue.id").
+		Where(cond).
+		Desc("issue.updated_unix").
+		Limit(pageSize, (page-1)*pageSize).
+		Find(&prs); err != nil {
+		return nil, 0, fmt.Errorf("Find: %v", err)
+	}
+
+	if err = PullRequestList(prs).LoadAttributes(); err != nil {
+		return nil, 0, err
+	}
+
+	repoIDs := make([]int64, 0, len(prs))
+	for _, pr := range prs {
+		repoIDs = append(repoIDs, pr.BaseRepoID)
+	}
+	repos, err := GetRepositoriesMapByIDs(repoIDs)
+	if err != nil {
+		return nil, 0, fmt.Errorf("GetRepositoriesMapByIDs: %v", err)
+	}
+	for _, pr := range prs {
+		pr.BaseRepo = repos[pr.BaseRepoID]
+		if pr.Issue != nil {
+			pr.Issue.Repo = pr.BaseRepo
+		}
+	}
+
+	return prs, count, nil
+}
+
+// GetPullRequestsByLabel returns the open pull requests into the repository which carry the label,
+// see GetPullRequestsByLabelAndState
+func GetPullRequestsByLabel(repoID, labelID int64, page, pageSize int) ([]*PullRequest, int64, error) {
+	return GetPullRequestsByLabelAndState(repoID, labelID, "open", page, pageSize)
+}
+
+// GetPullRequestsByLabelAndState returns a page of the pull requests into the repository which carry
+// the label, along with their total count. state is "open" or "closed", any other state returns both.
+// The pull requests come with their status and their issues loaded, newest first.
+func GetPullRequestsByLabelAndState(repoID, labelID int64, state string, page, pageSize int) ([]*PullRequest, int64, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = ItemsPerPage
+	}
+
+	cond := builder.NewCond().And(
+		builder.Eq{"pull_request.base_repo_id": repoID},
+		builder.In("pull_request.issue_id", builder.Select("issue_id").From("issue_label").
+			Where(builder.Eq{"label_id": labelID})),
+	)
+	switch state {
+	case "open", "closed":
+		cond = cond.And(builder.Eq{"issue.is_closed": state == "closed"})
+	}
+
+	count, err := x.Join("INNER", "issue", "pull_request.issue_id = issue.id").
+		Where(cond).
+		Count(new(PullRequest))
+	if err != nil {
+		return nil, 0, fmt.Errorf("Count: %v", err)
+	}
+
+	prs := make([]*PullRequest, 0, pageSize)
+	if err = x.Join("INNER", "issue", "pull_request.issue_id = issue.id").
+		Where(cond).
+		Desc("issue.created_unix", "pull_request.id").
+		Limit(pageSize, (page-1)*pageSize).
+		Find(&prs); err != nil {
+		return nil, 0, fmt.Errorf("Find: %v", err)
+	}
+
+	return prs, count, PullRequestList(prs).LoadAttributes()
+}
+
+// FilterMergeablePullRequests returns the pull requests which are ready to be merged as far as
+// their last conflict check is concerned, i.e. unmerged and open ones which merge cleanly.
+// Branch protection is not taken into account, see PullRequest.ComputeMergeable for that.
+func FilterMergeablePullRequests(prs []*PullRequest) []*PullRequest {
+	mergeable := make([]*PullRequest, 0, len(prs))
+	for _, pr := range prs {
+		if pr.HasMerged || pr.Status != PullRequestStatusMergeable {
+			continue
+		}
+		if pr.Issue != nil && pr.Issue.IsClosed {
+			continue
+		}
+		mergeable = append(mergeable, pr)
+	}
+	return mergeable
+}
+
+// GetPullRequestsMergedBetween returns the pull requests of the repository whose merge commit
+// is reachable from toRef but not from fromRef, ordered by merge time. Their issues are loaded.
+func GetPullRequestsMergedBetween(repo *Repository, fromRef, toRef string) ([]*PullRequest, error) {
+	if strings.HasPrefix(fromRef, "-") || strings.HasPrefix(toRef, "-") {
+		return nil, fmt.Errorf("invalid ref range: %s..%s", fromRef, toRef)
+	}
+	stdout, err := git.NewCommand("rev-list", fromRef+".."+toRef, "--").RunInDir(repo.RepoPath())
+	if err != nil {
+		return nil, fmt.Errorf("rev-list: %v", err)
+	}
+	commitIDs := strings.Fields(stdout)
+
+	prs := make(PullRequestList, 0, 10)
+	for len(commitIDs) > 0 {
+		var limit = defaultMaxInSize
+		if len(commitIDs) < limit {
+			limit = len(commitIDs)
+		}
+
+		if err := x.
+			Where("base_repo_id = ? AND has_merged = ?", repo.ID, true).
+			In("merged_commit_id", commitIDs[:limit]).
+			Find(&prs); err != nil {
+			return nil, err
+		}
+		commitIDs = commitIDs[limit:]
+	}
+
+	sort.SliceStable(prs, func(i, j int) bool {
+		return prs[i].MergedUnix < prs[j].MergedUnix
+	})
+
+	if err := prs.LoadAttributes(); err != nil {
+		return nil, err
+	}
+	return prs, prs.LoadMergers()
+}
+
 // PullRequestList defines a list of pull requests
 type PullRequestList []*PullRequest
 
@@ -146,6 +363,59 @@ func (prs PullRequestList) LoadAttributes() error {
 	return prs.loadAttributes(x)
 }
 
+func (prs PullRequestList) getMergerIDs() []int64 {
+	mergerIDs := make(map[int64]struct{}, len(prs))
+	for _, pr := range prs {
+		if pr.HasMerged && pr.Merger == nil && pr.MergerID > 0 {
+			mergerIDs[pr.MergerID] = struct{}{}
+		}
+	}
+	return keysInt64(mergerIDs)
+}
+
+func (prs PullRequestList) loadMergers(e Engine) error {
+	if len(prs) == 0 {
+		return nil
+	}
+
+	mergerIDs := prs.getMergerIDs()
+	mergerMaps := make(map[int64]*User, len(mergerIDs))
+	var left = len(mergerIDs)
+	for left > 0 {
+		var limit = defaultMaxInSize
+		if left < limit {
+			limit = left
+		}
+		err := e.
+			In("id", mergerIDs[:limit]).
+			Find(&mergerMaps)
+		if err != nil {
+			return err
+		}
+		left -= limit
+		mergerIDs = mergerIDs[limit:]
+	}
+
+	for _, pr := range prs {
+		if !pr.HasMerged || pr.Merger != nil {
+			continue
+		}
+		var ok bool
+		if pr.Merger, ok = mergerMaps[pr.MergerID]; !ok {
+			// Same as PullRequest.loadAttributes for deleted mergers
+			pr.MergerID = -1
+			pr.Merger = NewGhostUser()
+		}
+	}
+	return nil
+}
+
+// LoadMergers loads the mergers of all merged pull requests of the list in one go.
+// Mergers which do not exist anymore are replaced by the ghost user.
+func (prs PullRequestList) LoadMergers() error {
+	return prs.loadMergers(x)
+}
+
 func (prs PullRequestList) invalidateCodeComments(e Engine, doer *User, repo *git.Repository, branch string) error {
 	if len(prs) == 0 {
 		return nil
@@ -170,3 +440,41 @@ func (prs PullRequestList) invalidateCodeComments(e Engine, doer *User, repo *gi
 func (prs PullRequestList) InvalidateCodeComments(doer *User, repo *git.Repository, branch string) error {
 	return prs.invalidateCodeComments(x, doer, repo, branch)
 }
+
+// StalePullRequestKeepOpenLabel is the name of the label which keeps a pull request from being closed as stale
+const StalePullRequestKeepOpenLabel = "keep open"
+
+// GetStalePullRequestIssues returns the issues of the open, unmerged pull requests of the repository which
+// have not been updated since updatedBefore and are not labeled StalePullRequestKeepOpenLabel.
+func GetStalePullRequestIssues(repoID int64, updatedBefore timeutil.TimeStamp) ([]*Issue, error) {
+	keepOpen := builder.Select("issue_label.issue_id").From("issue_label").
+		Join("INNER", "label", "label.id = issue_label.label_id").
+		Where(builder.Expr("LOWER(label.name) = ?", StalePullRequestKeepOpenLabel))
+
+	issues := make([]*Issue, 0, 10)
+	return issues, x.
+		Where("repo_id = ?", repoID).
+		And("is_pull = ?", true).
+		And("is_closed = ?", false).
+		And("updated_unix < ?", updatedBefore).
+		And(builder.In("id", builder.Select("issue_id").From("pull_request").Where(builder.Eq{"has_merged": false}))).
+		And(builder.NotIn("id", keepOpen)).
+		Asc("id").
+		Find(&issues)
+}
+
+// GetRepoIDsClosingStalePullRequests returns the IDs of the repositories which close stale pull requests
+func GetRepoIDsClosingStalePullRequests() ([]int64, error) {
+	units := make([]*RepoUnit, 0, 10)
+	if err := x.Where("`type` = ?", UnitTypePullRequests).Find(&units); err != nil {
+		return nil, err
+	}
+
+	repoIDs := make([]int64, 0, len(units))
+	for _, unit := range units {
+		if unit.PullRequestsConfig().CloseStale {
+			repoIDs = append(repoIDs, unit.RepoID)
+		}
+	}
+	return repoIDs, nil
+}
@@ -0,0 +1,143 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+)
+
+// GetRelatedPullRequests returns the other open pull requests into the base repository which
+// contain a commit with the same changes as a commit of this pull request, e.g. because one
+// of them was cherry-picked, to point reviewers to duplicate work.
+//
+// Commits are compared by their patch-id (git patch-id --stable), a hash of the diff of the
+// commit which ignores whitespace and line numbers. So the commits only match as long as their
+// changes are the same: changes adapted while cherry-picking, e.g. to resolve a conflict, or
+// commits which have been squashed or split are not detected. Merge commits are not compared.
+//
+// As the diff of every commit of all open pull requests has to be computed, nothing is returned
+// unless setting.Repository.PullRequest.EnableRelatedPullRequests is set, pull requests with
+// more than RelatedPullRequestsMaxCommits commits are not compared, and only the
+// RelatedPullRequestsMaxPulls most recent other pull requests are. The patch-ids of a pull request
+// are cached for its head and base commits.
+func (pr *PullRequest) GetRelatedPullRequests() ([]*PullRequest, error) {
+	if !setting.Repository.PullRequest.EnableRelatedPullRequests {
+		return nil, nil
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return nil, err
+	}
+	repoPath := pr.BaseRepo.RepoPath()
+
+	patchIDs, err := pr.getPatchIDs(repoPath)
+	if err != nil {
+		return nil, err
+	} else if len(patchIDs) == 0 {
+		return nil, nil
+	}
+
+	prs, err := GetUnmergedPullRequestsByBaseRepo(pr.BaseRepoID)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(prs, func(i, j int) bool {
+		return prs[i].ID > prs[j].ID
+	})
+	related := make([]*PullRequest, 0, 2)
+	compared := 0
+	for _, other := range prs {
+		if other.ID == pr.ID {
+			continue
+		} else if compared >= setting.Repository.PullRequest.RelatedPullRequestsMaxPulls {
+			break
+		}
+		compared++
+		other.BaseRepo = pr.BaseRepo
+
+		// an unavailable head of another pull request does not prevent comparing the others
+		otherPatchIDs, err := other.getPatchIDs(repoPath)
+		if err != nil {
+			log.Error("getPatchIDs [%d]: %v", other.ID, err)
+			continue
+		}
+		for patchID := range otherPatchIDs {
+			if patchIDs[patchID] {
+				related = append(related, other)
+				break
+			}
+		}
+	}
+	return related, nil
+}
+
+// getPatchIDs returns the patch-ids of the commits of the pull request which are not on its base
+// branch. Nothing is returned for pull requests with more commits than are compared.
+func (pr *PullRequest) getPatchIDs(repoPath string) (map[string]bool, error) {
+	stdout, err := git.NewCommand("rev-parse", git.BranchPrefix+pr.BaseBranch, pr.GetGitRefName()).RunInDir(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("git rev-parse: %v", err)
+	}
+	commitIDs := strings.Fields(stdout)
+	if len(commitIDs) != 2 {
+		return nil, fmt.Errorf("git rev-parse: unexpected output %q", stdout)
+	}
+
+	key := fmt.Sprintf("repo:%d:patch_ids:%s..%s", pr.BaseRepoID, commitIDs[0], commitIDs[1])
+	joined, err := cache.GetString(key, func() (string, error) {
+		return getPatchIDsBetween(repoPath, commitIDs[0], commitIDs[1])
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	patchIDs := make(map[string]bool)
+	for _, patchID := range strings.Fields(joined) {
+		patchIDs[patchID] = true
+	}
+	return patchIDs, nil
+}
+
+// getPatchIDsBetween returns the patch-ids of the commits reachable from head but not from base,
+// separated by newlines. Nothing is returned for more commits than are compared.
+func getPatchIDsBetween(repoPath, base, head string) (string, error) {
+	commitRange := base + ".." + head
+	count, err := countCommitsBetween(repoPath, base, head)
+	if err != nil {
+		return "", err
+	} else if count == 0 || count > setting.Repository.PullRequest.RelatedPullRequestsMaxCommits {
+		return "", nil
+	}
+
+	patches := new(bytes.Buffer)
+	stderr := new(bytes.Buffer)
+	if err := git.NewCommand("log", "-p", "--no-merges", "--no-color", "--format=commit %H", commitRange, "--").
+		RunInDirPipeline(repoPath, patches, stderr); err != nil {
+		return "", fmt.Errorf("git log: %v - %s", err, stderr)
+	}
+
+	stdout := new(bytes.Buffer)
+	stderr.Reset()
+	if err := git.NewCommand("patch-id", "--stable").RunInDirFullPipeline(repoPath, stdout, stderr, patches); err != nil {
+		return "", fmt.Errorf("git patch-id: %v - %s", err, stderr)
+	}
+
+	// every line consists of the patch-id and the commit ID
+	patchIDs := make([]string, 0, count)
+	for _, line := range strings.Split(stdout.String(), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			patchIDs = append(patchIDs, fields[0])
+		}
+	}
+	return strings.Join(patchIDs, "\n"), nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequest_GetRelatedPullRequests(t *testing.T) {
+	PrepareTestEnv(t)
+
+	const initialCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	repoPath := RepoPath("user2", "repo1")
+	run := func(stdin string, args ...string) string {
+		stdout := new(strings.Builder)
+		stderr := new(strings.Builder)
+		err := git.NewCommand(args...).RunInDirTimeoutEnvFullPipeline(nil, -1, repoPath, stdout, stderr, strings.NewReader(stdin))
+		assert.NoError(t, err, stderr.String())
+		return strings.TrimSpace(stdout.String())
+	}
+	// commit adds a file to the tree of the parent
+	commit := func(parent, name string) string {
+		blob := run(name+"\n", "hash-object", "-w", "--stdin")
+		entries := run("", "ls-tree", parent+"^{tree}")
+		tree := run(fmt.Sprintf("%s\n100644 blob %s\t%s\n", entries, blob, name), "mktree")
+		return CreateTestCommit(t, repoPath, TestCommitOptions{Tree: tree, Parents: []string{parent}, Message: name})
+	}
+
+	// pull request 1 is a cherry-pick of the second commit of pull request 2
+	pr1 := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	pr1.HasMerged = false
+	assert.NoError(t, pr1.UpdateCols("has_merged"))
+	pr2 := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	UpdateTestRef(t, repoPath, git.BranchPrefix+"master", initialCommitID)
+	UpdateTestRef(t, repoPath, pr2.GetGitRefName(), commit(commit(initialCommitID, "a.txt"), "b.txt"))
+	UpdateTestRef(t, repoPath, pr1.GetGitRefName(), commit(initialCommitID, "b.txt"))
+
+	defer func(enabled bool, maxCommits, maxPulls int) {
+		setting.Repository.PullRequest.EnableRelatedPullRequests = enabled
+		setting.Repository.PullRequest.RelatedPullRequestsMaxCommits = maxCommits
+		setting.Repository.PullRequest.RelatedPullRequestsMaxPulls = maxPulls
+	}(setting.Repository.PullRequest.EnableRelatedPullRequests, setting.Repository.PullRequest.RelatedPullRequestsMaxCommits,
+		setting.Repository.PullRequest.RelatedPullRequestsMaxPulls)
+
+	setting.Repository.PullRequest.EnableRelatedPullRequests = false
+	related, err := pr2.GetRelatedPullRequests()
+	assert.NoError(t, err)
+	assert.Empty(t, related)
+
+	setting.Repository.PullRequest.EnableRelatedPullRequests = true
+	related, err = pr2.GetRelatedPullRequests()
+	assert.NoError(t, err)
+	if assert.Len(t, related, 1) {
+		assert.EqualValues(t, pr1.ID, related[0].ID)
+	}
+
+	// pull request 2 has too many commits to be compared
+	setting.Repository.PullRequest.RelatedPullRequestsMaxCommits = 1
+	related, err = pr2.GetRelatedPullRequests()
+	assert.NoError(t, err)
+	assert.Empty(t, related)
+	setting.Repository.PullRequest.RelatedPullRequestsMaxCommits = 100
+
+	// only as many other pull requests as configured are compared
+	setting.Repository.PullRequest.RelatedPullRequestsMaxPulls = 0
+	related, err = pr2.GetRelatedPullRequests()
+	assert.NoError(t, err)
+	assert.Empty(t, related)
+	setting.Repository.PullRequest.RelatedPullRequestsMaxPulls = 50
+
+	// other changes are not related
+	UpdateTestRef(t, repoPath, pr1.GetGitRefName(), commit(commit(initialCommitID, "c.txt"), "d.txt"))
+	related, err = pr2.GetRelatedPullRequests()
+	assert.NoError(t, err)
+	assert.Empty(t, related)
+}
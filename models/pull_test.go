@@ -5,8 +5,14 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+
 	"github.com/stretchr/testify/assert"
 )
 
@@ -58,6 +64,23 @@ func TestPullRequest_GetHeadRepo(t *testing.T) {
 	assert.Equal(t, pr.HeadRepoID, pr.HeadRepo.ID)
 }
 
+func TestPullRequest_GetBranchHTMLURL(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	assert.Equal(t, setting.AppURL+"user2/repo1/src/branch/branch1", pr.GetHeadBranchHTMLURL())
+	assert.Equal(t, setting.AppURL+"user2/repo1/src/branch/master", pr.GetBaseBranchHTMLURL())
+
+	// the head branch of a pull request from a fork lives in the fork
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 3}).(*PullRequest)
+	assert.Equal(t, setting.AppURL+"user13/repo11/src/branch/branch2", pr.GetHeadBranchHTMLURL())
+	assert.Equal(t, setting.AppURL+"user12/repo10/src/branch/master", pr.GetBaseBranchHTMLURL())
+
+	pr = &PullRequest{HeadRepoID: NonexistentID, BaseRepoID: NonexistentID, HeadBranch: "branch1"}
+	assert.Empty(t, pr.GetHeadBranchHTMLURL())
+	assert.Empty(t, pr.GetBaseBranchHTMLURL())
+}
+
 // TODO TestMerge
 
 // TODO TestNewPullRequest
@@ -127,6 +150,138 @@ func TestGetUnmergedPullRequestsByBaseInfo(t *testing.T) {
 	assert.Equal(t, "master", pr.BaseBranch)
 }
 
+func TestFindDuplicateOpenPullRequests(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	prs, err := FindDuplicateOpenPullRequests()
+	assert.NoError(t, err)
+	assert.Len(t, prs, 0)
+
+	duplicate := &PullRequest{
+		IssueID:    3,
+		Index:      3,
+		HeadRepoID: 1,
+		HeadBranch: "branch2",
+		BaseRepoID: 1,
+		BaseBranch: "master",
+	}
+	_, err = x.Insert(duplicate)
+	assert.NoError(t, err)
+
+	prs, err = FindDuplicateOpenPullRequests()
+	assert.NoError(t, err)
+	if assert.Len(t, prs, 1) {
+		assert.Equal(t, duplicate.ID, prs[0].ID)
+	}
+}
+
+func TestGetPullRequestsAwaitingReview(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	issue := AssertExistsAndLoadBean(t, &Issue{ID: 3}).(*Issue)
+	reviewer := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+
+	prs, count, err := GetPullRequestsAwaitingReview(reviewer.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+	assert.Len(t, prs, 0)
+
+	request, err := CreateReviewRequest(issue, reviewer)
+	assert.NoError(t, err)
+	again, err := CreateReviewRequest(issue, reviewer)
+	assert.NoError(t, err)
+	assert.Equal(t, request.ID, again.ID)
+
+	prs, count, err = GetPullRequestsAwaitingReview(reviewer.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	if assert.Len(t, prs, 1) {
+		assert.EqualValues(t, 2, prs[0].ID)
+		assert.NotNil(t, prs[0].Issue)
+		assert.EqualValues(t, "repo1", prs[0].BaseRepo.Name)
+	}
+
+	_, err = CreateReview(CreateReviewOptions{Type: ReviewTypeApprove, Issue: issue, Reviewer: reviewer})
+	assert.NoError(t, err)
+	_, count, err = GetPullRequestsAwaitingReview(reviewer.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+
+	// new commits dismiss the approval
+	assert.NoError(t, MarkReviewsAsStale(issue.ID))
+	_, count, err = GetPullRequestsAwaitingReview(reviewer.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	review, err := CreateReview(CreateReviewOptions{Type: ReviewTypeComment, Issue: issue, Reviewer: reviewer})
+	assert.NoError(t, err)
+	_, count, err = GetPullRequestsAwaitingReview(reviewer.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+
+	// a push after the review asks for a review of the new commits
+	pr := AssertExistsAndLoadBean(t, &PullRequest{IssueID: issue.ID}).(*PullRequest)
+	pr.HeadUpdatedUnix = review.UpdatedUnix + 1
+	assert.NoError(t, pr.UpdateCols("head_updated_unix"))
+	_, count, err = GetPullRequestsAwaitingReview(reviewer.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+}
+
+func TestGetPullRequestsByLabel(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	label := AssertExistsAndLoadBean(t, &Label{ID: 1}).(*Label)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	issue3 := AssertExistsAndLoadBean(t, &Issue{ID: 3}).(*Issue)
+
+	// the issue of pull request 1 carries the label, see fixtures/issue_label.yml
+	prs, count, err := GetPullRequestsByLabel(1, label.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	if assert.Len(t, prs, 1) {
+		assert.EqualValues(t, 1, prs[0].ID)
+		assert.NotNil(t, prs[0].Issue)
+	}
+	// it is merged already
+	assert.Empty(t, FilterMergeablePullRequests(prs))
+
+	assert.NoError(t, NewIssueLabel(issue3, label, doer))
+	prs, count, err = GetPullRequestsByLabel(1, label.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	assert.Len(t, prs, 2)
+	mergeable := FilterMergeablePullRequests(prs)
+	if assert.Len(t, mergeable, 1) {
+		assert.EqualValues(t, 2, mergeable[0].ID)
+		assert.Equal(t, PullRequestStatusMergeable, mergeable[0].Status)
+	}
+
+	prs, count, err = GetPullRequestsByLabel(1, label.ID, 2, 1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+	assert.Len(t, prs, 1)
+
+	issue3.IsClosed = true
+	_, err = x.ID(issue3.ID).Cols("is_closed").Update(issue3)
+	assert.NoError(t, err)
+	_, count, err = GetPullRequestsByLabel(1, label.ID, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	prs, count, err = GetPullRequestsByLabelAndState(1, label.ID, "closed", 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	if assert.Len(t, prs, 1) {
+		assert.EqualValues(t, 2, prs[0].ID)
+	}
+	_, count, err = GetPullRequestsByLabelAndState(1, label.ID, "all", 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, count)
+
+	_, count, err = GetPullRequestsByLabel(1, 2, 1, 10)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+}
+
 func TestGetPullRequestByIndex(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 	pr, err := GetPullRequestByIndex(1, 2)
@@ -206,6 +361,28 @@ func TestPullRequestList_LoadAttributes(t *testing.T) {
 	assert.NoError(t, PullRequestList([]*PullRequest{}).LoadAttributes())
 }
 
+func TestPullRequestList_LoadMergers(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	deleted := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	deleted.MergerID = NonexistentID
+	prs := []*PullRequest{
+		AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest),
+		AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest),
+		deleted,
+	}
+	assert.NoError(t, PullRequestList(prs).LoadMergers())
+
+	assert.NotNil(t, prs[0].Merger)
+	assert.EqualValues(t, 2, prs[0].Merger.ID)
+	assert.Nil(t, prs[1].Merger)
+	assert.NotNil(t, prs[2].Merger)
+	assert.EqualValues(t, -1, prs[2].Merger.ID)
+	assert.EqualValues(t, -1, prs[2].MergerID)
+
+	assert.NoError(t, PullRequestList([]*PullRequest{}).LoadMergers())
+}
+
 // TODO TestAddTestPullRequestTask
 
 func TestPullRequest_IsWorkInProgress(t *testing.T) {
@@ -238,3 +415,755 @@ func TestPullRequest_GetWorkInProgressPrefixWorkInProgress(t *testing.T) {
 	pr.Issue.Title = "[wip] " + original
 	assert.Equal(t, "[wip]", pr.GetWorkInProgressPrefix())
 }
+
+func TestPullRequest_SetPoster(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	err := pr.SetPoster(4)
+	assert.True(t, IsErrPullRequestNotImported(err))
+	AssertExistsAndLoadBean(t, &Issue{ID: pr.IssueID, PosterID: 1})
+
+	_, err = x.ID(pr.IssueID).Cols("original_author", "original_author_id").
+		Update(&Issue{OriginalAuthor: "imported", OriginalAuthorID: 1234})
+	assert.NoError(t, err)
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.NoError(t, pr.SetPoster(4))
+	assert.EqualValues(t, 4, pr.Issue.PosterID)
+	assert.EqualValues(t, 4, pr.Issue.Poster.ID)
+	issue := AssertExistsAndLoadBean(t, &Issue{ID: pr.IssueID, PosterID: 4}).(*Issue)
+	assert.Empty(t, issue.OriginalAuthor)
+	assert.Zero(t, issue.OriginalAuthorID)
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	assert.NoError(t, pr.ForceSetPoster(4))
+	AssertExistsAndLoadBean(t, &Issue{ID: pr.IssueID, PosterID: 4})
+
+	assert.True(t, IsErrUserNotExist(pr.ForceSetPoster(NonexistentID)))
+}
+
+func TestPullRequest_GetMergeBlockers(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+
+	blockers, err := pr.GetMergeBlockers(doer)
+	assert.NoError(t, err)
+	assert.Len(t, blockers, 0)
+
+	blockers, err = pr.GetMergeBlockers(nil)
+	assert.NoError(t, err)
+	if assert.Len(t, blockers, 1) {
+		assert.Equal(t, MergeBlockerNotAllowed, blockers[0].Type)
+	}
+
+	pr.Status = PullRequestStatusConflict
+	pr.Issue.Title = "WIP: " + pr.Issue.Title
+	blockers, err = pr.GetMergeBlockers(doer)
+	assert.NoError(t, err)
+	if assert.Len(t, blockers, 2) {
+		assert.Equal(t, MergeBlockerWorkInProgress, blockers[0].Type)
+		assert.Equal(t, MergeBlockerConflicts, blockers[1].Type)
+	}
+
+	assert.NoError(t, UpdateProtectBranch(pr.BaseRepo, &ProtectedBranch{
+		RepoID:            pr.BaseRepoID,
+		BranchName:        pr.BaseBranch,
+		RequiredApprovals: 1,
+	}, WhitelistOptions{}))
+	pr.Status = PullRequestStatusMergeable
+	pr.Issue.Title = "Ready"
+	blockers, err = pr.GetMergeBlockers(doer)
+	assert.NoError(t, err)
+	if assert.Len(t, blockers, 1) {
+		assert.Equal(t, MergeBlockerApprovals, blockers[0].Type)
+	}
+}
+
+func TestPullRequest_ComputeMergeable(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// pull request from repo11 into its fork parent repo10
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 3}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 12}).(*User)
+	assert.NoError(t, pr.LoadIssue())
+
+	mergeable, err := pr.ComputeMergeable(doer)
+	assert.NoError(t, err)
+	assert.True(t, mergeable)
+
+	// the protection of the head repository does not apply
+	headRepo := AssertExistsAndLoadBean(t, &Repository{ID: pr.HeadRepoID}).(*Repository)
+	assert.NoError(t, UpdateProtectBranch(headRepo, &ProtectedBranch{
+		RepoID:            headRepo.ID,
+		BranchName:        pr.BaseBranch,
+		RequiredApprovals: 1,
+	}, WhitelistOptions{}))
+	mergeable, err = pr.ComputeMergeable(doer)
+	assert.NoError(t, err)
+	assert.True(t, mergeable)
+
+	assert.NoError(t, pr.LoadBaseRepo())
+	assert.NoError(t, UpdateProtectBranch(pr.BaseRepo, &ProtectedBranch{
+		RepoID:            pr.BaseRepoID,
+		BranchName:        pr.BaseBranch,
+		RequiredApprovals: 1,
+	}, WhitelistOptions{}))
+	mergeable, err = pr.ComputeMergeable(doer)
+	assert.NoError(t, err)
+	assert.False(t, mergeable)
+
+	// only the format for a doer reports the base branch protection
+	assert.True(t, pr.APIFormat().Mergeable)
+	assert.False(t, pr.APIFormatForDoer(doer).Mergeable)
+}
+
+func TestPullRequest_SetReadyForReview(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.NoError(t, pr.LoadIssue())
+	title := pr.Issue.Title
+
+	// not a work in progress, nothing to do
+	assert.NoError(t, pr.SetReadyForReview(doer))
+	AssertNotExistsBean(t, &Comment{IssueID: pr.IssueID, Type: CommentTypeReadyForReview})
+
+	pr.Issue.Title = "WIP: " + title
+	assert.True(t, pr.IsWorkInProgress())
+	assert.NoError(t, pr.SetReadyForReview(doer))
+	assert.False(t, pr.IsWorkInProgress())
+	AssertExistsAndLoadBean(t, &Issue{ID: pr.IssueID, Title: title})
+	AssertExistsAndLoadBean(t, &Comment{IssueID: pr.IssueID, PosterID: doer.ID, Type: CommentTypeReadyForReview})
+}
+
+func TestPullRequest_HasEnoughApprovals(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.NoError(t, pr.GetBaseRepo())
+
+	// base branch is not protected
+	assert.False(t, pr.HasEnoughApprovals())
+	assert.False(t, pr.HasTooFewReviews())
+
+	assert.NoError(t, UpdateProtectBranch(pr.BaseRepo, &ProtectedBranch{
+		RepoID:            pr.BaseRepoID,
+		BranchName:        pr.BaseBranch,
+		RequiredApprovals: 1,
+	}, WhitelistOptions{}))
+	pr.ProtectedBranch = nil
+	assert.False(t, pr.HasEnoughApprovals())
+	assert.False(t, pr.HasTooFewReviews())
+
+	_, err := x.Where("issue_id = ?", pr.IssueID).Delete(new(Review))
+	assert.NoError(t, err)
+	assert.True(t, pr.HasTooFewReviews())
+
+	_, err = x.Insert(&Review{
+		Type:       ReviewTypeApprove,
+		ReviewerID: 1,
+		IssueID:    pr.IssueID,
+		Official:   true,
+	})
+	assert.NoError(t, err)
+	assert.True(t, pr.HasEnoughApprovals())
+	assert.False(t, pr.HasTooFewReviews())
+}
+
+func TestPullRequest_SetMerged(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	merger := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	pr.MergedCommitID = "1234567890abcdef1234567890abcdef12345678"
+	pr.MergedUnix = timeutil.TimeStampNow()
+	pr.Merger = merger
+	pr.MergerID = merger.ID
+	assert.NoError(t, pr.SetMerged())
+
+	AssertExistsAndLoadBean(t, &PullRequest{ID: 2, HasMerged: true})
+	AssertExistsAndLoadBean(t, &Comment{
+		Type:      CommentTypeMergedPR,
+		IssueID:   pr.IssueID,
+		PosterID:  merger.ID,
+		CommitSHA: pr.MergedCommitID,
+	})
+
+	// A stale copy must not record the merge a second time
+	stale := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	stale.HasMerged = false
+	stale.MergedCommitID = pr.MergedCommitID
+	stale.MergedUnix = pr.MergedUnix
+	stale.Merger = merger
+	assert.Error(t, stale.SetMerged())
+	AssertCount(t, &Comment{Type: CommentTypeMergedPR, IssueID: pr.IssueID}, 1)
+}
+
+func TestGetPullRequestsMergedBetween(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 16}).(*Repository)
+
+	for i, commitID := range []string{
+		"69554a64c1e6030f051e5c3f94bfbd773cd6a324",
+		"27566bd5738fc8b4e3fef3c5e72cce608537bd95",
+		"5099b81332712fe655e34e8dd63574f503f61811",
+	} {
+		issue := &Issue{
+			RepoID:   repo.ID,
+			Index:    int64(i + 1),
+			PosterID: 2,
+			Title:    fmt.Sprintf("pull %d", i+1),
+			IsPull:   true,
+		}
+		_, err := x.Insert(issue)
+		assert.NoError(t, err)
+		_, err = x.NoAutoTime().Insert(&PullRequest{
+			IssueID:        issue.ID,
+			Index:          issue.Index,
+			HeadRepoID:     repo.ID,
+			BaseRepoID:     repo.ID,
+			HasMerged:      true,
+			MergedCommitID: commitID,
+			MergedUnix:     timeutil.TimeStamp(1000 - i),
+		})
+		assert.NoError(t, err)
+	}
+
+	prs, err := GetPullRequestsMergedBetween(repo, "5099b81332712fe655e34e8dd63574f503f61811", "not-signed")
+	assert.NoError(t, err)
+	if assert.Len(t, prs, 2) {
+		assert.Equal(t, "pull 2", prs[0].Issue.Title)
+		assert.Equal(t, "pull 1", prs[1].Issue.Title)
+	}
+}
+
+func TestPullRequest_SetMergeHold(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.True(t, pr.CanAutoMerge())
+	assert.NoError(t, pr.CheckUserAllowedToMerge(doer))
+
+	assert.NoError(t, pr.SetMergeHold(doer, "CI is running"))
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2, IsMergeOnHold: true, MergeHoldUserID: doer.ID}).(*PullRequest)
+	assert.Equal(t, "CI is running", pr.MergeHoldReason)
+	assert.False(t, pr.CanAutoMerge())
+
+	err := pr.CheckUserAllowedToMerge(doer)
+	assert.True(t, IsErrMergeOnHold(err))
+	assert.Equal(t, "CI is running", err.(ErrMergeOnHold).Reason)
+
+	blockers, err := pr.GetMergeBlockers(doer)
+	assert.NoError(t, err)
+	var types []MergeBlockerType
+	for _, blocker := range blockers {
+		types = append(types, blocker.Type)
+	}
+	assert.Contains(t, types, MergeBlockerOnHold)
+	assert.NotContains(t, types, MergeBlockerConflicts)
+
+	assert.NoError(t, pr.ClearMergeHold())
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.False(t, pr.IsMergeOnHold)
+	assert.Empty(t, pr.MergeHoldReason)
+	assert.True(t, pr.CanAutoMerge())
+}
+
+func TestPullRequest_IsBlockedByChangedProtectedFiles(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.NoError(t, pr.GetBaseRepo())
+	assert.False(t, pr.IsBlockedByChangedProtectedFiles())
+
+	pr.ChangedProtectedFiles = []string{"go.mod"}
+	// base branch is not protected
+	assert.False(t, pr.IsBlockedByChangedProtectedFiles())
+
+	assert.NoError(t, UpdateProtectBranch(pr.BaseRepo, &ProtectedBranch{
+		RepoID:                pr.BaseRepoID,
+		BranchName:            pr.BaseBranch,
+		ProtectedFilePatterns: "go.mod",
+	}, WhitelistOptions{}))
+	pr.ProtectedBranch = nil
+	_, err := x.Where("issue_id = ?", pr.IssueID).Delete(new(Review))
+	assert.NoError(t, err)
+	assert.True(t, pr.IsBlockedByChangedProtectedFiles())
+
+	err = pr.CheckUserAllowedToMerge(doer)
+	assert.True(t, IsErrProtectedFilesChanged(err))
+	assert.Equal(t, []string{"go.mod"}, err.(ErrProtectedFilesChanged).Files)
+
+	_, err = x.Insert(&Review{
+		Type:       ReviewTypeApprove,
+		ReviewerID: 1,
+		IssueID:    pr.IssueID,
+		Official:   true,
+	})
+	assert.NoError(t, err)
+	assert.False(t, pr.IsBlockedByChangedProtectedFiles())
+	assert.NoError(t, pr.CheckUserAllowedToMerge(doer))
+}
+
+func TestPullRequest_CheckUserAllowedToForceMerge(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	admin := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	user := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+	assert.NoError(t, pr.GetBaseRepo())
+
+	// nothing to override
+	overridden, err := pr.CheckUserAllowedToForceMerge(admin, true, "")
+	assert.NoError(t, err)
+	assert.False(t, overridden)
+
+	// nobody is allowed to merge into the base branch
+	assert.NoError(t, UpdateProtectBranch(pr.BaseRepo, &ProtectedBranch{
+		RepoID:               pr.BaseRepoID,
+		BranchName:           pr.BaseBranch,
+		EnableMergeWhitelist: true,
+	}, WhitelistOptions{}))
+	assert.True(t, IsErrNotAllowedToMerge(pr.CheckUserAllowedToMerge(admin)))
+
+	_, err = pr.CheckUserAllowedToForceMerge(admin, true, " ")
+	assert.True(t, IsErrNotAllowedToMerge(err))
+	_, err = pr.CheckUserAllowedToForceMerge(user, true, "hotfix")
+	assert.True(t, IsErrNotAllowedToMerge(err))
+
+	overridden, err = pr.CheckUserAllowedToForceMerge(admin, true, "hotfix")
+	assert.NoError(t, err)
+	assert.True(t, overridden)
+
+	// a hold can not be overridden
+	pr.IsMergeOnHold = true
+	_, err = pr.CheckUserAllowedToForceMerge(admin, true, "hotfix")
+	assert.True(t, IsErrMergeOnHold(err))
+}
+
+func TestPullRequest_GetSquashPreview(t *testing.T) {
+	PrepareTestEnv(t)
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	// Stack two commits on top of the merge base as the pull request head
+	repoPath := RepoPath("user2", "repo1")
+	head := pr.MergeBase
+	for i := 0; i < 2; i++ {
+		head = CreateTestCommit(t, repoPath, TestCommitOptions{Parents: []string{head}, Message: fmt.Sprintf("commit %d", i)})
+	}
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+
+	count, message, err := pr.GetSquashPreview()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, pr.GetDefaultSquashMessage(), message)
+	assert.Equal(t, "issue3 (#3)", message)
+}
+
+func TestPullRequest_GetBranchPoint(t *testing.T) {
+	PrepareTestEnv(t)
+
+	const initialCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	repoPath := RepoPath("user2", "repo1")
+	commitTree := func(message string, parents ...string) string {
+		return CreateTestCommit(t, repoPath, TestCommitOptions{Tree: initialCommitID + "^{tree}", Parents: parents, Message: message})
+	}
+
+	// the head is on the base branch
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), initialCommitID)
+	commit, err := pr.GetBranchPoint()
+	assert.NoError(t, err)
+	assert.Equal(t, initialCommitID, commit.ID.String())
+
+	// the base branch has advanced and was merged into the head branch, which
+	// moves the merge base but not the branch point
+	head := commitTree("head", initialCommitID)
+	base := commitTree("base", initialCommitID)
+	head = commitTree("merge base into head", head, base)
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+	UpdateTestRef(t, repoPath, git.BranchPrefix+pr.BaseBranch, base)
+
+	commit, err = pr.GetBranchPoint()
+	assert.NoError(t, err)
+	assert.Equal(t, initialCommitID, commit.ID.String())
+
+	// unrelated histories have no branch point
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), commitTree("unrelated"))
+	_, err = pr.GetBranchPoint()
+	assert.True(t, IsErrPullRequestBranchPointNotExist(err))
+
+	// merged pull requests use the recorded merge base
+	pr.HasMerged = true
+	pr.MergeBase = base
+	commit, err = pr.GetBranchPoint()
+	assert.NoError(t, err)
+	assert.Equal(t, base, commit.ID.String())
+}
+
+func TestPullRequest_GetEffectiveMergeBase(t *testing.T) {
+	PrepareTestEnv(t)
+
+	const initialCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	repoPath := RepoPath("user2", "repo1")
+	commitTree := func(message string, parents ...string) string {
+		return CreateTestCommit(t, repoPath, TestCommitOptions{Tree: initialCommitID + "^{tree}", Parents: parents, Message: message})
+	}
+
+	// the head has two commits and the base branch one since the merge base
+	head := commitTree("head 2", commitTree("head 1", initialCommitID))
+	base := commitTree("base", initialCommitID)
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+	UpdateTestRef(t, repoPath, git.BranchPrefix+pr.BaseBranch, base)
+	pr.MergeBase = initialCommitID
+	assert.NoError(t, pr.UpdateCols("merge_base"))
+
+	divergence, err := pr.GetCommitDivergence(false)
+	assert.NoError(t, err)
+	assert.Equal(t, git.DivergeObject{Ahead: 2, Behind: 1}, divergence)
+	updated, err := pr.IsBaseBranchUpdatedSinceMergeBase()
+	assert.NoError(t, err)
+	assert.True(t, updated)
+
+	// merging the base into the head leaves the stored merge base stale
+	head = commitTree("merge base into head", head, base)
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+
+	mergeBase, err := pr.GetEffectiveMergeBase(false)
+	assert.NoError(t, err)
+	assert.Equal(t, initialCommitID, mergeBase)
+
+	mergeBase, err = pr.GetEffectiveMergeBase(true)
+	assert.NoError(t, err)
+	assert.Equal(t, base, mergeBase)
+	AssertExistsAndLoadBean(t, &PullRequest{ID: pr.ID, MergeBase: base})
+
+	divergence, err = pr.GetCommitDivergence(false)
+	assert.NoError(t, err)
+	assert.Equal(t, git.DivergeObject{Ahead: 3, Behind: 0}, divergence)
+	updated, err = pr.IsBaseBranchUpdatedSinceMergeBase()
+	assert.NoError(t, err)
+	assert.False(t, updated)
+
+	// merged pull requests keep their merge base
+	pr.HasMerged = true
+	pr.MergeBase = initialCommitID
+	mergeBase, err = pr.GetEffectiveMergeBase(true)
+	assert.NoError(t, err)
+	assert.Equal(t, initialCommitID, mergeBase)
+}
+
+func TestPullRequest_HeadChangedSince(t *testing.T) {
+	PrepareTestEnv(t)
+
+	const initialCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+
+	repoPath := RepoPath("user2", "repo1")
+	commitTree := func(message string, parents ...string) string {
+		return CreateTestCommit(t, repoPath, TestCommitOptions{Tree: initialCommitID + "^{tree}", Parents: parents, Message: message})
+	}
+	updateHead := func(commitID string) {
+		UpdateTestRef(t, repoPath, git.BranchPrefix+pr.HeadBranch, commitID)
+	}
+
+	approved := commitTree("approved", initialCommitID)
+	updateHead(approved)
+	changed, err := pr.HeadChangedSince(approved)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// new commits on top of the approved one
+	updateHead(commitTree("appended", approved))
+	changed, err = pr.HeadChangedSince(approved)
+	assert.NoError(t, err)
+	assert.False(t, changed)
+
+	// force-pushed history which doesn't contain the approved commit
+	updateHead(commitTree("rewritten", initialCommitID))
+	changed, err = pr.HeadChangedSince(approved)
+	assert.NoError(t, err)
+	assert.True(t, changed)
+
+	// the approved commit doesn't exist anymore
+	changed, err = pr.HeadChangedSince("0000000000000000000000000000000000000001")
+	assert.NoError(t, err)
+	assert.True(t, changed)
+}
+
+func TestPullRequest_GetClosingIssues(t *testing.T) {
+	PrepareTestEnv(t)
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	assert.NoError(t, pr.LoadIssue())
+	pr.Issue.Content = "This fixes #4 and is related to #1"
+
+	repoPath := RepoPath("user2", "repo1")
+	head := pr.MergeBase
+	for _, message := range []string{
+		"Fix reading\n\nCloses #1",
+		"Resolves user2/repo1#1 and closes !2",
+		"fixes user3/repo3#1",
+	} {
+		head = CreateTestCommit(t, repoPath, TestCommitOptions{Parents: []string{head}, Message: message})
+	}
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+
+	issues, err := pr.GetClosingIssues()
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 2) {
+		assert.EqualValues(t, 5, issues[0].ID)
+		assert.EqualValues(t, 1, issues[1].ID)
+	}
+
+	setting.Repository.PullRequest.EnableCrossRepoClosing = true
+	defer func() {
+		setting.Repository.PullRequest.EnableCrossRepoClosing = false
+	}()
+	issues, err = pr.GetClosingIssues()
+	assert.NoError(t, err)
+	if assert.Len(t, issues, 3) {
+		assert.EqualValues(t, 6, issues[2].ID)
+	}
+}
+
+func TestPullRequest_IsRevertOf(t *testing.T) {
+	PrepareTestEnv(t)
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	repoPath := RepoPath("user2", "repo1")
+	pushCommits := func(messages ...string) {
+		head := pr.MergeBase
+		for _, message := range messages {
+			head = CreateTestCommit(t, repoPath, TestCommitOptions{Parents: []string{head}, Message: message})
+		}
+		UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+	}
+	revert := "Revert \"Initial commit\"\n\nThis reverts commit 65f1bf27bc3bf70f64657658635e66094edbcb4d."
+
+	pushCommits(revert)
+	commit, err := pr.IsRevertOf()
+	assert.NoError(t, err)
+	if assert.NotNil(t, commit) {
+		assert.Equal(t, "65f1bf27bc3bf70f64657658635e66094edbcb4d", commit.ID.String())
+	}
+
+	pushCommits(revert, "Revert again\n\nThis reverts commit 65f1bf27bc.")
+	commit, err = pr.IsRevertOf()
+	assert.NoError(t, err)
+	assert.NotNil(t, commit)
+
+	pushCommits(revert, "Fix reading")
+	commit, err = pr.IsRevertOf()
+	assert.NoError(t, err)
+	assert.Nil(t, commit)
+
+	pushCommits("Revert \"Unknown\"\n\nThis reverts commit 0123456789012345678901234567890123456789.")
+	commit, err = pr.IsRevertOf()
+	assert.NoError(t, err)
+	assert.Nil(t, commit)
+}
+
+func TestPullRequest_GetMergedCommit(t *testing.T) {
+	PrepareTestEnv(t)
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	_, err := pr.GetMergedCommit()
+	assert.True(t, IsErrPullRequestNotMerged(err))
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	_, err = pr.GetMergedCommit()
+	assert.True(t, IsErrPullRequestMergedCommitNotExist(err))
+
+	pr.MergedCommitID = "0123456789012345678901234567890123456789"
+	_, err = pr.GetMergedCommit()
+	assert.True(t, IsErrPullRequestMergedCommitNotExist(err))
+
+	pr.MergedCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	commit, err := pr.GetMergedCommit()
+	assert.NoError(t, err)
+	assert.Equal(t, pr.MergedCommitID, commit.ID.String())
+	assert.Equal(t, "Initial commit\n", commit.Message())
+	assert.EqualValues(t, 0, commit.ParentCount())
+}
+
+func TestPullRequest_GetMergeCommitParents(t *testing.T) {
+	PrepareTestEnv(t)
+
+	const initialCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	_, err := pr.GetMergeCommitParents()
+	assert.True(t, IsErrPullRequestNotMerged(err))
+
+	repoPath := RepoPath("user2", "repo1")
+	commitTree := func(message string, parents ...string) string {
+		return CreateTestCommit(t, repoPath, TestCommitOptions{Tree: initialCommitID + "^{tree}", Parents: parents, Message: message})
+	}
+
+	pr = AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	head := commitTree("head", initialCommitID)
+	pr.MergedCommitID = commitTree("merge", initialCommitID, head)
+	parents, err := pr.GetMergeCommitParents()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{initialCommitID, head}, parents)
+
+	// squashed
+	pr.MergedCommitID = commitTree("squash", initialCommitID)
+	parents, err = pr.GetMergeCommitParents()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{initialCommitID}, parents)
+
+	pr.MergedCommitID = "0123456789012345678901234567890123456789"
+	_, err = pr.GetMergeCommitParents()
+	assert.True(t, IsErrPullRequestMergedCommitNotExist(err))
+}
+
+func TestPullRequest_GetDiffTrees(t *testing.T) {
+	PrepareTestEnv(t)
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	repoPath := RepoPath("user2", "repo1")
+
+	// the head ref has not been pushed to the base repository
+	_, _, _, err := pr.GetDiffTrees()
+	assert.True(t, IsErrPullRequestRefNotExist(err))
+
+	master := "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	emptyTree, err := git.NewCommand("mktree").RunInDir(repoPath)
+	assert.NoError(t, err)
+	emptyTree = strings.TrimSpace(emptyTree)
+	head := CreateTestCommit(t, repoPath, TestCommitOptions{Tree: emptyTree, Parents: []string{master}, Message: "Remove all files"})
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+
+	masterTree, err := git.NewCommand("rev-parse", master+"^{tree}").RunInDir(repoPath)
+	assert.NoError(t, err)
+	baseTree, headTree, mergeBaseTree, err := pr.GetDiffTrees()
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimSpace(masterTree), baseTree)
+	assert.Equal(t, emptyTree, headTree)
+	assert.Equal(t, strings.TrimSpace(masterTree), mergeBaseTree)
+
+	// unrelated histories have no merge base
+	head = CreateTestCommit(t, repoPath, TestCommitOptions{Tree: emptyTree, Message: "Unrelated"})
+	UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+	_, _, _, err = pr.GetDiffTrees()
+	assert.True(t, IsErrPullRequestRefNotExist(err))
+
+	pr.BaseBranch = "does-not-exist"
+	_, _, _, err = pr.GetDiffTrees()
+	assert.True(t, IsErrPullRequestRefNotExist(err))
+}
+
+func TestPullRequest_RequireLinearHistory(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 2}).(*User)
+	assert.NoError(t, pr.GetBaseRepo())
+
+	styles, err := pr.AllowedMergeStyles()
+	assert.NoError(t, err)
+	assert.Equal(t, []MergeStyle{MergeStyleMerge, MergeStyleRebase, MergeStyleRebaseMerge, MergeStyleSquash}, styles)
+	assert.NoError(t, pr.CheckMergeStyleAllowed(MergeStyleMerge))
+
+	assert.NoError(t, UpdateProtectBranch(pr.BaseRepo, &ProtectedBranch{
+		RepoID:               pr.BaseRepoID,
+		BranchName:           pr.BaseBranch,
+		RequireLinearHistory: true,
+	}, WhitelistOptions{}))
+	pr.ProtectedBranch = nil
+
+	styles, err = pr.AllowedMergeStyles()
+	assert.NoError(t, err)
+	assert.Equal(t, []MergeStyle{MergeStyleRebase, MergeStyleSquash}, styles)
+	styles, err = pr.AvailableMergeStyles(doer)
+	assert.NoError(t, err)
+	assert.Equal(t, []MergeStyle{MergeStyleRebase, MergeStyleSquash}, styles)
+
+	for _, style := range []MergeStyle{MergeStyleMerge, MergeStyleRebaseMerge} {
+		err = pr.CheckMergeStyleAllowed(style)
+		assert.True(t, IsErrLinearHistoryRequired(err), "style %s", style)
+	}
+	assert.NoError(t, pr.CheckMergeStyleAllowed(MergeStyleRebase))
+	assert.NoError(t, pr.CheckMergeStyleAllowed(MergeStyleSquash))
+
+	blockers, err := pr.GetMergeBlockers(doer)
+	assert.NoError(t, err)
+	assert.Len(t, blockers, 0)
+
+	// only merge commits are allowed by the repository
+	prUnit, err := pr.BaseRepo.GetUnit(UnitTypePullRequests)
+	assert.NoError(t, err)
+	prUnit.PullRequestsConfig().AllowRebase = false
+	prUnit.PullRequestsConfig().AllowSquash = false
+	_, err = x.ID(prUnit.ID).Cols("config").Update(prUnit)
+	assert.NoError(t, err)
+	pr.BaseRepo = nil
+	pr.ProtectedBranch = nil
+
+	blockers, err = pr.GetMergeBlockers(doer)
+	assert.NoError(t, err)
+	if assert.Len(t, blockers, 1) {
+		assert.Equal(t, MergeBlockerNoMergeStyle, blockers[0].Type)
+	}
+}
+
+func TestPullRequest_GetParticipants(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+
+	checkParticipants := func(userIDs []int64) {
+		participants, err := pr.GetParticipants()
+		if assert.NoError(t, err) {
+			participantIDs := make([]int64, len(participants))
+			for i, u := range participants {
+				participantIDs[i] = u.ID
+			}
+			assert.Equal(t, userIDs, participantIDs)
+		}
+	}
+
+	// User 1 is the poster, reviewer and commenter of the pull request (see fixtures)
+	checkParticipants([]int64{1})
+
+	// User 2 unsubscribed from the pull request, so being assigned does not make it a participant
+	_, err := x.Insert(&IssueAssignees{IssueID: pr.IssueID, AssigneeID: 2})
+	assert.NoError(t, err)
+	_, err = x.Insert(&Review{IssueID: pr.IssueID, ReviewerID: 5, Type: ReviewTypeRequest})
+	assert.NoError(t, err)
+	_, err = x.Insert(&Review{IssueID: pr.IssueID, ReviewerID: 8, Type: ReviewTypePending})
+	assert.NoError(t, err)
+	for _, posterID := range []int64{4, 4, NonexistentID} {
+		_, err = x.Insert(&Comment{IssueID: pr.IssueID, PosterID: posterID, Type: CommentTypeComment})
+		assert.NoError(t, err)
+	}
+	checkParticipants([]int64{1, 4, 5})
+
+	_, err = x.Where("user_id = ? AND issue_id = ?", 2, pr.IssueID).Cols("is_watching").Update(&IssueWatch{IsWatching: true})
+	assert.NoError(t, err)
+	assert.NoError(t, CreateOrUpdateIssueWatch(4, pr.IssueID, false))
+	checkParticipants([]int64{1, 2, 5})
+}
+
+func TestPullRequest_GetDefaultSquashSubjectAndBody(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest)
+	assert.Equal(t, "issue3 (#3)", pr.GetDefaultSquashSubject())
+	assert.Equal(t, "content for the third issue", pr.GetDefaultSquashBody())
+	assert.Equal(t, pr.GetDefaultSquashSubject(), pr.GetDefaultSquashMessage())
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/modules/git"
+)
+
+// GetCommitAuthorAllowedDomains returns the email domains whose addresses may author commits
+// on protected branches of the repository without belonging to a verified account.
+func (repo *Repository) GetCommitAuthorAllowedDomains() []string {
+	var domains []string
+	for _, domain := range strings.Split(repo.CommitAuthorAllowedDomains, ",") {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if len(domain) > 0 {
+			domains = append(domains, domain)
+		}
+	}
+	return domains
+}
+
+// IsCommitAuthorEmailAllowed returns true if commits authored by the email address may be
+// pushed to protected branches of the repository: the address has to belong to a verified
+// account or to one of the allowed domains.
+func (repo *Repository) IsCommitAuthorEmailAllowed(email string) (bool, error) {
+	if !repo.RequireVerifiedCommitAuthors {
+		return true, nil
+	}
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	if i := strings.LastIndex(email, "@"); i >= 0 {
+		domain := email[i+1:]
+		for _, allowed := range repo.GetCommitAuthorAllowedDomains() {
+			if domain == allowed {
+				return true, nil
+			}
+		}
+	}
+
+	if len(email) == 0 {
+		return false, nil
+	}
+	if _, err := GetUserByVerifiedEmail(email); err != nil {
+		if IsErrUserNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckCommitAuthors checks the authors of the commits listed by the revisions in the git
+// repository at repoPath, as given to git rev-list, and returns ErrCommitAuthorNotAllowed for
+// the first commit whose author may not push to protected branches of the repository.
+// Commits authored by one of the emails in skipEmails are not checked.
+func (repo *Repository) CheckCommitAuthors(repoPath string, env []string, revs []string, skipEmails ...string) error {
+	if !repo.RequireVerifiedCommitAuthors {
+		return nil
+	}
+
+	args := append([]string{"log", "--format=%H %ae"}, revs...)
+	stdout, err := git.NewCommand(append(args, "--")...).RunInDirWithEnv(repoPath, env)
+	if err != nil {
+		return fmt.Errorf("git log: %v", err)
+	}
+
+	checked := make(map[string]bool)
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(fields[0]) == 0 {
+			continue
+		}
+		var email string
+		if len(fields) == 2 {
+			email = strings.ToLower(fields[1])
+		}
+		if checked[email] || isEmailIn(email, skipEmails) {
+			continue
+		}
+
+		allowed, err := repo.IsCommitAuthorEmailAllowed(email)
+		if err != nil {
+			return err
+		} else if !allowed {
+			return ErrCommitAuthorNotAllowed{RepoID: repo.ID, CommitID: fields[0], Email: email}
+		}
+		checked[email] = true
+	}
+	return nil
+}
+
+func isEmailIn(email string, emails []string) bool {
+	for _, e := range emails {
+		if strings.EqualFold(email, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_IsCommitAuthorEmailAllowed(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	assertAllowed := func(email string, expected bool) {
+		allowed, err := repo.IsCommitAuthorEmailAllowed(email)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, allowed, email)
+	}
+
+	// everyone is allowed unless verified authors are required
+	assertAllowed("someone@example.org", true)
+
+	repo.RequireVerifiedCommitAuthors = true
+	assertAllowed("user2@example.com", true)
+	assertAllowed("User2@Example.com", true)
+	assertAllowed("unverified@example.com", false)
+	assertAllowed("someone@example.org", false)
+	assertAllowed("", false)
+
+	repo.CommitAuthorAllowedDomains = " @Example.org, example.net,"
+	assert.Equal(t, []string{"example.org", "example.net"}, repo.GetCommitAuthorAllowedDomains())
+	assertAllowed("someone@example.org", true)
+	assertAllowed("someone@sub.example.org", false)
+	assertAllowed("unverified@example.com", false)
+}
+
+func TestRepository_CheckCommitAuthors(t *testing.T) {
+	PrepareTestEnv(t)
+
+	const initialCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	repoPath := RepoPath("user2", "repo1")
+	commitTree := func(email, parent string) string {
+		return CreateTestCommit(t, repoPath, TestCommitOptions{
+			Parents: []string{parent},
+			Message: email,
+			Author:  &git.Signature{Name: "author", Email: email},
+		})
+	}
+
+	verified := commitTree("user2@example.com", initialCommitID)
+	unverified := commitTree("unverified@example.com", verified)
+	revs := []string{initialCommitID + ".." + unverified}
+
+	// nothing is checked unless verified authors are required
+	assert.NoError(t, repo.CheckCommitAuthors(repoPath, nil, revs))
+
+	repo.RequireVerifiedCommitAuthors = true
+	assert.NoError(t, repo.CheckCommitAuthors(repoPath, nil, []string{initialCommitID + ".." + verified}))
+
+	err := repo.CheckCommitAuthors(repoPath, nil, revs)
+	assert.True(t, IsErrCommitAuthorNotAllowed(err))
+	assert.Equal(t, ErrCommitAuthorNotAllowed{RepoID: repo.ID, CommitID: unverified, Email: "unverified@example.com"}, err)
+
+	assert.NoError(t, repo.CheckCommitAuthors(repoPath, nil, revs, "UNVERIFIED@example.com"))
+}
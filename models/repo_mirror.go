@@ -6,6 +6,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"code.gitea.io/gitea/modules/log"
@@ -103,3 +104,25 @@ func InsertMirror(mirror *Mirror) error {
 	_, err := x.Insert(mirror)
 	return err
 }
+
+// ConvertToMirror inserts the mirror and marks its repository as a mirror in one transaction.
+func ConvertToMirror(repo *Repository, mirror *Mirror) (err error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err = sess.Begin(); err != nil {
+		return err
+	}
+
+	mirror.RepoID = repo.ID
+	if _, err = sess.Insert(mirror); err != nil {
+		return fmt.Errorf("insert mirror: %v", err)
+	}
+
+	repo.IsMirror = true
+	if err = updateRepository(sess, repo, false); err != nil {
+		repo.IsMirror = false
+		return fmt.Errorf("updateRepository: %v", err)
+	}
+
+	return sess.Commit()
+}
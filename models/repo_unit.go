@@ -74,6 +74,9 @@ type IssuesConfig struct {
 	EnableTimetracker                bool
 	AllowOnlyContributorsToTrackTime bool
 	EnableDependencies               bool
+	// AllowedReactions restricts the reactions of the instance which may be used in the repository,
+	// all of them are allowed if it is empty
+	AllowedReactions []string
 }
 
 // FromDB fills up a IssuesConfig from serialized format.
@@ -93,6 +96,15 @@ type PullRequestsConfig struct {
 	AllowRebase               bool
 	AllowRebaseMerge          bool
 	AllowSquash               bool
+	DefaultMergeStyle         MergeStyle
+	// CloseStale enables closing pull requests which have been inactive for a while
+	CloseStale bool
+	// AddReviewedByTrailers appends a Reviewed-by trailer for each approving reviewer to merge commit messages
+	AddReviewedByTrailers bool
+	// AddCoAuthoredByTrailers appends a Co-authored-by trailer for each other author of the squashed commits to squash commit messages
+	AddCoAuthoredByTrailers bool
+	// NeedsRebaseLabel is the name of the label added to pull requests which conflict with or are behind their base branch, empty disables it
+	NeedsRebaseLabel string
 }
 
 // FromDB fills up a PullRequestsConfig from serialized format.
@@ -113,6 +125,29 @@ func (cfg *PullRequestsConfig) IsMergeStyleAllowed(mergeStyle MergeStyle) bool {
 		mergeStyle == MergeStyleSquash && cfg.AllowSquash
 }
 
+// AllowedMergeStyles returns the list of merge styles allowed by this config
+func (cfg *PullRequestsConfig) AllowedMergeStyles() []MergeStyle {
+	styles := make([]MergeStyle, 0, 4)
+	for _, style := range []MergeStyle{MergeStyleMerge, MergeStyleRebase, MergeStyleRebaseMerge, MergeStyleSquash} {
+		if cfg.IsMergeStyleAllowed(style) {
+			styles = append(styles, style)
+		}
+	}
+	return styles
+}
+
+// GetDefaultMergeStyle returns the configured default merge style if it is allowed,
+// the first allowed merge style otherwise. It returns an empty style if none is allowed.
+func (cfg *PullRequestsConfig) GetDefaultMergeStyle() MergeStyle {
+	if len(cfg.DefaultMergeStyle) > 0 && cfg.IsMergeStyleAllowed(cfg.DefaultMergeStyle) {
+		return cfg.DefaultMergeStyle
+	}
+	if styles := cfg.AllowedMergeStyles(); len(styles) > 0 {
+		return styles[0]
+	}
+	return ""
+}
+
 // BeforeSet is invoked from XORM before setting the value of a field of this object.
 func (r *RepoUnit) BeforeSet(colName string, val xorm.Cell) {
 	switch colName {
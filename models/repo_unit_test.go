@@ -0,0 +1,27 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPullRequestsConfig_GetDefaultMergeStyle(t *testing.T) {
+	cfg := &PullRequestsConfig{AllowMerge: true, AllowSquash: true}
+	assert.Equal(t, []MergeStyle{MergeStyleMerge, MergeStyleSquash}, cfg.AllowedMergeStyles())
+	assert.Equal(t, MergeStyleMerge, cfg.GetDefaultMergeStyle())
+
+	cfg.DefaultMergeStyle = MergeStyleSquash
+	assert.Equal(t, MergeStyleSquash, cfg.GetDefaultMergeStyle())
+
+	cfg.DefaultMergeStyle = MergeStyleRebase
+	assert.Equal(t, MergeStyleMerge, cfg.GetDefaultMergeStyle())
+
+	cfg = &PullRequestsConfig{DefaultMergeStyle: MergeStyleSquash}
+	assert.Empty(t, cfg.AllowedMergeStyles())
+	assert.EqualValues(t, "", cfg.GetDefaultMergeStyle())
+}
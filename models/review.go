@@ -5,8 +5,10 @@
 package models
 
 import (
+	"sort"
 	"strings"
 
+	api "code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/timeutil"
 
 	"xorm.io/builder"
@@ -27,6 +29,8 @@ const (
 	ReviewTypeComment
 	// ReviewTypeReject gives feedback blocking merge
 	ReviewTypeReject
+	// ReviewTypeRequest requests a review from the reviewer
+	ReviewTypeRequest
 )
 
 // Icon returns the corresponding icon for the review type
@@ -54,6 +58,10 @@ type Review struct {
 	Content    string `xorm:"TEXT"`
 	// Official is a review made by an assigned approver (counts towards approval)
 	Official bool `xorm:"NOT NULL DEFAULT false"`
+	// Stale is an approval given before new commits were pushed (does not count towards approval)
+	Stale bool `xorm:"NOT NULL DEFAULT false"`
+	// CommitID is the head commit of the pull request the review was submitted for
+	CommitID string `xorm:"VARCHAR(40)"`
 
 	CreatedUnix timeutil.TimeStamp `xorm:"INDEX created"`
 	UpdatedUnix timeutil.TimeStamp `xorm:"INDEX updated"`
@@ -165,6 +173,7 @@ type CreateReviewOptions struct {
 	Issue    *Issue
 	Reviewer *User
 	Official bool
+	CommitID string
 }
 
 // IsOfficialReviewer check if reviewer can make official reviews in issue (counts towards required approvals)
@@ -196,6 +205,7 @@ func createReview(e Engine, opts CreateReviewOptions) (*Review, error) {
 		ReviewerID: opts.Reviewer.ID,
 		Content:    opts.Content,
 		Official:   opts.Official,
+		CommitID:   opts.CommitID,
 	}
 	if _, err := e.Insert(review); err != nil {
 		return nil, err
@@ -254,7 +264,7 @@ func IsContentEmptyErr(err error) bool {
 }
 
 // SubmitReview creates a review out of the existing pending review or creates a new one if no pending review exist
-func SubmitReview(doer *User, issue *Issue, reviewType ReviewType, content string) (*Review, *Comment, error) {
+func SubmitReview(doer *User, issue *Issue, reviewType ReviewType, content, commitID string) (*Review, *Comment, error) {
 	sess := x.NewSession()
 	defer sess.Close()
 	if err := sess.Begin(); err != nil {
@@ -291,6 +301,7 @@ func SubmitReview(doer *User, issue *Issue, reviewType ReviewType, content strin
 			Reviewer: doer,
 			Content:  content,
 			Official: official,
+			CommitID: commitID,
 		})
 		if err != nil {
 			return nil, nil, err
@@ -318,8 +329,9 @@ func SubmitReview(doer *User, issue *Issue, reviewType ReviewType, content strin
 		review.Issue = issue
 		review.Content = content
 		review.Type = reviewType
+		review.CommitID = commitID
 
-		if _, err := sess.ID(review.ID).Cols("content, type, official").Update(review); err != nil {
+		if _, err := sess.ID(review.ID).Cols("content, type, official, commit_id").Update(review); err != nil {
 			return nil, nil, err
 		}
 	}
@@ -340,6 +352,118 @@ func SubmitReview(doer *User, issue *Issue, reviewType ReviewType, content strin
 	return review, comm, sess.Commit()
 }
 
+// CreateReviewRequest requests a review of the pull request from reviewer.
+// Nothing is created if there is a pending request of reviewer already.
+func CreateReviewRequest(issue *Issue, reviewer *User) (*Review, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	review := &Review{}
+	has, err := sess.Where("issue_id = ? AND reviewer_id = ? AND type = ?", issue.ID, reviewer.ID, ReviewTypeRequest).
+		And(builder.NotIn("issue_id", reviewedSinceRequestCond(reviewer.ID))).
+		Get(review)
+	if err != nil {
+		return nil, err
+	} else if has {
+		return review, nil
+	}
+
+	if review, err = createReview(sess, CreateReviewOptions{
+		Type:     ReviewTypeRequest,
+		Issue:    issue,
+		Reviewer: reviewer,
+	}); err != nil {
+		return nil, err
+	}
+	return review, sess.Commit()
+}
+
+// reviewedSinceRequestCond returns a condition selecting the ids of the issues which the user
+// reviewed after both the last review request and the last push to the head branch, approvals
+// dismissed as stale excepted
+func reviewedSinceRequestCond(userID int64) *builder.Builder {
+	return builder.Select("r.issue_id").From("review", "r").
+		Where(builder.Eq{"r.reviewer_id": userID, "r.stale": false}).
+		And(builder.In("r.type", ReviewTypeApprove, ReviewTypeComment, ReviewTypeReject)).
+		And(builder.Expr("r.id > (SELECT max(req.id) FROM review req WHERE req.issue_id = r.issue_id AND req.reviewer_id = r.reviewer_id AND req.type = ?)", ReviewTypeRequest)).
+		And(builder.Expr("r.updated_unix >= (SELECT pr.head_updated_unix FROM pull_request pr WHERE pr.issue_id = r.issue_id)"))
+}
+
+// GetReviewerWorkload returns for every user eligible to review the pull requests of the repository,
+// i.e. every user with write access, the number of open pull requests which requested a review of the
+// user and which the user has not reviewed since. Requests of a review from the poster are not counted.
+func GetReviewerWorkload(repoID int64) (map[int64]int, error) {
+	return getReviewerWorkload(x, repoID)
+}
+
+func getReviewerWorkload(e Engine, repoID int64) (map[int64]int, error) {
+	repo, err := getRepositoryByID(e, repoID)
+	if err != nil {
+		return nil, err
+	}
+	reviewers, err := repo.getAssignees(e)
+	if err != nil {
+		return nil, err
+	}
+
+	var loads []*struct {
+		ReviewerID int64
+		Num        int
+	}
+	if err = e.Table("review").
+		Select("review.reviewer_id, COUNT(DISTINCT review.issue_id) AS num").
+		Join("INNER", "issue", "issue.id = review.issue_id").
+		Join("INNER", "pull_request", "pull_request.issue_id = review.issue_id").
+		Where(builder.Eq{
+			"issue.repo_id":           repoID,
+			"issue.is_closed":         false,
+			"pull_request.has_merged": false,
+			"review.type":             ReviewTypeRequest,
+		}).
+		And("review.reviewer_id <> issue.poster_id").
+		// the reviewer has not reviewed since the request, approvals dismissed as stale excepted
+		And(builder.Expr("NOT EXISTS (SELECT r.id FROM review r WHERE r.issue_id = review.issue_id AND r.reviewer_id = review.reviewer_id AND r.id > review.id AND r.stale = ? AND r.type IN (?, ?, ?))",
+			false, ReviewTypeApprove, ReviewTypeComment, ReviewTypeReject)).
+		GroupBy("review.reviewer_id").
+		Find(&loads); err != nil {
+		return nil, err
+	}
+
+	workload := make(map[int64]int, len(reviewers))
+	for _, reviewer := range reviewers {
+		workload[reviewer.ID] = 0
+	}
+	for _, load := range loads {
+		if _, ok := workload[load.ReviewerID]; ok {
+			workload[load.ReviewerID] = load.Num
+		}
+	}
+	return workload, nil
+}
+
+// MarkReviewsAsStale marks all approvals of the given issue as stale
+func MarkReviewsAsStale(issueID int64) (err error) {
+	_, err = x.Where("issue_id = ?", issueID).
+		And("type = ?", ReviewTypeApprove).
+		And("stale = ?", false).
+		Cols("stale").
+		NoAutoTime().
+		Update(&Review{Stale: true})
+	return
+}
+
+// MarkReviewAsStale marks the given review as stale
+func MarkReviewAsStale(reviewID int64) error {
+	_, err := x.ID(reviewID).
+		Cols("stale").
+		NoAutoTime().
+		Update(&Review{Stale: true})
+	return err
+}
+
 // GetReviewersByIssueID gets the latest review of each reviewer for a pull request
 func GetReviewersByIssueID(issueID int64) (reviews []*Review, err error) {
 	reviewsUnfiltered := []*Review{}
@@ -370,3 +494,170 @@ func GetReviewersByIssueID(issueID int64) (reviews []*Review, err error) {
 
 	return reviews, nil
 }
+
+// ReviewThread represents the code comments on the same line of a file of a pull request
+type ReviewThread struct {
+	TreePath string
+	Line     int64
+	Comments []*Comment
+	// Resolved is true if all comments of the thread are resolved
+	Resolved bool
+	// Outdated is true if the commented line does not exist anymore
+	Outdated bool
+}
+
+// APIFormat converts a ReviewThread to an api.PullReviewThread
+func (t *ReviewThread) APIFormat() *api.PullReviewThread {
+	apiThread := &api.PullReviewThread{
+		Path:     t.TreePath,
+		Line:     t.Line,
+		Resolved: t.Resolved,
+		Outdated: t.Outdated,
+		Comments: make([]*api.Comment, 0, len(t.Comments)),
+	}
+	for _, c := range t.Comments {
+		apiThread.Comments = append(apiThread.Comments, c.APIFormat())
+	}
+	return apiThread
+}
+
+// CountUnresolvedReviewThreads returns the number of review threads of the pull request which are
+// not resolved yet. Outdated threads are not counted as their lines do not exist anymore.
+func (pr *PullRequest) CountUnresolvedReviewThreads() (int, error) {
+	threads, err := pr.getReviewThreads(x)
+	if err != nil {
+		return 0, err
+	}
+	return countUnresolvedReviewThreads(threads), nil
+}
+
+func (pr *PullRequest) loadUnresolvedReviewThreads(e Engine) error {
+	if pr.UnresolvedReviewThreads != nil {
+		return nil
+	}
+	return PullRequestList{pr}.loadUnresolvedReviewThreads(e)
+}
+
+func (prs PullRequestList) loadUnresolvedReviewThreads(e Engine) error {
+	type unresolvedThread struct {
+		IssueID  int64
+		TreePath string
+		Line     int64
+	}
+	if len(prs) == 0 {
+		return nil
+	}
+	counts := make(map[int64]int, len(prs))
+
+	issueIDs := prs.getIssueIDs()
+	var left = len(issueIDs)
+	for left > 0 {
+		var limit = defaultMaxInSize
+		if left < limit {
+			limit = left
+		}
+
+		// A thread is unresolved as long as one of its comments is, see getReviewThreads
+		threads := make([]*unresolvedThread, 0, limit)
+		if err := e.Table("comment").
+			Select("issue_id, tree_path, line").
+			Where(builder.Eq{"type": CommentTypeCode, "invalidated": false}).
+			And(builder.Or(builder.Eq{"resolve_doer_id": 0}, builder.IsNull{"resolve_doer_id"})).
+			And(builder.Or(
+				builder.IsNull{"review_id"},
+				builder.NotIn("review_id", builder.Select("id").From("review").Where(builder.Eq{"type": ReviewTypePending})),
+			)).
+			In("issue_id", issueIDs[:limit]).
+			GroupBy("issue_id, tree_path, line").
+			Find(&threads); err != nil {
+			return err
+		}
+		for _, thread := range threads {
+			counts[thread.IssueID]++
+		}
+		left -= limit
+		issueIDs = issueIDs[limit:]
+	}
+
+	for _, pr := range prs {
+		count := counts[pr.IssueID]
+		pr.UnresolvedReviewThreads = &count
+	}
+	return nil
+}
+
+// LoadUnresolvedReviewThreads loads the number of unresolved review threads of all pull requests
+// of the list in one go, which APIFormat uses instead of loading the threads per pull request
+func (prs PullRequestList) LoadUnresolvedReviewThreads() error {
+	return prs.loadUnresolvedReviewThreads(x)
+}
+
+func countUnresolvedReviewThreads(threads []*ReviewThread) int {
+	count := 0
+	for _, thread := range threads {
+		if !thread.Resolved && !thread.Outdated {
+			count++
+		}
+	}
+	return count
+}
+
+// GetReviewThreads returns the code comments of the pull request grouped by file and line,
+// ordered by file and line. Comments of pending reviews are left out. Outdated comments
+// are grouped into separate threads.
+func (pr *PullRequest) GetReviewThreads() ([]*ReviewThread, error) {
+	return pr.getReviewThreads(x)
+}
+
+func (pr *PullRequest) getReviewThreads(e Engine) ([]*ReviewThread, error) {
+	if err := pr.loadIssue(e); err != nil {
+		return nil, err
+	}
+
+	var comments CommentList
+	if err := e.Where("type = ? AND issue_id = ?", CommentTypeCode, pr.IssueID).
+		And(builder.Or(
+			builder.IsNull{"review_id"},
+			builder.NotIn("review_id", builder.Select("id").From("review").Where(builder.Eq{"type": ReviewTypePending})),
+		)).
+		Asc("created_unix", "id").
+		Find(&comments); err != nil {
+		return nil, err
+	}
+	if err := comments.loadPosters(e); err != nil {
+		return nil, err
+	}
+
+	type threadKey struct {
+		treePath string
+		line     int64
+		outdated bool
+	}
+	threadsByKey := make(map[threadKey]*ReviewThread)
+	threads := make([]*ReviewThread, 0, len(comments))
+	for _, c := range comments {
+		c.Issue = pr.Issue
+		key := threadKey{c.TreePath, c.Line, c.Invalidated}
+		thread, ok := threadsByKey[key]
+		if !ok {
+			thread = &ReviewThread{
+				TreePath: c.TreePath,
+				Line:     c.Line,
+				Resolved: true,
+				Outdated: c.Invalidated,
+			}
+			threadsByKey[key] = thread
+			threads = append(threads, thread)
+		}
+		thread.Comments = append(thread.Comments, c)
+		thread.Resolved = thread.Resolved && c.IsResolved()
+	}
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].TreePath != threads[j].TreePath {
+			return threads[i].TreePath < threads[j].TreePath
+		}
+		return threads[i].Line < threads[j].Line
+	})
+	return threads, nil
+}
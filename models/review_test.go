@@ -132,3 +132,163 @@ func TestGetReviewersByIssueID(t *testing.T) {
 		assert.Equal(t, expectedReviews[i].UpdatedUnix, review.UpdatedUnix)
 	}
 }
+
+func TestMarkReviewsAsStale(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	assert.NoError(t, MarkReviewsAsStale(2))
+
+	review := AssertExistsAndLoadBean(t, &Review{ID: 1}).(*Review)
+	assert.True(t, review.Stale)
+	assert.EqualValues(t, 946684810, review.UpdatedUnix)
+
+	// Pending reviews are not approvals and stay untouched
+	review = AssertExistsAndLoadBean(t, &Review{ID: 4}).(*Review)
+	assert.False(t, review.Stale)
+}
+
+func TestPullRequest_GetReviewThreads(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+
+	// comments of the pending review are left out
+	threads, err := pr.GetReviewThreads()
+	assert.NoError(t, err)
+	if assert.Len(t, threads, 2) {
+		assert.Equal(t, "README.md", threads[0].TreePath)
+		assert.EqualValues(t, -4, threads[0].Line)
+		assert.Len(t, threads[0].Comments, 1)
+		assert.EqualValues(t, 5, threads[0].Comments[0].ID)
+		assert.False(t, threads[0].Outdated)
+		assert.False(t, threads[0].Resolved)
+
+		assert.EqualValues(t, 6, threads[1].Comments[0].ID)
+		assert.True(t, threads[1].Outdated)
+	}
+
+	comment := AssertExistsAndLoadBean(t, &Comment{ID: 5}).(*Comment)
+	assert.NoError(t, comment.SetResolved(doer, true))
+	threads, err = pr.GetReviewThreads()
+	assert.NoError(t, err)
+	if assert.Len(t, threads, 2) {
+		assert.True(t, threads[0].Resolved)
+		assert.False(t, threads[1].Resolved)
+	}
+}
+
+func TestPullRequestList_LoadUnresolvedReviewThreads(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	prs := PullRequestList{
+		AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest),
+		AssertExistsAndLoadBean(t, &PullRequest{ID: 2}).(*PullRequest),
+	}
+	doer := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+
+	// the outdated thread and the comments of the pending review are not counted
+	assert.NoError(t, prs.LoadUnresolvedReviewThreads())
+	assert.EqualValues(t, 1, *prs[0].UnresolvedReviewThreads)
+	assert.EqualValues(t, 0, *prs[1].UnresolvedReviewThreads)
+
+	comment := AssertExistsAndLoadBean(t, &Comment{ID: 5}).(*Comment)
+	assert.NoError(t, comment.SetResolved(doer, true))
+	prs[0].UnresolvedReviewThreads = nil
+	assert.NoError(t, prs[0].LoadAttributes())
+	assert.NoError(t, prs[0].LoadIssue())
+	assert.EqualValues(t, 0, prs[0].APIFormat().UnresolvedReviewThreads)
+}
+
+func TestPullRequest_CountUnresolvedReviewThreads(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	pr := AssertExistsAndLoadBean(t, &PullRequest{ID: 1}).(*PullRequest)
+	doer := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	assert.NoError(t, pr.LoadBaseRepo())
+
+	// the outdated thread is not counted
+	count, err := pr.CountUnresolvedReviewThreads()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+
+	count, err = pr.CountBlockingUnresolvedConversations()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+	assert.NoError(t, pr.CheckUserAllowedToMerge(doer))
+
+	assert.NoError(t, UpdateProtectBranch(pr.BaseRepo, &ProtectedBranch{
+		RepoID:                       pr.BaseRepoID,
+		BranchName:                   pr.BaseBranch,
+		RequireResolvedConversations: true,
+	}, WhitelistOptions{}))
+	pr.ProtectedBranch = nil
+
+	count, err = pr.CountBlockingUnresolvedConversations()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, count)
+	err = pr.CheckUserAllowedToMerge(doer)
+	assert.True(t, IsErrUnresolvedConversations(err))
+	overridden, err := pr.CheckUserAllowedToForceMerge(doer, true, "hotfix")
+	assert.NoError(t, err)
+	assert.True(t, overridden)
+
+	blockers, err := pr.GetMergeBlockers(doer)
+	assert.NoError(t, err)
+	var types []MergeBlockerType
+	for _, blocker := range blockers {
+		types = append(types, blocker.Type)
+	}
+	assert.Contains(t, types, MergeBlockerUnresolvedConversations)
+
+	comment := AssertExistsAndLoadBean(t, &Comment{ID: 5}).(*Comment)
+	assert.NoError(t, comment.SetResolved(doer, true))
+
+	count, err = pr.CountBlockingUnresolvedConversations()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, count)
+	assert.NoError(t, pr.CheckUserAllowedToMerge(doer))
+}
+
+func TestGetReviewerWorkload(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	repo := AssertExistsAndLoadBean(t, &Repository{ID: 1}).(*Repository)
+	openPR := AssertExistsAndLoadBean(t, &Issue{ID: 3}).(*Issue)
+	mergedPR := AssertExistsAndLoadBean(t, &Issue{ID: 2}).(*Issue)
+	user1 := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	user4 := AssertExistsAndLoadBean(t, &User{ID: 4}).(*User)
+	user5 := AssertExistsAndLoadBean(t, &User{ID: 5}).(*User)
+	user8 := AssertExistsAndLoadBean(t, &User{ID: 8}).(*User)
+	for _, u := range []*User{user1, user4, user5} {
+		assert.NoError(t, repo.AddCollaborator(u))
+	}
+
+	for _, request := range []struct {
+		issue    *Issue
+		reviewer *User
+	}{
+		{openPR, user4},
+		{openPR, user1},   // the poster
+		{openPR, user8},   // no write access
+		{mergedPR, user5}, // not open anymore
+	} {
+		_, err := CreateReviewRequest(request.issue, request.reviewer)
+		assert.NoError(t, err)
+	}
+
+	workload, err := GetReviewerWorkload(repo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, map[int64]int{1: 0, 2: 0, 4: 1, 5: 0}, workload)
+
+	// a review answers the request
+	_, err = CreateReview(CreateReviewOptions{
+		Type:     ReviewTypeComment,
+		Issue:    openPR,
+		Reviewer: user4,
+	})
+	assert.NoError(t, err)
+	workload, err = GetReviewerWorkload(repo.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, workload[4])
+}
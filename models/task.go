@@ -7,11 +7,13 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/migrations/base"
 	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/timeutil"
+	"code.gitea.io/gitea/modules/util"
 
 	"xorm.io/builder"
 )
@@ -29,9 +31,12 @@ type Task struct {
 	Status         structs.TaskStatus `xorm:"index"`
 	StartTime      timeutil.TimeStamp
 	EndTime        timeutil.TimeStamp
-	PayloadContent string             `xorm:"TEXT"`
-	Errors         string             `xorm:"TEXT"` // if task failed, saved the error reason
-	Created        timeutil.TimeStamp `xorm:"created"`
+	PayloadContent string               `xorm:"TEXT"`
+	Errors         string               `xorm:"TEXT"`               // if task failed, saved the error reason
+	Result         string               `xorm:"TEXT"`               // result of a task which creates nothing, e.g. a dry-run migration
+	Warnings       string               `xorm:"TEXT"`               // problems which didn't fail the task, one per line
+	Checkpoint     structs.MigratePhase `xorm:"NOT NULL DEFAULT 0"` // last completed phase of a migration
+	Created        timeutil.TimeStamp   `xorm:"created"`
 }
 
 // LoadRepo loads repository of the task
@@ -102,6 +107,25 @@ func (task *Task) UpdateCols(cols ...string) error {
 	return err
 }
 
+// MarkRunning marks a queued task as running and reports whether this call
+// did so, which guarantees that a task is only started once even when
+// several workers have picked it up.
+func (task *Task) MarkRunning() (bool, error) {
+	task.StartTime = timeutil.TimeStampNow()
+	task.Status = structs.TaskStatusRunning
+	affected, err := x.ID(task.ID).
+		And("status = ?", structs.TaskStatusQueue).
+		Cols("start_time", "status").
+		Update(task)
+	return affected > 0, err
+}
+
+// SaveCheckpoint records that the phase of a migrate task is completed
+func (task *Task) SaveCheckpoint(phase structs.MigratePhase) error {
+	task.Checkpoint = phase
+	return task.UpdateCols("checkpoint")
+}
+
 // MigrateConfig returns task config when migrate repository
 func (task *Task) MigrateConfig() (*structs.MigrateRepoOption, error) {
 	if task.Type == structs.TaskTypeMigrateRepo {
@@ -115,6 +139,52 @@ func (task *Task) MigrateConfig() (*structs.MigrateRepoOption, error) {
 	return nil, fmt.Errorf("Task type is %s, not Migrate Repo", task.Type.Name())
 }
 
+// MigrateConfigWithoutCredentials returns the task config of a migration like MigrateConfig,
+// but with the credentials removed so it can be shown to others
+func (task *Task) MigrateConfigWithoutCredentials() (*structs.MigrateRepoOption, error) {
+	opts, err := task.MigrateConfig()
+	if err != nil {
+		return nil, err
+	}
+	opts.AuthUsername = ""
+	opts.AuthPassword = ""
+	opts.CloneAddr = util.SanitizeURLCredentials(opts.CloneAddr, false)
+	if opts.OriginalURL != "" {
+		opts.OriginalURL = util.SanitizeURLCredentials(opts.OriginalURL, false)
+	}
+	return opts, nil
+}
+
+// MigrateProbeResult returns the result of a dry-run migrate task
+func (task *Task) MigrateProbeResult() (*structs.MigrateProbeResult, error) {
+	if task.Type != structs.TaskTypeMigrateRepo {
+		return nil, fmt.Errorf("Task type is %s, not Migrate Repo", task.Type.Name())
+	}
+	if len(task.Result) == 0 {
+		return nil, nil
+	}
+
+	var result structs.MigrateProbeResult
+	if err := json.Unmarshal([]byte(task.Result), &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// APIFormatMigrateProbe formats a dry-run migrate task for the API
+func (task *Task) APIFormatMigrateProbe() (*structs.MigrateProbe, error) {
+	result, err := task.MigrateProbeResult()
+	if err != nil {
+		return nil, err
+	}
+	return &structs.MigrateProbe{
+		ID:     task.ID,
+		Status: task.Status.Name(),
+		Result: result,
+		Error:  task.Errors,
+	}, nil
+}
+
 // ErrTaskDoesNotExist represents a "TaskDoesNotExist" kind of error.
 type ErrTaskDoesNotExist struct {
 	ID     int64
@@ -133,6 +203,49 @@ func (err ErrTaskDoesNotExist) Error() string {
 		err.ID, err.RepoID, err.Type)
 }
 
+// ErrTaskNotRetryable represents a "TaskNotRetryable" kind of error.
+type ErrTaskNotRetryable struct {
+	ID int64
+}
+
+// IsErrTaskNotRetryable checks if an error is a ErrTaskNotRetryable.
+func IsErrTaskNotRetryable(err error) bool {
+	_, ok := err.(ErrTaskNotRetryable)
+	return ok
+}
+
+func (err ErrTaskNotRetryable) Error() string {
+	return fmt.Sprintf("task can not be retried [id: %d]", err.ID)
+}
+
+// ErrMigrationNotConvertible represents an error if a migrated repository can not be converted into a mirror.
+type ErrMigrationNotConvertible struct {
+	RepoID int64
+	Reason string
+}
+
+// IsErrMigrationNotConvertible checks if an error is a ErrMigrationNotConvertible.
+func IsErrMigrationNotConvertible(err error) bool {
+	_, ok := err.(ErrMigrationNotConvertible)
+	return ok
+}
+
+func (err ErrMigrationNotConvertible) Error() string {
+	return fmt.Sprintf("migration can not be converted into a mirror [repo_id: %d]: %s", err.RepoID, err.Reason)
+}
+
+// GetTaskByID returns the task by its id
+func GetTaskByID(id int64) (*Task, error) {
+	var task Task
+	has, err := x.ID(id).Get(&task)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrTaskDoesNotExist{ID: id}
+	}
+	return &task, nil
+}
+
 // GetMigratingTask returns the migrating task by repo's id
 func GetMigratingTask(repoID int64) (*Task, error) {
 	var task = Task{
@@ -148,6 +261,16 @@ func GetMigratingTask(repoID int64) (*Task, error) {
 	return &task, nil
 }
 
+// GetStuckTasks returns the tasks which are in running state and have been started longer ago than runningLongerThan
+func GetStuckTasks(runningLongerThan time.Duration) ([]*Task, error) {
+	startedBefore := time.Now().Add(-runningLongerThan).Unix()
+	tasks := make([]*Task, 0, 10)
+	return tasks, x.Where("status = ?", structs.TaskStatusRunning).
+		And("start_time <= ?", startedBefore).
+		Asc("id").
+		Find(&tasks)
+}
+
 // FindTaskOptions find all tasks
 type FindTaskOptions struct {
 	Status int
@@ -169,6 +292,58 @@ func FindTasks(opts FindTaskOptions) ([]*Task, error) {
 	return tasks, err
 }
 
+// SearchTaskOptions contains the options for searching tasks
+type SearchTaskOptions struct {
+	Types    []structs.TaskType   // empty for all types
+	Statuses []structs.TaskStatus // empty for all statuses
+	OwnerID  int64
+	DoerID   int64
+	Page     int
+	PageSize int
+}
+
+func (opts *SearchTaskOptions) toConds() builder.Cond {
+	var cond = builder.NewCond()
+	if len(opts.Types) > 0 {
+		cond = cond.And(builder.In("type", opts.Types))
+	}
+	if len(opts.Statuses) > 0 {
+		cond = cond.And(builder.In("status", opts.Statuses))
+	}
+	if opts.OwnerID > 0 {
+		cond = cond.And(builder.Eq{"owner_id": opts.OwnerID})
+	}
+	if opts.DoerID > 0 {
+		cond = cond.And(builder.Eq{"doer_id": opts.DoerID})
+	}
+	return cond
+}
+
+// SearchTasks returns the tasks matching the given options, newest first,
+// and the total number of matching tasks.
+func SearchTasks(opts *SearchTaskOptions) ([]*Task, int64, error) {
+	cond := opts.toConds()
+	count, err := x.Where(cond).Count(new(Task))
+	if err != nil {
+		return nil, 0, fmt.Errorf("Count: %v", err)
+	}
+
+	if opts.Page <= 0 {
+		opts.Page = 1
+	}
+
+	sess := x.Where(cond).Desc("id")
+	if opts.PageSize > 0 {
+		sess = sess.Limit(opts.PageSize, (opts.Page-1)*opts.PageSize)
+	}
+
+	tasks := make([]*Task, 0, opts.PageSize)
+	if err = sess.Find(&tasks); err != nil {
+		return nil, 0, err
+	}
+	return tasks, count, nil
+}
+
 func createTask(e Engine, task *Task) error {
 	_, err := e.Insert(task)
 	return err
@@ -193,6 +368,11 @@ func CreateMigrateTask(doer, u *User, opts base.MigrateOptions) (*Task, error) {
 		return nil, err
 	}
 
+	// a dry run only probes the source, so there is no repository to migrate into
+	if opts.DryRun {
+		return &task, nil
+	}
+
 	repo, err := CreateRepository(doer, u, CreateRepoOptions{
 		Name:        opts.RepoName,
 		Description: opts.Description,
@@ -219,6 +399,55 @@ func CreateMigrateTask(doer, u *User, opts base.MigrateOptions) (*Task, error) {
 	return &task, nil
 }
 
+// RetryMigrateTask queues a failed migrate task again. The migration resumes after the last
+// completed phase, so only a task which completed a phase and whose repository is still being
+// migrated can be retried, the repository of any other failed migration has been deleted.
+func RetryMigrateTask(task *Task) error {
+	if task.Type != structs.TaskTypeMigrateRepo || task.Status != structs.TaskStatusFailed ||
+		task.Checkpoint == structs.MigratePhaseNone {
+		return ErrTaskNotRetryable{task.ID}
+	}
+	if err := task.LoadRepo(); err != nil {
+		if IsErrRepoNotExist(err) {
+			return ErrTaskNotRetryable{task.ID}
+		}
+		return err
+	}
+	if task.Repo.Status != RepositoryBeingMigrated {
+		return ErrTaskNotRetryable{task.ID}
+	}
+
+	opts, err := task.MigrateConfig()
+	if err != nil {
+		return err
+	}
+	opts.ResumeFrom = task.Checkpoint + 1
+	bs, err := json.Marshal(opts)
+	if err != nil {
+		return err
+	}
+
+	task.PayloadContent = string(bs)
+	task.Status = structs.TaskStatusQueue
+	task.Errors = ""
+	task.StartTime = 0
+	task.EndTime = 0
+	return task.UpdateCols("payload_content", "status", "errors", "start_time", "end_time")
+}
+
+// FinishMigrateProbeTask saves the result of a dry-run migrate task and marks it finished
+func FinishMigrateProbeTask(task *Task, result *structs.MigrateProbeResult) error {
+	bs, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	task.Result = string(bs)
+	task.Status = structs.TaskStatusFinished
+	task.EndTime = timeutil.TimeStampNow()
+	return task.UpdateCols("result", "status", "end_time")
+}
+
 // FinishMigrateTask updates database when migrate task finished
 func FinishMigrateTask(task *Task) error {
 	task.Status = structs.TaskStatusFinished
@@ -11,10 +11,12 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/setting"
 
 	"github.com/stretchr/testify/assert"
@@ -214,3 +216,56 @@ func AssertInt64InRange(t testing.TB, low, high, value int64) {
 	assert.True(t, value >= low && value <= high,
 		"Expected value in range [%d, %d], found %d", low, high, value)
 }
+
+// TestCommitOptions represents the options of a commit created by CreateTestCommit
+type TestCommitOptions struct {
+	// Tree defaults to the tree of the first parent
+	Tree    string
+	Parents []string
+	Message string
+	// Author and Committer default to user2
+	Author    *git.Signature
+	Committer *git.Signature
+}
+
+// TestGitEnv returns the environment of git commands creating commits in tests.
+// The author and the committer default to user2, dates are only set if given.
+func TestGitEnv(author, committer *git.Signature) []string {
+	env := os.Environ()
+	for _, sig := range []struct {
+		prefix string
+		sig    *git.Signature
+	}{{"GIT_AUTHOR_", author}, {"GIT_COMMITTER_", committer}} {
+		if sig.sig == nil {
+			env = append(env, sig.prefix+"NAME=user2", sig.prefix+"EMAIL=user2@example.com")
+			continue
+		}
+		env = append(env, sig.prefix+"NAME="+sig.sig.Name, sig.prefix+"EMAIL="+sig.sig.Email)
+		if !sig.sig.When.IsZero() {
+			env = append(env, sig.prefix+"DATE="+sig.sig.When.Format(time.RFC3339))
+		}
+	}
+	return env
+}
+
+// CreateTestCommit creates a commit in the repository at repoPath without updating any ref
+// and returns its ID
+func CreateTestCommit(t testing.TB, repoPath string, opts TestCommitOptions) string {
+	tree := opts.Tree
+	if len(tree) == 0 && len(opts.Parents) > 0 {
+		tree = opts.Parents[0] + "^{tree}"
+	}
+	args := []string{"commit-tree", "-m", opts.Message}
+	for _, parent := range opts.Parents {
+		args = append(args, "-p", parent)
+	}
+	stdout, err := git.NewCommand(append(args, tree)...).RunInDirWithEnv(repoPath, TestGitEnv(opts.Author, opts.Committer))
+	assert.NoError(t, err)
+	return strings.TrimSpace(stdout)
+}
+
+// UpdateTestRef points a ref of the repository at repoPath to the given commit
+func UpdateTestRef(t testing.TB, repoPath, refName, commitID string) {
+	_, err := git.NewCommand("update-ref", refName, commitID).RunInDir(repoPath)
+	assert.NoError(t, err)
+}
@@ -25,6 +25,7 @@ const (
 	EnvKeyID        = "GITEA_KEY_ID"
 	EnvIsDeployKey  = "GITEA_IS_DEPLOY_KEY"
 	EnvIsInternal   = "GITEA_INTERNAL_PUSH"
+	EnvForceMerge   = "GITEA_FORCE_MERGE"
 )
 
 // CommitToPushCommit transforms a git.Commit to PushCommit type.
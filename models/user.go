@@ -98,7 +98,9 @@ type User struct {
 	// Email is the primary email address (to be used for communication)
 	Email                        string `xorm:"NOT NULL"`
 	KeepEmailPrivate             bool
+	KeepReactionsPrivate         bool   `xorm:"NOT NULL DEFAULT false"`
 	EmailNotificationsPreference string `xorm:"VARCHAR(20) NOT NULL DEFAULT 'enabled'"`
+	NotifyOnReactions            bool   `xorm:"NOT NULL DEFAULT false"`
 	Passwd                       string `xorm:"NOT NULL"`
 	PasswdHashAlgo               string `xorm:"NOT NULL DEFAULT 'pbkdf2'"`
 
@@ -760,6 +762,16 @@ func (u *User) SetEmailNotifications(set string) error {
 	return nil
 }
 
+// SetNotifyOnReactions sets whether the user gets a notification when someone reacts to his/her content
+func (u *User) SetNotifyOnReactions(notify bool) error {
+	u.NotifyOnReactions = notify
+	if err := UpdateUserCols(u, "notify_on_reactions"); err != nil {
+		log.Error("SetNotifyOnReactions: %v", err)
+		return err
+	}
+	return nil
+}
+
 func isUserExist(e Engine, uid int64, name string) (bool, error) {
 	if len(name) == 0 {
 		return false, nil
@@ -791,6 +803,16 @@ func NewGhostUser() *User {
 	}
 }
 
+// NewPrivateReactionUser creates and returns a placeholder user shown instead of
+// the users who keep their reactions private.
+func NewPrivateReactionUser() *User {
+	return &User{
+		ID:        -2,
+		Name:      "Anonymous",
+		LowerName: "anonymous",
+	}
+}
+
 var (
 	reservedUsernames = []string{
 		"attachments",
@@ -8,11 +8,24 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
+
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+
+	"xorm.io/builder"
 )
 
+// EmailBounceThreshold is the number of hard bounces after which an email address is deactivated.
+const EmailBounceThreshold = 3
+
 var (
 	// ErrEmailAddressNotExist email address not exist
 	ErrEmailAddressNotExist = errors.New("Email address does not exist")
+
+	// ErrEmailVerificationExpired email verification link expired
+	ErrEmailVerificationExpired = errors.New("Email verification has expired")
 )
 
 // EmailAddress is the list of all email addresses of a user. Can contain the
@@ -22,7 +35,72 @@ type EmailAddress struct {
 	UID         int64  `xorm:"INDEX NOT NULL"`
 	Email       string `xorm:"UNIQUE NOT NULL"`
 	IsActivated bool
-	IsPrimary   bool `xorm:"-"`
+	IsPrimary   bool               `xorm:"-"`
+	BounceCount int                `xorm:"NOT NULL DEFAULT 0"`
+	LastBounce  timeutil.TimeStamp `xorm:"INDEX"`
+
+	VerificationSentUnix timeutil.TimeStamp
+	CreatedUnix          timeutil.TimeStamp `xorm:"INDEX created"`
+
+	// CanonicalEmail is Email without the +tag of the local part, see CanonicalEmail.
+	// It falls back to Email if the canonical form is already taken by another address.
+	CanonicalEmail string `xorm:"UNIQUE"`
+}
+
+// BeforeInsert will be invoked by XORM before inserting a record
+func (email *EmailAddress) BeforeInsert() {
+	if len(email.CanonicalEmail) == 0 {
+		email.CanonicalEmail = CanonicalEmail(email.Email)
+	}
+}
+
+// setCanonicalEmail sets the canonical form of the email address, or the lower cased address itself
+// if another address, e.g. one added while canonicalization was off, already uses it.
+// Forms in taken count as used as well. It returns ErrEmailAlreadyUsed if both forms are used.
+func setCanonicalEmail(e Engine, email *EmailAddress, taken map[string]bool) error {
+	for _, canonical := range []string{CanonicalEmail(email.Email), strings.ToLower(strings.TrimSpace(email.Email))} {
+		if taken[canonical] {
+			continue
+		}
+		has, err := e.Where("canonical_email = ?", canonical).Exist(new(EmailAddress))
+		if err != nil {
+			return err
+		} else if !has {
+			email.CanonicalEmail = canonical
+			return nil
+		}
+	}
+	return ErrEmailAlreadyUsed{email.Email}
+}
+
+// CanonicalEmail returns the lower cased email address without the +tag of the local part,
+// i.e. user+tag@example.com becomes user@example.com.
+func CanonicalEmail(email string) string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return email
+	}
+	local, domain := email[:at], email[at:]
+	if plus := strings.Index(local, "+"); plus > 0 {
+		local = local[:plus]
+	}
+	return local + domain
+}
+
+// isEmailCanonicalized returns true if email addresses of the domain of email are compared
+// by their canonical form, since the mail provider ignores the +tag.
+func isEmailCanonicalized(email string) bool {
+	if !setting.Service.EnableEmailCanonicalization {
+		return false
+	}
+	domain := email[strings.LastIndex(email, "@")+1:]
+	for _, d := range setting.Service.EmailCanonicalizationDomains {
+		if strings.EqualFold(domain, strings.TrimSpace(d)) {
+			return true
+		}
+	}
+	return false
 }
 
 // GetEmailAddresses returns all email addresses belongs to given user.
@@ -30,6 +108,7 @@ func GetEmailAddresses(uid int64) ([]*EmailAddress, error) {
 	emails := make([]*EmailAddress, 0, 5)
 	if err := x.
 		Where("uid=?", uid).
+		Asc("email").
 		Find(&emails); err != nil {
 		return nil, err
 	}
@@ -38,27 +117,84 @@ func GetEmailAddresses(uid int64) ([]*EmailAddress, error) {
 	if err != nil {
 		return nil, err
 	}
+	return withPrimaryEmailFirst(u, emails), nil
+}
 
-	isPrimaryFound := false
-	for _, email := range emails {
+// withPrimaryEmailFirst marks the primary email address of u among emails and moves it to the front.
+func withPrimaryEmailFirst(u *User, emails []*EmailAddress) []*EmailAddress {
+	primaryIndex := -1
+	for i, email := range emails {
 		if email.Email == u.Email {
-			isPrimaryFound = true
+			primaryIndex = i
 			email.IsPrimary = true
 		} else {
 			email.IsPrimary = false
 		}
 	}
 
-	// We always want the primary email address displayed, even if it's not in
-	// the email address table (yet).
-	if !isPrimaryFound {
-		emails = append(emails, &EmailAddress{
+	// The primary email address is always listed first, followed by the others in alphabetical order.
+	// We always want it displayed, even if it's not in the email address table (yet).
+	if primaryIndex < 0 {
+		emails = append([]*EmailAddress{{
 			Email:       u.Email,
 			IsActivated: true,
 			IsPrimary:   true,
-		})
+		}}, emails...)
+	} else if primaryIndex > 0 {
+		primary := emails[primaryIndex]
+		copy(emails[1:primaryIndex+1], emails[:primaryIndex])
+		emails[0] = primary
 	}
-	return emails, nil
+	return emails
+}
+
+// GetOrgMemberEmails returns the email addresses of all members of the organization, keyed by user ID.
+// Like GetEmailAddresses, the primary email address of every member is listed first.
+func GetOrgMemberEmails(orgID int64, activatedOnly bool) (map[int64][]*EmailAddress, error) {
+	members, _, err := FindOrgMembers(FindOrgMembersOpts{OrgID: orgID})
+	if err != nil {
+		return nil, err
+	}
+
+	emails := make([]*EmailAddress, 0, len(members))
+	if err = x.
+		Where(builder.In("uid", builder.Select("uid").From("org_user").Where(builder.Eq{"org_id": orgID}))).
+		Asc("email").
+		Find(&emails); err != nil {
+		return nil, err
+	}
+
+	emailsByUID := make(map[int64][]*EmailAddress, len(members))
+	for _, email := range emails {
+		emailsByUID[email.UID] = append(emailsByUID[email.UID], email)
+	}
+
+	result := make(map[int64][]*EmailAddress, len(members))
+	for _, member := range members {
+		memberEmails := withPrimaryEmailFirst(member, emailsByUID[member.ID])
+		if activatedOnly {
+			activated := memberEmails[:0]
+			for _, email := range memberEmails {
+				if email.IsActivated {
+					activated = append(activated, email)
+				}
+			}
+			memberEmails = activated
+		}
+		result[member.ID] = memberEmails
+	}
+	return result, nil
+}
+
+// GetEmailAddressByID returns the email address of given user with given id.
+func GetEmailAddressByID(uid, id int64) (*EmailAddress, error) {
+	email := &EmailAddress{UID: uid, ID: id}
+	if has, err := x.Get(email); err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrEmailAddressNotExist
+	}
+	return email, nil
 }
 
 func isEmailUsed(e Engine, email string) (bool, error) {
@@ -66,7 +202,22 @@ func isEmailUsed(e Engine, email string) (bool, error) {
 		return true, nil
 	}
 
-	return e.Get(&EmailAddress{Email: email})
+	has, err := e.Get(&EmailAddress{Email: email})
+	if err != nil || has || !isEmailCanonicalized(email) {
+		return has, err
+	}
+
+	canonical := CanonicalEmail(email)
+	has, err = e.Where("canonical_email = ?", canonical).Exist(new(EmailAddress))
+	if err != nil || has {
+		return has, err
+	}
+
+	// Primary email addresses are not necessarily in the email_address table
+	at := strings.LastIndex(canonical, "@")
+	return e.Where("LOWER(email) = ?", canonical).
+		Or("LOWER(email) LIKE ?", canonical[:at]+"+%"+canonical[at:]).
+		Exist(new(User))
 }
 
 // IsEmailUsed returns true if the email has been used.
@@ -74,6 +225,143 @@ func IsEmailUsed(email string) (bool, error) {
 	return isEmailUsed(x, email)
 }
 
+func isEmailActivatedForUser(e Engine, uid int64, email string) (bool, error) {
+	if uid <= 0 || len(email) == 0 {
+		return false, nil
+	}
+	email = strings.ToLower(email)
+
+	// Primary email addresses are not necessarily in the email_address table
+	has, err := e.Where("id = ? AND is_active = ? AND LOWER(email) = ?", uid, true, email).
+		Exist(new(User))
+	if err != nil || has {
+		return has, err
+	}
+
+	return e.Where("uid = ? AND is_activated = ? AND LOWER(email) = ?", uid, true, email).
+		Exist(new(EmailAddress))
+}
+
+// IsEmailActivatedForUser returns true if the email is an activated address of the given user.
+func IsEmailActivatedForUser(uid int64, email string) (bool, error) {
+	return isEmailActivatedForUser(x, uid, email)
+}
+
+func getUserByVerifiedEmail(e Engine, email string) (*User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) == 0 {
+		return nil, ErrUserNotExist{0, email, 0}
+	}
+
+	emailAddress := new(EmailAddress)
+	has, err := e.Where("LOWER(email) = ? AND is_activated = ?", email, true).Get(emailAddress)
+	if err != nil {
+		return nil, err
+	} else if has {
+		return getUserByID(e, emailAddress.UID)
+	}
+
+	user := new(User)
+	has, err = e.Where("LOWER(email) = ? AND is_active = ?", email, true).Get(user)
+	if err != nil {
+		return nil, err
+	} else if !has {
+		return nil, ErrUserNotExist{0, email, 0}
+	}
+	return user, nil
+}
+
+// GetUserByVerifiedEmail returns the user the given email address belongs to,
+// if the address has been activated. Primary email addresses count as activated
+// once the user is.
+func GetUserByVerifiedEmail(email string) (*User, error) {
+	return getUserByVerifiedEmail(x, email)
+}
+
+// canBeEmailMentioned reports whether an email address of the user written in content may be
+// resolved to a mention. Addresses of users who keep their email address private are not resolved,
+// so they don't reveal who the address belongs to.
+func (u *User) canBeEmailMentioned() bool {
+	return !u.KeepEmailPrivate && !u.IsOrganization() && u.IsActive && !u.ProhibitLogin
+}
+
+// getEmailMentions resolves the verified email addresses to the names of the users they are
+// mentions of, keyed by the lower case address, with a fixed number of queries.
+func getEmailMentions(e Engine, emails []string) (map[string]string, error) {
+	lowerEmails := make([]string, 0, len(emails))
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if len(email) > 0 {
+			lowerEmails = append(lowerEmails, email)
+		}
+	}
+	mentions := make(map[string]string, len(lowerEmails))
+	if len(lowerEmails) == 0 {
+		return mentions, nil
+	}
+
+	uids := make(map[string]int64, len(lowerEmails))
+	emailAddresses := make([]*EmailAddress, 0, len(lowerEmails))
+	if err := e.Where(builder.In("LOWER(email)", lowerEmails)).And("is_activated = ?", true).Find(&emailAddresses); err != nil {
+		return nil, err
+	}
+	for _, emailAddress := range emailAddresses {
+		uids[strings.ToLower(emailAddress.Email)] = emailAddress.UID
+	}
+
+	// Primary email addresses count as activated once the user is
+	users := make(map[int64]*User, len(lowerEmails))
+	if err := e.Where(builder.In("LOWER(email)", lowerEmails)).And("is_active = ?", true).Find(&users); err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		if _, ok := uids[strings.ToLower(u.Email)]; !ok {
+			uids[strings.ToLower(u.Email)] = u.ID
+		}
+	}
+
+	missingIDs := make([]int64, 0, len(uids))
+	for _, uid := range uids {
+		if _, ok := users[uid]; !ok {
+			missingIDs = append(missingIDs, uid)
+		}
+	}
+	if len(missingIDs) > 0 {
+		if err := e.In("id", missingIDs).Find(&users); err != nil {
+			return nil, err
+		}
+	}
+
+	for email, uid := range uids {
+		if u, ok := users[uid]; ok && u.canBeEmailMentioned() {
+			mentions[email] = u.Name
+		}
+	}
+	return mentions, nil
+}
+
+// GetEmailMentions returns the names of the users the given email addresses written in content
+// are mentions of, keyed by the lower case address. Unknown and unverified addresses are skipped.
+func GetEmailMentions(emails []string) (map[string]string, error) {
+	return getEmailMentions(x, emails)
+}
+
+// ResolveEmailMentions returns the names of the users the given email addresses written
+// in content are mentions of. Unknown and unverified addresses are skipped.
+func ResolveEmailMentions(ctx DBContext, emails []string) ([]string, error) {
+	mentions, err := getEmailMentions(ctx.e, emails)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(mentions))
+	for _, email := range emails {
+		if name, ok := mentions[strings.ToLower(strings.TrimSpace(email))]; ok {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
 func addEmailAddress(e Engine, email *EmailAddress) error {
 	email.Email = strings.ToLower(strings.TrimSpace(email.Email))
 	used, err := isEmailUsed(e, email.Email)
@@ -83,6 +371,9 @@ func addEmailAddress(e Engine, email *EmailAddress) error {
 		return ErrEmailAlreadyUsed{email.Email}
 	}
 
+	if err = setCanonicalEmail(e, email, nil); err != nil {
+		return err
+	}
 	_, err = e.Insert(email)
 	return err
 }
@@ -99,14 +390,22 @@ func AddEmailAddresses(emails []*EmailAddress) error {
 	}
 
 	// Check if any of them has been used
+	// The addresses of the batch are not inserted yet, so their canonical forms and
+	// the addresses themselves are tracked here
+	taken := make(map[string]bool, 2*len(emails))
 	for i := range emails {
 		emails[i].Email = strings.ToLower(strings.TrimSpace(emails[i].Email))
 		used, err := IsEmailUsed(emails[i].Email)
 		if err != nil {
 			return err
-		} else if used {
+		} else if used || taken[emails[i].Email] {
 			return ErrEmailAlreadyUsed{emails[i].Email}
 		}
+		if err = setCanonicalEmail(x, emails[i], taken); err != nil {
+			return err
+		}
+		taken[emails[i].CanonicalEmail] = true
+		taken[emails[i].Email] = true
 	}
 
 	if _, err := x.Insert(emails); err != nil {
@@ -116,8 +415,29 @@ func AddEmailAddresses(emails []*EmailAddress) error {
 	return nil
 }
 
+// IsVerificationExpired returns true if the last verification mail for the email address
+// has been sent more than ttl ago. Addresses without a recorded verification never expire.
+func (email *EmailAddress) IsVerificationExpired(ttl time.Duration) bool {
+	if email.VerificationSentUnix == 0 {
+		return false
+	}
+	return time.Since(email.VerificationSentUnix.AsTime()) > ttl
+}
+
+// UpdateVerificationSent records that a verification mail has just been sent for the email address.
+func (email *EmailAddress) UpdateVerificationSent() error {
+	email.VerificationSentUnix = timeutil.TimeStampNow()
+	_, err := x.ID(email.ID).Cols("verification_sent_unix").Update(email)
+	return err
+}
+
 // Activate activates the email address to given user.
+// It returns ErrEmailVerificationExpired if the verification mail is older than the activation code lifetime.
 func (email *EmailAddress) Activate() error {
+	if email.IsVerificationExpired(time.Duration(setting.Service.ActiveCodeLives) * time.Minute) {
+		return ErrEmailVerificationExpired
+	}
+
 	user, err := GetUserByID(email.UID)
 	if err != nil {
 		return err
@@ -145,6 +465,217 @@ func (email *EmailAddress) Activate() error {
 	return sess.Commit()
 }
 
+// RecordEmailBounce records a hard bounce for given email address and
+// deactivates the address once it reached EmailBounceThreshold.
+func RecordEmailBounce(email string) error {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if len(email) == 0 {
+		return nil
+	}
+
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return err
+	}
+
+	if _, err := sess.Where("email = ?", email).
+		Incr("bounce_count").
+		Cols("last_bounce").
+		Update(&EmailAddress{LastBounce: timeutil.TimeStampNow()}); err != nil {
+		return fmt.Errorf("increase bounce count: %v", err)
+	}
+
+	if _, err := sess.Where("email = ?", email).
+		And("bounce_count >= ?", EmailBounceThreshold).
+		Cols("is_activated").
+		Update(&EmailAddress{IsActivated: false}); err != nil {
+		return fmt.Errorf("deactivate email: %v", err)
+	}
+
+	return sess.Commit()
+}
+
+// GetBouncedEmailAddresses returns all email addresses which reached EmailBounceThreshold,
+// most recently bounced first.
+func GetBouncedEmailAddresses() ([]*EmailAddress, error) {
+	emails := make([]*EmailAddress, 0, 10)
+	return emails, x.
+		Where("bounce_count >= ?", EmailBounceThreshold).
+		Desc("last_bounce").
+		Find(&emails)
+}
+
+// GetUnactivatedEmailAddresses returns all email addresses which have not been activated
+// and were added more than olderThan ago. Primary email addresses are never returned.
+func GetUnactivatedEmailAddresses(olderThan time.Duration) ([]*EmailAddress, error) {
+	return getUnactivatedEmailAddresses(x, olderThan)
+}
+
+func getUnactivatedEmailAddresses(e Engine, olderThan time.Duration) ([]*EmailAddress, error) {
+	emails := make([]*EmailAddress, 0, 10)
+	return emails, e.
+		Table("email_address").
+		Select("email_address.*").
+		Join("INNER", "`user`", "`user`.id = email_address.uid").
+		Where("email_address.is_activated = ?", false).
+		And("email_address.created_unix < ?", timeutil.TimeStampNow().AddDuration(-olderThan)).
+		And("LOWER(email_address.email) <> LOWER(`user`.email)").
+		Asc("email_address.created_unix").
+		Find(&emails)
+}
+
+// DeleteUnactivatedEmailAddresses deletes all email addresses which have not been activated
+// and were added more than olderThan ago. Primary email addresses are never deleted.
+// It returns the number of deleted email addresses.
+func DeleteUnactivatedEmailAddresses(olderThan time.Duration) (int64, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return 0, err
+	}
+
+	emails, err := getUnactivatedEmailAddresses(sess, olderThan)
+	if err != nil {
+		return 0, fmt.Errorf("getUnactivatedEmailAddresses: %v", err)
+	}
+	if len(emails) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, len(emails))
+	for _, email := range emails {
+		ids = append(ids, email.ID)
+	}
+
+	deleted, err := sess.In("id", ids).Delete(new(EmailAddress))
+	if err != nil {
+		return 0, err
+	}
+	return deleted, sess.Commit()
+}
+
+// FindOrphanedEmailAddresses returns all email addresses which belong to a user who does not exist anymore.
+func FindOrphanedEmailAddresses() ([]*EmailAddress, error) {
+	return findOrphanedEmailAddresses(x)
+}
+
+func findOrphanedEmailAddresses(e Engine) ([]*EmailAddress, error) {
+	emails := make([]*EmailAddress, 0, 10)
+	return emails, e.
+		Table("email_address").
+		Select("email_address.*").
+		Join("LEFT", "`user`", "`user`.id = email_address.uid").
+		Where("`user`.id IS NULL").
+		Asc("email_address.id").
+		Find(&emails)
+}
+
+// DeleteOrphanedEmailAddresses deletes all email addresses which belong to a user who does not exist
+// anymore, so they can be used again, e.g. to register. It returns the number of deleted email addresses.
+func DeleteOrphanedEmailAddresses() (int64, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return 0, err
+	}
+
+	emails, err := findOrphanedEmailAddresses(sess)
+	if err != nil {
+		return 0, fmt.Errorf("findOrphanedEmailAddresses: %v", err)
+	}
+	if len(emails) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]int64, 0, len(emails))
+	for _, email := range emails {
+		ids = append(ids, email.ID)
+	}
+
+	deleted, err := sess.In("id", ids).Delete(new(EmailAddress))
+	if err != nil {
+		return 0, err
+	}
+	if err = sess.Commit(); err != nil {
+		return 0, err
+	}
+
+	for _, email := range emails {
+		log.Info("Deleted orphaned email address %s of deleted user %d", email.Email, email.UID)
+	}
+	return deleted, nil
+}
+
+// ActivateEmailAddresses activates the given email addresses of the user in a single transaction,
+// adding the ones which don't exist yet, and rotates the salt of the user once.
+// It returns the email addresses which could not be activated because they belong to another user.
+func ActivateEmailAddresses(uid int64, emails []string) ([]string, error) {
+	sess := x.NewSession()
+	defer sess.Close()
+	if err := sess.Begin(); err != nil {
+		return nil, err
+	}
+
+	user, err := getUserByID(sess, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	var notFound []string
+	seen := make(map[string]bool, len(emails))
+	changed := false
+	for _, email := range emails {
+		email = strings.ToLower(strings.TrimSpace(email))
+		if len(email) == 0 || seen[email] {
+			continue
+		}
+		seen[email] = true
+
+		emailAddress := &EmailAddress{Email: email}
+		has, err := sess.Get(emailAddress)
+		if err != nil {
+			return nil, err
+		}
+
+		if !has {
+			emailAddress = &EmailAddress{UID: uid, Email: email, IsActivated: true}
+			if err = setCanonicalEmail(sess, emailAddress, nil); err != nil {
+				return nil, err
+			}
+			if _, err = sess.Insert(emailAddress); err != nil {
+				return nil, fmt.Errorf("insert email address %s: %v", email, err)
+			}
+			changed = true
+			continue
+		}
+		if emailAddress.UID != uid {
+			notFound = append(notFound, email)
+			continue
+		}
+		if emailAddress.IsActivated {
+			continue
+		}
+
+		emailAddress.IsActivated = true
+		if _, err = sess.ID(emailAddress.ID).Cols("is_activated").Update(emailAddress); err != nil {
+			return nil, fmt.Errorf("activate email address %s: %v", email, err)
+		}
+		changed = true
+	}
+
+	if changed {
+		if user.Rands, err = GetUserSalt(); err != nil {
+			return nil, err
+		}
+		if err = updateUserCols(sess, user, "rands"); err != nil {
+			return nil, err
+		}
+	}
+
+	return notFound, sess.Commit()
+}
+
 // DeleteEmailAddress deletes an email address of given user.
 func DeleteEmailAddress(email *EmailAddress) (err error) {
 	var deleted int64
@@ -216,6 +747,9 @@ func MakeEmailPrimary(email *EmailAddress) error {
 	if !has {
 		formerPrimaryEmail.UID = user.ID
 		formerPrimaryEmail.IsActivated = user.IsActive
+		if err = setCanonicalEmail(sess, formerPrimaryEmail, nil); err != nil {
+			return err
+		}
 		if _, err = sess.Insert(formerPrimaryEmail); err != nil {
 			return err
 		}
@@ -6,6 +6,10 @@ package models
 
 import (
 	"testing"
+	"time"
+
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -15,9 +19,12 @@ func TestGetEmailAddresses(t *testing.T) {
 
 	emails, _ := GetEmailAddresses(int64(1))
 	if assert.Len(t, emails, 3) {
-		assert.False(t, emails[0].IsPrimary)
-		assert.True(t, emails[2].IsActivated)
-		assert.True(t, emails[2].IsPrimary)
+		assert.True(t, emails[0].IsActivated)
+		assert.True(t, emails[0].IsPrimary)
+		assert.Equal(t, "user1@example.com", emails[0].Email)
+		assert.Equal(t, "user11@example.com", emails[1].Email)
+		assert.False(t, emails[1].IsPrimary)
+		assert.Equal(t, "user12@example.com", emails[2].Email)
 	}
 
 	emails, _ = GetEmailAddresses(int64(2))
@@ -25,6 +32,44 @@ func TestGetEmailAddresses(t *testing.T) {
 		assert.True(t, emails[0].IsPrimary)
 		assert.True(t, emails[0].IsActivated)
 	}
+
+	// the primary address is listed first even if it sorts after the others
+	assert.NoError(t, AddEmailAddress(&EmailAddress{UID: 2, Email: "a-user2@example.com"}))
+	emails, _ = GetEmailAddresses(int64(2))
+	if assert.Len(t, emails, 3) {
+		assert.Equal(t, "user2@example.com", emails[0].Email)
+		assert.True(t, emails[0].IsPrimary)
+		assert.Equal(t, "a-user2@example.com", emails[1].Email)
+		assert.Equal(t, "user21@example.com", emails[2].Email)
+	}
+}
+
+func TestGetOrgMemberEmails(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	emails, err := GetOrgMemberEmails(3, false)
+	assert.NoError(t, err)
+	assert.Len(t, emails, 3)
+	if assert.Len(t, emails[2], 2) {
+		assert.Equal(t, "user2@example.com", emails[2][0].Email)
+		assert.True(t, emails[2][0].IsPrimary)
+		assert.Equal(t, "user21@example.com", emails[2][1].Email)
+	}
+	// a primary address missing from the email table is still listed
+	if assert.Len(t, emails[4], 1) {
+		assert.Equal(t, "user4@example.com", emails[4][0].Email)
+		assert.True(t, emails[4][0].IsPrimary)
+	}
+
+	emails, err = GetOrgMemberEmails(3, true)
+	assert.NoError(t, err)
+	if assert.Len(t, emails[2], 1) {
+		assert.Equal(t, "user2@example.com", emails[2][0].Email)
+	}
+
+	emails, err = GetOrgMemberEmails(NonexistentID, false)
+	assert.NoError(t, err)
+	assert.Empty(t, emails)
 }
 
 func TestIsEmailUsed(t *testing.T) {
@@ -38,6 +83,131 @@ func TestIsEmailUsed(t *testing.T) {
 	assert.False(t, isExist)
 }
 
+func TestIsEmailActivatedForUser(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	activated, err := IsEmailActivatedForUser(1, "User1@Example.com")
+	assert.NoError(t, err)
+	assert.True(t, activated)
+
+	activated, err = IsEmailActivatedForUser(10, "user101@example.com")
+	assert.NoError(t, err)
+	assert.True(t, activated)
+
+	// unactivated address
+	activated, err = IsEmailActivatedForUser(1, "user11@example.com")
+	assert.NoError(t, err)
+	assert.False(t, activated)
+
+	// address of another user
+	activated, err = IsEmailActivatedForUser(1, "user101@example.com")
+	assert.NoError(t, err)
+	assert.False(t, activated)
+
+	// primary address of an inactive user
+	activated, err = IsEmailActivatedForUser(9, "user9@example.com")
+	assert.NoError(t, err)
+	assert.False(t, activated)
+
+	activated, err = IsEmailActivatedForUser(0, "user1@example.com")
+	assert.NoError(t, err)
+	assert.False(t, activated)
+}
+
+func TestIsEmailUsed_Canonicalization(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	assert.Equal(t, "user11@example.com", CanonicalEmail(" User11+Tag@Example.com"))
+
+	isExist, _ := IsEmailUsed("user11+tag@example.com")
+	assert.False(t, isExist)
+
+	defer func(enabled bool, domains []string) {
+		setting.Service.EnableEmailCanonicalization = enabled
+		setting.Service.EmailCanonicalizationDomains = domains
+	}(setting.Service.EnableEmailCanonicalization, setting.Service.EmailCanonicalizationDomains)
+	setting.Service.EnableEmailCanonicalization = true
+	setting.Service.EmailCanonicalizationDomains = []string{"example.com"}
+
+	isExist, _ = IsEmailUsed("user11+tag@example.com")
+	assert.True(t, isExist)
+	// primary email address of user1, which is not in the email_address table
+	isExist, _ = IsEmailUsed("user1+tag@example.com")
+	assert.True(t, isExist)
+	isExist, _ = IsEmailUsed("user1234567890+tag@example.com")
+	assert.False(t, isExist)
+
+	setting.Service.EmailCanonicalizationDomains = []string{"gmail.com"}
+	isExist, _ = IsEmailUsed("user11+tag@example.com")
+	assert.False(t, isExist)
+
+	email := &EmailAddress{UID: 2, Email: "user2.alias+Tag@example.com"}
+	assert.NoError(t, AddEmailAddress(email))
+	AssertExistsAndLoadBean(t, &EmailAddress{Email: "user2.alias+tag@example.com", CanonicalEmail: "user2.alias@example.com"})
+
+	// the canonical form is already taken, so the address itself is stored
+	email = &EmailAddress{UID: 2, Email: "user2+Tag@example.com"}
+	assert.NoError(t, AddEmailAddress(email))
+	AssertExistsAndLoadBean(t, &EmailAddress{Email: "user2+tag@example.com", CanonicalEmail: "user2+tag@example.com"})
+
+	// both forms are taken by an address stored with different case
+	_, err := x.Insert(&EmailAddress{UID: 2, Email: "User2+Mixed@example.com", CanonicalEmail: "user2+mixed@example.com"})
+	assert.NoError(t, err)
+	err = AddEmailAddress(&EmailAddress{UID: 2, Email: "user2+mixed@example.com"})
+	assert.True(t, IsErrEmailAlreadyUsed(err))
+
+	// mixed case duplicates within a batch
+	err = AddEmailAddresses([]*EmailAddress{
+		{UID: 2, Email: "user2.batch+A@example.com"},
+		{UID: 2, Email: "User2.Batch+a@example.com"},
+	})
+	assert.True(t, IsErrEmailAlreadyUsed(err))
+	AssertNotExistsBean(t, &EmailAddress{Email: "user2.batch+a@example.com"})
+}
+
+func TestGetUserByVerifiedEmail(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	u, err := GetUserByVerifiedEmail("user101@example.com")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 10, u.ID)
+
+	// primary address
+	u, err = GetUserByVerifiedEmail("User4@Example.com")
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, u.ID)
+
+	// unactivated address
+	assert.NoError(t, AddEmailAddress(&EmailAddress{UID: 4, Email: "user4-new@example.com"}))
+	_, err = GetUserByVerifiedEmail("user4-new@example.com")
+	assert.True(t, IsErrUserNotExist(err))
+
+	// primary address of an inactive user
+	_, err = GetUserByVerifiedEmail("user9@example.com")
+	assert.True(t, IsErrUserNotExist(err))
+
+	_, err = GetUserByVerifiedEmail("user1234567890@example.com")
+	assert.True(t, IsErrUserNotExist(err))
+}
+
+func TestResolveEmailMentions(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// user2 keeps the email address private
+	assert.NoError(t, AddEmailAddress(&EmailAddress{UID: 4, Email: "user4-new@example.com"}))
+	names, err := ResolveEmailMentions(DefaultDBContext(), []string{
+		"user101@example.com",
+		"user2@example.com",
+		"user4-new@example.com",
+		"user1234567890@example.com",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user10"}, names)
+
+	mentions, err := GetEmailMentions([]string{"User4@Example.com", "user101@example.com", "user2@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"user4@example.com": "user4", "user101@example.com": "user10"}, mentions)
+}
+
 func TestAddEmailAddress(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 
@@ -76,6 +246,87 @@ func TestAddEmailAddresses(t *testing.T) {
 	assert.True(t, IsErrEmailAlreadyUsed(err))
 }
 
+func TestEmailAddress_IsVerificationExpired(t *testing.T) {
+	email := &EmailAddress{}
+	assert.False(t, email.IsVerificationExpired(time.Minute))
+
+	email.VerificationSentUnix = timeutil.TimeStampNow()
+	assert.False(t, email.IsVerificationExpired(time.Minute))
+
+	email.VerificationSentUnix = timeutil.TimeStamp(time.Now().Add(-2 * time.Minute).Unix())
+	assert.True(t, email.IsVerificationExpired(time.Minute))
+}
+
+func TestEmailAddress_Activate(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+	defer func(lives int) {
+		setting.Service.ActiveCodeLives = lives
+	}(setting.Service.ActiveCodeLives)
+	setting.Service.ActiveCodeLives = 180
+
+	email, err := GetEmailAddressByID(1, 1)
+	assert.NoError(t, err)
+	assert.False(t, email.IsActivated)
+
+	email.VerificationSentUnix = timeutil.TimeStamp(time.Now().Add(-time.Duration(setting.Service.ActiveCodeLives+1) * time.Minute).Unix())
+	assert.Equal(t, ErrEmailVerificationExpired, email.Activate())
+	AssertExistsAndLoadBean(t, &EmailAddress{ID: 1}, Cond("is_activated = ?", false))
+
+	assert.NoError(t, email.UpdateVerificationSent())
+	email, err = GetEmailAddressByID(1, 1)
+	assert.NoError(t, err)
+	assert.NoError(t, email.Activate())
+	AssertExistsAndLoadBean(t, &EmailAddress{ID: 1}, Cond("is_activated = ?", true))
+
+	_, err = GetEmailAddressByID(2, 1)
+	assert.Equal(t, ErrEmailAddressNotExist, err)
+}
+
+func TestActivateEmailAddresses(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	user := AssertExistsAndLoadBean(t, &User{ID: 1}).(*User)
+	notFound, err := ActivateEmailAddresses(1, []string{"user11@example.com", " USER12@example.com", "user11@example.com", "sso-new@example.com", "user2@example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user2@example.com"}, notFound)
+
+	AssertExistsAndLoadBean(t, &EmailAddress{ID: 1}, Cond("is_activated = ?", true))
+	AssertExistsAndLoadBean(t, &EmailAddress{ID: 2}, Cond("is_activated = ?", true))
+	AssertExistsAndLoadBean(t, &EmailAddress{UID: 1, Email: "sso-new@example.com"}, Cond("is_activated = ?", true))
+	AssertExistsAndLoadBean(t, &EmailAddress{ID: 3, UID: 2})
+	assert.NotEqual(t, user.Rands, AssertExistsAndLoadBean(t, &User{ID: 1}).(*User).Rands)
+
+	_, err = ActivateEmailAddresses(NonexistentID, []string{"user11@example.com"})
+	assert.True(t, IsErrUserNotExist(err))
+}
+
+func TestRecordEmailBounce(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	bounced, err := GetBouncedEmailAddresses()
+	assert.NoError(t, err)
+	assert.Len(t, bounced, 0)
+
+	for i := 0; i < EmailBounceThreshold-1; i++ {
+		assert.NoError(t, RecordEmailBounce("User2@example.com"))
+	}
+	email := AssertExistsAndLoadBean(t, &EmailAddress{ID: 3}).(*EmailAddress)
+	assert.EqualValues(t, EmailBounceThreshold-1, email.BounceCount)
+	assert.True(t, email.IsActivated)
+	assert.NotZero(t, email.LastBounce)
+
+	assert.NoError(t, RecordEmailBounce("user2@example.com"))
+	email = AssertExistsAndLoadBean(t, &EmailAddress{ID: 3}).(*EmailAddress)
+	assert.EqualValues(t, EmailBounceThreshold, email.BounceCount)
+	assert.False(t, email.IsActivated)
+
+	bounced, err = GetBouncedEmailAddresses()
+	assert.NoError(t, err)
+	if assert.Len(t, bounced, 1) {
+		assert.EqualValues(t, 3, bounced[0].ID)
+	}
+}
+
 func TestDeleteEmailAddress(t *testing.T) {
 	assert.NoError(t, PrepareTestDatabase())
 
@@ -151,6 +402,16 @@ func TestMakeEmailPrimary(t *testing.T) {
 
 	user, _ := GetUserByID(int64(10))
 	assert.Equal(t, "user101@example.com", user.Email)
+	// the former primary email address is kept with its canonical form
+	AssertExistsAndLoadBean(t, &EmailAddress{UID: 10, Email: "user10@example.com", CanonicalEmail: "user10@example.com"})
+
+	// the canonical form of the former primary email address is taken by the new one
+	_, err = x.Insert(&EmailAddress{UID: 4, Email: "user4+new@example.com", CanonicalEmail: "user4@example.com", IsActivated: true})
+	assert.NoError(t, err)
+	err = MakeEmailPrimary(&EmailAddress{Email: "user4+new@example.com"})
+	assert.True(t, IsErrEmailAlreadyUsed(err))
+	user, _ = GetUserByID(int64(4))
+	assert.Equal(t, "user4@example.com", user.Email)
 }
 
 func TestActivate(t *testing.T) {
@@ -166,6 +427,69 @@ func TestActivate(t *testing.T) {
 	emails, _ := GetEmailAddresses(int64(1))
 	assert.Len(t, emails, 3)
 	assert.True(t, emails[0].IsActivated)
-	assert.True(t, emails[2].IsActivated)
-	assert.True(t, emails[2].IsPrimary)
+	assert.True(t, emails[0].IsPrimary)
+	assert.Equal(t, "user11@example.com", emails[1].Email)
+	assert.True(t, emails[1].IsActivated)
+}
+
+func TestGetUnactivatedEmailAddresses(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	// an unactivated primary email address is never returned
+	primary := &EmailAddress{UID: 1, Email: "user1@example.com"}
+	assert.NoError(t, AddEmailAddress(primary))
+	_, err := x.Exec("UPDATE email_address SET created_unix = ? WHERE id = ?", 1, primary.ID)
+	assert.NoError(t, err)
+
+	// a recently added email address is not old enough
+	assert.NoError(t, AddEmailAddress(&EmailAddress{UID: 2, Email: "user22@example.com"}))
+
+	emails, err := GetUnactivatedEmailAddresses(24 * time.Hour)
+	assert.NoError(t, err)
+	if assert.Len(t, emails, 3) {
+		assert.EqualValues(t, 1, emails[0].ID)
+		assert.EqualValues(t, 2, emails[1].ID)
+		assert.EqualValues(t, 4, emails[2].ID)
+	}
+
+	deleted, err := DeleteUnactivatedEmailAddresses(24 * time.Hour)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, deleted)
+	AssertNotExistsBean(t, &EmailAddress{ID: 1})
+	AssertNotExistsBean(t, &EmailAddress{ID: 4})
+	AssertExistsAndLoadBean(t, &EmailAddress{ID: primary.ID})
+	AssertExistsAndLoadBean(t, &EmailAddress{Email: "user22@example.com"})
+}
+
+func TestDeleteOrphanedEmailAddresses(t *testing.T) {
+	assert.NoError(t, PrepareTestDatabase())
+
+	orphaned := &EmailAddress{UID: 10000, Email: "orphaned@example.com", IsActivated: true}
+	assert.NoError(t, AddEmailAddress(orphaned))
+
+	emails, err := FindOrphanedEmailAddresses()
+	assert.NoError(t, err)
+	if assert.Len(t, emails, 2) {
+		assert.EqualValues(t, 5, emails[0].ID)
+		assert.Equal(t, orphaned.ID, emails[1].ID)
+	}
+
+	used, err := IsEmailUsed(orphaned.Email)
+	assert.NoError(t, err)
+	assert.True(t, used)
+
+	deleted, err := DeleteOrphanedEmailAddresses()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, deleted)
+	AssertNotExistsBean(t, &EmailAddress{ID: 5})
+	AssertNotExistsBean(t, &EmailAddress{ID: orphaned.ID})
+	AssertExistsAndLoadBean(t, &EmailAddress{ID: 1})
+
+	used, err = IsEmailUsed(orphaned.Email)
+	assert.NoError(t, err)
+	assert.False(t, used)
+
+	deleted, err = DeleteOrphanedEmailAddresses()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, deleted)
 }
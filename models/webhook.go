@@ -57,15 +57,16 @@ func IsValidHookContentType(name string) bool {
 
 // HookEvents is a set of web hook events
 type HookEvents struct {
-	Create       bool `json:"create"`
-	Delete       bool `json:"delete"`
-	Fork         bool `json:"fork"`
-	Issues       bool `json:"issues"`
-	IssueComment bool `json:"issue_comment"`
-	Push         bool `json:"push"`
-	PullRequest  bool `json:"pull_request"`
-	Repository   bool `json:"repository"`
-	Release      bool `json:"release"`
+	Create        bool `json:"create"`
+	Delete        bool `json:"delete"`
+	Fork          bool `json:"fork"`
+	Issues        bool `json:"issues"`
+	IssueComment  bool `json:"issue_comment"`
+	Push          bool `json:"push"`
+	PullRequest   bool `json:"pull_request"`
+	Repository    bool `json:"repository"`
+	Release       bool `json:"release"`
+	TaskCompleted bool `json:"task_completed"`
 }
 
 // HookEvent represents events that will delivery hook.
@@ -184,6 +185,12 @@ func (w *Webhook) HasRepositoryEvent() bool {
 		(w.ChooseEvents && w.HookEvents.Repository)
 }
 
+// HasTaskCompletedEvent returns true if hook enabled task completed event.
+func (w *Webhook) HasTaskCompletedEvent() bool {
+	return w.SendEverything ||
+		(w.ChooseEvents && w.HookEvents.TaskCompleted)
+}
+
 // EventCheckers returns event checkers
 func (w *Webhook) EventCheckers() []struct {
 	Has  func() bool
@@ -202,6 +209,7 @@ func (w *Webhook) EventCheckers() []struct {
 		{w.HasPullRequestEvent, HookEventPullRequest},
 		{w.HasRepositoryEvent, HookEventRepository},
 		{w.HasReleaseEvent, HookEventRelease},
+		{w.HasTaskCompletedEvent, HookEventTaskCompleted},
 	}
 }
 
@@ -490,6 +498,7 @@ const (
 	HookEventPullRequest         HookEventType = "pull_request"
 	HookEventRepository          HookEventType = "repository"
 	HookEventRelease             HookEventType = "release"
+	HookEventTaskCompleted       HookEventType = "task_completed"
 	HookEventPullRequestApproved HookEventType = "pull_request_approved"
 	HookEventPullRequestRejected HookEventType = "pull_request_rejected"
 	HookEventPullRequestComment  HookEventType = "pull_request_comment"
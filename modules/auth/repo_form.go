@@ -70,6 +70,15 @@ type MigrateRepoForm struct {
 	Issues       bool   `json:"issues"`
 	PullRequests bool   `json:"pull_requests"`
 	Releases     bool   `json:"releases"`
+	// clone only the given number of most recent commits, 0 clones the full history
+	Depth int `json:"depth" binding:"Range(0,2147483647)"`
+	// only probe the source and report what would be migrated, no repository is created
+	DryRun bool `json:"dry_run"`
+	// import pull requests whose head is gone as issues instead
+	FallbackPRsToIssues bool `json:"fallback_prs_to_issues" form:"fallback_prs_to_issues"`
+	// keep the repository of a failed migration so that it can be resumed, only
+	// migrations run as tasks can be resumed, which the API does not create
+	Resumable bool `json:"-"`
 }
 
 // Validate validates the fields
@@ -133,11 +142,21 @@ type RepoSettingForm struct {
 	PullsAllowRebase                 bool
 	PullsAllowRebaseMerge            bool
 	PullsAllowSquash                 bool
+	PullsDefaultMergeStyle           string
+	PullsCloseStale                  bool
+	PullsAddReviewedByTrailers       bool
+	PullsAddCoAuthoredByTrailers     bool
+	PullsNeedsRebaseLabel            string `binding:"MaxSize(50)"`
 	EnableTimetracker                bool
 	AllowOnlyContributorsToTrackTime bool
 	EnableIssueDependencies          bool
+	AllowedReactions                 string
 	IsArchived                       bool
 
+	// Commit author settings
+	RequireVerifiedCommitAuthors bool
+	CommitAuthorAllowedDomains   string
+
 	// Admin settings
 	EnableHealthCheck                     bool
 	EnableCloseIssuesViaCommitInAnyBranch bool
@@ -157,20 +176,24 @@ func (f *RepoSettingForm) Validate(ctx *macaron.Context, errs binding.Errors) bi
 
 // ProtectBranchForm form for changing protected branch settings
 type ProtectBranchForm struct {
-	Protected                bool
-	EnablePush               string
-	WhitelistUsers           string
-	WhitelistTeams           string
-	WhitelistDeployKeys      bool
-	EnableMergeWhitelist     bool
-	MergeWhitelistUsers      string
-	MergeWhitelistTeams      string
-	EnableStatusCheck        bool `xorm:"NOT NULL DEFAULT false"`
-	StatusCheckContexts      []string
-	RequiredApprovals        int64
-	EnableApprovalsWhitelist bool
-	ApprovalsWhitelistUsers  string
-	ApprovalsWhitelistTeams  string
+	Protected                    bool
+	EnablePush                   string
+	WhitelistUsers               string
+	WhitelistTeams               string
+	WhitelistDeployKeys          bool
+	EnableMergeWhitelist         bool
+	MergeWhitelistUsers          string
+	MergeWhitelistTeams          string
+	EnableStatusCheck            bool `xorm:"NOT NULL DEFAULT false"`
+	StatusCheckContexts          []string
+	RequiredApprovals            int64
+	EnableApprovalsWhitelist     bool
+	ApprovalsWhitelistUsers      string
+	ApprovalsWhitelistTeams      string
+	DismissStaleApprovals        bool
+	ProtectedFilePatterns        string
+	RequireLinearHistory         bool
+	RequireResolvedConversations bool
 }
 
 // Validate validates the fields
@@ -187,18 +210,19 @@ func (f *ProtectBranchForm) Validate(ctx *macaron.Context, errs binding.Errors)
 
 // WebhookForm form for changing web hook
 type WebhookForm struct {
-	Events       string
-	Create       bool
-	Delete       bool
-	Fork         bool
-	Issues       bool
-	IssueComment bool
-	Release      bool
-	Push         bool
-	PullRequest  bool
-	Repository   bool
-	Active       bool
-	BranchFilter string `binding:"GlobPattern"`
+	Events        string
+	Create        bool
+	Delete        bool
+	Fork          bool
+	Issues        bool
+	IssueComment  bool
+	Release       bool
+	Push          bool
+	PullRequest   bool
+	Repository    bool
+	TaskCompleted bool
+	Active        bool
+	BranchFilter  string `binding:"GlobPattern"`
 }
 
 // PushOnly if the hook will be triggered when push
@@ -446,6 +470,22 @@ type MergePullRequestForm struct {
 	Do                string `binding:"Required;In(merge,rebase,rebase-merge,squash)"`
 	MergeTitleField   string
 	MergeMessageField string
+	// merge only the commits up to and including this commit of the pull request
+	MergeUpToCommit string
+	// delete the head branch once the pull request has been merged
+	DeleteBranchAfterMerge bool
+	// make the poster of the pull request the author of a squash commit instead of the merger, defaults to true
+	SquashKeepAuthor *bool
+	// record an empty commit if the pull request does not change anything
+	AllowEmptyMerge bool
+	// merge even if the protection of the base branch blocks it, only allowed for repository admins
+	ForceMerge bool
+	// reason for overriding the protection of the base branch, required with ForceMerge
+	ForceMergeReason string
+	// trailers like "Signed-off-by: Name <email>" to append to the merge commit message
+	Trailers []string
+	// set the committer date of the rebased commits to their author date instead of the time of the merge
+	PreserveCommitterDate bool
 }
 
 // Validate validates the fields
@@ -195,14 +195,15 @@ func (f *AccessTokenForm) Validate(ctx *macaron.Context, errs binding.Errors) bi
 
 // UpdateProfileForm form for updating profile
 type UpdateProfileForm struct {
-	Name             string `binding:"AlphaDashDot;MaxSize(40)"`
-	FullName         string `binding:"MaxSize(100)"`
-	Email            string `binding:"Required;Email;MaxSize(254)"`
-	KeepEmailPrivate bool
-	Website          string `binding:"ValidUrl;MaxSize(255)"`
-	Location         string `binding:"MaxSize(50)"`
-	Language         string `binding:"Size(5)"`
-	Description      string `binding:"MaxSize(255)"`
+	Name                 string `binding:"AlphaDashDot;MaxSize(40)"`
+	FullName             string `binding:"MaxSize(100)"`
+	Email                string `binding:"Required;Email;MaxSize(254)"`
+	KeepEmailPrivate     bool
+	KeepReactionsPrivate bool
+	Website              string `binding:"ValidUrl;MaxSize(255)"`
+	Location             string `binding:"MaxSize(50)"`
+	Language             string `binding:"Size(5)"`
+	Description          string `binding:"MaxSize(255)"`
 }
 
 // Validate validates the fields
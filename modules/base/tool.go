@@ -375,6 +375,42 @@ func IsTextFile(data []byte) bool {
 	return strings.Contains(http.DetectContentType(data), "text/")
 }
 
+// knownContentTypes maps file extensions to the content type used to serve them
+var knownContentTypes = map[string]string{
+	".md":       "text/markdown; charset=utf-8",
+	".markdown": "text/markdown; charset=utf-8",
+	".svg":      "image/svg+xml",
+	".css":      "text/css; charset=utf-8",
+	".csv":      "text/csv; charset=utf-8",
+	".json":     "application/json",
+	".html":     "text/html; charset=utf-8",
+	".htm":      "text/html; charset=utf-8",
+	".xhtml":    "application/xhtml+xml",
+	".xml":      "text/xml; charset=utf-8",
+}
+
+// DetectContentType returns the content type of a file with given name, based on its extension
+// if it is a known one, on its first 512 bytes otherwise.
+func DetectContentType(name string, data []byte) string {
+	if contentType, ok := knownContentTypes[strings.ToLower(path.Ext(name))]; ok {
+		return contentType
+	}
+	if len(data) > 512 {
+		data = data[:512]
+	}
+	return http.DetectContentType(data)
+}
+
+// IsUnsafeContentType returns true if content of given type may execute scripts when rendered by a browser.
+func IsUnsafeContentType(contentType string) bool {
+	mediaType := strings.TrimSpace(strings.ToLower(strings.SplitN(contentType, ";", 2)[0]))
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "image/svg+xml", "text/xml", "application/xml":
+		return true
+	}
+	return false
+}
+
 // IsImageFile detects if data is an image format
 func IsImageFile(data []byte) bool {
 	return strings.Contains(http.DetectContentType(data), "image/")
@@ -230,5 +230,22 @@ func TestIsTextFile(t *testing.T) {
 	assert.True(t, IsTextFile([]byte("lorem ipsum")))
 }
 
+func TestDetectContentType(t *testing.T) {
+	assert.Equal(t, "text/markdown; charset=utf-8", DetectContentType("README.md", []byte("# title")))
+	assert.Equal(t, "image/svg+xml", DetectContentType("logo.SVG", []byte("<svg></svg>")))
+	assert.Equal(t, "text/plain; charset=utf-8", DetectContentType("LICENSE", []byte("lorem ipsum")))
+	assert.Equal(t, "text/html; charset=utf-8", DetectContentType("index", []byte("<html><body></body></html>")))
+	assert.Equal(t, "image/png", DetectContentType("image", []byte("\x89PNG\x0D\x0A\x1A\x0A")))
+}
+
+func TestIsUnsafeContentType(t *testing.T) {
+	assert.True(t, IsUnsafeContentType("text/html; charset=utf-8"))
+	assert.True(t, IsUnsafeContentType("image/svg+xml"))
+	assert.True(t, IsUnsafeContentType("Application/XHTML+XML"))
+	assert.False(t, IsUnsafeContentType("text/plain; charset=utf-8"))
+	assert.False(t, IsUnsafeContentType("text/markdown; charset=utf-8"))
+	assert.False(t, IsUnsafeContentType("image/png"))
+}
+
 // TODO: IsImageFile(), currently no idea how to test
 // TODO: IsPDFFile(), currently no idea how to test
@@ -79,3 +79,18 @@ func IsErrBranchNotExist(err error) bool {
 func (err ErrBranchNotExist) Error() string {
 	return fmt.Sprintf("branch does not exist [name: %s]", err.Name)
 }
+
+// ErrShallowHistory represents an error if the history of a shallow repository does not suffice to answer a question.
+type ErrShallowHistory struct {
+	Path string
+}
+
+// IsErrShallowHistory checks if an error is a ErrShallowHistory.
+func IsErrShallowHistory(err error) bool {
+	_, ok := err.(ErrShallowHistory)
+	return ok
+}
+
+func (err ErrShallowHistory) Error() string {
+	return fmt.Sprintf("shallow repository lacks the history [path: %s]", err.Path)
+}
@@ -0,0 +1,85 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"code.gitea.io/gitea/modules/git"
+)
+
+// LargeBlob is a blob whose size exceeds a limit
+type LargeBlob struct {
+	SHA  string
+	Size int64
+	// Path is the first path the blob was found at
+	Path string
+}
+
+// FindLargeBlobs returns the blobs reachable from any ref of the repository at
+// basePath which are larger than minSize bytes, largest first
+func FindLargeBlobs(basePath string, minSize int64) ([]*LargeBlob, error) {
+	revListReader, revListWriter := io.Pipe()
+	catFileCheckReader, catFileCheckWriter := io.Pipe()
+	errChan := make(chan error, 1)
+	wg := sync.WaitGroup{}
+	wg.Add(2)
+
+	go RevListAllObjects(revListWriter, &wg, basePath, errChan)
+	go func() {
+		defer wg.Done()
+		defer revListReader.Close()
+
+		stderr := new(bytes.Buffer)
+		cmd := git.NewCommand("cat-file", "--batch-check=%(objectname) %(objecttype) %(objectsize) %(rest)")
+		if err := cmd.RunInDirFullPipeline(basePath, catFileCheckWriter, stderr, revListReader); err != nil {
+			_ = catFileCheckWriter.CloseWithError(fmt.Errorf("git cat-file --batch-check [%s]: %v - %s", basePath, err, stderr.String()))
+			return
+		}
+		_ = catFileCheckWriter.Close()
+	}()
+
+	blobs := make([]*LargeBlob, 0, 10)
+	scanner := bufio.NewScanner(catFileCheckReader)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), " ", 4)
+		if len(fields) < 3 || fields[1] != "blob" {
+			continue
+		}
+		size, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil || size <= minSize {
+			continue
+		}
+		blob := &LargeBlob{SHA: fields[0], Size: size}
+		if len(fields) == 4 {
+			blob.Path = fields[3]
+		}
+		blobs = append(blobs, blob)
+	}
+	scanErr := scanner.Err()
+	_ = catFileCheckReader.Close()
+	wg.Wait()
+
+	select {
+	case err := <-errChan:
+		return nil, err
+	default:
+	}
+	if scanErr != nil {
+		return nil, scanErr
+	}
+
+	sort.SliceStable(blobs, func(i, j int) bool {
+		return blobs[i].Size > blobs[j].Size
+	})
+	return blobs, nil
+}
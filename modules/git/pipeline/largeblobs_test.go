@@ -0,0 +1,35 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pipeline
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindLargeBlobs(t *testing.T) {
+	bareRepo1Path := filepath.Join("../tests/repos", "repo1_bare")
+
+	blobs, err := FindLargeBlobs(bareRepo1Path, 14)
+	assert.NoError(t, err)
+	if assert.Len(t, blobs, 3) {
+		assert.Equal(t, &LargeBlob{
+			SHA:  "643a35374408002fcf2f0e8d42d262a1e0e2f80e",
+			Size: 18,
+			Path: "foo/outside_repo",
+		}, blobs[0])
+		assert.EqualValues(t, 15, blobs[1].Size)
+		assert.EqualValues(t, 15, blobs[2].Size)
+	}
+
+	blobs, err = FindLargeBlobs(bareRepo1Path, 1<<20)
+	assert.NoError(t, err)
+	assert.Empty(t, blobs)
+
+	_, err = FindLargeBlobs(filepath.Join("../tests/repos", "not_exist"), 0)
+	assert.Error(t, err)
+}
@@ -11,6 +11,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path"
 	"path/filepath"
 	"strconv"
@@ -77,6 +78,24 @@ func IsRepoURLAccessible(url string) bool {
 	return err == nil
 }
 
+// GetRemoteRefs returns the names of the branches and tags of the repository at the given URL.
+func GetRemoteRefs(url string) ([]string, error) {
+	stdout, err := NewCommand("ls-remote", "-q", "--heads", "--tags", url).Run()
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make([]string, 0, strings.Count(stdout, "\n"))
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasSuffix(fields[1], "^{}") {
+			continue
+		}
+		refs = append(refs, fields[1])
+	}
+	return refs, nil
+}
+
 // InitRepository initializes a new Git repository.
 func InitRepository(repoPath string, bare bool) error {
 	err := os.MkdirAll(repoPath, os.ModePerm)
@@ -152,6 +171,29 @@ func (repo *Repository) IsEmpty() (bool, error) {
 	return false, nil
 }
 
+// IsShallow returns true if the repository has been cloned with a limited history depth.
+func (repo *Repository) IsShallow() bool {
+	return isFile(filepath.Join(repo.Path, "shallow"))
+}
+
+// IsAncestor returns true if ancestor is an ancestor of descendant. A shallow repository may lack
+// the commits which connect them, so ErrShallowHistory is returned instead of false for it.
+func (repo *Repository) IsAncestor(ancestor, descendant string) (bool, error) {
+	var errbuf strings.Builder
+	if err := NewCommand("merge-base", "--is-ancestor", ancestor, descendant).RunInDirPipeline(repo.Path, nil, &errbuf); err != nil {
+		// A commit may be missing or unconnected beyond the shallow boundary
+		if repo.IsShallow() {
+			return false, ErrShallowHistory{Path: repo.Path}
+		}
+		// merge-base exits with 1 if ancestor is not an ancestor of descendant
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != 1 {
+			return false, fmt.Errorf("git merge-base --is-ancestor %s %s: %v - %s", ancestor, descendant, err, errbuf.String())
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
 // CloneRepoOptions options when clone a repository
 type CloneRepoOptions struct {
 	Timeout    time.Duration
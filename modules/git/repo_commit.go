@@ -335,6 +335,78 @@ func (repo *Repository) CommitsBetweenIDs(last, before string) (*list.List, erro
 	return repo.CommitsBetween(lastCommit, beforeCommit)
 }
 
+// CommitStats represents the number of lines added and deleted by a commit
+type CommitStats struct {
+	Additions int
+	Deletions int
+}
+
+// CommitWithStats represents a commit alongside its stats
+type CommitWithStats struct {
+	*Commit
+	Stats *CommitStats
+}
+
+// GetCommits returns the commits reachable from head but not from base, newest first.
+// If base is empty all commits reachable from head are returned.
+// With withStats the lines added and deleted by every commit are computed as well. This requires git
+// to diff every commit of the range against its first parent, which is considerably more expensive
+// than listing the commits only, so it should only be requested when the stats are shown.
+// Stats of merge commits and binary changes are not counted.
+func (repo *Repository) GetCommits(base, head string, withStats bool) ([]*CommitWithStats, error) {
+	revRange := head
+	if len(base) > 0 {
+		revRange = base + ".." + head
+	}
+
+	cmd := NewCommand("log", "--format=%x00%H")
+	if withStats {
+		cmd.AddArguments("--numstat", "--no-renames")
+	}
+	stdout, err := cmd.AddArguments(revRange, "--").RunInDirBytes(repo.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	commits := make([]*CommitWithStats, 0, 10)
+	for _, part := range bytes.Split(stdout, []byte{'\x00'}) {
+		lines := bytes.Split(bytes.TrimSpace(part), []byte{'\n'})
+		if len(lines[0]) == 0 {
+			continue
+		}
+
+		commit, err := repo.GetCommit(string(lines[0]))
+		if err != nil {
+			return nil, err
+		}
+		c := &CommitWithStats{Commit: commit}
+		if withStats {
+			c.Stats = parseNumStat(lines[1:])
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// parseNumStat sums up the lines of git log --numstat output of a single commit
+func parseNumStat(lines [][]byte) *CommitStats {
+	stats := &CommitStats{}
+	for _, line := range lines {
+		fields := bytes.SplitN(line, []byte{'\t'}, 3)
+		if len(fields) != 3 {
+			continue
+		}
+		// binary files are reported as "-"
+		if additions, err := strconv.Atoi(string(fields[0])); err == nil {
+			stats.Additions += additions
+		}
+		if deletions, err := strconv.Atoi(string(fields[1])); err == nil {
+			stats.Deletions += deletions
+		}
+	}
+	return stats
+}
+
 // CommitsCountBetween return numbers of commits between two commits
 func (repo *Repository) CommitsCountBetween(start, end string) (int64, error) {
 	return commitsCount(repo.Path, start+"..."+end, "")
@@ -63,3 +63,25 @@ func TestGetCommitWithBadCommitID(t *testing.T) {
 	assert.Error(t, err)
 	assert.True(t, IsErrNotExist(err))
 }
+
+func TestRepository_GetCommits(t *testing.T) {
+	bareRepo1Path := filepath.Join(testReposDir, "repo1_bare")
+	bareRepo1, err := OpenRepository(bareRepo1Path)
+	assert.NoError(t, err)
+	defer bareRepo1.Close()
+
+	commits, err := bareRepo1.GetCommits("95bb4d39648ee7e325106df01a621c530863a653", "2839944139e0de9737a044f78b0e4b40d989a9e3", false)
+	assert.NoError(t, err)
+	if assert.Len(t, commits, 2) {
+		assert.Equal(t, "2839944139e0de9737a044f78b0e4b40d989a9e3", commits[0].ID.String())
+		assert.Equal(t, "9c9aef8dd84e02bc7ec12641deb4c930a7c30185", commits[1].ID.String())
+		assert.Nil(t, commits[0].Stats)
+	}
+
+	commits, err = bareRepo1.GetCommits("95bb4d39648ee7e325106df01a621c530863a653", "2839944139e0de9737a044f78b0e4b40d989a9e3", true)
+	assert.NoError(t, err)
+	if assert.Len(t, commits, 2) {
+		assert.Equal(t, &CommitStats{Additions: 1, Deletions: 1}, commits[0].Stats)
+		assert.Equal(t, &CommitStats{Additions: 1, Deletions: 0}, commits[1].Stats)
+	}
+}
@@ -5,6 +5,8 @@
 package git
 
 import (
+	"io/ioutil"
+	"os"
 	"path/filepath"
 	"testing"
 	"time"
@@ -35,3 +37,83 @@ func TestRepoIsEmpty(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, isEmpty)
 }
+
+func TestRepoIsShallow(t *testing.T) {
+	bareRepo1Path := filepath.Join(testReposDir, "repo1_bare")
+	bareRepo1, err := OpenRepository(bareRepo1Path)
+	assert.NoError(t, err)
+	defer bareRepo1.Close()
+	assert.False(t, bareRepo1.IsShallow())
+
+	tmpDir, err := ioutil.TempDir("", "repo1_shallow")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	absRepo1Path, err := filepath.Abs(bareRepo1Path)
+	assert.NoError(t, err)
+	shallowPath := filepath.Join(tmpDir, "repo1_shallow")
+	assert.NoError(t, Clone("file://"+absRepo1Path, shallowPath, CloneRepoOptions{
+		Mirror: true,
+		Quiet:  true,
+		Depth:  1,
+	}))
+
+	shallowRepo, err := OpenRepository(shallowPath)
+	assert.NoError(t, err)
+	defer shallowRepo.Close()
+	assert.True(t, shallowRepo.IsShallow())
+}
+
+func TestRepository_IsAncestor(t *testing.T) {
+	bareRepo1Path := filepath.Join(testReposDir, "repo1_bare")
+	bareRepo1, err := OpenRepository(bareRepo1Path)
+	assert.NoError(t, err)
+	defer bareRepo1.Close()
+
+	isAncestor, err := bareRepo1.IsAncestor("95bb4d39648ee7e325106df01a621c530863a653", "master")
+	assert.NoError(t, err)
+	assert.True(t, isAncestor)
+	isAncestor, err = bareRepo1.IsAncestor("branch1", "master")
+	assert.NoError(t, err)
+	assert.False(t, isAncestor)
+
+	tmpDir, err := ioutil.TempDir("", "repo1_shallow")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	absRepo1Path, err := filepath.Abs(bareRepo1Path)
+	assert.NoError(t, err)
+	shallowPath := filepath.Join(tmpDir, "repo1_shallow")
+	assert.NoError(t, Clone("file://"+absRepo1Path, shallowPath, CloneRepoOptions{
+		Mirror: true,
+		Quiet:  true,
+		Depth:  1,
+	}))
+	shallowRepo, err := OpenRepository(shallowPath)
+	assert.NoError(t, err)
+	defer shallowRepo.Close()
+
+	isAncestor, err = shallowRepo.IsAncestor("master", "master")
+	assert.NoError(t, err)
+	assert.True(t, isAncestor)
+	// the history connecting the branches has not been fetched
+	_, err = shallowRepo.IsAncestor("branch1", "master")
+	assert.True(t, IsErrShallowHistory(err))
+}
+
+func TestGetRemoteRefs(t *testing.T) {
+	absRepo1Path, err := filepath.Abs(filepath.Join(testReposDir, "repo1_bare"))
+	assert.NoError(t, err)
+
+	refs, err := GetRemoteRefs("file://" + absRepo1Path)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"refs/heads/branch1",
+		"refs/heads/branch2",
+		"refs/heads/master",
+		"refs/tags/test",
+	}, refs)
+
+	_, err = GetRemoteRefs("file://" + filepath.Join(absRepo1Path, "does-not-exist"))
+	assert.Error(t, err)
+}
@@ -790,13 +790,38 @@ func sha1CurrentPatternProcessor(ctx *postProcessCtx, node *html.Node) {
 		createCodeLink(util.URLJoin(setting.AppURL, ctx.metas["user"], ctx.metas["repo"], "commit", hash), base.ShortSha(hash), "commit"))
 }
 
-// emailAddressProcessor replaces raw email addresses with a mailto: link.
+// emailMentionMetaPrefix prefixes the metas which resolve a lower case email
+// address to the name of the user it is a mention of
+const emailMentionMetaPrefix = "email-mention:"
+
+// ComposeEmailMentionMetas returns a copy of metas in which the given email
+// addresses are resolved to the names of the users they are mentions of.
+func ComposeEmailMentionMetas(metas, mentions map[string]string) map[string]string {
+	if len(mentions) == 0 {
+		return metas
+	}
+	composed := make(map[string]string, len(metas)+len(mentions))
+	for k, v := range metas {
+		composed[k] = v
+	}
+	for email, name := range mentions {
+		composed[emailMentionMetaPrefix+strings.ToLower(email)] = name
+	}
+	return composed
+}
+
+// emailAddressProcessor replaces raw email addresses with a mailto: link,
+// or with a link to the user if the metas resolve the address to a mention.
 func emailAddressProcessor(ctx *postProcessCtx, node *html.Node) {
 	m := emailRegex.FindStringSubmatchIndex(node.Data)
 	if m == nil {
 		return
 	}
 	mail := node.Data[m[2]:m[3]]
+	if name, ok := ctx.metas[emailMentionMetaPrefix+strings.ToLower(mail)]; ok {
+		replaceContent(node, m[2], m[3], createLink(util.URLJoin(setting.AppURL, name), mail, "mention"))
+		return
+	}
 	replaceContent(node, m[2], m[3], createLink("mailto:"+mail, mail, "mailto"))
 }
 
@@ -204,6 +204,13 @@ func TestRender_email(t *testing.T) {
 		"send email to info@gitea.co.uk.",
 		`<p>send email to <a href="mailto:info@gitea.co.uk" rel="nofollow">info@gitea.co.uk</a>.</p>`)
 
+	// Email addresses which are resolved to a user are linked to the user
+	metas := ComposeEmailMentionMetas(nil, map[string]string{"info@gitea.com": "info"})
+	assert.Equal(t, strings.TrimSpace(`<p><a href="`+util.URLJoin(AppURL, "info")+`" rel="nofollow">Info@gitea.com</a></p>`),
+		strings.TrimSpace(RenderString("a.md", "Info@gitea.com", setting.AppSubURL, metas)))
+	assert.Equal(t, `<p><a href="mailto:other@gitea.com" rel="nofollow">other@gitea.com</a></p>`,
+		strings.TrimSpace(RenderString("a.md", "other@gitea.com", setting.AppSubURL, metas)))
+
 	// Test that should *not* be turned into email links
 	test(
 		"\"info@gitea.com\"",
@@ -16,4 +16,5 @@ type Repository struct {
 	AuthPassword string
 	CloneURL     string
 	OriginalURL  string
+	Size         int64 // in KiB, 0 if unknown
 }
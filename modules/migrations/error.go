@@ -7,6 +7,7 @@ package migrations
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/google/go-github/v24/github"
 )
@@ -16,6 +17,22 @@ var (
 	ErrNotSupported = errors.New("not supported")
 )
 
+// ErrDuplicateIndex represents an error that an issue or pull request to be migrated has the
+// same number as another one. Issues and pull requests share their numbers in a repository.
+type ErrDuplicateIndex struct {
+	Index int64
+}
+
+// IsErrDuplicateIndex returns true if the err is ErrDuplicateIndex
+func IsErrDuplicateIndex(err error) bool {
+	_, ok := err.(ErrDuplicateIndex)
+	return ok
+}
+
+func (err ErrDuplicateIndex) Error() string {
+	return fmt.Sprintf("duplicate issue or pull request number [index: %d]", err.Index)
+}
+
 // IsRateLimitError returns true if the err is github.RateLimitError
 func IsRateLimitError(err error) bool {
 	_, ok := err.(*github.RateLimitError)
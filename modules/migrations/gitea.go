@@ -34,6 +34,10 @@ var (
 	_ base.Uploader = &GiteaLocalUploader{}
 )
 
+// FallbackPullRequestLabel is the label of the issues which have been imported from pull requests
+// whose head was gone. It is created in the repository if it does not exist yet.
+const FallbackPullRequestLabel = "migrated/pull-request"
+
 // GiteaLocalUploader implements an Uploader to gitea sites
 type GiteaLocalUploader struct {
 	ctx            context.Context
@@ -48,6 +52,12 @@ type GiteaLocalUploader struct {
 	prHeadCache    map[string]struct{}
 	userMap        map[int64]int64 // external user id mapping to user id
 	gitServiceType structs.GitServiceType
+	// import pull requests whose head is gone as issues
+	fallbackPRsToIssues bool
+	// set when resuming a migration, everything imported before is skipped
+	resuming        bool
+	resumedIssues   map[int64]bool            // indices of the issues and pull requests imported before
+	resumedComments map[int64]map[string]bool // keys of the comments imported before by issue id
 }
 
 // NewGiteaLocalUploader creates an gitea Uploader via gitea API v1
@@ -98,6 +108,10 @@ func (g *GiteaLocalUploader) CreateRepo(repo *base.Repository, opts base.Migrate
 		remoteAddr = u.String()
 	}
 
+	if opts.ResumeFrom > structs.MigratePhaseRepository {
+		return g.resumeRepo(opts)
+	}
+
 	var r *models.Repository
 	if opts.MigrateToRepoID <= 0 {
 		r, err = models.CreateRepository(g.doer, owner, models.CreateRepoOptions{
@@ -125,16 +139,94 @@ func (g *GiteaLocalUploader) CreateRepo(repo *base.Repository, opts base.Migrate
 		Private:        repo.IsPrivate,
 		Wiki:           opts.Wiki,
 		Releases:       opts.Releases, // if didn't get releases, then sync them from tags
+		Depth:          opts.Depth,
 	})
 
 	g.repo = r
 	if err != nil {
 		return err
 	}
+	g.fallbackPRsToIssues = opts.FallbackPRsToIssues
 	g.gitRepo, err = git.OpenRepository(r.RepoPath())
 	return err
 }
 
+// resumeRepo loads the repository whose git data was migrated by a previous run of the migration,
+// together with the milestones, labels and issues imported into it
+func (g *GiteaLocalUploader) resumeRepo(opts base.MigrateOptions) error {
+	if opts.MigrateToRepoID <= 0 {
+		return fmt.Errorf("resuming a migration from %s needs the repository migrated into", opts.ResumeFrom.Name())
+	}
+	r, err := models.GetRepositoryByID(opts.MigrateToRepoID)
+	if err != nil {
+		return err
+	}
+	g.repo = r
+	g.fallbackPRsToIssues = opts.FallbackPRsToIssues
+	g.gitRepo, err = git.OpenRepository(r.RepoPath())
+	if err != nil {
+		return err
+	}
+
+	milestones, err := models.GetMilestonesByRepoID(r.ID, structs.StateAll)
+	if err != nil {
+		return err
+	}
+	for _, ms := range milestones {
+		g.milestones.Store(ms.Name, ms.ID)
+	}
+
+	labels, err := models.GetLabelsByRepoID(r.ID, "")
+	if err != nil {
+		return err
+	}
+	for _, lb := range labels {
+		g.labels.Store(lb.Name, lb)
+	}
+
+	issueIDs, err := models.GetIssueIDsByIndex(r.ID)
+	if err != nil {
+		return err
+	}
+	g.resumedIssues = make(map[int64]bool, len(issueIDs))
+	for index, id := range issueIDs {
+		g.issues.Store(index, id)
+		g.resumedIssues[index] = true
+	}
+	g.resumedComments = make(map[int64]map[string]bool)
+	g.resuming = true
+	return nil
+}
+
+// isCommentImported reports whether the comment was imported before the migration was resumed
+func (g *GiteaLocalUploader) isCommentImported(index int64, cm *models.Comment) (bool, error) {
+	if !g.resuming || !g.resumedIssues[index] {
+		return false, nil
+	}
+
+	keys, ok := g.resumedComments[cm.IssueID]
+	if !ok {
+		comments, err := models.FindComments(models.FindCommentsOptions{
+			IssueID: cm.IssueID,
+			Type:    models.CommentTypeComment,
+		})
+		if err != nil {
+			return false, err
+		}
+		keys = make(map[string]bool, len(comments))
+		for _, comment := range comments {
+			keys[commentKey(comment)] = true
+		}
+		g.resumedComments[cm.IssueID] = keys
+	}
+	return keys[commentKey(cm)], nil
+}
+
+// commentKey identifies a migrated comment of an issue
+func commentKey(cm *models.Comment) string {
+	return fmt.Sprintf("%d/%d/%d/%s", cm.CreatedUnix, cm.PosterID, cm.OriginalAuthorID, cm.Content)
+}
+
 // Close closes this uploader
 func (g *GiteaLocalUploader) Close() {
 	if g.gitRepo != nil {
@@ -151,6 +243,9 @@ func (g *GiteaLocalUploader) CreateTopics(topics ...string) error {
 func (g *GiteaLocalUploader) CreateMilestones(milestones ...*base.Milestone) error {
 	var mss = make([]*models.Milestone, 0, len(milestones))
 	for _, milestone := range milestones {
+		if _, ok := g.milestones.Load(milestone.Title); ok && g.resuming {
+			continue
+		}
 		var deadline timeutil.TimeStamp
 		if milestone.Deadline != nil {
 			deadline = timeutil.TimeStamp(milestone.Deadline.Unix())
@@ -186,6 +281,9 @@ func (g *GiteaLocalUploader) CreateMilestones(milestones ...*base.Milestone) err
 func (g *GiteaLocalUploader) CreateLabels(labels ...*base.Label) error {
 	var lbs = make([]*models.Label, 0, len(labels))
 	for _, label := range labels {
+		if _, ok := g.labels.Load(label.Name); ok && g.resuming {
+			continue
+		}
 		lbs = append(lbs, &models.Label{
 			RepoID:      g.repo.ID,
 			Name:        label.Name,
@@ -208,6 +306,15 @@ func (g *GiteaLocalUploader) CreateLabels(labels ...*base.Label) error {
 func (g *GiteaLocalUploader) CreateReleases(releases ...*base.Release) error {
 	var rels = make([]*models.Release, 0, len(releases))
 	for _, release := range releases {
+		if g.resuming {
+			exist, err := models.IsReleaseExist(g.repo.ID, release.TagName)
+			if err != nil {
+				return err
+			} else if exist {
+				continue
+			}
+		}
+
 		var rel = models.Release{
 			RepoID:       g.repo.ID,
 			TagName:      release.TagName,
@@ -301,8 +408,44 @@ func (g *GiteaLocalUploader) SyncTags() error {
 	return repository.SyncReleasesWithTags(g.repo, g.gitRepo)
 }
 
+// checkIndices checks that the numbers of the issues or pull requests to be created are valid,
+// i.e. neither used by an issue or pull request created before nor given twice. The numbers of
+// the source are kept as index, including any gaps between them, and new issues continue after
+// the highest one.
+func (g *GiteaLocalUploader) checkIndices(numbers []int64) error {
+	seen := make(map[int64]bool, len(numbers))
+	for _, number := range numbers {
+		if number <= 0 {
+			return fmt.Errorf("invalid issue or pull request number [index: %d]", number)
+		}
+		if _, ok := g.issues.Load(number); ok || seen[number] {
+			return ErrDuplicateIndex{Index: number}
+		}
+		seen[number] = true
+	}
+	return nil
+}
+
 // CreateIssues creates issues
 func (g *GiteaLocalUploader) CreateIssues(issues ...*base.Issue) error {
+	if g.resuming {
+		var remaining = make([]*base.Issue, 0, len(issues))
+		for _, issue := range issues {
+			if !g.resumedIssues[issue.Number] {
+				remaining = append(remaining, issue)
+			}
+		}
+		issues = remaining
+	}
+
+	var numbers = make([]int64, 0, len(issues))
+	for _, issue := range issues {
+		numbers = append(numbers, issue.Number)
+	}
+	if err := g.checkIndices(numbers); err != nil {
+		return err
+	}
+
 	var iss = make([]*models.Issue, 0, len(issues))
 	for _, issue := range issues {
 		var labels []*models.Label
@@ -416,6 +559,12 @@ func (g *GiteaLocalUploader) CreateComments(comments ...*base.Comment) error {
 			cm.OriginalAuthorID = comment.PosterID
 		}
 
+		if imported, err := g.isCommentImported(comment.IssueIndex, &cm); err != nil {
+			return err
+		} else if imported {
+			continue
+		}
+
 		cms = append(cms, &cm)
 
 		// TODO: Reactions
@@ -424,9 +573,29 @@ func (g *GiteaLocalUploader) CreateComments(comments ...*base.Comment) error {
 	return models.InsertIssueComments(cms)
 }
 
-// CreatePullRequests creates pull requests
+// CreatePullRequests creates pull requests. If enabled, pull requests whose head commit
+// is not available are created as issues instead.
 func (g *GiteaLocalUploader) CreatePullRequests(prs ...*base.PullRequest) error {
+	if g.resuming {
+		var remaining = make([]*base.PullRequest, 0, len(prs))
+		for _, pr := range prs {
+			if !g.resumedIssues[pr.Number] {
+				remaining = append(remaining, pr)
+			}
+		}
+		prs = remaining
+	}
+
+	var numbers = make([]int64, 0, len(prs))
+	for _, pr := range prs {
+		numbers = append(numbers, pr.Number)
+	}
+	if err := g.checkIndices(numbers); err != nil {
+		return err
+	}
+
 	var gprs = make([]*models.PullRequest, 0, len(prs))
+	var fallbacks []*models.Issue
 	for _, pr := range prs {
 		gpr, err := g.newPullRequest(pr)
 		if err != nil {
@@ -454,6 +623,15 @@ func (g *GiteaLocalUploader) CreatePullRequests(prs ...*base.PullRequest) error
 			gpr.Issue.OriginalAuthorID = pr.PosterID
 		}
 
+		if g.fallbackPRsToIssues && (pr.Head.SHA == "" || !g.gitRepo.IsCommitExist(pr.Head.SHA)) {
+			issue, err := g.pullRequestToIssue(pr, gpr)
+			if err != nil {
+				return err
+			}
+			fallbacks = append(fallbacks, issue)
+			continue
+		}
+
 		gprs = append(gprs, gpr)
 	}
 	if err := models.InsertPullRequests(gprs...); err != nil {
@@ -462,9 +640,102 @@ func (g *GiteaLocalUploader) CreatePullRequests(prs ...*base.PullRequest) error
 	for _, pr := range gprs {
 		g.issues.Store(pr.Issue.Index, pr.Issue.ID)
 	}
+
+	if len(fallbacks) == 0 {
+		return nil
+	}
+	if err := models.InsertIssues(fallbacks...); err != nil {
+		return err
+	}
+	for _, issue := range fallbacks {
+		g.issues.Store(issue.Index, issue.ID)
+		if err := g.attachPullRequestPatch(issue); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// pullRequestToIssue turns a pull request whose head commit is gone into an issue, which keeps
+// its discussion. The head ref written for the pull request is removed again.
+func (g *GiteaLocalUploader) pullRequestToIssue(pr *base.PullRequest, gpr *models.PullRequest) (*models.Issue, error) {
+	if err := os.RemoveAll(filepath.Join(g.repo.RepoPath(), "refs", "pull", fmt.Sprintf("%d", pr.Number))); err != nil {
+		return nil, err
+	}
+
+	label, err := g.getFallbackPullRequestLabel()
+	if err != nil {
+		return nil, err
+	}
+
+	issue := gpr.Issue
+	issue.IsPull = false
+	issue.Labels = append(issue.Labels, label)
+	issue.Content += fmt.Sprintf("\n\n---\nThis issue was migrated from a pull request from `%s` into `%s`, whose head commit %s was not available anymore.",
+		pr.Head.Ref, pr.Base.Ref, pr.Head.SHA)
+	if _, err := os.Stat(g.pullRequestPatchPath(pr.Number)); err == nil {
+		issue.Content += " Its changes are attached as patch."
+	}
+	return issue, nil
+}
+
+// getFallbackPullRequestLabel returns the FallbackPullRequestLabel of the repository, creating the label if needed
+func (g *GiteaLocalUploader) getFallbackPullRequestLabel() (*models.Label, error) {
+	if lb, ok := g.labels.Load(FallbackPullRequestLabel); ok {
+		return lb.(*models.Label), nil
+	}
+
+	label, err := models.GetLabelInRepoByName(g.repo.ID, FallbackPullRequestLabel)
+	if err == nil {
+		g.labels.Store(label.Name, label)
+		return label, nil
+	} else if !models.IsErrLabelNotExist(err) {
+		return nil, err
+	}
+
+	label = &models.Label{
+		RepoID:      g.repo.ID,
+		Name:        FallbackPullRequestLabel,
+		Description: "Migrated from a pull request whose head was not available anymore",
+		Color:       "#5319e7",
+	}
+	if err := models.NewLabel(label); err != nil {
+		return nil, err
+	}
+	g.labels.Store(label.Name, label)
+	return label, nil
+}
+
+// attachPullRequestPatch moves the patch downloaded for a pull request which has been imported
+// as issue to an attachment of the issue. Nothing is attached if the patch was not available.
+func (g *GiteaLocalUploader) attachPullRequestPatch(issue *models.Issue) error {
+	patchPath := g.pullRequestPatchPath(issue.Index)
+	f, err := os.Open(patchPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer func() {
+		f.Close()
+		if err := os.Remove(patchPath); err != nil {
+			log.Error("Remove %s: %v", patchPath, err)
+		}
+	}()
+
+	_, err = models.NewAttachment(&models.Attachment{
+		IssueID:    issue.ID,
+		UploaderID: g.doer.ID,
+		Name:       fmt.Sprintf("%d.patch", issue.Index),
+	}, nil, f)
+	return err
+}
+
+// pullRequestPatchPath returns the path the patch of the pull request is downloaded to
+func (g *GiteaLocalUploader) pullRequestPatchPath(number int64) string {
+	return filepath.Join(g.repo.RepoPath(), "pulls", fmt.Sprintf("%d.patch", number))
+}
+
 func (g *GiteaLocalUploader) newPullRequest(pr *base.PullRequest) (*models.PullRequest, error) {
 	var labels []*models.Label
 	for _, label := range pr.Labels {
@@ -489,11 +760,15 @@ func (g *GiteaLocalUploader) newPullRequest(pr *base.PullRequest) (*models.PullR
 			return err
 		}
 		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			log.Warn("Patch of pull request %d is not available: %s", pr.Number, resp.Status)
+			return nil
+		}
 		pullDir := filepath.Join(g.repo.RepoPath(), "pulls")
 		if err = os.MkdirAll(pullDir, os.ModePerm); err != nil {
 			return err
 		}
-		f, err := os.Create(filepath.Join(pullDir, fmt.Sprintf("%d.patch", pr.Number)))
+		f, err := os.Create(g.pullRequestPatchPath(pr.Number))
 		if err != nil {
 			return err
 		}
@@ -6,17 +6,152 @@
 package migrations
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/graceful"
+	"code.gitea.io/gitea/modules/migrations/base"
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/util"
 
 	"github.com/stretchr/testify/assert"
 )
 
+func TestGiteaLocalUploader_CheckIndices(t *testing.T) {
+	uploader := &GiteaLocalUploader{}
+	uploader.issues.Store(int64(1), int64(10))
+	uploader.issues.Store(int64(4), int64(11))
+
+	// gaps are kept
+	assert.NoError(t, uploader.checkIndices([]int64{2, 7, 5}))
+
+	err := uploader.checkIndices([]int64{2, 4})
+	assert.True(t, IsErrDuplicateIndex(err))
+	assert.Equal(t, ErrDuplicateIndex{Index: 4}, err)
+
+	err = uploader.checkIndices([]int64{6, 8, 6})
+	assert.Equal(t, ErrDuplicateIndex{Index: 6}, err)
+
+	assert.Error(t, uploader.checkIndices([]int64{0}))
+}
+
+func TestGiteaLocalUploader_Resume(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	user := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	opts := base.MigrateOptions{
+		MigrateToRepoID: 1,
+		ResumeFrom:      structs.MigratePhaseLabels,
+	}
+	comment := &base.Comment{
+		IssueIndex: 1,
+		PosterID:   42,
+		PosterName: "external",
+		Created:    time.Unix(946684900, 0),
+		Content:    "imported before",
+	}
+
+	uploader := NewGiteaLocalUploader(graceful.GetManager().HammerContext(), user, user.Name, "repo1")
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{}, opts))
+	defer uploader.Close()
+
+	assert.NoError(t, uploader.CreateLabels(&base.Label{Name: "label1"}, &base.Label{Name: "resumed"}))
+	labels, err := models.GetLabelsByRepoID(1, "")
+	assert.NoError(t, err)
+	assert.Len(t, labels, 3)
+
+	// issue 1 was imported before and is kept as it is
+	assert.NoError(t, uploader.CreateIssues(
+		&base.Issue{Number: 1, Title: "imported again", Created: time.Now()},
+		&base.Issue{Number: 10, Title: "resumed", Labels: []*base.Label{{Name: "resumed"}}, Created: time.Now()},
+	))
+	issue := models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: 1, Index: 1}).(*models.Issue)
+	assert.Equal(t, "issue1", issue.Title)
+	models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: 1, Index: 10, Title: "resumed"})
+
+	assert.NoError(t, uploader.CreateComments(comment))
+	models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: issue.ID, Content: comment.Content})
+
+	// resuming once more does not import the comment twice
+	uploader = NewGiteaLocalUploader(graceful.GetManager().HammerContext(), user, user.Name, "repo1")
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{}, opts))
+	defer uploader.Close()
+	assert.NoError(t, uploader.CreateComments(comment))
+	models.AssertCount(t, &models.Comment{IssueID: issue.ID, Content: comment.Content}, 1)
+}
+
+func TestGiteaLocalUploader_FallbackPRsToIssues(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	attachmentPath, err := ioutil.TempDir("", "attachments")
+	assert.NoError(t, err)
+	defer os.RemoveAll(attachmentPath)
+	defer func(path string) {
+		setting.AttachmentPath = path
+	}(setting.AttachmentPath)
+	setting.AttachmentPath = attachmentPath
+
+	// only the patch of pull request 100 is available
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/100.patch" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte("diff --git a/README.md b/README.md\n"))
+	}))
+	defer srv.Close()
+
+	user := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	uploader := NewGiteaLocalUploader(graceful.GetManager().HammerContext(), user, user.Name, "repo1")
+	assert.NoError(t, uploader.CreateRepo(&base.Repository{}, base.MigrateOptions{
+		MigrateToRepoID:     1,
+		ResumeFrom:          structs.MigratePhasePullRequests,
+		FallbackPRsToIssues: true,
+	}))
+	defer uploader.Close()
+
+	pullRequest := func(number int64, headSHA string) *base.PullRequest {
+		return &base.PullRequest{
+			Number:   number,
+			Title:    fmt.Sprintf("pull %d", number),
+			PosterID: 42,
+			State:    "open",
+			Created:  time.Now(),
+			PatchURL: fmt.Sprintf("%s/%d.patch", srv.URL, number),
+			Head:     base.PullRequestBranch{Ref: "feature", SHA: headSHA, OwnerName: "user2", RepoName: "repo1"},
+			Base:     base.PullRequestBranch{Ref: "master", OwnerName: "user2", RepoName: "repo1"},
+		}
+	}
+	assert.NoError(t, uploader.CreatePullRequests(
+		pullRequest(100, "0000000000000000000000000000000000000001"),
+		pullRequest(101, "0000000000000000000000000000000000000002"),
+		pullRequest(102, "65f1bf27bc3bf70f64657658635e66094edbcb4d"),
+	))
+
+	label := models.AssertExistsAndLoadBean(t, &models.Label{RepoID: 1, Name: FallbackPullRequestLabel}).(*models.Label)
+	issue := models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: 1, Index: 100, IsPull: false}).(*models.Issue)
+	assert.True(t, models.HasIssueLabel(issue.ID, label.ID))
+	assert.Contains(t, issue.Content, "Its changes are attached as patch.")
+	models.AssertExistsAndLoadBean(t, &models.Attachment{IssueID: issue.ID, Name: "100.patch"})
+
+	// a missing patch does not fail the migration
+	issue = models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: 1, Index: 101, IsPull: false}).(*models.Issue)
+	assert.True(t, models.HasIssueLabel(issue.ID, label.ID))
+	assert.NotContains(t, issue.Content, "attached as patch")
+	models.AssertNotExistsBean(t, &models.Attachment{IssueID: issue.ID})
+
+	// pull requests whose head is available stay pull requests
+	issue = models.AssertExistsAndLoadBean(t, &models.Issue{RepoID: 1, Index: 102, IsPull: true}).(*models.Issue)
+	models.AssertExistsAndLoadBean(t, &models.PullRequest{IssueID: issue.ID})
+}
+
 func TestGiteaUploadRepo(t *testing.T) {
 	// FIXME: Since no accesskey or user/password will trigger rate limit of github, just skip
 	t.Skip()
@@ -45,7 +180,7 @@ func TestGiteaUploadRepo(t *testing.T) {
 		PullRequests: true,
 		Private:      true,
 		Mirror:       false,
-	})
+	}, nil)
 	assert.NoError(t, err)
 
 	repo := models.AssertExistsAndLoadBean(t, &models.Repository{OwnerID: user.ID, Name: repoName}).(*models.Repository)
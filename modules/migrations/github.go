@@ -150,6 +150,7 @@ func (g *GithubDownloaderV3) GetRepoInfo() (*base.Repository, error) {
 		Description: gr.GetDescription(),
 		OriginalURL: gr.GetHTMLURL(),
 		CloneURL:    gr.GetCloneURL(),
+		Size:        int64(gr.GetSize()),
 	}, nil
 }
 
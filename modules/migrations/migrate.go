@@ -8,8 +8,10 @@ package migrations
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/migrations/base"
 	"code.gitea.io/gitea/modules/setting"
@@ -28,19 +30,69 @@ func RegisterDownloaderFactory(factory base.DownloaderFactory) {
 	factories = append(factories, factory)
 }
 
+// CheckpointFunc is called once a phase of a migration is completed
+type CheckpointFunc func(phase structs.MigratePhase) error
+
 // MigrateRepository migrate repository according MigrateOptions
 func MigrateRepository(ctx context.Context, doer *models.User, ownerName string, opts base.MigrateOptions) (*models.Repository, error) {
+	return MigrateRepositoryWithCheckpoints(ctx, doer, ownerName, opts, nil)
+}
+
+// MigrateRepositoryWithCheckpoints migrates a repository like MigrateRepository and calls checkpoint
+// whenever a phase is completed. If the migration fails after a phase was completed, the repository
+// is kept, so that the migration can be resumed from the next phase by setting opts.ResumeFrom.
+func MigrateRepositoryWithCheckpoints(ctx context.Context, doer *models.User, ownerName string, opts base.MigrateOptions, checkpoint CheckpointFunc) (*models.Repository, error) {
+	var uploader = NewGiteaLocalUploader(ctx, doer, ownerName, opts.RepoName)
+
+	downloader, err := newDownloader(ctx, ownerName, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	uploader.gitServiceType = opts.GitServiceType
+
+	var completed = opts.ResumeFrom - 1
+	var saveCheckpoint CheckpointFunc
+	if checkpoint != nil {
+		saveCheckpoint = func(phase structs.MigratePhase) error {
+			if err := checkpoint(phase); err != nil {
+				return err
+			}
+			completed = phase
+			return nil
+		}
+	}
+
+	if err := migrateRepository(downloader, uploader, opts, saveCheckpoint); err != nil {
+		if checkpoint != nil && completed > structs.MigratePhaseNone {
+			log.Warn("Migration of repository from %s failed after phase %s, keeping it to be resumed",
+				opts.OriginalURL, completed.Name())
+		} else if err1 := uploader.Rollback(); err1 != nil {
+			log.Error("rollback failed: %v", err1)
+		}
+
+		if err2 := models.CreateRepositoryNotice(fmt.Sprintf("Migrate repository from %s failed: %v", opts.OriginalURL, err)); err2 != nil {
+			log.Error("create respotiry notice failed: ", err2)
+		}
+		return nil, err
+	}
+
+	return uploader.repo, nil
+}
+
+// newDownloader creates the downloader matching the options, it falls back to plain git
+// and disables everything but the git data and the wiki if no downloader factory matches
+func newDownloader(ctx context.Context, ownerName string, opts *base.MigrateOptions) (base.Downloader, error) {
 	var (
 		downloader base.Downloader
-		uploader   = NewGiteaLocalUploader(ctx, doer, ownerName, opts.RepoName)
 		theFactory base.DownloaderFactory
 	)
 
 	for _, factory := range factories {
-		if match, err := factory.Match(opts); err != nil {
+		if match, err := factory.Match(*opts); err != nil {
 			return nil, err
 		} else if match {
-			downloader, err = factory.New(opts)
+			downloader, err = factory.New(*opts)
 			if err != nil {
 				return nil, err
 			}
@@ -64,32 +116,96 @@ func MigrateRepository(ctx context.Context, doer *models.User, ownerName string,
 		opts.GitServiceType = theFactory.GitServiceType()
 	}
 
-	uploader.gitServiceType = opts.GitServiceType
-
 	if setting.Migrations.MaxAttempts > 1 {
 		downloader = base.NewRetryDownloader(downloader, setting.Migrations.MaxAttempts, setting.Migrations.RetryBackoff)
 	}
 
 	downloader.SetContext(ctx)
+	return downloader, nil
+}
 
-	if err := migrateRepository(downloader, uploader, opts); err != nil {
-		if err1 := uploader.Rollback(); err1 != nil {
-			log.Error("rollback failed: %v", err1)
+const probeBatchSize = 100
+
+// ProbeRepository checks that the source of a migration can be accessed with the
+// given credentials and counts what migrating it would import. No repository is created.
+func ProbeRepository(ctx context.Context, ownerName string, opts base.MigrateOptions) (*structs.MigrateProbeResult, error) {
+	downloader, err := newDownloader(ctx, ownerName, &opts)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := downloader.GetRepoInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := git.GetRemoteRefs(opts.CloneAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	var result = structs.MigrateProbeResult{
+		Size: repo.Size,
+	}
+	for _, ref := range refs {
+		if strings.HasPrefix(ref, git.BranchPrefix) {
+			result.Branches++
+		} else if strings.HasPrefix(ref, git.TagPrefix) {
+			result.Tags++
 		}
+	}
 
-		if err2 := models.CreateRepositoryNotice(fmt.Sprintf("Migrate repository from %s failed: %v", opts.OriginalURL, err)); err2 != nil {
-			log.Error("create respotiry notice failed: ", err2)
+	if opts.Issues {
+		for i := 1; ; i++ {
+			issues, isEnd, err := downloader.GetIssues(i, probeBatchSize)
+			if err != nil {
+				return nil, err
+			}
+			result.Issues += len(issues)
+			if isEnd {
+				break
+			}
 		}
-		return nil, err
 	}
 
-	return uploader.repo, nil
+	if opts.PullRequests {
+		for i := 1; ; i++ {
+			prs, err := downloader.GetPullRequests(i, probeBatchSize)
+			if err != nil {
+				return nil, err
+			}
+			result.PullRequests += len(prs)
+			if len(prs) < probeBatchSize {
+				break
+			}
+		}
+	}
+
+	return &result, nil
 }
 
-// migrateRepository will download informations and upload to Uploader, this is a simple
-// process for small repository. For a big repository, save all the data to disk
-// before upload is better
-func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts base.MigrateOptions) error {
+// migrateRepository will download informations and upload to Uploader. Issues, pull requests
+// and their comments are streamed in batches of at most batchSize, each inserted in its own
+// transaction, so the memory used does not grow with the size of the repository
+//
+// The phases before opts.ResumeFrom are skipped, and checkpoint, if not nil, is called whenever
+// a phase is completed.
+func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts base.MigrateOptions, checkpoint CheckpointFunc) error {
+	// run reports whether a phase has to be run, and done records that it is completed
+	run := func(phase structs.MigratePhase) bool {
+		if phase < opts.ResumeFrom {
+			log.Trace("skipping %s, already migrated", phase.Name())
+			return false
+		}
+		return true
+	}
+	done := func(phase structs.MigratePhase) error {
+		if checkpoint == nil || phase < opts.ResumeFrom {
+			return nil
+		}
+		return checkpoint(phase)
+	}
+
 	repo, err := downloader.GetRepoInfo()
 	if err != nil {
 		return err
@@ -99,24 +215,33 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 	if opts.Description != "" {
 		repo.Description = opts.Description
 	}
+	// the uploader only loads the existing repository if the git data was migrated before
 	log.Trace("migrating git data")
 	if err := uploader.CreateRepo(repo, opts); err != nil {
 		return err
 	}
 	defer uploader.Close()
-
-	log.Trace("migrating topics")
-	topics, err := downloader.GetTopics()
-	if err != nil {
+	if err := done(structs.MigratePhaseRepository); err != nil {
 		return err
 	}
-	if len(topics) > 0 {
-		if err := uploader.CreateTopics(topics...); err != nil {
+
+	if run(structs.MigratePhaseTopics) {
+		log.Trace("migrating topics")
+		topics, err := downloader.GetTopics()
+		if err != nil {
 			return err
 		}
+		if len(topics) > 0 {
+			if err := uploader.CreateTopics(topics...); err != nil {
+				return err
+			}
+		}
+	}
+	if err := done(structs.MigratePhaseTopics); err != nil {
+		return err
 	}
 
-	if opts.Milestones {
+	if opts.Milestones && run(structs.MigratePhaseMilestones) {
 		log.Trace("migrating milestones")
 		milestones, err := downloader.GetMilestones()
 		if err != nil {
@@ -129,14 +254,17 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 				msBatchSize = len(milestones)
 			}
 
-			if err := uploader.CreateMilestones(milestones...); err != nil {
+			if err := uploader.CreateMilestones(milestones[:msBatchSize]...); err != nil {
 				return err
 			}
 			milestones = milestones[msBatchSize:]
 		}
 	}
+	if err := done(structs.MigratePhaseMilestones); err != nil {
+		return err
+	}
 
-	if opts.Labels {
+	if opts.Labels && run(structs.MigratePhaseLabels) {
 		log.Trace("migrating labels")
 		labels, err := downloader.GetLabels()
 		if err != nil {
@@ -149,14 +277,17 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 				lbBatchSize = len(labels)
 			}
 
-			if err := uploader.CreateLabels(labels...); err != nil {
+			if err := uploader.CreateLabels(labels[:lbBatchSize]...); err != nil {
 				return err
 			}
 			labels = labels[lbBatchSize:]
 		}
 	}
+	if err := done(structs.MigratePhaseLabels); err != nil {
+		return err
+	}
 
-	if opts.Releases {
+	if opts.Releases && run(structs.MigratePhaseReleases) {
 		log.Trace("migrating releases")
 		releases, err := downloader.GetReleases()
 		if err != nil {
@@ -180,13 +311,19 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 			return err
 		}
 	}
+	if err := done(structs.MigratePhaseReleases); err != nil {
+		return err
+	}
 
-	var commentBatchSize = uploader.MaxBatchInsertSize("comment")
+	var commentBatchSize = batchSize(uploader, "comment")
 
-	if opts.Issues {
+	if opts.Issues && run(structs.MigratePhaseIssues) {
 		log.Trace("migrating issues and comments")
-		var issueBatchSize = uploader.MaxBatchInsertSize("issue")
+		var issueBatchSize = batchSize(uploader, "issue")
+		var migrated int
 
+		// every page is inserted before the next one is downloaded, so only a single
+		// page of issues and a batch of comments are held in memory at a time
 		for i := 1; ; i++ {
 			issues, isEnd, err := downloader.GetIssues(i, issueBatchSize)
 			if err != nil {
@@ -197,43 +334,32 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 				return err
 			}
 
-			if !opts.Comments {
-				continue
-			}
-
-			var allComments = make([]*base.Comment, 0, commentBatchSize)
-			for _, issue := range issues {
-				comments, err := downloader.GetComments(issue.Number)
-				if err != nil {
-					return err
+			if opts.Comments {
+				var numbers = make([]int64, 0, len(issues))
+				for _, issue := range issues {
+					numbers = append(numbers, issue.Number)
 				}
-
-				allComments = append(allComments, comments...)
-
-				if len(allComments) >= commentBatchSize {
-					if err := uploader.CreateComments(allComments[:commentBatchSize]...); err != nil {
-						return err
-					}
-
-					allComments = allComments[commentBatchSize:]
-				}
-			}
-
-			if len(allComments) > 0 {
-				if err := uploader.CreateComments(allComments...); err != nil {
+				if err := migrateComments(downloader, uploader, commentBatchSize, numbers); err != nil {
 					return err
 				}
 			}
 
+			migrated += len(issues)
+			log.Trace("migrated %d issues", migrated)
+
 			if isEnd {
 				break
 			}
 		}
 	}
+	if err := done(structs.MigratePhaseIssues); err != nil {
+		return err
+	}
 
-	if opts.PullRequests {
+	if opts.PullRequests && run(structs.MigratePhasePullRequests) {
 		log.Trace("migrating pull requests and comments")
-		var prBatchSize = uploader.MaxBatchInsertSize("pullrequest")
+		var prBatchSize = batchSize(uploader, "pullrequest")
+		var migrated int
 		for i := 1; ; i++ {
 			prs, err := downloader.GetPullRequests(i, prBatchSize)
 			if err != nil {
@@ -244,37 +370,59 @@ func migrateRepository(downloader base.Downloader, uploader base.Uploader, opts
 				return err
 			}
 
-			if !opts.Comments {
-				continue
-			}
-
-			var allComments = make([]*base.Comment, 0, commentBatchSize)
-			for _, pr := range prs {
-				comments, err := downloader.GetComments(pr.Number)
-				if err != nil {
-					return err
-				}
-
-				allComments = append(allComments, comments...)
-
-				if len(allComments) >= commentBatchSize {
-					if err := uploader.CreateComments(allComments[:commentBatchSize]...); err != nil {
-						return err
-					}
-					allComments = allComments[commentBatchSize:]
+			if opts.Comments {
+				var numbers = make([]int64, 0, len(prs))
+				for _, pr := range prs {
+					numbers = append(numbers, pr.Number)
 				}
-			}
-			if len(allComments) > 0 {
-				if err := uploader.CreateComments(allComments...); err != nil {
+				if err := migrateComments(downloader, uploader, commentBatchSize, numbers); err != nil {
 					return err
 				}
 			}
 
+			migrated += len(prs)
+			log.Trace("migrated %d pull requests", migrated)
+
 			if len(prs) < prBatchSize {
 				break
 			}
 		}
 	}
+	return done(structs.MigratePhasePullRequests)
+}
+
+// batchSize returns the number of items of the type which are downloaded and inserted at once,
+// the uploader's limit lowered to setting.Migrations.BatchSize if it is set
+func batchSize(uploader base.Uploader, tp string) int {
+	size := uploader.MaxBatchInsertSize(tp)
+	if setting.Migrations.BatchSize > 0 && setting.Migrations.BatchSize < size {
+		size = setting.Migrations.BatchSize
+	}
+	return size
+}
+
+// migrateComments migrates the comments of the issues or pull requests with the given numbers,
+// inserting them as soon as a batch is complete
+func migrateComments(downloader base.Downloader, uploader base.Uploader, batchSize int, numbers []int64) error {
+	var allComments = make([]*base.Comment, 0, batchSize)
+	for _, number := range numbers {
+		comments, err := downloader.GetComments(number)
+		if err != nil {
+			return err
+		}
 
+		allComments = append(allComments, comments...)
+
+		for len(allComments) >= batchSize {
+			if err := uploader.CreateComments(allComments[:batchSize]...); err != nil {
+				return err
+			}
+			allComments = allComments[batchSize:]
+		}
+	}
+
+	if len(allComments) > 0 {
+		return uploader.CreateComments(allComments...)
+	}
 	return nil
 }
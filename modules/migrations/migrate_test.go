@@ -0,0 +1,155 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package migrations
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"code.gitea.io/gitea/modules/migrations/base"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/structs"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbeRepository(t *testing.T) {
+	repoPath, err := filepath.Abs(filepath.Join("..", "git", "tests", "repos", "repo1_bare"))
+	assert.NoError(t, err)
+
+	result, err := ProbeRepository(context.Background(), "user2", base.MigrateOptions{
+		CloneAddr: "file://" + repoPath,
+		RepoName:  "repo1",
+		Issues:    true,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, result.Branches)
+	assert.EqualValues(t, 1, result.Tags)
+	// plain git sources have no issues to migrate
+	assert.EqualValues(t, 0, result.Issues)
+
+	_, err = ProbeRepository(context.Background(), "user2", base.MigrateOptions{
+		CloneAddr: "file://" + filepath.Join(repoPath, "does-not-exist"),
+		RepoName:  "repo1",
+	})
+	assert.Error(t, err)
+}
+
+// fakeDownloader serves numIssues issues with commentsPerIssue comments each
+type fakeDownloader struct {
+	base.Downloader
+	numIssues        int
+	commentsPerIssue int
+	pageSizes        []int
+}
+
+func (d *fakeDownloader) GetRepoInfo() (*base.Repository, error) {
+	return &base.Repository{Name: "repo"}, nil
+}
+
+func (d *fakeDownloader) GetTopics() ([]string, error) {
+	return nil, nil
+}
+
+func (d *fakeDownloader) GetIssues(page, perPage int) ([]*base.Issue, bool, error) {
+	d.pageSizes = append(d.pageSizes, perPage)
+	var issues []*base.Issue
+	for i := (page-1)*perPage + 1; i <= page*perPage && i <= d.numIssues; i++ {
+		issues = append(issues, &base.Issue{Number: int64(i)})
+	}
+	return issues, page*perPage >= d.numIssues, nil
+}
+
+func (d *fakeDownloader) GetComments(issueNumber int64) ([]*base.Comment, error) {
+	comments := make([]*base.Comment, d.commentsPerIssue)
+	for i := range comments {
+		comments[i] = &base.Comment{IssueIndex: issueNumber}
+	}
+	return comments, nil
+}
+
+// fakeUploader records the sizes of the inserted batches
+type fakeUploader struct {
+	base.Uploader
+	issueBatches   []int
+	commentBatches []int
+}
+
+func (u *fakeUploader) MaxBatchInsertSize(tp string) int {
+	return 100
+}
+
+func (u *fakeUploader) CreateRepo(repo *base.Repository, opts base.MigrateOptions) error {
+	return nil
+}
+
+func (u *fakeUploader) Close() {}
+
+func (u *fakeUploader) CreateIssues(issues ...*base.Issue) error {
+	u.issueBatches = append(u.issueBatches, len(issues))
+	return nil
+}
+
+func (u *fakeUploader) CreateComments(comments ...*base.Comment) error {
+	u.commentBatches = append(u.commentBatches, len(comments))
+	return nil
+}
+
+func TestMigrateRepositoryBatches(t *testing.T) {
+	defer func(batchSize int) {
+		setting.Migrations.BatchSize = batchSize
+	}(setting.Migrations.BatchSize)
+	setting.Migrations.BatchSize = 4
+
+	downloader := &fakeDownloader{numIssues: 10, commentsPerIssue: 3}
+	uploader := &fakeUploader{}
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues:   true,
+		Comments: true,
+	}, nil))
+
+	assert.Equal(t, []int{4, 4, 4}, downloader.pageSizes)
+	assert.Equal(t, []int{4, 4, 2}, uploader.issueBatches)
+	// the 12 comments of each page of issues are inserted in full batches, the 6 of the last page in two
+	assert.Equal(t, []int{4, 4, 4, 4, 4, 4, 4, 2}, uploader.commentBatches)
+}
+
+func TestMigrateRepositoryCheckpoints(t *testing.T) {
+	downloader := &fakeDownloader{numIssues: 3}
+	uploader := &fakeUploader{}
+	var phases []structs.MigratePhase
+	checkpoint := func(phase structs.MigratePhase) error {
+		phases = append(phases, phase)
+		return nil
+	}
+
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues: true,
+	}, checkpoint))
+	assert.Equal(t, []int{3}, uploader.issueBatches)
+	// phases which are not migrated are completed as well
+	assert.Equal(t, []structs.MigratePhase{
+		structs.MigratePhaseRepository,
+		structs.MigratePhaseTopics,
+		structs.MigratePhaseMilestones,
+		structs.MigratePhaseLabels,
+		structs.MigratePhaseReleases,
+		structs.MigratePhaseIssues,
+		structs.MigratePhasePullRequests,
+	}, phases)
+
+	// resuming skips the completed phases
+	downloader = &fakeDownloader{numIssues: 3}
+	uploader = &fakeUploader{}
+	phases = nil
+	assert.NoError(t, migrateRepository(downloader, uploader, base.MigrateOptions{
+		Issues:     true,
+		ResumeFrom: structs.MigratePhasePullRequests,
+	}, checkpoint))
+	assert.Empty(t, downloader.pageSizes)
+	assert.Empty(t, uploader.issueBatches)
+	assert.Equal(t, []structs.MigratePhase{structs.MigratePhasePullRequests}, phases)
+}
@@ -19,6 +19,7 @@ type Notifier interface {
 	NotifyForkRepository(doer *models.User, oldRepo, repo *models.Repository)
 	NotifyRenameRepository(doer *models.User, repo *models.Repository, oldRepoName string)
 	NotifyTransferRepository(doer *models.User, repo *models.Repository, oldOwnerName string)
+	NotifyTaskCompleted(task *models.Task)
 
 	NotifyNewIssue(*models.Issue)
 	NotifyIssueChangeStatus(*models.User, *models.Issue, *models.Comment, bool)
@@ -35,11 +36,13 @@ type Notifier interface {
 	NotifyPullRequestSynchronized(doer *models.User, pr *models.PullRequest)
 	NotifyPullRequestReview(*models.PullRequest, *models.Review, *models.Comment)
 	NotifyPullRequestChangeTargetBranch(doer *models.User, pr *models.PullRequest, oldBranch string)
+	NotifyPullRequestReadyForReview(doer *models.User, pr *models.PullRequest)
 
 	NotifyCreateIssueComment(*models.User, *models.Repository,
 		*models.Issue, *models.Comment)
 	NotifyUpdateComment(*models.User, *models.Comment, string)
 	NotifyDeleteComment(*models.User, *models.Comment)
+	NotifyIssueReaction(doer *models.User, issue *models.Issue, comment *models.Comment, reaction *models.Reaction)
 
 	NotifyNewRelease(rel *models.Release)
 	NotifyUpdateRelease(doer *models.User, rel *models.Release)
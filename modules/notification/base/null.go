@@ -54,6 +54,10 @@ func (*NullNotifier) NotifyPullRequestSynchronized(doer *models.User, pr *models
 func (*NullNotifier) NotifyPullRequestChangeTargetBranch(doer *models.User, pr *models.PullRequest, oldBranch string) {
 }
 
+// NotifyPullRequestReadyForReview places a place holder function
+func (*NullNotifier) NotifyPullRequestReadyForReview(doer *models.User, pr *models.PullRequest) {
+}
+
 // NotifyUpdateComment places a place holder function
 func (*NullNotifier) NotifyUpdateComment(doer *models.User, c *models.Comment, oldContent string) {
 }
@@ -62,6 +66,10 @@ func (*NullNotifier) NotifyUpdateComment(doer *models.User, c *models.Comment, o
 func (*NullNotifier) NotifyDeleteComment(doer *models.User, c *models.Comment) {
 }
 
+// NotifyIssueReaction places a place holder function
+func (*NullNotifier) NotifyIssueReaction(doer *models.User, issue *models.Issue, comment *models.Comment, reaction *models.Reaction) {
+}
+
 // NotifyNewRelease places a place holder function
 func (*NullNotifier) NotifyNewRelease(rel *models.Release) {
 }
@@ -115,6 +123,10 @@ func (*NullNotifier) NotifyForkRepository(doer *models.User, oldRepo, repo *mode
 func (*NullNotifier) NotifyMigrateRepository(doer *models.User, u *models.User, repo *models.Repository) {
 }
 
+// NotifyTaskCompleted places a place holder function
+func (*NullNotifier) NotifyTaskCompleted(task *models.Task) {
+}
+
 // NotifyPushCommits notifies commits pushed to notifiers
 func (*NullNotifier) NotifyPushCommits(pusher *models.User, repo *models.Repository, refName, oldCommitID, newCommitID string, commits *models.PushCommits) {
 }
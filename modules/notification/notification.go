@@ -94,6 +94,13 @@ func NotifyPullRequestChangeTargetBranch(doer *models.User, pr *models.PullReque
 	}
 }
 
+// NotifyPullRequestReadyForReview notifies when a work in progress pull request was marked as ready for review
+func NotifyPullRequestReadyForReview(doer *models.User, pr *models.PullRequest) {
+	for _, notifier := range notifiers {
+		notifier.NotifyPullRequestReadyForReview(doer, pr)
+	}
+}
+
 // NotifyUpdateComment notifies update comment to notifiers
 func NotifyUpdateComment(doer *models.User, c *models.Comment, oldContent string) {
 	for _, notifier := range notifiers {
@@ -108,6 +115,13 @@ func NotifyDeleteComment(doer *models.User, c *models.Comment) {
 	}
 }
 
+// NotifyIssueReaction notifies a new reaction on an issue or comment to notifiers
+func NotifyIssueReaction(doer *models.User, issue *models.Issue, comment *models.Comment, reaction *models.Reaction) {
+	for _, notifier := range notifiers {
+		notifier.NotifyIssueReaction(doer, issue, comment, reaction)
+	}
+}
+
 // NotifyNewRelease notifies new release to notifiers
 func NotifyNewRelease(rel *models.Release) {
 	for _, notifier := range notifiers {
@@ -186,6 +200,13 @@ func NotifyMigrateRepository(doer *models.User, u *models.User, repo *models.Rep
 	}
 }
 
+// NotifyTaskCompleted notifies a finished or failed task to notifiers
+func NotifyTaskCompleted(task *models.Task) {
+	for _, notifier := range notifiers {
+		notifier.NotifyTaskCompleted(task)
+	}
+}
+
 // NotifyTransferRepository notifies create repository to notifiers
 func NotifyTransferRepository(doer *models.User, repo *models.Repository, newOwnerName string) {
 	for _, notifier := range notifiers {
@@ -21,6 +21,7 @@ type (
 		issueID              int64
 		commentID            int64
 		notificationAuthorID int64
+		isReaction           bool
 	}
 )
 
@@ -37,6 +38,12 @@ func NewNotifier() base.Notifier {
 
 func (ns *notificationService) Run() {
 	for opts := range ns.issueQueue {
+		if opts.isReaction {
+			if err := models.CreateOrUpdateReactionNotification(opts.issueID, opts.commentID, opts.notificationAuthorID); err != nil {
+				log.Error("Was unable to create reaction notification: %v", err)
+			}
+			continue
+		}
 		if err := models.CreateOrUpdateIssueNotifications(opts.issueID, opts.commentID, opts.notificationAuthorID); err != nil {
 			log.Error("Was unable to create issue notification: %v", err)
 		}
@@ -93,3 +100,15 @@ func (ns *notificationService) NotifyPullRequestReview(pr *models.PullRequest, r
 	}
 	ns.issueQueue <- opts
 }
+
+func (ns *notificationService) NotifyIssueReaction(doer *models.User, issue *models.Issue, comment *models.Comment, reaction *models.Reaction) {
+	var opts = issueNotificationOpts{
+		issueID:              issue.ID,
+		notificationAuthorID: doer.ID,
+		isReaction:           true,
+	}
+	if comment != nil {
+		opts.commentID = comment.ID
+	}
+	ns.issueQueue <- opts
+}
@@ -5,6 +5,9 @@
 package webhook
 
 import (
+	"bytes"
+	"errors"
+
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
@@ -124,6 +127,48 @@ func (m *webhookNotifier) NotifyDeleteRepository(doer *models.User, repo *models
 	}
 }
 
+func (m *webhookNotifier) NotifyTaskCompleted(task *models.Task) {
+	if task.Type != api.TaskTypeMigrateRepo {
+		return
+	}
+	if err := task.LoadRepo(); err != nil {
+		log.Error("LoadRepo [task_id: %d]: %v", task.ID, err)
+		return
+	}
+	if err := task.LoadDoer(); err != nil {
+		log.Error("LoadDoer [task_id: %d]: %v", task.ID, err)
+		return
+	}
+	opts, err := task.MigrateConfigWithoutCredentials()
+	if err != nil {
+		log.Error("MigrateConfigWithoutCredentials [task_id: %d]: %v", task.ID, err)
+		return
+	}
+
+	payload := &api.TaskCompletedPayload{
+		TaskType:   task.Type.Name(),
+		Status:     api.HookTaskFinished,
+		Options:    opts,
+		Repository: task.Repo.APIFormat(models.AccessModeOwner),
+		Sender:     task.Doer.APIFormat(),
+	}
+	if task.Status != api.TaskStatusFinished {
+		payload.Status = api.HookTaskFailed
+		payload.Error = task.Errors
+
+		// the repository of a failed migration is deleted together with its hook tasks,
+		// so they have to be delivered before that happens
+		if err := webhook_module.PrepareAndDeliverWebhooks(task.Repo, models.HookEventTaskCompleted, payload); err != nil {
+			log.Error("PrepareAndDeliverWebhooks [repo_id: %d]: %v", task.RepoID, err)
+		}
+		return
+	}
+
+	if err := webhook_module.PrepareWebhooks(task.Repo, models.HookEventTaskCompleted, payload); err != nil {
+		log.Error("PrepareWebhooks [repo_id: %d]: %v", task.RepoID, err)
+	}
+}
+
 func (m *webhookNotifier) NotifyIssueChangeAssignee(doer *models.User, issue *models.Issue, assignee *models.User, removed bool, comment *models.Comment) {
 	if issue.IsPull {
 		mode, _ := models.AccessLevelUnit(doer, issue.Repo, models.UnitTypePullRequests)
@@ -287,6 +332,14 @@ func (m *webhookNotifier) NotifyNewPullRequest(pull *models.PullRequest) {
 		return
 	}
 
+	var patch string
+	if setting.Webhook.IncludePatchInPROpen {
+		var err error
+		if patch, err = getPullRequestPatch(pull, setting.Webhook.PatchMaxSize); err != nil {
+			log.Error("getPullRequestPatch[%d]: %v", pull.ID, err)
+		}
+	}
+
 	mode, _ := models.AccessLevel(pull.Issue.Poster, pull.Issue.Repo)
 	if err := webhook_module.PrepareWebhooks(pull.Issue.Repo, models.HookEventPullRequest, &api.PullRequestPayload{
 		Action:      api.HookIssueOpened,
@@ -294,11 +347,55 @@ func (m *webhookNotifier) NotifyNewPullRequest(pull *models.PullRequest) {
 		PullRequest: pull.APIFormat(),
 		Repository:  pull.Issue.Repo.APIFormat(mode),
 		Sender:      pull.Issue.Poster.APIFormat(),
+		Patch:       patch,
 	}); err != nil {
 		log.Error("PrepareWebhooks: %v", err)
 	}
 }
 
+var errPatchTooLarge = errors.New("patch too large")
+
+// limitedBuffer is a buffer refusing to grow beyond limit bytes. The bytes.Buffer
+// is not embedded as its ReadFrom would let io.Copy bypass the limit.
+type limitedBuffer struct {
+	buf      bytes.Buffer
+	limit    int64
+	exceeded bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if int64(b.buf.Len()+len(p)) > b.limit {
+		b.exceeded = true
+		return 0, errPatchTooLarge
+	}
+	return b.buf.Write(p)
+}
+
+func (b *limitedBuffer) String() string {
+	return b.buf.String()
+}
+
+// getPullRequestPatch returns the unified diff between the merge base and the head of the pull request.
+// Binary files are only summarized. It returns an empty string if the diff is larger than maxSize.
+func getPullRequestPatch(pr *models.PullRequest, maxSize int64) (string, error) {
+	if len(pr.MergeBase) == 0 || maxSize <= 0 {
+		return "", nil
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return "", err
+	}
+
+	buf := &limitedBuffer{limit: maxSize}
+	if err := git.NewCommand("diff", "-p", "-M", pr.MergeBase, pr.GetGitRefName()).
+		RunInDirPipeline(pr.BaseRepo.RepoPath(), buf, nil); err != nil {
+		if buf.exceeded {
+			return "", nil
+		}
+		return "", err
+	}
+	return buf.String(), nil
+}
+
 func (m *webhookNotifier) NotifyIssueChangeContent(doer *models.User, issue *models.Issue, oldContent string) {
 	mode, _ := models.AccessLevel(issue.Poster, issue.Repo)
 	var err error
@@ -0,0 +1,84 @@
+// Copyright 2020 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain(m *testing.M) {
+	models.MainTest(m, filepath.Join("..", "..", ".."))
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	buf := &limitedBuffer{limit: 4}
+
+	n, err := buf.Write([]byte("ab"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.False(t, buf.exceeded)
+
+	// filling the buffer up to the limit is allowed
+	n, err = buf.Write([]byte("cd"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.False(t, buf.exceeded)
+	assert.Equal(t, "abcd", buf.String())
+
+	// anything beyond is refused and leaves the buffer untouched
+	n, err = buf.Write([]byte("e"))
+	assert.Equal(t, errPatchTooLarge, err)
+	assert.Equal(t, 0, n)
+	assert.True(t, buf.exceeded)
+	assert.Equal(t, "abcd", buf.String())
+}
+
+func TestGetPullRequestPatch(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.MergeBase = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+
+	// Let the head delete every file of the merge base
+	repoPath := models.RepoPath("user2", "repo1")
+	head := models.CreateTestCommit(t, repoPath, models.TestCommitOptions{
+		Tree:    "4b825dc642cb6eb9a060e54bf8d69288fbee4904",
+		Parents: []string{pr.MergeBase},
+		Message: "delete everything",
+	})
+	models.UpdateTestRef(t, repoPath, pr.GetGitRefName(), head)
+
+	patch, err := getPullRequestPatch(pr, 1<<20)
+	assert.NoError(t, err)
+	assert.Contains(t, patch, "deleted file mode")
+	size := int64(len(patch))
+
+	// under and exactly at the limit
+	for _, maxSize := range []int64{size + 1, size} {
+		got, err := getPullRequestPatch(pr, maxSize)
+		assert.NoError(t, err)
+		assert.Equal(t, patch, got)
+	}
+
+	// a truncated patch is left out
+	got, err := getPullRequestPatch(pr, size-1)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	// a disabled limit or an unknown merge base leave out the patch
+	got, err = getPullRequestPatch(pr, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+
+	pr.MergeBase = ""
+	got, err = getPullRequestPatch(pr, size)
+	assert.NoError(t, err)
+	assert.Empty(t, got)
+}
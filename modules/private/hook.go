@@ -33,6 +33,7 @@ type HookOptions struct {
 	GitQuarantinePath               string
 	ProtectedBranchID               int64
 	IsDeployKey                     bool
+	IsForceMerge                    bool
 }
 
 // HookPostReceiveResult represents an individual result from PostReceive
@@ -28,6 +28,8 @@ var (
 
 	// mentionPattern matches all mentions in the form of "@user"
 	mentionPattern = regexp.MustCompile(`(?:\s|^|\(|\[)(@[0-9a-zA-Z-_]+|@[0-9a-zA-Z-_][0-9a-zA-Z-_.]+[0-9a-zA-Z-_])(?:\s|[:,;.?!]\s|[:,;.?!]?$|\)|\])`)
+	// emailMentionPattern matches email addresses which may be resolved to a mention of their user
+	emailMentionPattern = regexp.MustCompile("(?:\\s|^|\\(|\\[)([a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9]{2,}(?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)+)(?:\\s|$|\\)|\\]|[:,;.?!](?:\\s|$))")
 	// issueNumericPattern matches string that references to a numeric issue, e.g. #1287
 	issueNumericPattern = regexp.MustCompile(`(?:\s|^|\(|\[)([#!][0-9]+)(?:\s|$|\)|\]|:|\.(\s|$))`)
 	// issueAlphanumericPattern matches string that references to an alphanumeric issue, e.g. ABC-1234
@@ -180,6 +182,18 @@ func FindAllMentionsBytes(content []byte) []RefSpan {
 	return ret
 }
 
+// FindAllEmailMentionsMarkdown matches email addresses in given content and
+// returns a list of found unvalidated addresses, which may be resolved to mentions.
+func FindAllEmailMentionsMarkdown(content string) []string {
+	bcontent, _ := mdstripper.StripMarkdownBytes([]byte(content))
+	matches := emailMentionPattern.FindAllSubmatch(bcontent, -1)
+	emails := make([]string, len(matches))
+	for i, match := range matches {
+		emails[i] = string(match[1])
+	}
+	return emails
+}
+
 // FindFirstMentionBytes matches the first mention in then given content
 // and returns the location of the unvalidated user name, including the @ prefix.
 func FindFirstMentionBytes(content []byte) (bool, RefSpan) {
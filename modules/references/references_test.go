@@ -276,6 +276,15 @@ func TestRegExp_mentionPattern(t *testing.T) {
 	}
 }
 
+func TestFindAllEmailMentionsMarkdown(t *testing.T) {
+	assert.Equal(t, []string{"user1@example.com", "user2@example.com"},
+		FindAllEmailMentionsMarkdown("ping user1@example.com, and (user2@example.com)"))
+	assert.Equal(t, []string{"user1@example.com"},
+		FindAllEmailMentionsMarkdown("send it to user1@example.com."))
+	assert.Empty(t, FindAllEmailMentionsMarkdown("git@try.gitea.io:go-gitea/gitea.git"))
+	assert.Empty(t, FindAllEmailMentionsMarkdown("`user1@example.com`"))
+}
+
 func TestRegExp_issueNumericPattern(t *testing.T) {
 	trueTestCases := []string{
 		"#1234",
@@ -0,0 +1,231 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repofiles
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/cache"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+
+	"github.com/gobwas/glob"
+)
+
+// exportIgnoreRule is a line of a .gitattributes file which sets or unsets the export-ignore attribute
+type exportIgnoreRule struct {
+	// dir is the directory of the .gitattributes file, patterns are relative to it
+	dir string
+	// matchBase is set for patterns without a slash, they match the name at any depth below dir
+	matchBase bool
+	globs     []glob.Glob
+	ignore    bool
+}
+
+func (rule *exportIgnoreRule) match(treePath string) bool {
+	name := treePath
+	if len(rule.dir) > 0 {
+		if !strings.HasPrefix(treePath, rule.dir+"/") {
+			return false
+		}
+		name = treePath[len(rule.dir)+1:]
+	}
+	if rule.matchBase {
+		name = path.Base(name)
+	}
+	for _, g := range rule.globs {
+		if g.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitAttributesLine splits a .gitattributes line into its pattern, which may be quoted, and its attributes
+func splitAttributesLine(line string) (pattern string, attrs []string, ok bool) {
+	if !strings.HasPrefix(line, `"`) {
+		fields := strings.Fields(line)
+		return fields[0], fields[1:], true
+	}
+
+	for i := 1; i < len(line); i++ {
+		switch line[i] {
+		case '\\':
+			i++
+		case '"':
+			unquoted, err := strconv.Unquote(line[:i+1])
+			if err != nil {
+				return "", nil, false
+			}
+			return unquoted, strings.Fields(line[i+1:]), true
+		}
+	}
+	return "", nil, false
+}
+
+// parseExportIgnoreRules returns the rules of a .gitattributes file in dir which mention export-ignore
+func parseExportIgnoreRules(dir, content string) []*exportIgnoreRule {
+	var rules []*exportIgnoreRule
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		pattern, attrs, ok := splitAttributesLine(line)
+		if !ok || len(attrs) == 0 {
+			continue
+		}
+		// negative patterns are forbidden and patterns with a trailing slash match nothing in .gitattributes
+		if strings.HasPrefix(pattern, "!") || strings.HasSuffix(pattern, "/") {
+			continue
+		}
+
+		var (
+			ignore    bool
+			mentioned bool
+		)
+		for _, attr := range attrs {
+			switch attr {
+			case "export-ignore":
+				ignore, mentioned = true, true
+			case "-export-ignore", "!export-ignore":
+				ignore, mentioned = false, true
+			default:
+				if strings.HasPrefix(attr, "export-ignore=") {
+					ignore, mentioned = false, true
+				}
+			}
+		}
+		if !mentioned {
+			continue
+		}
+
+		rule := &exportIgnoreRule{
+			dir:       dir,
+			matchBase: !strings.Contains(pattern, "/"),
+			ignore:    ignore,
+		}
+		pattern = strings.TrimPrefix(pattern, "/")
+		patterns := []string{pattern}
+		if strings.HasPrefix(pattern, "**/") {
+			// a leading "**/" matches in all directories, including dir itself
+			patterns = append(patterns, pattern[3:])
+		}
+		for _, p := range patterns {
+			g, err := glob.Compile(p, '/')
+			if err != nil {
+				log.Trace("Invalid .gitattributes pattern '%s' in '%s' (skipped): %v", p, dir, err)
+				continue
+			}
+			rule.globs = append(rule.globs, g)
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// isExportIgnored returns true if the last rule matching the path sets export-ignore. Rules of
+// .gitattributes files in deeper directories come later and take precedence.
+func isExportIgnored(rules []*exportIgnoreRule, treePath string) bool {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if rules[i].match(treePath) {
+			return rules[i].ignore
+		}
+	}
+	return false
+}
+
+func readGitAttributes(entry *git.TreeEntry) (string, error) {
+	dataRc, err := entry.Blob().DataAsync()
+	if err != nil {
+		return "", err
+	}
+	defer dataRc.Close()
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(dataRc)
+	for scanner.Scan() {
+		content.WriteString(scanner.Text())
+		content.WriteByte('\n')
+	}
+	return content.String(), scanner.Err()
+}
+
+func collectExportIgnoredPaths(tree *git.Tree, dir string, rules []*exportIgnoreRule, paths []string) ([]string, error) {
+	entries, err := tree.ListEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == ".gitattributes" && entry.IsRegular() {
+			content, err := readGitAttributes(entry)
+			if err != nil {
+				return nil, err
+			}
+			// copy the rules, they are shared with the sibling directories
+			rules = append(rules[:len(rules):len(rules)], parseExportIgnoreRules(dir, content)...)
+			break
+		}
+	}
+
+	for _, entry := range entries {
+		treePath := path.Join(dir, entry.Name())
+		if isExportIgnored(rules, treePath) {
+			paths = append(paths, treePath)
+			continue
+		}
+		if !entry.IsDir() {
+			continue
+		}
+		subTree, err := tree.SubTree(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if paths, err = collectExportIgnoredPaths(subTree, treePath, rules, paths); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// GetExportIgnoredPaths returns the paths of the tree at ref which are excluded from archives by the
+// export-ignore attribute of the .gitattributes files, like git archive does. The contents of an
+// ignored directory are not listed separately. The result is cached per resolved commit.
+func GetExportIgnoredPaths(repo *models.Repository, ref string) ([]string, error) {
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	commit, err := gitRepo.GetCommit(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("repo:%d:export_ignore:%s", repo.ID, commit.ID.String())
+	joined, err := cache.GetString(key, func() (string, error) {
+		paths, err := collectExportIgnoredPaths(&commit.Tree, "", nil, nil)
+		if err != nil {
+			return "", err
+		}
+		return strings.Join(paths, "\n"), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(joined) == 0 {
+		return []string{}, nil
+	}
+	return strings.Split(joined, "\n"), nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repofiles
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseExportIgnoreRules(t *testing.T) {
+	rules := parseExportIgnoreRules("", `# comment
+*.log export-ignore
+/docs export-ignore
+"with space.txt" export-ignore
+**/vendor export-ignore
+*.go text eol=lf
+build/ export-ignore
+keep.log -export-ignore
+`)
+	assert.Len(t, rules, 5)
+
+	assert.True(t, isExportIgnored(rules, "build.log"))
+	assert.True(t, isExportIgnored(rules, "a/b/build.log"))
+	assert.False(t, isExportIgnored(rules, "keep.log"))
+	assert.False(t, isExportIgnored(rules, "a/keep.log"))
+	assert.True(t, isExportIgnored(rules, "docs"))
+	assert.False(t, isExportIgnored(rules, "a/docs"))
+	assert.True(t, isExportIgnored(rules, "with space.txt"))
+	assert.True(t, isExportIgnored(rules, "vendor"))
+	assert.True(t, isExportIgnored(rules, "a/vendor"))
+	assert.False(t, isExportIgnored(rules, "main.go"))
+	assert.False(t, isExportIgnored(rules, "build"))
+
+	rules = append(rules, parseExportIgnoreRules("sub", "/only.txt export-ignore\n*.log !export-ignore\n")...)
+	assert.True(t, isExportIgnored(rules, "sub/only.txt"))
+	assert.False(t, isExportIgnored(rules, "sub/a/only.txt"))
+	assert.False(t, isExportIgnored(rules, "only.txt"))
+	assert.False(t, isExportIgnored(rules, "sub/build.log"))
+	assert.True(t, isExportIgnored(rules, "build.log"))
+}
+
+func TestGetExportIgnoredPaths(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+
+	paths, err := GetExportIgnoredPaths(repo, "master")
+	assert.NoError(t, err)
+	assert.Len(t, paths, 0)
+
+	tmpDir, err := ioutil.TempDir("", "export-ignore")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	assert.NoError(t, git.Clone(repo.RepoPath(), tmpDir, git.CloneRepoOptions{}))
+	files := map[string]string{
+		".gitattributes":       "*.log export-ignore\n/docs export-ignore\ntests/** export-ignore\n",
+		"build.log":            "log",
+		"docs/index.md":        "docs",
+		"sub/.gitattributes":   "keep.log -export-ignore\n",
+		"sub/keep.log":         "log",
+		"sub/other.log":        "log",
+		"sub/tests/unit.go":    "package tests",
+		"tests/integration.go": "package tests",
+	}
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Join(tmpDir, filepath.Dir(name)), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+
+	_, err = git.NewCommand("add", "--all").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	_, err = git.NewCommand("commit", "-m", "Add .gitattributes").RunInDirWithEnv(tmpDir, models.TestGitEnv(nil, nil))
+	assert.NoError(t, err)
+	_, err = git.NewCommand("push", "origin", "HEAD:refs/heads/export-ignore").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	sha, err := git.NewCommand("rev-parse", "HEAD").RunInDir(tmpDir)
+	assert.NoError(t, err)
+
+	expected := []string{"build.log", "docs", "sub/other.log", "tests/integration.go"}
+	paths, err = GetExportIgnoredPaths(repo, "export-ignore")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, paths)
+
+	paths, err = GetExportIgnoredPaths(repo, strings.TrimSpace(sha))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, paths)
+
+	_, err = GetExportIgnoredPaths(repo, "does-not-exist")
+	assert.True(t, git.IsErrNotExist(err))
+}
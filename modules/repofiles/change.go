@@ -0,0 +1,259 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repofiles
+
+import (
+	"fmt"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/lfs"
+	"code.gitea.io/gitea/modules/setting"
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// Operations supported by ChangeRepoFiles
+const (
+	ChangeFileOperationCreate = "create"
+	ChangeFileOperationUpdate = "update"
+	ChangeFileOperationDelete = "delete"
+)
+
+// ChangeRepoFile describes a single file operation of ChangeRepoFiles
+type ChangeRepoFile struct {
+	Operation string
+	TreePath  string
+	Content   string
+	SHA       string
+}
+
+// ChangeFilesOptions holds the repository files change options
+type ChangeFilesOptions struct {
+	OldBranch string
+	NewBranch string
+	Message   string
+	Files     []*ChangeRepoFile
+	Author    *IdentityOptions
+	Committer *IdentityOptions
+	Dates     *CommitDateOptions
+}
+
+// ChangeRepoFiles creates, updates and deletes multiple files of the given repository in a single commit
+func ChangeRepoFiles(repo *models.Repository, doer *models.User, opts *ChangeFilesOptions) (*api.FilesResponse, error) {
+	// If no branch name is set, assume the repo's default branch
+	if opts.OldBranch == "" {
+		opts.OldBranch = repo.DefaultBranch
+	}
+	if opts.NewBranch == "" {
+		opts.NewBranch = opts.OldBranch
+	}
+
+	// oldBranch must exist for this operation
+	if _, err := repo.GetBranch(opts.OldBranch); err != nil {
+		return nil, err
+	}
+
+	// A NewBranch can be specified for the files to be changed in a new branch.
+	// Check to make sure the branch does not already exist, otherwise we can't proceed.
+	// If we aren't branching to a new branch, make sure user can commit to the given branch
+	if opts.NewBranch != opts.OldBranch {
+		existingBranch, err := repo.GetBranch(opts.NewBranch)
+		if existingBranch != nil {
+			return nil, models.ErrBranchAlreadyExists{
+				BranchName: opts.NewBranch,
+			}
+		}
+		if err != nil && !git.IsErrBranchNotExist(err) {
+			return nil, err
+		}
+	} else if protected, _ := repo.IsProtectedBranchForPush(opts.OldBranch, doer); protected {
+		return nil, models.ErrUserCannotCommit{UserName: doer.LowerName}
+	}
+
+	// Check that every path is valid (not a git path) and only changed once
+	treePaths := make(map[string]bool, len(opts.Files))
+	for _, file := range opts.Files {
+		treePath := CleanUploadFileName(file.TreePath)
+		if treePath == "" {
+			return nil, models.ErrFilenameInvalid{
+				Path: file.TreePath,
+			}
+		}
+		if treePaths[treePath] {
+			return nil, models.ErrRepoFilePathDuplicated{
+				Path: treePath,
+			}
+		}
+		treePaths[treePath] = true
+		file.TreePath = treePath
+
+		switch file.Operation {
+		case ChangeFileOperationCreate:
+		case ChangeFileOperationUpdate, ChangeFileOperationDelete:
+			if file.SHA == "" {
+				return nil, models.ErrSHANotProvided{
+					Path: treePath,
+				}
+			}
+		default:
+			return nil, fmt.Errorf("ChangeRepoFiles: invalid operation %q for %s", file.Operation, file.TreePath)
+		}
+	}
+
+	message := strings.TrimSpace(opts.Message)
+
+	author, committer := GetAuthorAndCommitterUsers(opts.Author, opts.Committer, doer)
+
+	t, err := NewTemporaryUploadRepository(repo)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+	if err := t.Clone(opts.OldBranch); err != nil {
+		return nil, err
+	}
+	if err := t.SetDefaultIndex(); err != nil {
+		return nil, err
+	}
+
+	// Get the commit of the original branch
+	commit, err := t.GetBranchCommit(opts.OldBranch)
+	if err != nil {
+		return nil, err // Couldn't get a commit for the branch
+	}
+
+
+	// Validate all operations against the original commit before touching the index
+	for _, file := range opts.Files {
+		if file.Operation == ChangeFileOperationCreate {
+			if err := checkTreePathForWrite(commit, file.TreePath, true); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		entry, err := commit.GetTreeEntryByPath(file.TreePath)
+		if err != nil {
+			if git.IsErrNotExist(err) {
+				return nil, models.ErrRepoFileDoesNotExist{
+					Path: file.TreePath,
+				}
+			}
+			return nil, err
+		}
+		if entry.IsDir() {
+			return nil, models.ErrFilePathInvalid{
+				Message: fmt.Sprintf("a directory exists where you’re trying to change a file [path: %s]", file.TreePath),
+				Path:    file.TreePath,
+				Name:    entry.Name(),
+				Type:    git.EntryModeTree,
+			}
+		}
+		// The given SHA has to match the SHA of the file
+		if file.SHA != entry.ID.String() {
+			return nil, models.ErrSHADoesNotMatch{
+				Path:       file.TreePath,
+				GivenSHA:   file.SHA,
+				CurrentSHA: entry.ID.String(),
+			}
+		}
+	}
+
+	lfsMetaObjects := make(map[*models.LFSMetaObject]string)
+	for _, file := range opts.Files {
+		if file.Operation == ChangeFileOperationDelete {
+			if err := t.RemoveFilesFromIndex(file.TreePath); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		content := file.Content
+		if file.Operation == ChangeFileOperationUpdate {
+			entry, err := commit.GetTreeEntryByPath(file.TreePath)
+			if err != nil {
+				return nil, err
+			}
+			encoding, bom := detectEncodingAndBOM(entry, repo)
+			content = encodeContent(content, encoding, bom, file.TreePath)
+		}
+
+		if setting.LFS.StartServer {
+			filename2attribute2info, err := t.CheckAttribute("filter", file.TreePath)
+			if err != nil {
+				return nil, err
+			}
+
+			if filename2attribute2info[file.TreePath] != nil && filename2attribute2info[file.TreePath]["filter"] == "lfs" {
+				// OK so we are supposed to LFS this data!
+				oid, err := models.GenerateLFSOid(strings.NewReader(content))
+				if err != nil {
+					return nil, err
+				}
+				lfsMetaObject := &models.LFSMetaObject{Oid: oid, Size: int64(len(content)), RepositoryID: repo.ID}
+				lfsMetaObjects[lfsMetaObject] = content
+				content = lfsMetaObject.Pointer()
+			}
+		}
+
+		// Add the object to the database
+		objectHash, err := t.HashObject(strings.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+
+		// Add the object to the index
+		if err := t.AddObjectToIndex("100644", objectHash, file.TreePath); err != nil {
+			return nil, err
+		}
+	}
+
+	// Now write the tree
+	treeHash, err := t.WriteTree()
+	if err != nil {
+		return nil, err
+	}
+
+	// Now commit the tree
+	var commitHash string
+	if opts.Dates != nil {
+		commitHash, err = t.CommitTreeWithDate(author, committer, treeHash, message, opts.Dates.Author, opts.Dates.Committer)
+	} else {
+		commitHash, err = t.CommitTree(author, committer, treeHash, message)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	contentStore := &lfs.ContentStore{BasePath: setting.LFS.ContentPath}
+	for lfsMetaObject, content := range lfsMetaObjects {
+		// We have an LFS object - create it
+		lfsMetaObject, err = models.NewLFSMetaObject(lfsMetaObject)
+		if err != nil {
+			return nil, err
+		}
+		if !contentStore.Exists(lfsMetaObject) {
+			if err := contentStore.Put(lfsMetaObject, strings.NewReader(content)); err != nil {
+				if _, err2 := repo.RemoveLFSMetaObjectByOid(lfsMetaObject.Oid); err2 != nil {
+					return nil, fmt.Errorf("Error whilst removing failed inserted LFS object %s: %v (Prev Error: %v)", lfsMetaObject.Oid, err2, err)
+				}
+				return nil, err
+			}
+		}
+	}
+
+	// Then push this tree to NewBranch
+	if err := t.Push(doer, commitHash, opts.NewBranch); err != nil {
+		return nil, err
+	}
+
+	commit, err = t.GetCommit(commitHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetFilesResponseFromCommit(repo, commit, opts.NewBranch, opts.Files)
+}
@@ -5,8 +5,17 @@
 package repofiles
 
 import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/convert"
 	"code.gitea.io/gitea/modules/git"
+	api "code.gitea.io/gitea/modules/structs"
 )
 
 // CountDivergingCommits determines how many commits a branch is ahead or behind the repository's base branch
@@ -17,3 +26,131 @@ func CountDivergingCommits(repo *models.Repository, branch string) (*git.Diverge
 	}
 	return &divergence, nil
 }
+
+// LastCommitForPathsMaxCommits is the maximum number of commits walked by GetLastCommitForPaths
+const LastCommitForPathsMaxCommits = 2000
+
+// GetLastCommitForPaths returns, for every immediate child of treePath at ref, the most recent commit
+// touching it, keyed by the child's name.
+//
+// All children are resolved with a single "git log" walk over the history of treePath, which is stopped as
+// soon as every child has been found. The cost is therefore bounded by the age of the least recently
+// modified child rather than the number of children, but the walk never goes beyond
+// LastCommitForPathsMaxCommits commits: children not modified within that window are left out of the result.
+func GetLastCommitForPaths(repo *models.Repository, ref, treePath string) (map[string]*api.Commit, error) {
+	if ref == "" {
+		ref = repo.DefaultBranch
+	}
+
+	// Check that the path given in treePath is valid (not a git path)
+	cleanTreePath := CleanUploadFileName(treePath)
+	if cleanTreePath == "" && treePath != "" {
+		return nil, models.ErrFilenameInvalid{
+			Path: treePath,
+		}
+	}
+	treePath = cleanTreePath
+
+	gitRepo, err := git.OpenRepository(repo.RepoPath())
+	if err != nil {
+		return nil, err
+	}
+	defer gitRepo.Close()
+
+	commit, err := gitRepo.GetCommit(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := &commit.Tree
+	if treePath != "" {
+		if tree, err = commit.SubTree(treePath); err != nil {
+			return nil, err
+		}
+	}
+	entries, err := tree.ListEntries()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		pending[entry.Name()] = true
+	}
+	commitIDs := make(map[string]string, len(entries))
+	if len(pending) == 0 {
+		return map[string]*api.Commit{}, nil
+	}
+
+	prefix := ""
+	pathSpec := "."
+	if treePath != "" {
+		prefix = treePath + "/"
+		pathSpec = treePath
+	}
+
+	stdout, w := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		var commitID string
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "\x00") {
+				commitID = line[1:]
+				continue
+			}
+			if len(line) == 0 || len(commitID) == 0 {
+				continue
+			}
+			if line[0] == '"' {
+				if unquoted, err := strconv.Unquote(line); err == nil {
+					line = unquoted
+				}
+			}
+			if !strings.HasPrefix(line, prefix) {
+				continue
+			}
+			name := strings.SplitN(strings.TrimPrefix(line, prefix), "/", 2)[0]
+			if pending[name] {
+				delete(pending, name)
+				commitIDs[name] = commitID
+				if len(pending) == 0 {
+					break
+				}
+			}
+		}
+		// Stop git log as soon as every entry has been found
+		_ = stdout.CloseWithError(io.EOF)
+		close(done)
+	}()
+
+	stderr := new(bytes.Buffer)
+	err = git.NewCommand("-c", "core.quotepath=false", "log", "--format=%x00%H", "--name-only", "--no-renames",
+		fmt.Sprintf("--max-count=%d", LastCommitForPathsMaxCommits), commit.ID.String(), "--", pathSpec).
+		RunInDirPipeline(repo.RepoPath(), w, stderr)
+	w.Close()
+	<-done
+	if err != nil && len(pending) > 0 {
+		return nil, fmt.Errorf("git log: %v - %s", err, stderr.String())
+	}
+
+	commits := make(map[string]*api.Commit, len(commitIDs))
+	userCache := make(map[string]*models.User)
+	result := make(map[string]*api.Commit, len(commitIDs))
+	for name, commitID := range commitIDs {
+		apiCommit, ok := commits[commitID]
+		if !ok {
+			c, err := gitRepo.GetCommit(commitID)
+			if err != nil {
+				return nil, err
+			}
+			if apiCommit, err = convert.ToAPICommit(repo, c, userCache); err != nil {
+				return nil, err
+			}
+			commits[commitID] = apiCommit
+		}
+		result[name] = apiCommit
+	}
+	return result, nil
+}
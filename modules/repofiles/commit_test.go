@@ -0,0 +1,46 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repofiles
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetLastCommitForPaths(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	repo16 := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 16}).(*models.Repository)
+
+	commits, err := GetLastCommitForPaths(repo16, "", "")
+	assert.NoError(t, err)
+	if assert.Len(t, commits, 1) && assert.NotNil(t, commits["readme.md"]) {
+		assert.EqualValues(t, "69554a64c1e6030f051e5c3f94bfbd773cd6a324", commits["readme.md"].SHA)
+		assert.NotEmpty(t, commits["readme.md"].RepoCommit.Message)
+		assert.EqualValues(t, "24f83a471f77579fea57bac7255d6e64e70fce1c", commits["readme.md"].RepoCommit.Tree.SHA)
+	}
+
+	commits, err = GetLastCommitForPaths(repo16, "27566bd5738fc8b4e3fef3c5e72cce608537bd95", "")
+	assert.NoError(t, err)
+	if assert.Len(t, commits, 1) && assert.NotNil(t, commits["readme.md"]) {
+		assert.EqualValues(t, "27566bd5738fc8b4e3fef3c5e72cce608537bd95", commits["readme.md"].SHA)
+	}
+
+	repo20 := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 31}).(*models.Repository)
+	commits, err = GetLastCommitForPaths(repo20, "master", "a")
+	assert.NoError(t, err)
+	assert.Len(t, commits, 3)
+	for _, name := range []string{"b", "c", "link_annex"} {
+		if assert.NotNil(t, commits[name]) {
+			assert.EqualValues(t, "808038d2f71b0ab020991439cffd24309c7bc530", commits[name].SHA)
+		}
+	}
+
+	_, err = GetLastCommitForPaths(repo20, "master", "does/not/exist")
+	assert.Error(t, err)
+}
@@ -139,6 +139,52 @@ func TestGetContentsOrListForFile(t *testing.T) {
 	})
 }
 
+func TestGetContentsForSymlink(t *testing.T) {
+	models.PrepareTestEnv(t)
+	ctx := test.MockContext(t, "user2/repo20")
+	ctx.SetParams(":id", "31")
+	test.LoadRepo(t, ctx, 31)
+	test.LoadRepoCommit(t, ctx)
+	test.LoadUser(t, ctx, 2)
+	test.LoadGitRepo(t, ctx)
+	defer ctx.Repo.GitRepo.Close()
+
+	ref := ctx.Repo.Repository.DefaultBranch
+
+	t.Run("Get symlink pointing inside the tree", func(t *testing.T) {
+		fileContentResponse, err := GetContents(ctx.Repo.Repository, "link_hi", ref, false)
+		assert.NoError(t, err)
+		assert.EqualValues(t, "symlink", fileContentResponse.Type)
+		if assert.NotNil(t, fileContentResponse.Target) {
+			assert.EqualValues(t, "a/c/hi", *fileContentResponse.Target)
+		}
+		assert.Nil(t, fileContentResponse.Content)
+		assert.Nil(t, fileContentResponse.Encoding)
+		assert.NotNil(t, fileContentResponse.DownloadURL)
+	})
+
+	t.Run("Get symlink pointing outside the tree", func(t *testing.T) {
+		fileContentResponse, err := GetContents(ctx.Repo.Repository, "a/link_annex", ref, false)
+		assert.NoError(t, err)
+		assert.EqualValues(t, "symlink", fileContentResponse.Type)
+		if assert.NotNil(t, fileContentResponse.Target) {
+			assert.EqualValues(t, "../.git/annex/objects/aaa/bbb/ccc", *fileContentResponse.Target)
+		}
+	})
+
+	t.Run("List directory containing symlinks", func(t *testing.T) {
+		list, err := GetContentsOrList(ctx.Repo.Repository, "a/b", ref)
+		assert.NoError(t, err)
+		fileList, ok := list.([]*api.ContentsResponse)
+		if assert.True(t, ok) && assert.Len(t, fileList, 2) {
+			for _, entry := range fileList {
+				assert.EqualValues(t, "symlink", entry.Type)
+				assert.NotNil(t, entry.Target)
+			}
+		}
+	})
+}
+
 func TestGetContentsErrors(t *testing.T) {
 	models.PrepareTestEnv(t)
 	ctx := test.MockContext(t, "user2/repo1")
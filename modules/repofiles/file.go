@@ -28,6 +28,24 @@ func GetFileResponseFromCommit(repo *models.Repository, commit *git.Commit, bran
 	return fileResponse, nil
 }
 
+// GetFilesResponseFromCommit Constructs a FilesResponse from a Commit object
+func GetFilesResponseFromCommit(repo *models.Repository, commit *git.Commit, branch string, files []*ChangeRepoFile) (*api.FilesResponse, error) {
+	filesResponse := &api.FilesResponse{
+		Files: make([]*api.ContentsResponse, 0, len(files)),
+	}
+	for _, file := range files {
+		if file.Operation == ChangeFileOperationDelete {
+			filesResponse.Files = append(filesResponse.Files, nil)
+			continue
+		}
+		fileContents, _ := GetContents(repo, file.TreePath, branch, false) // ok if fails, then will be nil
+		filesResponse.Files = append(filesResponse.Files, fileContents)
+	}
+	filesResponse.Commit, _ = GetFileCommitResponse(repo, commit) // ok if fails, then will be nil
+	filesResponse.Verification = GetPayloadCommitVerification(commit)
+	return filesResponse, nil
+}
+
 // GetFileCommitResponse Constructs a FileCommitResponse from a Commit object
 func GetFileCommitResponse(repo *models.Repository, commit *git.Commit) (*api.FileCommitResponse, error) {
 	if repo == nil {
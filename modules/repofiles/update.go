@@ -122,6 +122,76 @@ func detectEncodingAndBOM(entry *git.TreeEntry, repo *models.Repository) (string
 	return encoding, false
 }
 
+// encodeContent re-encodes UTF-8 content in the encoding (and BOM) detected for the original file
+func encodeContent(content, encoding string, bom bool, treePath string) string {
+	if bom {
+		content = string(charset.UTF8BOM) + content
+	}
+	if encoding != "UTF-8" {
+		charsetEncoding, _ := stdcharset.Lookup(encoding)
+		if charsetEncoding != nil {
+			result, _, err := transform.String(charsetEncoding.NewEncoder(), content)
+			if err != nil {
+				// Look if we can't encode back in to the original we should just stick with utf-8
+				log.Error("Error re-encoding %s as %s - will stay as UTF-8: %v", treePath, encoding, err)
+				result = content
+			}
+			content = result
+		} else {
+			log.Error("Unknown encoding: %s", encoding)
+		}
+	}
+	return content
+}
+
+// checkTreePathForWrite makes sure no parts of treePath are existing files or links
+// except for the last item in the path, which must not exist if mustNotExist is set.
+func checkTreePathForWrite(commit *git.Commit, treePath string, mustNotExist bool) error {
+	treePathParts := strings.Split(treePath, "/")
+	subTreePath := ""
+	for index, part := range treePathParts {
+		subTreePath = path.Join(subTreePath, part)
+		entry, err := commit.GetTreeEntryByPath(subTreePath)
+		if err != nil {
+			if git.IsErrNotExist(err) {
+				// Means there is no item with that name, so we're good
+				break
+			}
+			return err
+		}
+		if index < len(treePathParts)-1 {
+			if !entry.IsDir() {
+				return models.ErrFilePathInvalid{
+					Message: fmt.Sprintf("a file exists where you’re trying to create a subdirectory [path: %s]", subTreePath),
+					Path:    subTreePath,
+					Name:    part,
+					Type:    git.EntryModeBlob,
+				}
+			}
+		} else if entry.IsLink() {
+			return models.ErrFilePathInvalid{
+				Message: fmt.Sprintf("a symbolic link exists where you’re trying to create a subdirectory [path: %s]", subTreePath),
+				Path:    subTreePath,
+				Name:    part,
+				Type:    git.EntryModeSymlink,
+			}
+		} else if entry.IsDir() {
+			return models.ErrFilePathInvalid{
+				Message: fmt.Sprintf("a directory exists where you’re trying to create a file [path: %s]", subTreePath),
+				Path:    subTreePath,
+				Name:    part,
+				Type:    git.EntryModeTree,
+			}
+		} else if mustNotExist {
+			// The entry shouldn't exist if we are creating new file or moving to a new path
+			return models.ErrRepoFileAlreadyExists{
+				Path: treePath,
+			}
+		}
+	}
+	return nil
+}
+
 // CreateOrUpdateRepoFile adds or updates a file in the given repository
 func CreateOrUpdateRepoFile(repo *models.Repository, doer *models.User, opts *UpdateRepoFileOptions) (*structs.FileResponse, error) {
 	// If no branch name is set, assume master
@@ -251,48 +321,8 @@ func CreateOrUpdateRepoFile(repo *models.Repository, doer *models.User, opts *Up
 	// sure no parts of the path are existing files or links except for the last
 	// item in the path which is the file name, and that shouldn't exist IF it is
 	// a new file OR is being moved to a new path.
-	treePathParts := strings.Split(treePath, "/")
-	subTreePath := ""
-	for index, part := range treePathParts {
-		subTreePath = path.Join(subTreePath, part)
-		entry, err := commit.GetTreeEntryByPath(subTreePath)
-		if err != nil {
-			if git.IsErrNotExist(err) {
-				// Means there is no item with that name, so we're good
-				break
-			}
-			return nil, err
-		}
-		if index < len(treePathParts)-1 {
-			if !entry.IsDir() {
-				return nil, models.ErrFilePathInvalid{
-					Message: fmt.Sprintf("a file exists where you’re trying to create a subdirectory [path: %s]", subTreePath),
-					Path:    subTreePath,
-					Name:    part,
-					Type:    git.EntryModeBlob,
-				}
-			}
-		} else if entry.IsLink() {
-			return nil, models.ErrFilePathInvalid{
-				Message: fmt.Sprintf("a symbolic link exists where you’re trying to create a subdirectory [path: %s]", subTreePath),
-				Path:    subTreePath,
-				Name:    part,
-				Type:    git.EntryModeSymlink,
-			}
-		} else if entry.IsDir() {
-			return nil, models.ErrFilePathInvalid{
-				Message: fmt.Sprintf("a directory exists where you’re trying to create a file [path: %s]", subTreePath),
-				Path:    subTreePath,
-				Name:    part,
-				Type:    git.EntryModeTree,
-			}
-		} else if fromTreePath != treePath || opts.IsNewFile {
-			// The entry shouldn't exist if we are creating new file or moving to a new path
-			return nil, models.ErrRepoFileAlreadyExists{
-				Path: treePath,
-			}
-		}
-
+	if err := checkTreePathForWrite(commit, treePath, fromTreePath != treePath || opts.IsNewFile); err != nil {
+		return nil, err
 	}
 
 	// Get the two paths (might be the same if not moving) from the index if they exist
@@ -322,24 +352,7 @@ func CreateOrUpdateRepoFile(repo *models.Repository, doer *models.User, opts *Up
 		}
 	}
 
-	content := opts.Content
-	if bom {
-		content = string(charset.UTF8BOM) + content
-	}
-	if encoding != "UTF-8" {
-		charsetEncoding, _ := stdcharset.Lookup(encoding)
-		if charsetEncoding != nil {
-			result, _, err := transform.String(charsetEncoding.NewEncoder(), content)
-			if err != nil {
-				// Look if we can't encode back in to the original we should just stick with utf-8
-				log.Error("Error re-encoding %s (%s) as %s - will stay as UTF-8: %v", opts.TreePath, opts.FromTreePath, encoding, err)
-				result = content
-			}
-			content = result
-		} else {
-			log.Error("Unknown encoding: %s", encoding)
-		}
-	}
+	content := encodeContent(opts.Content, encoding, bom, opts.TreePath)
 	// Reset the opts.Content to our adjusted content to ensure that LFS gets the correct content
 	opts.Content = content
 	var lfsMetaObject *models.LFSMetaObject
@@ -25,6 +25,7 @@ func GetPayloadCommitVerification(commit *git.Commit) *structs.PayloadCommitVeri
 		}
 	}
 	verification.Verified = commitVerification.Verified
+	verification.UnlistedEmail = commitVerification.UnlistedEmail
 	verification.Reason = commitVerification.Reason
 	if verification.Reason == "" && !verification.Verified {
 		verification.Reason = "gpg.error.not_signed_commit"
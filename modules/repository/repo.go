@@ -64,6 +64,7 @@ func MigrateRepositoryGitData(doer, u *models.User, repo *models.Repository, opt
 		Mirror:  true,
 		Quiet:   true,
 		Timeout: migrateTimeout,
+		Depth:   opts.Depth,
 	}); err != nil {
 		return repo, fmt.Errorf("Clone: %v", err)
 	}
@@ -100,6 +101,7 @@ func MigrateRepositoryGitData(doer, u *models.User, repo *models.Repository, opt
 	if err != nil {
 		return repo, fmt.Errorf("git.IsEmpty: %v", err)
 	}
+	repo.IsShallow = gitRepo.IsShallow()
 
 	if !opts.Releases && !repo.IsEmpty {
 		// Try to get HEAD branch and set it as default branch.
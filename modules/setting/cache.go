@@ -20,6 +20,10 @@ type Cache struct {
 	Interval int
 	Conn     string
 	TTL      time.Duration
+
+	// in-memory cache of the combined commit status shown for pull requests
+	CommitStatusTTL      time.Duration
+	CommitStatusMaxItems int
 }
 
 var (
@@ -41,6 +45,8 @@ func newCacheService() {
 		log.Fatal("Unknown cache adapter: %s", CacheService.Adapter)
 	}
 	CacheService.TTL = sec.Key("ITEM_TTL").MustDuration(16 * time.Hour)
+	CacheService.CommitStatusTTL = sec.Key("COMMIT_STATUS_TTL").MustDuration(10 * time.Second)
+	CacheService.CommitStatusMaxItems = sec.Key("COMMIT_STATUS_MAX_ITEMS").MustInt(1000)
 
 	log.Info("Cache Service Enabled")
 }
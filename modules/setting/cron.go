@@ -52,6 +52,12 @@ var (
 		UpdateMigrationPosterID struct {
 			Schedule string
 		} `ini:"cron.update_migration_poster_id"`
+		CloseStalePullRequests struct {
+			Enabled    bool
+			RunAtStart bool
+			Schedule   string
+			OlderThan  time.Duration
+		} `ini:"cron.close_stale_pull_requests"`
 	}{
 		UpdateMirror: struct {
 			Enabled    bool
@@ -122,6 +128,17 @@ var (
 		}{
 			Schedule: "@every 24h",
 		},
+		CloseStalePullRequests: struct {
+			Enabled    bool
+			RunAtStart bool
+			Schedule   string
+			OlderThan  time.Duration
+		}{
+			Enabled:    true,
+			RunAtStart: false,
+			Schedule:   "@every 24h",
+			OlderThan:  90 * 24 * time.Hour,
+		},
 	}
 )
 
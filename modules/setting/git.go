@@ -21,6 +21,7 @@ var (
 		MaxGitDiffLines           int
 		MaxGitDiffLineCharacters  int
 		MaxGitDiffFiles           int
+		MaxRawDiffBytes           int
 		GCArgs                    []string `ini:"GC_ARGS" delim:" "`
 		EnableAutoGitWireProtocol bool
 		Timeout                   struct {
@@ -36,6 +37,7 @@ var (
 		MaxGitDiffLines:           1000,
 		MaxGitDiffLineCharacters:  5000,
 		MaxGitDiffFiles:           100,
+		MaxRawDiffBytes:           50 * 1024 * 1024,
 		GCArgs:                    []string{},
 		EnableAutoGitWireProtocol: true,
 		Timeout: struct {
@@ -7,11 +7,17 @@ package setting
 var (
 	// Migrations settings
 	Migrations = struct {
-		MaxAttempts  int
-		RetryBackoff int
+		MaxAttempts       int
+		RetryBackoff      int
+		LargeFileWarnSize int64
+		LargeFileMaxSize  int64
+		BatchSize         int
 	}{
-		MaxAttempts:  3,
-		RetryBackoff: 3,
+		MaxAttempts:       3,
+		RetryBackoff:      3,
+		LargeFileWarnSize: 50,
+		LargeFileMaxSize:  0,
+		BatchSize:         0,
 	}
 )
 
@@ -19,4 +25,7 @@ func newMigrationsService() {
 	sec := Cfg.Section("migrations")
 	Migrations.MaxAttempts = sec.Key("MAX_ATTEMPTS").MustInt(Migrations.MaxAttempts)
 	Migrations.RetryBackoff = sec.Key("RETRY_BACKOFF").MustInt(Migrations.RetryBackoff)
+	Migrations.LargeFileWarnSize = sec.Key("LARGE_FILE_WARN_SIZE").MustInt64(Migrations.LargeFileWarnSize)
+	Migrations.LargeFileMaxSize = sec.Key("LARGE_FILE_MAX_SIZE").MustInt64(Migrations.LargeFileMaxSize)
+	Migrations.BatchSize = sec.Key("BATCH_SIZE").MustInt(Migrations.BatchSize)
 }
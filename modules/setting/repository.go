@@ -37,6 +37,7 @@ var (
 		DefaultCloseIssuesViaCommitsInAnyBranch bool
 		EnablePushCreateUser                    bool
 		EnablePushCreateOrg                     bool
+		ForceSafeRawContentType                 bool
 
 		// Repository editor settings
 		Editor struct {
@@ -60,9 +61,13 @@ var (
 
 		// Pull request settings
 		PullRequest struct {
-			WorkInProgressPrefixes []string
-			CloseKeywords          []string
-			ReopenKeywords         []string
+			WorkInProgressPrefixes        []string
+			CloseKeywords                 []string
+			ReopenKeywords                []string
+			EnableCrossRepoClosing        bool
+			EnableRelatedPullRequests     bool
+			RelatedPullRequestsMaxCommits int
+			RelatedPullRequestsMaxPulls   int
 		} `ini:"repository.pull-request"`
 
 		// Issue Setting
@@ -93,6 +98,7 @@ var (
 		DefaultCloseIssuesViaCommitsInAnyBranch: false,
 		EnablePushCreateUser:                    false,
 		EnablePushCreateOrg:                     false,
+		ForceSafeRawContentType:                 true,
 
 		// Repository editor settings
 		Editor: struct {
@@ -127,15 +133,21 @@ var (
 
 		// Pull request settings
 		PullRequest: struct {
-			WorkInProgressPrefixes []string
-			CloseKeywords          []string
-			ReopenKeywords         []string
+			WorkInProgressPrefixes        []string
+			CloseKeywords                 []string
+			ReopenKeywords                []string
+			EnableCrossRepoClosing        bool
+			EnableRelatedPullRequests     bool
+			RelatedPullRequestsMaxCommits int
+			RelatedPullRequestsMaxPulls   int
 		}{
 			WorkInProgressPrefixes: []string{"WIP:", "[WIP]"},
 			// Same as GitHub. See
 			// https://help.github.com/articles/closing-issues-via-commit-messages
-			CloseKeywords:  strings.Split("close,closes,closed,fix,fixes,fixed,resolve,resolves,resolved", ","),
-			ReopenKeywords: strings.Split("reopen,reopens,reopened", ","),
+			CloseKeywords:                 strings.Split("close,closes,closed,fix,fixes,fixed,resolve,resolves,resolved", ","),
+			ReopenKeywords:                strings.Split("reopen,reopens,reopened", ","),
+			RelatedPullRequestsMaxCommits: 100,
+			RelatedPullRequestsMaxPulls:   50,
 		},
 
 		// Issue settings
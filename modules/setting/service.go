@@ -6,10 +6,14 @@ package setting
 
 import (
 	"regexp"
+	"time"
 
 	"code.gitea.io/gitea/modules/structs"
 )
 
+// defaultEmailCanonicalizationDomains are mail providers which deliver user+tag@domain to user@domain
+var defaultEmailCanonicalizationDomains = []string{"gmail.com", "googlemail.com", "outlook.com", "hotmail.com", "protonmail.com", "fastmail.com"}
+
 // Service settings
 var Service struct {
 	DefaultOrgVisibility                    string
@@ -47,6 +51,9 @@ var Service struct {
 	AutoWatchNewRepos                       bool
 	AutoWatchOnChanges                      bool
 	DefaultOrgMemberVisible                 bool
+	EnableEmailCanonicalization             bool
+	EmailCanonicalizationDomains            []string
+	ReactionNotificationWindow              time.Duration
 
 	// OpenID settings
 	EnableOpenIDSignIn bool
@@ -92,6 +99,12 @@ func newService() {
 	Service.DefaultOrgVisibility = sec.Key("DEFAULT_ORG_VISIBILITY").In("public", structs.ExtractKeysFromMapString(structs.VisibilityModes))
 	Service.DefaultOrgVisibilityMode = structs.VisibilityModes[Service.DefaultOrgVisibility]
 	Service.DefaultOrgMemberVisible = sec.Key("DEFAULT_ORG_MEMBER_VISIBLE").MustBool()
+	Service.EnableEmailCanonicalization = sec.Key("ENABLE_EMAIL_CANONICALIZATION").MustBool()
+	Service.EmailCanonicalizationDomains = sec.Key("EMAIL_CANONICALIZATION_DOMAINS").Strings(",")
+	if len(Service.EmailCanonicalizationDomains) == 0 {
+		Service.EmailCanonicalizationDomains = defaultEmailCanonicalizationDomains
+	}
+	Service.ReactionNotificationWindow = sec.Key("REACTION_NOTIFICATION_WINDOW").MustDuration(10 * time.Minute)
 
 	sec = Cfg.Section("openid")
 	Service.EnableOpenIDSignIn = sec.Key("ENABLE_OPENID_SIGNIN").MustBool(!InstallLock)
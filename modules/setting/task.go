@@ -4,16 +4,22 @@
 
 package setting
 
+import "time"
+
 var (
 	// Task settings
 	Task = struct {
 		QueueType    string
 		QueueLength  int
 		QueueConnStr string
+		Workers      int
+		StuckTimeout time.Duration
 	}{
 		QueueType:    ChannelQueueType,
 		QueueLength:  1000,
 		QueueConnStr: "addrs=127.0.0.1:6379 db=0",
+		Workers:      1,
+		StuckTimeout: 24 * time.Hour,
 	}
 )
 
@@ -22,4 +28,9 @@ func newTaskService() {
 	Task.QueueType = sec.Key("QUEUE_TYPE").MustString(ChannelQueueType)
 	Task.QueueLength = sec.Key("QUEUE_LENGTH").MustInt(1000)
 	Task.QueueConnStr = sec.Key("QUEUE_CONN_STR").MustString("addrs=127.0.0.1:6379 db=0")
+	Task.Workers = sec.Key("WORKERS").MustInt(1)
+	if Task.Workers < 1 {
+		Task.Workers = 1
+	}
+	Task.StuckTimeout = sec.Key("STUCK_TIMEOUT").MustDuration(24 * time.Hour)
 }
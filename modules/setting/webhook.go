@@ -21,6 +21,9 @@ var (
 		ProxyURL       string
 		ProxyURLFixed  *url.URL
 		ProxyHosts     []string
+
+		IncludePatchInPROpen bool
+		PatchMaxSize         int64
 	}{
 		QueueLength:    1000,
 		DeliverTimeout: 5,
@@ -28,6 +31,9 @@ var (
 		PagingNum:      10,
 		ProxyURL:       "",
 		ProxyHosts:     []string{},
+
+		IncludePatchInPROpen: false,
+		PatchMaxSize:         64 * 1024,
 	}
 )
 
@@ -48,4 +54,6 @@ func newWebhookService() {
 		}
 	}
 	Webhook.ProxyHosts = sec.Key("PROXY_HOSTS").Strings(",")
+	Webhook.IncludePatchInPROpen = sec.Key("INCLUDE_PATCH_IN_PR_OPEN").MustBool(false)
+	Webhook.PatchMaxSize = sec.Key("PATCH_MAX_SIZE").MustInt64(64 * 1024)
 }
@@ -91,11 +91,12 @@ type PayloadCommit struct {
 
 // PayloadCommitVerification represents the GPG verification of a commit
 type PayloadCommitVerification struct {
-	Verified  bool         `json:"verified"`
-	Reason    string       `json:"reason"`
-	Signature string       `json:"signature"`
-	Signer    *PayloadUser `json:"signer"`
-	Payload   string       `json:"payload"`
+	Verified      bool         `json:"verified"`
+	UnlistedEmail bool         `json:"unlisted_email"`
+	Reason        string       `json:"reason"`
+	Signature     string       `json:"signature"`
+	Signer        *PayloadUser `json:"signer"`
+	Payload       string       `json:"payload"`
 }
 
 var (
@@ -108,6 +109,7 @@ var (
 	_ Payloader = &PullRequestPayload{}
 	_ Payloader = &RepositoryPayload{}
 	_ Payloader = &ReleasePayload{}
+	_ Payloader = &TaskCompletedPayload{}
 )
 
 // _________                        __
@@ -422,6 +424,8 @@ type PullRequestPayload struct {
 	Repository  *Repository     `json:"repository"`
 	Sender      *User           `json:"sender"`
 	Review      *ReviewPayload  `json:"review"`
+	// Patch is the unified diff of the pull request, only embedded on open when enabled
+	Patch string `json:"patch,omitempty"`
 }
 
 // SetSecret modifies the secret of the PullRequestPayload.
@@ -475,3 +479,42 @@ func (p *RepositoryPayload) SetSecret(secret string) {
 func (p *RepositoryPayload) JSONPayload() ([]byte, error) {
 	return json.MarshalIndent(p, "", " ")
 }
+
+// ___________              __
+// \__    ___/____    _____|  | __
+//   |    |  \__  \  /  ___/  |/ /
+//   |    |   / __ \_\___ \|    <
+//   |____|  (____  /____  >__|_ \
+//                \/     \/     \/
+
+// HookTaskStatus is the status a task completed with
+type HookTaskStatus string
+
+const (
+	// HookTaskFinished finished successfully
+	HookTaskFinished HookTaskStatus = "finished"
+	// HookTaskFailed failed
+	HookTaskFailed HookTaskStatus = "failed"
+)
+
+// TaskCompletedPayload payload for task completed webhooks
+type TaskCompletedPayload struct {
+	Secret   string         `json:"secret"`
+	TaskType string         `json:"task_type"`
+	Status   HookTaskStatus `json:"status"`
+	Error    string         `json:"error"`
+	// Options of a migration, without any credentials
+	Options    *MigrateRepoOption `json:"options,omitempty"`
+	Repository *Repository        `json:"repository"`
+	Sender     *User              `json:"sender"`
+}
+
+// SetSecret modifies the secret of the TaskCompletedPayload
+func (p *TaskCompletedPayload) SetSecret(secret string) {
+	p.Secret = secret
+}
+
+// JSONPayload JSON representation of the payload
+func (p *TaskCompletedPayload) JSONPayload() ([]byte, error) {
+	return json.MarshalIndent(p, "", " ")
+}
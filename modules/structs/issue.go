@@ -54,6 +54,8 @@ type Issue struct {
 	// enum: open,closed
 	State    StateType `json:"state"`
 	Comments int       `json:"comments"`
+	// Number of reactions on the issue itself
+	Reactions int64 `json:"reactions"`
 	// swagger:strfmt date-time
 	Created time.Time `json:"created_at"`
 	// swagger:strfmt date-time
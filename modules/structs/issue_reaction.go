@@ -13,6 +13,20 @@ type EditReactionOption struct {
 	Reaction string `json:"content"`
 }
 
+// EditReactionsOption contain the complete set of reaction types of a user
+type EditReactionsOption struct {
+	Reactions []string `json:"contents"`
+}
+
+// ReactionDump contain one exported reaction, which refers to the user by name and ID
+type ReactionDump struct {
+	UserID   int64  `json:"user_id"`
+	UserName string `json:"user_name"`
+	Reaction string `json:"content"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+}
+
 // ReactionResponse contain one reaction
 type ReactionResponse struct {
 	User     *User  `json:"user"`
@@ -27,17 +27,28 @@ type PullRequest struct {
 	DiffURL  string `json:"diff_url"`
 	PatchURL string `json:"patch_url"`
 
+	// whether the authenticated user can merge the pull request, including the base branch protection.
+	// For anonymous requests, webhooks and merged or closed pull requests it only reflects conflicts,
+	// work in progress and merge holds.
 	Mergeable bool `json:"mergeable"`
 	HasMerged bool `json:"merged"`
 	// swagger:strfmt date-time
 	Merged         *time.Time `json:"merged_at"`
 	MergedCommitID *string    `json:"merge_commit_sha"`
 	MergedBy       *User      `json:"merged_by"`
+	// whether merging is held back, e.g. while an external CI is running
+	IsMergeOnHold   bool   `json:"is_merge_on_hold"`
+	MergeHoldReason string `json:"merge_hold_reason,omitempty"`
+	// number of review conversations which are neither resolved nor outdated
+	UnresolvedReviewThreads int `json:"unresolved_review_threads"`
 
 	Base      *PRBranchInfo `json:"base"`
 	Head      *PRBranchInfo `json:"head"`
 	MergeBase string        `json:"merge_base"`
 
+	// latest deployment per environment, only included if requested
+	Deployments []*PullRequestDeployment `json:"deployments,omitempty"`
+
 	// swagger:strfmt date-time
 	Deadline *time.Time `json:"due_date"`
 
@@ -91,3 +102,73 @@ type EditPullRequestOption struct {
 	Deadline       *time.Time `json:"due_date"`
 	RemoveDeadline *bool      `json:"unset_due_date"`
 }
+
+// MergeHoldOption options when putting a pull request on hold
+type MergeHoldOption struct {
+	Reason string `json:"reason"`
+}
+
+// PullRequestMergeStyles represents the merge styles a pull request can be merged with
+type PullRequestMergeStyles struct {
+	// merge styles the pull request can currently be merged with by the user
+	Styles []string `json:"styles"`
+	// merge style to preselect, empty if no merge style is available
+	Default string `json:"default"`
+}
+
+// PullReviewThread represents the review comments on the same line of a file of a pull request
+type PullReviewThread struct {
+	Path string `json:"path"`
+	// line of the comments, negative for lines of the previous version of the file
+	Line int64 `json:"line"`
+	// whether all comments of the thread are resolved
+	Resolved bool `json:"resolved"`
+	// whether the commented line does not exist anymore
+	Outdated bool       `json:"outdated"`
+	Comments []*Comment `json:"comments"`
+}
+
+// RetargetPullRequestsOption options for retargeting all open pull requests from one base branch to another
+type RetargetPullRequestsOption struct {
+	// required: true
+	OldBase string `json:"old_base" binding:"Required"`
+	// required: true
+	NewBase string `json:"new_base" binding:"Required"`
+}
+
+// RetargetPullRequestsResult represents the outcome of retargeting pull requests
+type RetargetPullRequestsResult struct {
+	// number of retargeted pull requests
+	Retargeted int                   `json:"retargeted"`
+	Skipped    []*SkippedPullRequest `json:"skipped"`
+}
+
+// SkippedPullRequest represents a pull request which was not retargeted
+type SkippedPullRequest struct {
+	Index  int64  `json:"index"`
+	Reason string `json:"reason"`
+}
+
+// PullRequestDeployment represents the state of a deployment of a pull request to an environment
+type PullRequestDeployment struct {
+	ID          int64  `json:"id"`
+	Environment string `json:"environment"`
+	URL         string `json:"url"`
+	// enum: pending,success,failure
+	State   string `json:"state"`
+	Creator *User  `json:"creator"`
+	// swagger:strfmt date-time
+	Created time.Time `json:"created_at"`
+	// swagger:strfmt date-time
+	Updated time.Time `json:"updated_at"`
+}
+
+// CreatePullRequestDeploymentOption options for recording the state of a deployment of a pull request
+type CreatePullRequestDeploymentOption struct {
+	// required: true
+	Environment string `json:"environment" binding:"Required;MaxSize(255)"`
+	URL         string `json:"url" binding:"ValidUrl"`
+	// required: true
+	// enum: pending,success,failure
+	State string `json:"state" binding:"Required;In(pending,success,failure)"`
+}
@@ -87,6 +87,7 @@ type Repository struct {
 	AllowRebase               bool             `json:"allow_rebase"`
 	AllowRebaseMerge          bool             `json:"allow_rebase_explicit"`
 	AllowSquash               bool             `json:"allow_squash_merge"`
+	DefaultMergeStyle         string           `json:"default_merge_style"`
 	AvatarURL                 string           `json:"avatar_url"`
 }
 
@@ -154,6 +155,8 @@ type EditRepoOption struct {
 	AllowRebaseMerge *bool `json:"allow_rebase_explicit,omitempty"`
 	// either `true` to allow squash-merging pull requests, or `false` to prevent squash-merging. `has_pull_requests` must be `true`.
 	AllowSquash *bool `json:"allow_squash_merge,omitempty"`
+	// set to a merge style to be used by this repository: "merge", "rebase", "rebase-merge", or "squash". `has_pull_requests` must be `true`.
+	DefaultMergeStyle *string `json:"default_merge_style,omitempty"`
 	// set to `true` to archive this repository.
 	Archived *bool `json:"archived,omitempty"`
 }
@@ -218,4 +221,46 @@ type MigrateRepoOption struct {
 	Comments        bool
 	PullRequests    bool
 	MigrateToRepoID int64
+	// Clone only the given number of most recent commits of every branch, 0 clones the full history.
+	// A shallow clone speeds up migrating large repositories, but detecting merged pull requests and
+	// computing merge bases fall back to the branch heads when the history is missing.
+	Depth int `json:"depth"`
+	// Only probe the source and report what would be migrated, no repository is created.
+	DryRun bool `json:"dry_run"`
+	// Import pull requests whose head commit can not be fetched anymore, e.g. because the branch
+	// of a fork was deleted, as issues with their patch attached instead of as broken pull requests.
+	FallbackPRsToIssues bool `json:"fallback_prs_to_issues"`
+	// Skip the phases before this one, which were completed by a previous run of the migration
+	// into the repository given by MigrateToRepoID. Already imported data is not imported twice.
+	ResumeFrom MigratePhase `json:"resume_from"`
+	// Keep the repository if a migration run as a task fails after completing a phase, so that it
+	// can be resumed. Otherwise the repository of a failed migration is deleted.
+	Resumable bool `json:"resumable"`
+}
+
+// ConvertToMirrorOption options for converting a migrated repository into a mirror of its source
+type ConvertToMirrorOption struct {
+	// interval between the syncs of the mirror, e.g. "8h", defaults to the configured default interval
+	Interval string `json:"interval"`
+}
+
+// MigrateProbe represents a dry-run migration, which probes the source in the background
+type MigrateProbe struct {
+	ID int64 `json:"id"`
+	// queued, running, failed or finished
+	Status string `json:"status"`
+	// what the migration would import, set once the probe is finished
+	Result *MigrateProbeResult `json:"result"`
+	// why the probe failed
+	Error string `json:"error"`
+}
+
+// MigrateProbeResult represents what a migration of a repository would import
+type MigrateProbeResult struct {
+	Branches     int `json:"branches"`
+	Tags         int `json:"tags"`
+	Issues       int `json:"issues"`
+	PullRequests int `json:"pull_requests"`
+	// estimated size of the repository in KiB, 0 if the source does not report it
+	Size int64 `json:"size"`
 }
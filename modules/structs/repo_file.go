@@ -48,6 +48,30 @@ type UpdateFileOptions struct {
 	FromPath string `json:"from_path" binding:"MaxSize(500)"`
 }
 
+// ChangeFileOperation describes one create, update or delete of a file
+type ChangeFileOperation struct {
+	// indicates what to do with the file
+	// required: true
+	// enum: create,update,delete
+	Operation string `json:"operation" binding:"Required;In(create,update,delete)"`
+	// path to the file
+	// required: true
+	Path string `json:"path" binding:"Required;MaxSize(500)"`
+	// content must be base64 encoded, required for create and update
+	Content string `json:"content"`
+	// sha is the SHA for the file that already exists, required for update and delete
+	SHA string `json:"sha"`
+}
+
+// ChangeFilesOptions options for creating, updating or deleting multiple files in a single commit
+// Note: `author` and `committer` are optional (if only one is given, it will be used for the other, otherwise the authenticated user will be used)
+type ChangeFilesOptions struct {
+	FileOptions
+	// list of file operations
+	// required: true
+	Files []*ChangeFileOperation `json:"files" binding:"Required"`
+}
+
 // FileLinksResponse contains the links for a repo's file
 type FileLinksResponse struct {
 	Self    *string `json:"self"`
@@ -96,6 +120,13 @@ type FileResponse struct {
 	Verification *PayloadCommitVerification `json:"verification"`
 }
 
+// FilesResponse contains information about the files changed in a single commit
+type FilesResponse struct {
+	Files        []*ContentsResponse        `json:"files"`
+	Commit       *FileCommitResponse        `json:"commit"`
+	Verification *PayloadCommitVerification `json:"verification"`
+}
+
 // FileDeleteResponse contains information about a repo's file that was deleted
 type FileDeleteResponse struct {
 	Content      interface{}                `json:"content"` // to be set to nil
@@ -32,3 +32,58 @@ const (
 	TaskStatusFailed                     // 3 task is failed
 	TaskStatusFinished                   // 4 task is finished
 )
+
+// Name returns the task status name
+func (taskStatus TaskStatus) Name() string {
+	switch taskStatus {
+	case TaskStatusQueue:
+		return "queued"
+	case TaskStatusRunning:
+		return "running"
+	case TaskStatusStopped:
+		return "stopped"
+	case TaskStatusFailed:
+		return "failed"
+	case TaskStatusFinished:
+		return "finished"
+	}
+	return ""
+}
+
+// MigratePhase defines a phase of a repository migration, phases are run in the order of their values
+type MigratePhase int
+
+// enumerate all the phases of a repository migration
+const (
+	MigratePhaseNone         MigratePhase = iota // 0 nothing is migrated yet
+	MigratePhaseRepository                       // 1 git data with all branches and tags, and the wiki
+	MigratePhaseTopics                           // 2 topics
+	MigratePhaseMilestones                       // 3 milestones
+	MigratePhaseLabels                           // 4 labels
+	MigratePhaseReleases                         // 5 releases
+	MigratePhaseIssues                           // 6 issues and their comments
+	MigratePhasePullRequests                     // 7 pull requests and their comments
+)
+
+// Name returns the migrate phase name
+func (phase MigratePhase) Name() string {
+	switch phase {
+	case MigratePhaseNone:
+		return "none"
+	case MigratePhaseRepository:
+		return "repository"
+	case MigratePhaseTopics:
+		return "topics"
+	case MigratePhaseMilestones:
+		return "milestones"
+	case MigratePhaseLabels:
+		return "labels"
+	case MigratePhaseReleases:
+		return "releases"
+	case MigratePhaseIssues:
+		return "issues"
+	case MigratePhasePullRequests:
+		return "pull requests"
+	}
+	return ""
+}
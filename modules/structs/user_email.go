@@ -12,6 +12,12 @@ type Email struct {
 	Primary  bool   `json:"primary"`
 }
 
+// MemberEmails the verified email addresses of an organization member
+type MemberEmails struct {
+	Username string   `json:"username"`
+	Emails   []*Email `json:"emails"`
+}
+
 // CreateEmailOption options when creating email addresses
 type CreateEmailOption struct {
 	// email addresses to add
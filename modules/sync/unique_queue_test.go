@@ -0,0 +1,28 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package sync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniqueQueue_Add(t *testing.T) {
+	queue := NewUniqueQueue(2)
+
+	queue.Add(1)
+	assert.True(t, queue.Exist(1))
+
+	// adding a queued id again is a no-op and must not keep the queue locked
+	queue.Add(1)
+	queue.Add(2)
+	assert.True(t, queue.Exist(2))
+	assert.Equal(t, "1", <-queue.Queue())
+	assert.Equal(t, "2", <-queue.Queue())
+
+	queue.Remove(1)
+	assert.False(t, queue.Exist(1))
+}
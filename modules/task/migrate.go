@@ -11,10 +11,13 @@ import (
 	"strings"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/base"
+	"code.gitea.io/gitea/modules/git/pipeline"
 	"code.gitea.io/gitea/modules/graceful"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/migrations"
 	"code.gitea.io/gitea/modules/notification"
+	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
@@ -36,6 +39,18 @@ func handleCreateError(owner *models.User, err error, name string) error {
 }
 
 func runMigrateTask(t *models.Task) (err error) {
+	// the task may have been started by another worker already
+	if started, err := t.MarkRunning(); err != nil {
+		return err
+	} else if !started {
+		log.Trace("Task [%d] is not queued anymore, skipping", t.ID)
+		return nil
+	}
+
+	if opts, err := t.MigrateConfig(); err == nil && opts.DryRun {
+		return runMigrateProbeTask(t, opts)
+	}
+
 	defer func() {
 		if e := recover(); e != nil {
 			var buf bytes.Buffer
@@ -48,6 +63,7 @@ func runMigrateTask(t *models.Task) (err error) {
 			err = models.FinishMigrateTask(t)
 			if err == nil {
 				notification.NotifyMigrateRepository(t.Doer, t.Owner, t.Repo)
+				notification.NotifyTaskCompleted(t)
 				return
 			}
 
@@ -61,7 +77,10 @@ func runMigrateTask(t *models.Task) (err error) {
 			log.Error("Task UpdateCols failed: %s", err.Error())
 		}
 
-		if t.Repo != nil {
+		notification.NotifyTaskCompleted(t)
+
+		// keep the repository if the migration can be resumed
+		if t.Repo != nil && t.Checkpoint == structs.MigratePhaseNone {
 			if errDelete := models.DeleteRepository(t.Doer, t.OwnerID, t.Repo.ID); errDelete != nil {
 				log.Error("DeleteRepository: %v", errDelete)
 			}
@@ -83,12 +102,6 @@ func runMigrateTask(t *models.Task) (err error) {
 	if err := t.LoadOwner(); err != nil {
 		return err
 	}
-	t.StartTime = timeutil.TimeStampNow()
-	t.Status = structs.TaskStatusRunning
-	if err := t.UpdateCols("start_time", "status"); err != nil {
-		return err
-	}
-
 	var opts *structs.MigrateRepoOption
 	opts, err = t.MigrateConfig()
 	if err != nil {
@@ -96,10 +109,18 @@ func runMigrateTask(t *models.Task) (err error) {
 	}
 
 	opts.MigrateToRepoID = t.RepoID
-	repo, err := migrations.MigrateRepository(graceful.GetManager().HammerContext(), t.Doer, t.Owner.Name, *opts)
+	if opts.ResumeFrom > structs.MigratePhaseNone {
+		log.Trace("Resuming migration of task [%d] from %s", t.ID, opts.ResumeFrom.Name())
+	}
+	// without checkpoints the repository of a failed migration is deleted as usual
+	var checkpoint migrations.CheckpointFunc
+	if opts.Resumable {
+		checkpoint = t.SaveCheckpoint
+	}
+	repo, err := migrations.MigrateRepositoryWithCheckpoints(graceful.GetManager().HammerContext(), t.Doer, t.Owner.Name, *opts, checkpoint)
 	if err == nil {
 		log.Trace("Repository migrated [%d]: %s/%s", repo.ID, t.Owner.Name, repo.Name)
-		return nil
+		return checkLargeFiles(t, repo)
 	}
 
 	if models.IsErrRepoAlreadyExist(err) {
@@ -117,3 +138,75 @@ func runMigrateTask(t *models.Task) (err error) {
 
 	return handleCreateError(t.Owner, err, "MigratePost")
 }
+
+// checkLargeFiles records the large files of a migrated repository as warnings
+// on the task, and fails the task if a file exceeds the hard limit
+func checkLargeFiles(t *models.Task, repo *models.Repository) error {
+	warnSize := setting.Migrations.LargeFileWarnSize * 1024 * 1024
+	maxSize := setting.Migrations.LargeFileMaxSize * 1024 * 1024
+	minSize := warnSize
+	if minSize <= 0 || (maxSize > 0 && maxSize < minSize) {
+		minSize = maxSize
+	}
+	if minSize <= 0 {
+		return nil
+	}
+
+	blobs, err := pipeline.FindLargeBlobs(repo.RepoPath(), minSize)
+	if err != nil {
+		return err
+	} else if len(blobs) == 0 {
+		return nil
+	}
+
+	// blobs are sorted by size, so the first one is the largest
+	if maxSize > 0 && blobs[0].Size > maxSize {
+		return fmt.Errorf("File %s is %s, which exceeds the limit of %s",
+			blobs[0].Path, base.FileSize(blobs[0].Size), base.FileSize(maxSize))
+	}
+
+	warnings := make([]string, len(blobs))
+	for i, blob := range blobs {
+		warnings[i] = fmt.Sprintf("Large file %s (%s): %s", blob.Path, blob.SHA, base.FileSize(blob.Size))
+	}
+	t.Warnings = strings.Join(warnings, "\n")
+	return t.UpdateCols("warnings")
+}
+
+// runMigrateProbeTask runs a dry-run migrate task, which only probes the source
+// and saves what would be migrated on the task
+func runMigrateProbeTask(t *models.Task, opts *structs.MigrateRepoOption) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, "Handler crashed with error: %v", log.Stack(2))
+
+			err = errors.New(buf.String())
+		}
+
+		if err == nil {
+			return
+		}
+
+		t.EndTime = timeutil.TimeStampNow()
+		t.Status = structs.TaskStatusFailed
+		t.Errors = err.Error()
+		if err := t.UpdateCols("status", "errors", "end_time"); err != nil {
+			log.Error("Task UpdateCols failed: %s", err.Error())
+		}
+	}()
+
+	if err := t.LoadOwner(); err != nil {
+		return err
+	}
+
+	result, err := migrations.ProbeRepository(graceful.GetManager().HammerContext(), t.Owner.Name, *opts)
+	if err != nil {
+		// remoteAddr may contain credentials, so we sanitize it
+		return util.URLSanitizedError(err, opts.CloneAddr)
+	}
+
+	log.Trace("Repository probed [%s]: %d branches, %d tags, %d issues, %d pull requests",
+		opts.RepoName, result.Branches, result.Tags, result.Issues, result.PullRequests)
+	return models.FinishMigrateProbeTask(t, result)
+}
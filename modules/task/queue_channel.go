@@ -5,8 +5,10 @@
 package task
 
 import (
+	"errors"
+	"sync"
+
 	"code.gitea.io/gitea/models"
-	"code.gitea.io/gitea/modules/log"
 )
 
 var (
@@ -15,34 +17,80 @@ var (
 
 // ChannelQueue implements
 type ChannelQueue struct {
-	queue chan *models.Task
+	queue     chan *models.Task
+	workers   int
+	closeChan chan struct{}
+	closeOnce sync.Once
 }
 
 // NewChannelQueue create a memory channel queue
-func NewChannelQueue(queueLen int) *ChannelQueue {
+func NewChannelQueue(queueLen, workers int) *ChannelQueue {
+	if workers < 1 {
+		workers = 1
+	}
 	return &ChannelQueue{
-		queue: make(chan *models.Task, queueLen),
+		queue:     make(chan *models.Task, queueLen),
+		workers:   workers,
+		closeChan: make(chan struct{}),
 	}
 }
 
-// Run starts to run the queue
+// Run starts the workers of the queue and waits until all of them have returned
 func (c *ChannelQueue) Run() error {
-	for task := range c.queue {
-		err := Run(task)
-		if err != nil {
-			log.Error("Run task failed: %s", err.Error())
-		}
+	var wg sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.work()
+		}()
 	}
+	wg.Wait()
 	return nil
 }
 
+func (c *ChannelQueue) work() {
+	for {
+		paused, pauseChanged := queuePauseState()
+		if paused {
+			select {
+			case <-c.closeChan:
+				return
+			case <-pauseChanged:
+			}
+			continue
+		}
+
+		// a pause while waiting for a task must keep the next task queued
+		select {
+		case <-c.closeChan:
+			return
+		case <-pauseChanged:
+		case task := <-c.queue:
+			runTask(task)
+		}
+	}
+}
+
 // Push will push the task ID to queue
 func (c *ChannelQueue) Push(task *models.Task) error {
-	c.queue <- task
-	return nil
+	select {
+	case <-c.closeChan:
+		return errors.New("task queue is stopped")
+	default:
+	}
+
+	select {
+	case <-c.closeChan:
+		return errors.New("task queue is stopped")
+	case c.queue <- task:
+		return nil
+	}
 }
 
-// Stop stop the queue
+// Stop stops the queue, running tasks are finished but no new tasks are started
 func (c *ChannelQueue) Stop() {
-	close(c.queue)
+	c.closeOnce.Do(func() {
+		close(c.closeChan)
+	})
 }
@@ -9,6 +9,7 @@ import (
 	"errors"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"code.gitea.io/gitea/models"
@@ -31,7 +32,9 @@ type redisClient interface {
 type RedisQueue struct {
 	client    redisClient
 	queueName string
-	closeChan chan bool
+	workers   int
+	closeChan chan struct{}
+	closeOnce sync.Once
 }
 
 func parseConnStr(connStr string) (addrs, password string, dbIdx int, err error) {
@@ -57,11 +60,15 @@ func parseConnStr(connStr string) (addrs, password string, dbIdx int, err error)
 }
 
 // NewRedisQueue creates single redis or cluster redis queue
-func NewRedisQueue(addrs string, password string, dbIdx int) (*RedisQueue, error) {
+func NewRedisQueue(addrs string, password string, dbIdx int, workers int) (*RedisQueue, error) {
+	if workers < 1 {
+		workers = 1
+	}
 	dbs := strings.Split(addrs, ",")
 	var queue = RedisQueue{
 		queueName: "task_queue",
-		closeChan: make(chan bool),
+		workers:   workers,
+		closeChan: make(chan struct{}),
 	}
 	if len(dbs) == 0 {
 		return nil, errors.New("no redis host found")
@@ -84,15 +91,33 @@ func NewRedisQueue(addrs string, password string, dbIdx int) (*RedisQueue, error
 	return &queue, nil
 }
 
-// Run starts to run the queue
+// Run starts the workers of the queue and waits until all of them have returned
 func (r *RedisQueue) Run() error {
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.work()
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+func (r *RedisQueue) work() {
 	for {
 		select {
 		case <-r.closeChan:
-			return nil
+			return
 		case <-time.After(time.Millisecond * 100):
 		}
 
+		// tasks stay in redis while the queue is paused
+		if !waitWhilePaused(r.closeChan) {
+			return
+		}
+
 		bs, err := r.client.LPop(r.queueName).Bytes()
 		if err != nil {
 			if err != redis.Nil {
@@ -107,10 +132,7 @@ func (r *RedisQueue) Run() error {
 		if err != nil {
 			log.Error("Unmarshal task failed: %s", err.Error())
 		} else {
-			err = Run(&task)
-			if err != nil {
-				log.Error("Run task failed: %s", err.Error())
-			}
+			runTask(&task)
 		}
 	}
 }
@@ -126,5 +148,7 @@ func (r *RedisQueue) Push(task *models.Task) error {
 
 // Stop stop the queue
 func (r *RedisQueue) Stop() {
-	r.closeChan <- true
+	r.closeOnce.Do(func() {
+		close(r.closeChan)
+	})
 }
@@ -5,17 +5,87 @@
 package task
 
 import (
+	"context"
 	"fmt"
+	"sync"
+	"time"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/graceful"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/migrations/base"
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/timeutil"
 )
 
-// taskQueue is a global queue of tasks
-var taskQueue Queue
+var (
+	// taskQueue is a global queue of tasks
+	taskQueue Queue
+	// workerSemaphore caps the number of tasks running at the same time
+	workerSemaphore = make(chan struct{}, 1)
+)
+
+// queuePause holds whether the task queue is paused. changed is closed and
+// replaced whenever paused flips, so that waiting workers wake up.
+var queuePause = struct {
+	sync.Mutex
+	paused  bool
+	changed chan struct{}
+}{changed: make(chan struct{})}
+
+// PauseQueue stops the queue from starting new tasks until ResumeQueue is
+// called. Tasks which are already running are not affected and queued tasks
+// are kept.
+func PauseQueue() {
+	setQueuePaused(true)
+}
+
+// ResumeQueue lets a paused queue start tasks again
+func ResumeQueue() {
+	setQueuePaused(false)
+}
+
+// IsQueuePaused returns whether the task queue is paused
+func IsQueuePaused() bool {
+	paused, _ := queuePauseState()
+	return paused
+}
+
+func setQueuePaused(paused bool) {
+	queuePause.Lock()
+	defer queuePause.Unlock()
+	if queuePause.paused == paused {
+		return
+	}
+	queuePause.paused = paused
+	close(queuePause.changed)
+	queuePause.changed = make(chan struct{})
+}
+
+// queuePauseState returns whether the queue is paused and a channel which is
+// closed once that changes
+func queuePauseState() (bool, <-chan struct{}) {
+	queuePause.Lock()
+	defer queuePause.Unlock()
+	return queuePause.paused, queuePause.changed
+}
+
+// waitWhilePaused blocks as long as the queue is paused and returns false if
+// the queue is stopped meanwhile
+func waitWhilePaused(closeChan <-chan struct{}) bool {
+	for {
+		paused, changed := queuePauseState()
+		if !paused {
+			return true
+		}
+		select {
+		case <-closeChan:
+			return false
+		case <-changed:
+		}
+	}
+}
 
 // Run a task
 func Run(t *models.Task) error {
@@ -27,18 +97,86 @@ func Run(t *models.Task) error {
 	}
 }
 
+// runTask runs a task once a worker slot is free
+func runTask(t *models.Task) {
+	workerSemaphore <- struct{}{}
+	defer func() {
+		<-workerSemaphore
+	}()
+
+	if err := Run(t); err != nil {
+		log.Error("Run task failed: %s", err.Error())
+	}
+}
+
+// ResetStuckTasks marks the tasks which have been running longer than setting.Task.StuckTimeout as failed,
+// e.g. because the server crashed while running them, and returns how many were reset. Like for any failed
+// migration, the repository a migrate task was migrating into is deleted unless the migration can be resumed.
+// Nothing is reset if setting.Task.StuckTimeout is 0.
+func ResetStuckTasks() (int, error) {
+	if setting.Task.StuckTimeout <= 0 {
+		return 0, nil
+	}
+
+	tasks, err := models.GetStuckTasks(setting.Task.StuckTimeout)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, t := range tasks {
+		age := time.Since(t.StartTime.AsTime()).Round(time.Second)
+		log.Warn("Task [%d] has been running for %v and is marked as failed", t.ID, age)
+
+		t.EndTime = timeutil.TimeStampNow()
+		t.Status = structs.TaskStatusFailed
+		t.Errors = fmt.Sprintf("Task has been stuck in running state for %v", age)
+		if err := t.UpdateCols("status", "errors", "end_time"); err != nil {
+			return 0, err
+		}
+
+		if t.Type != structs.TaskTypeMigrateRepo || t.RepoID == 0 {
+			continue
+		}
+		if err := t.LoadRepo(); err != nil {
+			if models.IsErrRepoNotExist(err) {
+				continue
+			}
+			return 0, err
+		}
+		if t.Repo.Status != models.RepositoryBeingMigrated || t.Checkpoint != structs.MigratePhaseNone {
+			continue
+		}
+		if err := t.LoadDoer(); err != nil {
+			log.Error("LoadDoer: %v", err)
+			continue
+		}
+		if err := models.DeleteRepository(t.Doer, t.OwnerID, t.RepoID); err != nil {
+			log.Error("DeleteRepository: %v", err)
+		}
+	}
+	return len(tasks), nil
+}
+
 // Init will start the service to get all unfinished tasks and run them
 func Init() error {
+	workerSemaphore = make(chan struct{}, setting.Task.Workers)
+
+	if reset, err := ResetStuckTasks(); err != nil {
+		return err
+	} else if reset > 0 {
+		log.Info("Reset %d stuck tasks", reset)
+	}
+
 	switch setting.Task.QueueType {
 	case setting.ChannelQueueType:
-		taskQueue = NewChannelQueue(setting.Task.QueueLength)
+		taskQueue = NewChannelQueue(setting.Task.QueueLength, setting.Task.Workers)
 	case setting.RedisQueueType:
 		var err error
 		addrs, pass, idx, err := parseConnStr(setting.Task.QueueConnStr)
 		if err != nil {
 			return err
 		}
-		taskQueue, err = NewRedisQueue(addrs, pass, idx)
+		taskQueue, err = NewRedisQueue(addrs, pass, idx, setting.Task.Workers)
 		if err != nil {
 			return err
 		}
@@ -46,15 +184,64 @@ func Init() error {
 		return fmt.Errorf("Unsupported task queue type: %v", setting.Task.QueueType)
 	}
 
-	go func() {
+	go graceful.GetManager().RunWithShutdownFns(func(atShutdown, atTerminate func(context.Context, func())) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		atShutdown(ctx, taskQueue.Stop)
+
 		if err := taskQueue.Run(); err != nil {
 			log.Error("taskQueue.Run end failed: %v", err)
 		}
-	}()
+	})
 
 	return nil
 }
 
+// TaskSearchOptions contains the options for listing tasks
+type TaskSearchOptions = models.SearchTaskOptions
+
+// GetTasks returns the tasks matching the given options together with the
+// total number of matching tasks. The owner and doer of every task are
+// loaded for display.
+func GetTasks(opts TaskSearchOptions) ([]*models.Task, int64, error) {
+	tasks, count, err := models.SearchTasks(&opts)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, t := range tasks {
+		if err := t.LoadOwner(); err != nil && !models.IsErrUserNotExist(err) {
+			return nil, 0, err
+		}
+		if err := t.LoadDoer(); err != nil && !models.IsErrUserNotExist(err) {
+			return nil, 0, err
+		}
+	}
+	return tasks, count, nil
+}
+
+// RetryMigrateTask queues a failed migrate task again, which resumes the migration
+// after the last phase it completed
+func RetryMigrateTask(t *models.Task) error {
+	if err := models.RetryMigrateTask(t); err != nil {
+		return err
+	}
+
+	return taskQueue.Push(t)
+}
+
+// ProbeRepository queues a dry-run migrate task, which only probes the source
+// and saves what would be migrated on the task
+func ProbeRepository(doer, u *models.User, opts base.MigrateOptions) (*models.Task, error) {
+	opts.DryRun = true
+	task, err := models.CreateMigrateTask(doer, u, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return task, taskQueue.Push(task)
+}
+
 // MigrateRepository add migration repository to task
 func MigrateRepository(doer, u *models.User, opts base.MigrateOptions) error {
 	task, err := models.CreateMigrateTask(doer, u, opts)
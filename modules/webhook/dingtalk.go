@@ -250,6 +250,21 @@ func getDingtalkReleasePayload(p *api.ReleasePayload) (*DingtalkPayload, error)
 	}, nil
 }
 
+func getDingtalkTaskCompletedPayload(p *api.TaskCompletedPayload) (*DingtalkPayload, error) {
+	text, _ := getTaskCompletedPayloadInfo(p, noneLinkFormatter)
+
+	return &DingtalkPayload{
+		MsgType: "actionCard",
+		ActionCard: dingtalk.ActionCard{
+			Text:        text,
+			Title:       text,
+			HideAvatar:  "0",
+			SingleTitle: "view repository",
+			SingleURL:   p.Repository.HTMLURL,
+		},
+	}, nil
+}
+
 // GetDingtalkPayload converts a ding talk webhook into a DingtalkPayload
 func GetDingtalkPayload(p api.Payloader, event models.HookEventType, meta string) (*DingtalkPayload, error) {
 	s := new(DingtalkPayload)
@@ -275,6 +290,8 @@ func GetDingtalkPayload(p api.Payloader, event models.HookEventType, meta string
 		return getDingtalkRepositoryPayload(p.(*api.RepositoryPayload))
 	case models.HookEventRelease:
 		return getDingtalkReleasePayload(p.(*api.ReleasePayload))
+	case models.HookEventTaskCompleted:
+		return getDingtalkTaskCompletedPayload(p.(*api.TaskCompletedPayload))
 	}
 
 	return s, nil
@@ -389,6 +389,27 @@ func getDiscordReleasePayload(p *api.ReleasePayload, meta *DiscordMeta) (*Discor
 	}, nil
 }
 
+func getDiscordTaskCompletedPayload(p *api.TaskCompletedPayload, meta *DiscordMeta) (*DiscordPayload, error) {
+	text, color := getTaskCompletedPayloadInfo(p, noneLinkFormatter)
+
+	return &DiscordPayload{
+		Username:  meta.Username,
+		AvatarURL: meta.IconURL,
+		Embeds: []DiscordEmbed{
+			{
+				Title: text,
+				URL:   p.Repository.HTMLURL,
+				Color: color,
+				Author: DiscordEmbedAuthor{
+					Name:    p.Sender.UserName,
+					URL:     setting.AppURL + p.Sender.UserName,
+					IconURL: p.Sender.AvatarURL,
+				},
+			},
+		},
+	}, nil
+}
+
 // GetDiscordPayload converts a discord webhook into a DiscordPayload
 func GetDiscordPayload(p api.Payloader, event models.HookEventType, meta string) (*DiscordPayload, error) {
 	s := new(DiscordPayload)
@@ -419,6 +440,8 @@ func GetDiscordPayload(p api.Payloader, event models.HookEventType, meta string)
 		return getDiscordRepositoryPayload(p.(*api.RepositoryPayload), discord)
 	case models.HookEventRelease:
 		return getDiscordReleasePayload(p.(*api.ReleasePayload), discord)
+	case models.HookEventTaskCompleted:
+		return getDiscordTaskCompletedPayload(p.(*api.TaskCompletedPayload), discord)
 	}
 
 	return s, nil
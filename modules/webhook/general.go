@@ -150,6 +150,21 @@ func getReleasePayloadInfo(p *api.ReleasePayload, linkFormatter linkFormatter) (
 	return text, color
 }
 
+func getTaskCompletedPayloadInfo(p *api.TaskCompletedPayload, linkFormatter linkFormatter) (text string, color int) {
+	repoLink := linkFormatter(p.Repository.HTMLURL, p.Repository.FullName)
+
+	switch p.Status {
+	case api.HookTaskFinished:
+		text = fmt.Sprintf("[%s] %s finished", repoLink, p.TaskType)
+		color = greenColor
+	case api.HookTaskFailed:
+		text = fmt.Sprintf("[%s] %s failed: %s", repoLink, p.TaskType, p.Error)
+		color = redColor
+	}
+
+	return text, color
+}
+
 func getIssueCommentPayloadInfo(p *api.IssueCommentPayload, linkFormatter linkFormatter) (string, string, int) {
 	senderLink := linkFormatter(setting.AppURL+p.Sender.UserName, p.Sender.UserName)
 	repoLink := linkFormatter(p.Repository.HTMLURL, p.Repository.FullName)
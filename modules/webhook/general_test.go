@@ -101,6 +101,22 @@ func pullReleaseTestPayload() *api.ReleasePayload {
 	}
 }
 
+func taskCompletedTestPayload() *api.TaskCompletedPayload {
+	return &api.TaskCompletedPayload{
+		TaskType: "Migrate Repository",
+		Status:   api.HookTaskFailed,
+		Error:    "Authentication failed",
+		Sender: &api.User{
+			UserName: "user1",
+		},
+		Repository: &api.Repository{
+			HTMLURL:  "http://localhost:3000/test/repo",
+			Name:     "repo",
+			FullName: "test/repo",
+		},
+	}
+}
+
 func pullRequestTestPayload() *api.PullRequestPayload {
 	return &api.PullRequestPayload{
 		Action: api.HookIssueOpened,
@@ -544,6 +544,47 @@ func getMSTeamsReleasePayload(p *api.ReleasePayload) (*MSTeamsPayload, error) {
 	}, nil
 }
 
+func getMSTeamsTaskCompletedPayload(p *api.TaskCompletedPayload) (*MSTeamsPayload, error) {
+	text, color := getTaskCompletedPayloadInfo(p, noneLinkFormatter)
+
+	return &MSTeamsPayload{
+		Type:       "MessageCard",
+		Context:    "https://schema.org/extensions",
+		ThemeColor: fmt.Sprintf("%x", color),
+		Title:      text,
+		Summary:    text,
+		Sections: []MSTeamsSection{
+			{
+				ActivityTitle:    p.Sender.FullName,
+				ActivitySubtitle: p.Sender.UserName,
+				ActivityImage:    p.Sender.AvatarURL,
+				Facts: []MSTeamsFact{
+					{
+						Name:  "Repository:",
+						Value: p.Repository.FullName,
+					},
+					{
+						Name:  "Task:",
+						Value: p.TaskType,
+					},
+				},
+			},
+		},
+		PotentialAction: []MSTeamsAction{
+			{
+				Type: "OpenUri",
+				Name: "View in Gitea",
+				Targets: []MSTeamsActionTarget{
+					{
+						Os:  "default",
+						URI: p.Repository.HTMLURL,
+					},
+				},
+			},
+		},
+	}, nil
+}
+
 // GetMSTeamsPayload converts a MSTeams webhook into a MSTeamsPayload
 func GetMSTeamsPayload(p api.Payloader, event models.HookEventType, meta string) (*MSTeamsPayload, error) {
 	s := new(MSTeamsPayload)
@@ -569,6 +610,8 @@ func GetMSTeamsPayload(p api.Payloader, event models.HookEventType, meta string)
 		return getMSTeamsRepositoryPayload(p.(*api.RepositoryPayload))
 	case models.HookEventRelease:
 		return getMSTeamsReleasePayload(p.(*api.ReleasePayload))
+	case models.HookEventTaskCompleted:
+		return getMSTeamsTaskCompletedPayload(p.(*api.TaskCompletedPayload))
 	}
 
 	return s, nil
@@ -194,6 +194,17 @@ func getSlackReleasePayload(p *api.ReleasePayload, slack *SlackMeta) (*SlackPayl
 	}, nil
 }
 
+func getSlackTaskCompletedPayload(p *api.TaskCompletedPayload, slack *SlackMeta) (*SlackPayload, error) {
+	text, _ := getTaskCompletedPayloadInfo(p, SlackLinkFormatter)
+
+	return &SlackPayload{
+		Channel:  slack.Channel,
+		Text:     text,
+		Username: slack.Username,
+		IconURL:  slack.IconURL,
+	}, nil
+}
+
 func getSlackPushPayload(p *api.PushPayload, slack *SlackMeta) (*SlackPayload, error) {
 	// n new commits
 	var (
@@ -343,6 +354,8 @@ func GetSlackPayload(p api.Payloader, event models.HookEventType, meta string) (
 		return getSlackRepositoryPayload(p.(*api.RepositoryPayload), slack)
 	case models.HookEventRelease:
 		return getSlackReleasePayload(p.(*api.ReleasePayload), slack)
+	case models.HookEventTaskCompleted:
+		return getSlackTaskCompletedPayload(p.(*api.TaskCompletedPayload), slack)
 	}
 
 	return s, nil
@@ -73,6 +73,24 @@ func TestSlackReleasePayload(t *testing.T) {
 	assert.Equal(t, "[<http://localhost:3000/test/repo|test/repo>] Release <http://localhost:3000/test/repo/src/v1.0|v1.0> created by <https://try.gitea.io/user1|user1>", pl.Text)
 }
 
+func TestSlackTaskCompletedPayload(t *testing.T) {
+	p := taskCompletedTestPayload()
+
+	sl := &SlackMeta{
+		Username: p.Sender.UserName,
+	}
+
+	pl, err := getSlackTaskCompletedPayload(p, sl)
+	require.Nil(t, err)
+	require.NotNil(t, pl)
+	assert.Equal(t, "[<http://localhost:3000/test/repo|test/repo>] Migrate Repository failed: Authentication failed", pl.Text)
+
+	p.Status = api.HookTaskFinished
+	pl, err = getSlackTaskCompletedPayload(p, sl)
+	require.Nil(t, err)
+	assert.Equal(t, "[<http://localhost:3000/test/repo|test/repo>] Migrate Repository finished", pl.Text)
+}
+
 func TestSlackPullRequestPayload(t *testing.T) {
 	p := pullRequestTestPayload()
 
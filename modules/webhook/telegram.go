@@ -173,6 +173,14 @@ func getTelegramReleasePayload(p *api.ReleasePayload) (*TelegramPayload, error)
 	}, nil
 }
 
+func getTelegramTaskCompletedPayload(p *api.TaskCompletedPayload) (*TelegramPayload, error) {
+	text, _ := getTaskCompletedPayloadInfo(p, htmlLinkFormatter)
+
+	return &TelegramPayload{
+		Message: text + "\n",
+	}, nil
+}
+
 // GetTelegramPayload converts a telegram webhook into a TelegramPayload
 func GetTelegramPayload(p api.Payloader, event models.HookEventType, meta string) (*TelegramPayload, error) {
 	s := new(TelegramPayload)
@@ -196,6 +204,8 @@ func GetTelegramPayload(p api.Payloader, event models.HookEventType, meta string
 		return getTelegramRepositoryPayload(p.(*api.RepositoryPayload))
 	case models.HookEventRelease:
 		return getTelegramReleasePayload(p.(*api.ReleasePayload))
+	case models.HookEventTaskCompleted:
+		return getTelegramTaskCompletedPayload(p.(*api.TaskCompletedPayload))
 	}
 
 	return s, nil
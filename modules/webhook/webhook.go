@@ -160,6 +160,25 @@ func PrepareWebhooks(repo *models.Repository, event models.HookEventType, p api.
 	return nil
 }
 
+// PrepareAndDeliverWebhooks adds new webhooks for given payload and delivers them right away
+// instead of through the task queue, which is needed when the repository is about to be deleted.
+func PrepareAndDeliverWebhooks(repo *models.Repository, event models.HookEventType, p api.Payloader) error {
+	if err := prepareWebhooks(repo, event, p); err != nil {
+		return err
+	}
+
+	tasks, err := models.FindRepoUndeliveredHookTasks(repo.ID)
+	if err != nil {
+		return fmt.Errorf("FindRepoUndeliveredHookTasks: %v", err)
+	}
+	for _, t := range tasks {
+		if err = Deliver(t); err != nil {
+			log.Error("deliver: %v", err)
+		}
+	}
+	return nil
+}
+
 func prepareWebhooks(repo *models.Repository, event models.HookEventType, p api.Payloader) error {
 	ws, err := models.GetActiveWebhooksByRepoID(repo.ID)
 	if err != nil {
@@ -134,6 +134,8 @@ const (
 	syncExternalUsers
 	gitFsck
 	deleteGeneratedRepositoryAvatars
+	deleteOrphanedEmailAddresses
+	deduplicateReactions
 )
 
 // Dashboard show admin panel dashboard
@@ -179,6 +181,16 @@ func Dashboard(ctx *context.Context) {
 		case deleteGeneratedRepositoryAvatars:
 			success = ctx.Tr("admin.dashboard.delete_generated_repository_avatars_success")
 			err = models.RemoveRandomAvatars()
+		case deleteOrphanedEmailAddresses:
+			var deleted int64
+			if deleted, err = models.DeleteOrphanedEmailAddresses(); err == nil {
+				success = ctx.Tr("admin.dashboard.delete_orphaned_email_addresses_success", deleted)
+			}
+		case deduplicateReactions:
+			var deleted int64
+			if deleted, err = models.DeduplicateReactions(); err == nil {
+				success = ctx.Tr("admin.dashboard.deduplicate_reactions_success", deleted)
+			}
 		}
 
 		if err != nil {
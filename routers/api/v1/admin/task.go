@@ -0,0 +1,90 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package admin
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/task"
+)
+
+// PauseTaskQueue api for pausing the task queue
+func PauseTaskQueue(ctx *context.APIContext) {
+	// swagger:operation POST /admin/tasks/pause admin adminPauseTaskQueue
+	// ---
+	// summary: Pause the task queue, running tasks are finished but no new tasks are started
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	task.PauseQueue()
+	log.Info("Task queue paused by %s", ctx.User.Name)
+	ctx.Status(http.StatusNoContent)
+}
+
+// ResumeTaskQueue api for resuming a paused task queue
+func ResumeTaskQueue(ctx *context.APIContext) {
+	// swagger:operation POST /admin/tasks/resume admin adminResumeTaskQueue
+	// ---
+	// summary: Resume a paused task queue
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	task.ResumeQueue()
+	log.Info("Task queue resumed by %s", ctx.User.Name)
+	ctx.Status(http.StatusNoContent)
+}
+
+// RetryTask api for retrying a failed migrate task
+func RetryTask(ctx *context.APIContext) {
+	// swagger:operation POST /admin/tasks/{id}/retry admin adminRetryTask
+	// ---
+	// summary: Retry a failed migration, which resumes after the last phase it completed
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of the task to retry
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "204":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	t, err := models.GetTaskByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrTaskDoesNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetTaskByID", err)
+		}
+		return
+	}
+
+	if err := task.RetryMigrateTask(t); err != nil {
+		if models.IsErrTaskNotRetryable(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "RetryMigrateTask", err)
+		}
+		return
+	}
+	log.Info("Task [%d] retried by %s", t.ID, ctx.User.Name)
+	ctx.Status(http.StatusNoContent)
+}
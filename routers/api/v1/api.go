@@ -605,6 +605,7 @@ func RegisterRoutes(m *macaron.Macaron) {
 
 		m.Group("/repos", func() {
 			m.Post("/migrate", reqToken(), bind(auth.MigrateRepoForm{}), repo.Migrate)
+			m.Get("/migrate/probes/:id", reqToken(), repo.GetMigrateProbe)
 
 			m.Group("/:username/:reponame", func() {
 				m.Combo("").Get(reqAnyRepoReader(), repo.Get).
@@ -708,6 +709,8 @@ func RegisterRoutes(m *macaron.Macaron) {
 							Get(repo.GetIssueReactions).
 							Post(bind(api.EditReactionOption{}), repo.PostIssueReaction).
 							Delete(bind(api.EditReactionOption{}), repo.DeleteIssueReaction)
+						m.Put("/reactions/mine", reqToken(), bind(api.EditReactionsOption{}), repo.SetIssueReactions)
+						m.Get("/reactions/export", reqToken(), reqSiteAdmin(), repo.ExportIssueReactions)
 					})
 				}, mustEnableIssuesOrPulls)
 				m.Group("/labels", func() {
@@ -750,13 +753,27 @@ func RegisterRoutes(m *macaron.Macaron) {
 					})
 				}, reqRepoReader(models.UnitTypeReleases))
 				m.Post("/mirror-sync", reqToken(), reqRepoWriter(models.UnitTypeCode), repo.MirrorSync)
+				m.Post("/convert-to-mirror", reqToken(), reqAdmin(), bind(api.ConvertToMirrorOption{}), repo.ConvertToMirror)
 				m.Get("/editorconfig/:filename", context.RepoRef(), reqRepoReader(models.UnitTypeCode), repo.GetEditorconfig)
 				m.Group("/pulls", func() {
 					m.Combo("").Get(bind(api.ListPullRequestsOptions{}), repo.ListPullRequests).
 						Post(reqToken(), mustNotBeArchived, bind(api.CreatePullRequestOption{}), repo.CreatePullRequest)
+					m.Post("/retarget", reqToken(), mustNotBeArchived, reqRepoWriter(models.UnitTypePullRequests), bind(api.RetargetPullRequestsOption{}), repo.RetargetPullRequests)
+					m.Combo("/reviews/:id/reactions", reqToken()).
+						Get(repo.GetPullReviewReactions).
+						Post(bind(api.EditReactionOption{}), repo.PostPullReviewReaction).
+						Delete(bind(api.EditReactionOption{}), repo.DeletePullReviewReaction)
 					m.Group("/:index", func() {
 						m.Combo("").Get(repo.GetPullRequest).
 							Patch(reqToken(), reqRepoWriter(models.UnitTypePullRequests), bind(api.EditPullRequestOption{}), repo.EditPullRequest)
+						m.Get("/threads", repo.ListPullReviewThreads)
+						m.Get("/merge-styles", repo.GetPullRequestMergeStyles)
+						m.Combo("/hold", reqToken(), reqRepoWriter(models.UnitTypePullRequests)).
+							Put(bind(api.MergeHoldOption{}), repo.SetPullRequestMergeHold).
+							Delete(repo.ClearPullRequestMergeHold)
+						m.Get("/merged-commit", repo.GetPullRequestMergedCommit)
+						m.Combo("/deployments").Get(repo.ListPullRequestDeployments).
+							Post(reqToken(), mustNotBeArchived, reqRepoWriter(models.UnitTypePullRequests), bind(api.CreatePullRequestDeploymentOption{}), repo.CreatePullRequestDeployment)
 						m.Combo("/merge").Get(repo.IsPullRequestMerged).
 							Post(reqToken(), mustNotBeArchived, reqRepoWriter(models.UnitTypePullRequests), bind(auth.MergePullRequestForm{}), repo.MergePullRequest)
 					})
@@ -785,6 +802,7 @@ func RegisterRoutes(m *macaron.Macaron) {
 				}, reqRepoReader(models.UnitTypeCode))
 				m.Group("/contents", func() {
 					m.Get("", repo.GetContentsList)
+					m.Post("", reqToken(), reqRepoWriter(models.UnitTypeCode), bind(api.ChangeFilesOptions{}), repo.ChangeFiles)
 					m.Get("/*", repo.GetContents)
 					m.Group("/*", func() {
 						m.Post("", bind(api.CreateFileOptions{}), repo.CreateFile)
@@ -818,6 +836,7 @@ func RegisterRoutes(m *macaron.Macaron) {
 				m.Combo("/:username").Get(org.IsMember).
 					Delete(reqToken(), reqOrgOwnership(), org.DeleteMember)
 			})
+			m.Get("/member_emails", reqToken(), reqOrgMembership(), org.ListMemberEmails)
 			m.Group("/public_members", func() {
 				m.Get("", org.ListPublicMembers)
 				m.Combo("/:username").Get(org.IsPublicMember).
@@ -877,6 +896,11 @@ func RegisterRoutes(m *macaron.Macaron) {
 					m.Post("/repos", bind(api.CreateRepoOption{}), admin.CreateRepo)
 				})
 			})
+			m.Group("/tasks", func() {
+				m.Post("/pause", admin.PauseTaskQueue)
+				m.Post("/resume", admin.ResumeTaskQueue)
+				m.Post("/:id/retry", admin.RetryTask)
+			})
 		}, reqToken(), reqSiteAdmin())
 
 		m.Group("/topics", func() {
@@ -64,6 +64,66 @@ func ListMembers(ctx *context.APIContext) {
 	listMembers(ctx, publicOnly)
 }
 
+// ListMemberEmails list the verified email addresses of an organization's members
+func ListMemberEmails(ctx *context.APIContext) {
+	// swagger:operation GET /orgs/{org}/member_emails organization orgListMemberEmails
+	// ---
+	// summary: List the verified email addresses of an organization's members
+	// description: Members who keep their email address private are only listed for organization owners.
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: org
+	//   in: path
+	//   description: name of the organization
+	//   type: string
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/MemberEmailsList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+
+	members, _, err := models.FindOrgMembers(models.FindOrgMembersOpts{
+		OrgID: ctx.Org.Organization.ID,
+	})
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindOrgMembers", err)
+		return
+	}
+
+	emails, err := models.GetOrgMemberEmails(ctx.Org.Organization.ID, true)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetOrgMemberEmails", err)
+		return
+	}
+
+	isOwner := ctx.User.IsAdmin
+	if !isOwner {
+		isOwner, err = ctx.Org.Organization.IsOwnedBy(ctx.User.ID)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "IsOwnedBy", err)
+			return
+		}
+	}
+
+	apiMembers := make([]*api.MemberEmails, 0, len(members))
+	for _, member := range members {
+		if member.KeepEmailPrivate && !isOwner && member.ID != ctx.User.ID {
+			continue
+		}
+		apiEmails := make([]*api.Email, len(emails[member.ID]))
+		for i, email := range emails[member.ID] {
+			apiEmails[i] = convert.ToEmail(email)
+		}
+		apiMembers = append(apiMembers, &api.MemberEmails{
+			Username: member.Name,
+			Emails:   apiEmails,
+		})
+	}
+	ctx.JSON(http.StatusOK, apiMembers)
+}
+
 // ListPublicMembers list an organization's public members
 func ListPublicMembers(ctx *context.APIContext) {
 	// swagger:operation GET /orgs/{org}/public_members organization orgListPublicMembers
@@ -8,6 +8,7 @@ package repo
 import (
 	"encoding/base64"
 	"net/http"
+	"strings"
 	"time"
 
 	"code.gitea.io/gitea/models"
@@ -329,6 +330,117 @@ func createOrUpdateFile(ctx *context.APIContext, opts *repofiles.UpdateRepoFileO
 	return repofiles.CreateOrUpdateRepoFile(ctx.Repo.Repository, ctx.User, opts)
 }
 
+// ChangeFiles handles API call for creating, updating and deleting multiple files in a single commit
+func ChangeFiles(ctx *context.APIContext, apiOpts api.ChangeFilesOptions) {
+	// swagger:operation POST /repos/{owner}/{repo}/contents repository repoChangeFiles
+	// ---
+	// summary: Create, update or delete multiple files in a repository in a single commit
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   required: true
+	//   schema:
+	//     "$ref": "#/definitions/ChangeFilesOptions"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/FilesResponse"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "409":
+	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	if !CanWriteFiles(ctx.Repo) {
+		ctx.Error(http.StatusForbidden, "ChangeFiles", models.ErrUserDoesNotHaveAccessToRepo{
+			UserID:   ctx.User.ID,
+			RepoName: ctx.Repo.Repository.LowerName,
+		})
+		return
+	}
+
+	files := make([]*repofiles.ChangeRepoFile, 0, len(apiOpts.Files))
+	for _, file := range apiOpts.Files {
+		content, err := base64.StdEncoding.DecodeString(file.Content)
+		if err != nil {
+			ctx.Error(http.StatusUnprocessableEntity, "DecodeString", err)
+			return
+		}
+		files = append(files, &repofiles.ChangeRepoFile{
+			Operation: file.Operation,
+			TreePath:  file.Path,
+			Content:   string(content),
+			SHA:       file.SHA,
+		})
+	}
+
+	opts := &repofiles.ChangeFilesOptions{
+		Files:     files,
+		Message:   apiOpts.Message,
+		OldBranch: apiOpts.BranchName,
+		NewBranch: apiOpts.NewBranchName,
+		Committer: &repofiles.IdentityOptions{
+			Name:  apiOpts.Committer.Name,
+			Email: apiOpts.Committer.Email,
+		},
+		Author: &repofiles.IdentityOptions{
+			Name:  apiOpts.Author.Name,
+			Email: apiOpts.Author.Email,
+		},
+		Dates: &repofiles.CommitDateOptions{
+			Author:    apiOpts.Dates.Author,
+			Committer: apiOpts.Dates.Committer,
+		},
+	}
+	if opts.Dates.Author.IsZero() {
+		opts.Dates.Author = time.Now()
+	}
+	if opts.Dates.Committer.IsZero() {
+		opts.Dates.Committer = time.Now()
+	}
+
+	if opts.Message == "" {
+		paths := make([]string, 0, len(files))
+		for _, file := range files {
+			paths = append(paths, file.TreePath)
+		}
+		opts.Message = ctx.Tr("repo.editor.update", strings.Join(paths, ", "))
+	}
+
+	filesResponse, err := repofiles.ChangeRepoFiles(ctx.Repo.Repository, ctx.User, opts)
+	if err != nil {
+		switch {
+		case models.IsErrUserCannotCommit(err):
+			ctx.Error(http.StatusForbidden, "ChangeRepoFiles", err)
+		case models.IsErrSHADoesNotMatch(err), models.IsErrCommitIDDoesNotMatch(err):
+			ctx.Error(http.StatusConflict, "ChangeRepoFiles", err)
+		case models.IsErrRepoFilePathDuplicated(err), models.IsErrFilenameInvalid(err),
+			models.IsErrFilePathInvalid(err), models.IsErrRepoFileAlreadyExists(err),
+			models.IsErrRepoFileDoesNotExist(err), models.IsErrBranchAlreadyExists(err),
+			models.IsErrSHANotProvided(err):
+			ctx.Error(http.StatusUnprocessableEntity, "ChangeRepoFiles", err)
+		default:
+			ctx.Error(http.StatusInternalServerError, "ChangeRepoFiles", err)
+		}
+		return
+	}
+	ctx.JSON(http.StatusCreated, filesResponse)
+}
+
 // DeleteFile Delete a fle in a repository
 func DeleteFile(ctx *context.APIContext, apiOpts api.DeleteFileOptions) {
 	// swagger:operation DELETE /repos/{owner}/{repo}/contents/{filepath} repository repoDeleteFile
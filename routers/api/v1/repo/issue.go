@@ -144,6 +144,10 @@ func SearchIssues(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "Issues", err)
 		return
 	}
+	if err = models.IssueList(issues).LoadReactionTotals(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadReactionTotals", err)
+		return
+	}
 
 	apiIssues := make([]*api.Issue, len(issues))
 	for i := range issues {
@@ -240,6 +244,10 @@ func ListIssues(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "Issues", err)
 		return
 	}
+	if err = models.IssueList(issues).LoadReactionTotals(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadReactionTotals", err)
+		return
+	}
 
 	apiIssues := make([]*api.Issue, len(issues))
 	for i := range issues {
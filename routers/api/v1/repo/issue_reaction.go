@@ -10,9 +10,63 @@ import (
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/notification"
 	api "code.gitea.io/gitea/modules/structs"
 )
 
+// getIssueCommentByParams returns the comment given by the :id parameter
+// if it belongs to an issue of the current repository
+func getIssueCommentByParams(ctx *context.APIContext) *models.Comment {
+	comment, err := models.GetCommentByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrCommentNotExist(err) {
+			ctx.NotFound(err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetCommentByID", err)
+		}
+		return nil
+	}
+
+	if err := comment.LoadIssue(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "comment.LoadIssue()", err)
+		return nil
+	}
+
+	if comment.Issue.RepoID != ctx.Repo.Repository.ID {
+		ctx.NotFound()
+		return nil
+	}
+	return comment
+}
+
+// checkCanReadReactions responds with an error and returns false
+// if the user is not allowed to see reactions in the current repository
+func checkCanReadReactions(ctx *context.APIContext, name string) bool {
+	canRead, err := models.CanReadReactions(ctx.User, ctx.Repo.Repository)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "CanReadReactions", err)
+		return false
+	} else if !canRead {
+		ctx.Error(http.StatusForbidden, name, errors.New("no permission to get reactions"))
+		return false
+	}
+	return true
+}
+
+// checkCanReactOn responds with an error and returns false
+// if the user is not allowed to change reactions on the issue
+func checkCanReactOn(ctx *context.APIContext, issue *models.Issue, name string) bool {
+	canReact, err := models.CanReactOn(ctx.User, issue)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "CanReactOn", err)
+		return false
+	} else if !canReact {
+		ctx.Error(http.StatusForbidden, name, errors.New("no permission to change reaction"))
+		return false
+	}
+	return true
+}
+
 // GetIssueCommentReactions list reactions of a issue comment
 func GetIssueCommentReactions(ctx *context.APIContext) {
 	// swagger:operation GET /repos/{owner}/{repo}/issues/comments/{id}/reactions issue issueGetCommentReactions
@@ -45,31 +99,20 @@ func GetIssueCommentReactions(ctx *context.APIContext) {
 	//   "403":
 	//     "$ref": "#/responses/forbidden"
 
-	comment, err := models.GetCommentByID(ctx.ParamsInt64(":id"))
-	if err != nil {
-		if models.IsErrCommentNotExist(err) {
-			ctx.NotFound(err)
-		} else {
-			ctx.Error(http.StatusInternalServerError, "GetCommentByID", err)
-		}
+	comment := getIssueCommentByParams(ctx)
+	if ctx.Written() {
 		return
 	}
 
-	if !ctx.Repo.CanRead(models.UnitTypeIssues) && !ctx.User.IsAdmin {
-		ctx.Error(http.StatusForbidden, "GetIssueCommentReactions", errors.New("no permission to get reactions"))
+	if !checkCanReadReactions(ctx, "GetIssueCommentReactions") {
 		return
 	}
 
-	reactions, err := models.FindCommentReactions(comment)
+	reactions, err := models.FindCommentReactions(comment, ctx.User)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, "FindIssueReactions", err)
 		return
 	}
-	_, err = reactions.LoadUsers()
-	if err != nil {
-		ctx.Error(http.StatusInternalServerError, "ReactionList.LoadUsers()", err)
-		return
-	}
 
 	var result []api.ReactionResponse
 	for _, r := range reactions {
@@ -118,6 +161,8 @@ func PostIssueCommentReaction(ctx *context.APIContext, form api.EditReactionOpti
 	//     "$ref": "#/responses/ReactionResponse"
 	//   "403":
 	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 
 	changeIssueCommentReaction(ctx, form, true)
 }
@@ -162,29 +207,30 @@ func DeleteIssueCommentReaction(ctx *context.APIContext, form api.EditReactionOp
 }
 
 func changeIssueCommentReaction(ctx *context.APIContext, form api.EditReactionOption, isCreateType bool) {
-	comment, err := models.GetCommentByID(ctx.ParamsInt64(":id"))
-	if err != nil {
-		if models.IsErrCommentNotExist(err) {
-			ctx.NotFound(err)
-		} else {
-			ctx.Error(http.StatusInternalServerError, "GetCommentByID", err)
-		}
+	comment := getIssueCommentByParams(ctx)
+	if ctx.Written() {
 		return
 	}
 
-	err = comment.LoadIssue()
-	if err != nil {
-		ctx.Error(http.StatusInternalServerError, "comment.LoadIssue() failed", err)
+	if !checkCanReactOn(ctx, comment.Issue, "ChangeIssueCommentReaction") {
+		return
 	}
 
-	if comment.Issue.IsLocked && !ctx.Repo.CanWrite(models.UnitTypeIssues) && !ctx.User.IsAdmin {
-		ctx.Error(http.StatusForbidden, "ChangeIssueCommentReaction", errors.New("no permission to change reaction"))
+	// Reactions which are no longer allowed can still be removed by their users
+	content, ok := models.NormalizeReactionContent(form.Reaction)
+	if isCreateType && !ok {
+		ctx.Error(http.StatusUnprocessableEntity, "NormalizeReactionContent", models.ErrForbiddenIssueReaction{Reaction: form.Reaction})
+		return
+	}
+
+	if isCreateType && !ctx.Repo.Repository.IsReactionAllowed(content) {
+		ctx.Error(http.StatusUnprocessableEntity, "IsReactionAllowed", models.ErrForbiddenIssueReaction{Reaction: form.Reaction})
 		return
 	}
 
 	if isCreateType {
 		// PostIssueCommentReaction part
-		reaction, err := models.CreateCommentReaction(ctx.User, comment.Issue, comment, form.Reaction)
+		reaction, err := models.CreateCommentReaction(ctx.User, comment.Issue, comment, content)
 		if err != nil {
 			if models.IsErrForbiddenIssueReaction(err) {
 				ctx.Error(http.StatusForbidden, err.Error(), err)
@@ -193,6 +239,8 @@ func changeIssueCommentReaction(ctx *context.APIContext, form api.EditReactionOp
 			}
 			return
 		}
+		notification.NotifyIssueReaction(ctx.User, comment.Issue, comment, reaction)
+
 		_, err = reaction.LoadUser()
 		if err != nil {
 			ctx.Error(http.StatusInternalServerError, "Reaction.LoadUser()", err)
@@ -206,8 +254,7 @@ func changeIssueCommentReaction(ctx *context.APIContext, form api.EditReactionOp
 		})
 	} else {
 		// DeleteIssueCommentReaction part
-		err = models.DeleteCommentReaction(ctx.User, comment.Issue, comment, form.Reaction)
-		if err != nil {
+		if err := models.DeleteCommentReaction(ctx.User, comment.Issue, comment, content); err != nil {
 			ctx.Error(http.StatusInternalServerError, "DeleteCommentReaction", err)
 			return
 		}
@@ -258,21 +305,15 @@ func GetIssueReactions(ctx *context.APIContext) {
 		return
 	}
 
-	if !ctx.Repo.CanRead(models.UnitTypeIssues) && !ctx.User.IsAdmin {
-		ctx.Error(http.StatusForbidden, "GetIssueReactions", errors.New("no permission to get reactions"))
+	if !checkCanReadReactions(ctx, "GetIssueReactions") {
 		return
 	}
 
-	reactions, err := models.FindIssueReactions(issue)
+	reactions, err := models.FindIssueReactions(issue, ctx.User)
 	if err != nil {
 		ctx.Error(http.StatusInternalServerError, "FindIssueReactions", err)
 		return
 	}
-	_, err = reactions.LoadUsers()
-	if err != nil {
-		ctx.Error(http.StatusInternalServerError, "ReactionList.LoadUsers()", err)
-		return
-	}
 
 	var result []api.ReactionResponse
 	for _, r := range reactions {
@@ -286,6 +327,61 @@ func GetIssueReactions(ctx *context.APIContext) {
 	ctx.JSON(http.StatusOK, result)
 }
 
+// ExportIssueReactions lists every stored reaction of an issue for exporting it
+func ExportIssueReactions(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/issues/{index}/reactions/export issue issueExportIssueReactions
+	// ---
+	// summary: Export all reactions of an issue, including reaction types which are not allowed anymore and private reaction users
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the issue
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ReactionDumpList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	issue, err := models.GetIssueByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrIssueNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetIssueByIndex", err)
+		}
+		return
+	}
+
+	dumps, err := issue.DumpReactions()
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "DumpReactions", err)
+		return
+	}
+
+	result := make([]*api.ReactionDump, len(dumps))
+	for i, dump := range dumps {
+		result[i] = dump.APIFormat()
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
 // PostIssueReaction add a reaction to a comment of a issue
 func PostIssueReaction(ctx *context.APIContext, form api.EditReactionOption) {
 	// swagger:operation POST /repos/{owner}/{repo}/issues/{index}/reactions issue issuePostIssueReaction
@@ -321,6 +417,8 @@ func PostIssueReaction(ctx *context.APIContext, form api.EditReactionOption) {
 	//     "$ref": "#/responses/ReactionResponse"
 	//   "403":
 	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 
 	changeIssueReaction(ctx, form, true)
 }
@@ -375,14 +473,25 @@ func changeIssueReaction(ctx *context.APIContext, form api.EditReactionOption, i
 		return
 	}
 
-	if issue.IsLocked && !ctx.Repo.CanWrite(models.UnitTypeIssues) && !ctx.User.IsAdmin {
-		ctx.Error(http.StatusForbidden, "ChangeIssueCommentReaction", errors.New("no permission to change reaction"))
+	if !checkCanReactOn(ctx, issue, "ChangeIssueReaction") {
+		return
+	}
+
+	// Reactions which are no longer allowed can still be removed by their users
+	content, ok := models.NormalizeReactionContent(form.Reaction)
+	if isCreateType && !ok {
+		ctx.Error(http.StatusUnprocessableEntity, "NormalizeReactionContent", models.ErrForbiddenIssueReaction{Reaction: form.Reaction})
+		return
+	}
+
+	if isCreateType && !ctx.Repo.Repository.IsReactionAllowed(content) {
+		ctx.Error(http.StatusUnprocessableEntity, "IsReactionAllowed", models.ErrForbiddenIssueReaction{Reaction: form.Reaction})
 		return
 	}
 
 	if isCreateType {
 		// PostIssueReaction part
-		reaction, err := models.CreateIssueReaction(ctx.User, issue, form.Reaction)
+		reaction, err := models.CreateIssueReaction(ctx.User, issue, content)
 		if err != nil {
 			if models.IsErrForbiddenIssueReaction(err) {
 				ctx.Error(http.StatusForbidden, err.Error(), err)
@@ -391,6 +500,8 @@ func changeIssueReaction(ctx *context.APIContext, form api.EditReactionOption, i
 			}
 			return
 		}
+		notification.NotifyIssueReaction(ctx.User, issue, nil, reaction)
+
 		_, err = reaction.LoadUser()
 		if err != nil {
 			ctx.Error(http.StatusInternalServerError, "Reaction.LoadUser()", err)
@@ -404,7 +515,7 @@ func changeIssueReaction(ctx *context.APIContext, form api.EditReactionOption, i
 		})
 	} else {
 		// DeleteIssueReaction part
-		err = models.DeleteIssueReaction(ctx.User, issue, form.Reaction)
+		err = models.DeleteIssueReaction(ctx.User, issue, content)
 		if err != nil {
 			ctx.Error(http.StatusInternalServerError, "DeleteIssueReaction", err)
 			return
@@ -413,3 +524,77 @@ func changeIssueReaction(ctx *context.APIContext, form api.EditReactionOption, i
 		ctx.Status(http.StatusOK)
 	}
 }
+
+// SetIssueReactions replaces the reactions of the authenticated user on an issue
+func SetIssueReactions(ctx *context.APIContext, form api.EditReactionsOption) {
+	// swagger:operation PUT /repos/{owner}/{repo}/issues/{index}/reactions/mine issue issueSetIssueReactions
+	// ---
+	// summary: Replace the reactions of the authenticated user on an issue
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the issue
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditReactionsOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ReactionResponseList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	issue, err := models.GetIssueWithAttrsByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrIssueNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetIssueByIndex", err)
+		}
+		return
+	}
+
+	if !checkCanReactOn(ctx, issue, "SetIssueReactions") {
+		return
+	}
+
+	reactions, err := models.SetIssueReactions(ctx.User, issue, form.Reactions)
+	if err != nil {
+		if models.IsErrForbiddenIssueReaction(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "SetIssueReactions", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "SetIssueReactions", err)
+		}
+		return
+	}
+
+	result := make([]api.ReactionResponse, 0, len(reactions))
+	for _, r := range reactions {
+		result = append(result, api.ReactionResponse{
+			User:     ctx.User.APIFormat(),
+			Reaction: r.Type,
+			Created:  r.CreatedUnix.AsTime(),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
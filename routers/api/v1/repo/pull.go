@@ -13,6 +13,7 @@ import (
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/auth"
 	"code.gitea.io/gitea/modules/context"
+	"code.gitea.io/gitea/modules/convert"
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
@@ -83,13 +84,26 @@ func ListPullRequests(ctx *context.APIContext, form api.ListPullRequestsOptions)
 		ctx.Error(http.StatusInternalServerError, "PullRequests", err)
 		return
 	}
+	if err = models.PullRequestList(prs).LoadUnresolvedReviewThreads(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadUnresolvedReviewThreads", err)
+		return
+	}
 
-	apiPrs := make([]*api.PullRequest, len(prs))
+	issues := make(models.IssueList, len(prs))
 	for i := range prs {
 		if err = prs[i].LoadIssue(); err != nil {
 			ctx.Error(http.StatusInternalServerError, "LoadIssue", err)
 			return
 		}
+		issues[i] = prs[i].Issue
+	}
+	if err = issues.LoadReactionTotals(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "LoadReactionTotals", err)
+		return
+	}
+
+	apiPrs := make([]*api.PullRequest, len(prs))
+	for i := range prs {
 		if err = prs[i].LoadAttributes(); err != nil {
 			ctx.Error(http.StatusInternalServerError, "LoadAttributes", err)
 			return
@@ -102,7 +116,7 @@ func ListPullRequests(ctx *context.APIContext, form api.ListPullRequestsOptions)
 			ctx.Error(http.StatusInternalServerError, "GetHeadRepo", err)
 			return
 		}
-		apiPrs[i] = prs[i].APIFormat()
+		apiPrs[i] = prs[i].APIFormatForDoer(ctx.User)
 	}
 
 	ctx.SetLinkHeader(int(maxResults), models.ItemsPerPage)
@@ -133,6 +147,10 @@ func GetPullRequest(ctx *context.APIContext) {
 	//   type: integer
 	//   format: int64
 	//   required: true
+	// - name: deployments
+	//   in: query
+	//   description: include the latest deployment per environment
+	//   type: boolean
 	// responses:
 	//   "200":
 	//     "$ref": "#/responses/PullRequest"
@@ -155,7 +173,11 @@ func GetPullRequest(ctx *context.APIContext) {
 		ctx.Error(http.StatusInternalServerError, "GetHeadRepo", err)
 		return
 	}
-	ctx.JSON(http.StatusOK, pr.APIFormat())
+	if ctx.QueryBool("deployments") {
+		ctx.JSON(http.StatusOK, pr.APIFormatWithDeployments(ctx.User))
+		return
+	}
+	ctx.JSON(http.StatusOK, pr.APIFormatForDoer(ctx.User))
 }
 
 // CreatePullRequest does what it says
@@ -319,7 +341,7 @@ func CreatePullRequest(ctx *context.APIContext, form api.CreatePullRequestOption
 	notification.NotifyNewPullRequest(pr)
 
 	log.Trace("Pull request created: %d/%d", repo.ID, prIssue.ID)
-	ctx.JSON(http.StatusCreated, pr.APIFormat())
+	ctx.JSON(http.StatusCreated, pr.APIFormatForDoer(ctx.User))
 }
 
 // EditPullRequest does what it says
@@ -477,7 +499,284 @@ func EditPullRequest(ctx *context.APIContext, form api.EditPullRequestOption) {
 	}
 
 	// TODO this should be 200, not 201
-	ctx.JSON(http.StatusCreated, pr.APIFormat())
+	ctx.JSON(http.StatusCreated, pr.APIFormatForDoer(ctx.User))
+}
+
+// ListPullReviewThreads lists the review comments of a pull request grouped by file and line
+func ListPullReviewThreads(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/{index}/threads repository repoListPullReviewThreads
+	// ---
+	// summary: List the review comments of a pull request grouped by file and line
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullReviewThreadList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	threads, err := pr.GetReviewThreads()
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetReviewThreads", err)
+		return
+	}
+
+	apiThreads := make([]*api.PullReviewThread, 0, len(threads))
+	for _, thread := range threads {
+		apiThreads = append(apiThreads, thread.APIFormat())
+	}
+	ctx.JSON(http.StatusOK, apiThreads)
+}
+
+// GetPullRequestMergeStyles returns the merge styles the current user can merge a pull request with
+func GetPullRequestMergeStyles(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/{index}/merge-styles repository repoGetPullRequestMergeStyles
+	// ---
+	// summary: Get the merge styles the pull request can be merged with by the current user
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullRequestMergeStyles"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	styles, err := pull_service.AvailableMergeStyles(pr, ctx.User)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "AvailableMergeStyles", err)
+		return
+	}
+
+	result := &api.PullRequestMergeStyles{
+		Styles: make([]string, 0, len(styles)),
+	}
+	for _, style := range styles {
+		result.Styles = append(result.Styles, string(style))
+	}
+	if len(styles) > 0 {
+		result.Default = string(styles[0])
+		if prUnit, err := pr.BaseRepo.GetUnit(models.UnitTypePullRequests); err == nil {
+			defaultStyle := prUnit.PullRequestsConfig().GetDefaultMergeStyle()
+			for _, style := range styles {
+				if style == defaultStyle {
+					result.Default = string(style)
+				}
+			}
+		}
+	}
+	ctx.JSON(http.StatusOK, result)
+}
+
+// SetPullRequestMergeHold puts a pull request on hold so it can not be merged
+func SetPullRequestMergeHold(ctx *context.APIContext, form api.MergeHoldOption) {
+	// swagger:operation PUT /repos/{owner}/{repo}/pulls/{index}/hold repository repoSetPullRequestMergeHold
+	// ---
+	// summary: Put a pull request on hold so it can not be merged, e.g. while an external CI is running
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/MergeHoldOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullRequest"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	changeMergeHold(ctx, func(pr *models.PullRequest) error {
+		return pull_service.SetMergeHold(pr, ctx.User, strings.TrimSpace(form.Reason))
+	})
+}
+
+// ClearPullRequestMergeHold releases the hold of a pull request
+func ClearPullRequestMergeHold(ctx *context.APIContext) {
+	// swagger:operation DELETE /repos/{owner}/{repo}/pulls/{index}/hold repository repoClearPullRequestMergeHold
+	// ---
+	// summary: Release the hold of a pull request
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullRequest"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	changeMergeHold(ctx, func(pr *models.PullRequest) error {
+		return pull_service.ClearMergeHold(pr, ctx.User)
+	})
+}
+
+func changeMergeHold(ctx *context.APIContext, change func(pr *models.PullRequest) error) {
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	if err = change(pr); err != nil {
+		if models.IsErrUserDoesNotHaveAccessToRepo(err) {
+			ctx.Error(http.StatusForbidden, "MergeHold", err)
+			return
+		}
+		ctx.Error(http.StatusInternalServerError, "MergeHold", err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, pr.APIFormatForDoer(ctx.User))
+}
+
+// RetargetPullRequests changes the base branch of all open pull requests targeting a branch
+func RetargetPullRequests(ctx *context.APIContext, form api.RetargetPullRequestsOption) {
+	// swagger:operation POST /repos/{owner}/{repo}/pulls/retarget repository repoRetargetPullRequests
+	// ---
+	// summary: Change the base branch of all open pull requests targeting a branch, e.g. after it was deleted
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/RetargetPullRequestsOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/RetargetPullRequestsResult"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	retargeted, skipped, err := pull_service.RetargetBranchPullRequests(ctx.Repo.Repository.ID, form.OldBase, form.NewBase, ctx.User)
+	if err != nil {
+		if git.IsErrBranchNotExist(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "RetargetBranchPullRequests", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "RetargetBranchPullRequests", err)
+		}
+		return
+	}
+
+	result := &api.RetargetPullRequestsResult{
+		Retargeted: retargeted,
+		Skipped:    make([]*api.SkippedPullRequest, 0, len(skipped)),
+	}
+	for _, s := range skipped {
+		result.Skipped = append(result.Skipped, &api.SkippedPullRequest{
+			Index:  s.PullRequest.Index,
+			Reason: s.Reason,
+		})
+	}
+	ctx.JSON(http.StatusOK, result)
 }
 
 // IsPullRequestMerged checks if a PR exists given an index
@@ -526,6 +825,64 @@ func IsPullRequestMerged(ctx *context.APIContext) {
 	ctx.NotFound()
 }
 
+// GetPullRequestMergedCommit gets the commit a pull request has been merged with
+func GetPullRequestMergedCommit(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/{index}/merged-commit repository repoGetPullRequestMergedCommit
+	// ---
+	// summary: Get the commit a pull request has been merged with
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/Commit"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	commit, err := pr.GetMergedCommit()
+	if err != nil {
+		if models.IsErrPullRequestNotMerged(err) || models.IsErrPullRequestMergedCommitNotExist(err) {
+			ctx.NotFound(err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetMergedCommit", err)
+		}
+		return
+	}
+
+	json, err := convert.ToAPICommit(ctx.Repo.Repository, commit, nil)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "ToAPICommit", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, json)
+}
+
 // MergePullRequest merges a PR given an index
 func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 	// swagger:operation POST /repos/{owner}/{repo}/pulls/{index}/merge repository repoMergePullRequest
@@ -561,6 +918,8 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 	//     "$ref": "#/responses/empty"
 	//   "409":
 	//     "$ref": "#/responses/error"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
 
 	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
 	if err != nil {
@@ -597,34 +956,39 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 		return
 	}
 
-	if !pr.CanAutoMerge() || pr.HasMerged || pr.IsWorkInProgress() {
-		ctx.Status(http.StatusMethodNotAllowed)
+	if pr.IsMergeOnHold {
+		ctx.Error(http.StatusMethodNotAllowed, "MergeOnHold", models.ErrMergeOnHold{ID: pr.ID, Reason: pr.MergeHoldReason})
 		return
 	}
 
-	isPass, err := pull_service.IsPullCommitStatusPass(pr)
-	if err != nil {
-		ctx.Error(http.StatusInternalServerError, "IsPullCommitStatusPass", err)
+	if !pr.CanAutoMerge() || pr.HasMerged || pr.IsWorkInProgress() {
+		ctx.Status(http.StatusMethodNotAllowed)
 		return
 	}
 
-	if !isPass && !ctx.IsUserRepoAdmin() {
-		ctx.Status(http.StatusMethodNotAllowed)
-		return
+	if err := pull_service.CheckPullCommitStatus(pr); err != nil {
+		if !models.IsErrRequiredStatusMissing(err) {
+			ctx.Error(http.StatusInternalServerError, "CheckPullCommitStatus", err)
+			return
+		}
+		if !ctx.IsUserRepoAdmin() {
+			ctx.Error(http.StatusMethodNotAllowed, "RequiredStatusMissing", err)
+			return
+		}
 	}
 
 	if len(form.Do) == 0 {
-		form.Do = string(models.MergeStyleMerge)
+		prUnit, err := ctx.Repo.Repository.GetUnit(models.UnitTypePullRequests)
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "GetUnit", err)
+			return
+		}
+		form.Do = string(prUnit.PullRequestsConfig().GetDefaultMergeStyle())
 	}
 
 	message := strings.TrimSpace(form.MergeTitleField)
 	if len(message) == 0 {
-		if models.MergeStyle(form.Do) == models.MergeStyleMerge {
-			message = pr.GetDefaultMergeMessage()
-		}
-		if models.MergeStyle(form.Do) == models.MergeStyleSquash {
-			message = pr.GetDefaultSquashMessage()
-		}
+		message = pr.GetDefaultMessage(models.MergeStyle(form.Do))
 	}
 
 	form.MergeMessageField = strings.TrimSpace(form.MergeMessageField)
@@ -632,10 +996,32 @@ func MergePullRequest(ctx *context.APIContext, form auth.MergePullRequestForm) {
 		message += "\n\n" + form.MergeMessageField
 	}
 
-	if err := pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message); err != nil {
-		if models.IsErrInvalidMergeStyle(err) {
+	if err := pull_service.MergeWithOptions(pr, ctx.User, ctx.Repo.GitRepo, &pull_service.MergeOptions{
+		Style:                      models.MergeStyle(form.Do),
+		Message:                    message,
+		UpToCommit:                 strings.TrimSpace(form.MergeUpToCommit),
+		DeleteHeadBranchAfterMerge: form.DeleteBranchAfterMerge,
+		SquashKeepAuthor:           form.SquashKeepAuthor == nil || *form.SquashKeepAuthor,
+		AllowEmpty:                 form.AllowEmptyMerge,
+		Force:                      form.ForceMerge,
+		ForceReason:                form.ForceMergeReason,
+		Trailers:                   form.Trailers,
+		PreserveCommitterDate:      form.PreserveCommitterDate,
+	}); err != nil {
+		if models.IsErrHeadBranchDeletionFailed(err) {
+			// The pull request has been merged, only the branch deletion failed
+			log.Warn("Pull request merged: %d, %v", pr.ID, err)
+			ctx.Status(http.StatusOK)
+			return
+		} else if models.IsErrInvalidMergeStyle(err) {
 			ctx.Status(http.StatusMethodNotAllowed)
 			return
+		} else if models.IsErrMergeOnHold(err) || models.IsErrProtectedFilesChanged(err) || models.IsErrNotAllowedToMerge(err) || models.IsErrLinearHistoryRequired(err) || models.IsErrUnresolvedConversations(err) || models.IsErrCommitAuthorNotAllowed(err) {
+			ctx.Error(http.StatusMethodNotAllowed, "Merge", err)
+			return
+		} else if models.IsErrInvalidMergeUpToCommit(err) || models.IsErrMergeEmptyDiff(err) || models.IsErrInvalidMergeTrailer(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "Merge", err)
+			return
 		} else if models.IsErrMergeConflicts(err) {
 			conflictError := err.(models.ErrMergeConflicts)
 			ctx.JSON(http.StatusConflict, conflictError)
@@ -0,0 +1,134 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// ListPullRequestDeployments lists the deployments of a pull request
+func ListPullRequestDeployments(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/{index}/deployments repository repoListPullRequestDeployments
+	// ---
+	// summary: List the deployment states of a pull request, newest first
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/PullRequestDeploymentList"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	deployments, err := models.GetPullRequestDeployments(pr.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetPullRequestDeployments", err)
+		return
+	}
+
+	apiDeployments := make([]*api.PullRequestDeployment, 0, len(deployments))
+	for _, deployment := range deployments {
+		apiDeployments = append(apiDeployments, deployment.APIFormat())
+	}
+	ctx.JSON(http.StatusOK, apiDeployments)
+}
+
+// CreatePullRequestDeployment records the state of a deployment of a pull request
+func CreatePullRequestDeployment(ctx *context.APIContext, form api.CreatePullRequestDeploymentOption) {
+	// swagger:operation POST /repos/{owner}/{repo}/pulls/{index}/deployments repository repoCreatePullRequestDeployment
+	// ---
+	// summary: Record the state of a deployment of a pull request, e.g. a preview environment created by CI
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: index
+	//   in: path
+	//   description: index of the pull request
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/CreatePullRequestDeploymentOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/PullRequestDeployment"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	pr, err := models.GetPullRequestByIndex(ctx.Repo.Repository.ID, ctx.ParamsInt64(":index"))
+	if err != nil {
+		if models.IsErrPullRequestNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetPullRequestByIndex", err)
+		}
+		return
+	}
+
+	deployment, err := models.CreatePullRequestDeployment(models.CreatePullRequestDeploymentOptions{
+		PullRequest: pr,
+		Creator:     ctx.User,
+		Environment: form.Environment,
+		URL:         form.URL,
+		State:       models.DeploymentState(form.State),
+	})
+	if err != nil {
+		if models.IsErrInvalidDeploymentState(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "CreatePullRequestDeployment", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "CreatePullRequestDeployment", err)
+		}
+		return
+	}
+	ctx.JSON(http.StatusCreated, deployment.APIFormat())
+}
@@ -0,0 +1,238 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package repo
+
+import (
+	"net/http"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/context"
+	api "code.gitea.io/gitea/modules/structs"
+)
+
+// getPullReviewByParams returns the review given by the :id parameter
+// if it belongs to a pull request of the current repository and is visible to the doer
+func getPullReviewByParams(ctx *context.APIContext) *models.Review {
+	review, err := models.GetReviewByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrReviewNotExist(err) {
+			ctx.NotFound(err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetReviewByID", err)
+		}
+		return nil
+	}
+
+	if err := review.LoadAttributes(); err != nil {
+		ctx.Error(http.StatusInternalServerError, "review.LoadAttributes()", err)
+		return nil
+	}
+
+	if review.Issue.RepoID != ctx.Repo.Repository.ID || !review.Issue.IsPull {
+		ctx.NotFound()
+		return nil
+	}
+
+	// Pending reviews are only visible to their reviewer
+	if review.Type == models.ReviewTypePending && (ctx.User == nil || ctx.User.ID != review.ReviewerID) {
+		ctx.NotFound()
+		return nil
+	}
+	return review
+}
+
+// GetPullReviewReactions list reactions of a pull request review
+func GetPullReviewReactions(ctx *context.APIContext) {
+	// swagger:operation GET /repos/{owner}/{repo}/pulls/reviews/{id}/reactions repository repoGetPullReviewReactions
+	// ---
+	// summary: Get a list reactions of a pull request review
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the review
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/ReactionResponseList"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	review := getPullReviewByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if !checkCanReadReactions(ctx, "GetPullReviewReactions") {
+		return
+	}
+
+	reactions, err := models.FindReviewReactions(review, ctx.User)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "FindReviewReactions", err)
+		return
+	}
+
+	var result []api.ReactionResponse
+	for _, r := range reactions {
+		result = append(result, api.ReactionResponse{
+			User:     r.User.APIFormat(),
+			Reaction: r.Type,
+			Created:  r.CreatedUnix.AsTime(),
+		})
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+// PostPullReviewReaction add a reaction to a pull request review
+func PostPullReviewReaction(ctx *context.APIContext, form api.EditReactionOption) {
+	// swagger:operation POST /repos/{owner}/{repo}/pulls/reviews/{id}/reactions repository repoPostPullReviewReaction
+	// ---
+	// summary: Add a reaction to a pull request review
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the review
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: content
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditReactionOption"
+	// responses:
+	//   "201":
+	//     "$ref": "#/responses/ReactionResponse"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	changePullReviewReaction(ctx, form, true)
+}
+
+// DeletePullReviewReaction remove a reaction from a pull request review
+func DeletePullReviewReaction(ctx *context.APIContext, form api.EditReactionOption) {
+	// swagger:operation DELETE /repos/{owner}/{repo}/pulls/reviews/{id}/reactions repository repoDeletePullReviewReaction
+	// ---
+	// summary: Remove a reaction from a pull request review
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo
+	//   type: string
+	//   required: true
+	// - name: id
+	//   in: path
+	//   description: id of the review
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// - name: content
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/EditReactionOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/empty"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	changePullReviewReaction(ctx, form, false)
+}
+
+func changePullReviewReaction(ctx *context.APIContext, form api.EditReactionOption, isCreateType bool) {
+	review := getPullReviewByParams(ctx)
+	if ctx.Written() {
+		return
+	}
+
+	if !checkCanReactOn(ctx, review.Issue, "ChangePullReviewReaction") {
+		return
+	}
+
+	// Reactions which are no longer allowed can still be removed by their users
+	content, ok := models.NormalizeReactionContent(form.Reaction)
+	if isCreateType && !ok {
+		ctx.Error(http.StatusUnprocessableEntity, "NormalizeReactionContent", models.ErrForbiddenIssueReaction{Reaction: form.Reaction})
+		return
+	}
+
+	if isCreateType {
+		// PostPullReviewReaction part
+		reaction, err := models.CreateReviewReaction(ctx.User, review, content)
+		if err != nil {
+			if models.IsErrForbiddenIssueReaction(err) {
+				ctx.Error(http.StatusForbidden, err.Error(), err)
+			} else {
+				ctx.Error(http.StatusInternalServerError, "CreateReviewReaction", err)
+			}
+			return
+		}
+		_, err = reaction.LoadUser()
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "Reaction.LoadUser()", err)
+			return
+		}
+
+		ctx.JSON(http.StatusCreated, api.ReactionResponse{
+			User:     reaction.User.APIFormat(),
+			Reaction: reaction.Type,
+			Created:  reaction.CreatedUnix.AsTime(),
+		})
+	} else {
+		// DeletePullReviewReaction part
+		if err := models.DeleteReviewReaction(ctx.User, review, content); err != nil {
+			ctx.Error(http.StatusInternalServerError, "DeleteReviewReaction", err)
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/auth"
@@ -25,6 +26,7 @@ import (
 	"code.gitea.io/gitea/modules/setting"
 	"code.gitea.io/gitea/modules/structs"
 	api "code.gitea.io/gitea/modules/structs"
+	"code.gitea.io/gitea/modules/task"
 	"code.gitea.io/gitea/modules/util"
 	"code.gitea.io/gitea/modules/validation"
 	mirror_service "code.gitea.io/gitea/services/mirror"
@@ -355,6 +357,8 @@ func Migrate(ctx *context.APIContext, form auth.MigrateRepoForm) {
 	// responses:
 	//   "201":
 	//     "$ref": "#/responses/Repository"
+	//   "202":
+	//     "$ref": "#/responses/MigrateProbe"
 	//   "403":
 	//     "$ref": "#/responses/forbidden"
 	//   "422":
@@ -427,21 +431,24 @@ func Migrate(ctx *context.APIContext, form auth.MigrateRepoForm) {
 	}
 
 	var opts = migrations.MigrateOptions{
-		CloneAddr:      remoteAddr,
-		RepoName:       form.RepoName,
-		Description:    form.Description,
-		Private:        form.Private || setting.Repository.ForcePrivate,
-		Mirror:         form.Mirror,
-		AuthUsername:   form.AuthUsername,
-		AuthPassword:   form.AuthPassword,
-		Wiki:           form.Wiki,
-		Issues:         form.Issues,
-		Milestones:     form.Milestones,
-		Labels:         form.Labels,
-		Comments:       true,
-		PullRequests:   form.PullRequests,
-		Releases:       form.Releases,
-		GitServiceType: gitServiceType,
+		CloneAddr:           remoteAddr,
+		RepoName:            form.RepoName,
+		Description:         form.Description,
+		Private:             form.Private || setting.Repository.ForcePrivate,
+		Mirror:              form.Mirror,
+		AuthUsername:        form.AuthUsername,
+		AuthPassword:        form.AuthPassword,
+		Wiki:                form.Wiki,
+		Issues:              form.Issues,
+		Milestones:          form.Milestones,
+		Labels:              form.Labels,
+		Comments:            true,
+		PullRequests:        form.PullRequests,
+		Releases:            form.Releases,
+		GitServiceType:      gitServiceType,
+		Depth:               form.Depth,
+		DryRun:              form.DryRun,
+		FallbackPRsToIssues: form.FallbackPRsToIssues,
 	}
 	if opts.Mirror {
 		opts.Issues = false
@@ -452,6 +459,21 @@ func Migrate(ctx *context.APIContext, form auth.MigrateRepoForm) {
 		opts.Releases = false
 	}
 
+	if opts.DryRun {
+		t, err := task.ProbeRepository(ctx.User, ctxUser, opts)
+		if err != nil {
+			handleMigrateError(ctx, ctxUser, remoteAddr, err)
+			return
+		}
+		probe, err := t.APIFormatMigrateProbe()
+		if err != nil {
+			ctx.Error(http.StatusInternalServerError, "APIFormatMigrateProbe", err)
+			return
+		}
+		ctx.JSON(http.StatusAccepted, probe)
+		return
+	}
+
 	repo, err := models.CreateRepository(ctx.User, ctxUser, models.CreateRepoOptions{
 		Name:        opts.RepoName,
 		Description: opts.Description,
@@ -499,6 +521,52 @@ func Migrate(ctx *context.APIContext, form auth.MigrateRepoForm) {
 	ctx.JSON(http.StatusCreated, repo.APIFormat(models.AccessModeAdmin))
 }
 
+// GetMigrateProbe returns a dry-run migration
+func GetMigrateProbe(ctx *context.APIContext) {
+	// swagger:operation GET /repos/migrate/probes/{id} repository repoGetMigrateProbe
+	// ---
+	// summary: Get a dry-run migration, which reports what would be migrated once it is finished
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: id
+	//   in: path
+	//   description: id of the dry-run migration
+	//   type: integer
+	//   format: int64
+	//   required: true
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/MigrateProbe"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+
+	t, err := models.GetTaskByID(ctx.ParamsInt64(":id"))
+	if err != nil {
+		if models.IsErrTaskDoesNotExist(err) {
+			ctx.NotFound()
+		} else {
+			ctx.Error(http.StatusInternalServerError, "GetTaskByID", err)
+		}
+		return
+	}
+	if t.DoerID != ctx.User.ID && !ctx.User.IsAdmin {
+		ctx.NotFound()
+		return
+	}
+	if opts, err := t.MigrateConfig(); err != nil || !opts.DryRun {
+		ctx.NotFound()
+		return
+	}
+
+	probe, err := t.APIFormatMigrateProbe()
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "APIFormatMigrateProbe", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, probe)
+}
+
 func handleMigrateError(ctx *context.APIContext, repoOwner *models.User, remoteAddr string, err error) {
 	switch {
 	case models.IsErrRepoAlreadyExist(err):
@@ -776,6 +844,10 @@ func updateRepoUnits(ctx *context.APIContext, opts api.EditRepoOption) error {
 					AllowOnlyContributorsToTrackTime: opts.InternalTracker.AllowOnlyContributorsToTrackTime,
 					EnableDependencies:               opts.InternalTracker.EnableIssueDependencies,
 				}
+				// the allowed reactions are not part of the API options, keep them
+				if unit, err := repo.GetUnit(models.UnitTypeIssues); err == nil {
+					config.AllowedReactions = unit.IssuesConfig().AllowedReactions
+				}
 			} else if unit, err := repo.GetUnit(models.UnitTypeIssues); err != nil {
 				// Unit type doesn't exist so we make a new config file with default values
 				config = &models.IssuesConfig{
@@ -868,6 +940,16 @@ func updateRepoUnits(ctx *context.APIContext, opts api.EditRepoOption) error {
 		if opts.AllowSquash != nil {
 			config.AllowSquash = *opts.AllowSquash
 		}
+		if opts.DefaultMergeStyle != nil {
+			// An empty style resets to the first allowed one
+			style := models.MergeStyle(*opts.DefaultMergeStyle)
+			if len(style) > 0 && !config.IsMergeStyleAllowed(style) {
+				err := fmt.Errorf("default merge style not valid: %s", style)
+				ctx.Error(http.StatusUnprocessableEntity, "", "Invalid or disallowed default merge style")
+				return err
+			}
+			config.DefaultMergeStyle = style
+		}
 
 		units = append(units, models.RepoUnit{
 			RepoID: repo.ID,
@@ -993,3 +1075,65 @@ func MirrorSync(ctx *context.APIContext) {
 
 	ctx.Status(http.StatusOK)
 }
+
+// ConvertToMirror converts a repository created by a migration into a mirror of its source
+func ConvertToMirror(ctx *context.APIContext, form api.ConvertToMirrorOption) {
+	// swagger:operation POST /repos/{owner}/{repo}/convert-to-mirror repository repoConvertToMirror
+	// ---
+	// summary: Convert a migrated repository into a mirror of its source
+	// consumes:
+	// - application/json
+	// produces:
+	// - application/json
+	// parameters:
+	// - name: owner
+	//   in: path
+	//   description: owner of the repo to convert
+	//   type: string
+	//   required: true
+	// - name: repo
+	//   in: path
+	//   description: name of the repo to convert
+	//   type: string
+	//   required: true
+	// - name: body
+	//   in: body
+	//   schema:
+	//     "$ref": "#/definitions/ConvertToMirrorOption"
+	// responses:
+	//   "200":
+	//     "$ref": "#/responses/Repository"
+	//   "403":
+	//     "$ref": "#/responses/forbidden"
+	//   "404":
+	//     "$ref": "#/responses/notFound"
+	//   "422":
+	//     "$ref": "#/responses/validationError"
+
+	interval := setting.Mirror.DefaultInterval
+	if len(form.Interval) > 0 {
+		var err error
+		if interval, err = time.ParseDuration(form.Interval); err != nil {
+			ctx.Error(http.StatusUnprocessableEntity, "ParseDuration", err)
+			return
+		}
+	}
+
+	if err := mirror_service.ConvertMigrationToMirror(ctx.Repo.Repository, interval); err != nil {
+		if models.IsErrTaskDoesNotExist(err) {
+			ctx.NotFound()
+		} else if models.IsErrMigrationNotConvertible(err) {
+			ctx.Error(http.StatusUnprocessableEntity, "ConvertMigrationToMirror", err)
+		} else {
+			ctx.Error(http.StatusInternalServerError, "ConvertMigrationToMirror", err)
+		}
+		return
+	}
+
+	repo, err := models.GetRepositoryByID(ctx.Repo.Repository.ID)
+	if err != nil {
+		ctx.Error(http.StatusInternalServerError, "GetRepositoryByID", err)
+		return
+	}
+	ctx.JSON(http.StatusOK, repo.APIFormat(ctx.Repo.AccessMode))
+}
@@ -119,3 +119,10 @@ type swaggerReactionResponseList struct {
 	// in:body
 	Body []api.ReactionResponse `json:"body"`
 }
+
+// ReactionDumpList
+// swagger:response ReactionDumpList
+type swaggerReactionDumpList struct {
+	// in:body
+	Body []api.ReactionDump `json:"body"`
+}
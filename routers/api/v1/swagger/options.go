@@ -106,6 +106,9 @@ type swaggerParameterBodies struct {
 	// in:body
 	MigrateRepoForm auth.MigrateRepoForm
 
+	// in:body
+	ConvertToMirrorOption api.ConvertToMirrorOption
+
 	// in:body
 	EditAttachmentOptions api.EditAttachmentOptions
 
@@ -118,9 +121,24 @@ type swaggerParameterBodies struct {
 	// in:body
 	DeleteFileOptions api.DeleteFileOptions
 
+	// in:body
+	ChangeFilesOptions api.ChangeFilesOptions
+
 	// in:body
 	CommitDateOptions api.CommitDateOptions
 
 	// in:body
 	RepoTopicOptions api.RepoTopicOptions
+
+	// in:body
+	EditReactionsOption api.EditReactionsOption
+
+	// in:body
+	MergeHoldOption api.MergeHoldOption
+
+	// in:body
+	RetargetPullRequestsOption api.RetargetPullRequestsOption
+
+	// in:body
+	CreatePullRequestDeploymentOption api.CreatePullRequestDeploymentOption
 }
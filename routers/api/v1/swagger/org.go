@@ -22,6 +22,13 @@ type swaggerResponseOrganizationList struct {
 	Body []api.Organization `json:"body"`
 }
 
+// MemberEmailsList
+// swagger:response MemberEmailsList
+type swaggerResponseMemberEmailsList struct {
+	// in:body
+	Body []api.MemberEmails `json:"body"`
+}
+
 // Team
 // swagger:response Team
 type swaggerResponseTeam struct {
@@ -127,6 +127,41 @@ type swaggerResponsePullRequestList struct {
 	Body []api.PullRequest `json:"body"`
 }
 
+// PullReviewThreadList
+// swagger:response PullReviewThreadList
+type swaggerResponsePullReviewThreadList struct {
+	// in:body
+	Body []api.PullReviewThread `json:"body"`
+}
+
+// RetargetPullRequestsResult
+// swagger:response RetargetPullRequestsResult
+type swaggerResponseRetargetPullRequestsResult struct {
+	// in:body
+	Body api.RetargetPullRequestsResult `json:"body"`
+}
+
+// PullRequestDeployment
+// swagger:response PullRequestDeployment
+type swaggerResponsePullRequestDeployment struct {
+	// in:body
+	Body api.PullRequestDeployment `json:"body"`
+}
+
+// PullRequestDeploymentList
+// swagger:response PullRequestDeploymentList
+type swaggerResponsePullRequestDeploymentList struct {
+	// in:body
+	Body []api.PullRequestDeployment `json:"body"`
+}
+
+// PullRequestMergeStyles
+// swagger:response PullRequestMergeStyles
+type swaggerResponsePullRequestMergeStyles struct {
+	// in:body
+	Body api.PullRequestMergeStyles `json:"body"`
+}
+
 // Status
 // swagger:response Status
 type swaggerResponseStatus struct {
@@ -226,6 +261,13 @@ type swaggerFileResponse struct {
 	Body api.FileResponse `json:"body"`
 }
 
+// FilesResponse
+// swagger:response FilesResponse
+type swaggerFilesResponse struct {
+	//in: body
+	Body api.FilesResponse `json:"body"`
+}
+
 // ContentsResponse
 // swagger:response ContentsResponse
 type swaggerContentsResponse struct {
@@ -260,3 +302,10 @@ type swaggerTopicNames struct {
 	//in: body
 	Body api.TopicName `json:"body"`
 }
+
+// MigrateProbe
+// swagger:response MigrateProbe
+type swaggerMigrateProbe struct {
+	//in: body
+	Body api.MigrateProbe `json:"body"`
+}
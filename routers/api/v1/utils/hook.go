@@ -103,15 +103,16 @@ func addHook(ctx *context.APIContext, form *api.CreateHookOption, orgID, repoID
 		HookEvent: &models.HookEvent{
 			ChooseEvents: true,
 			HookEvents: models.HookEvents{
-				Create:       com.IsSliceContainsStr(form.Events, string(models.HookEventCreate)),
-				Delete:       com.IsSliceContainsStr(form.Events, string(models.HookEventDelete)),
-				Fork:         com.IsSliceContainsStr(form.Events, string(models.HookEventFork)),
-				Issues:       com.IsSliceContainsStr(form.Events, string(models.HookEventIssues)),
-				IssueComment: com.IsSliceContainsStr(form.Events, string(models.HookEventIssueComment)),
-				Push:         com.IsSliceContainsStr(form.Events, string(models.HookEventPush)),
-				PullRequest:  com.IsSliceContainsStr(form.Events, string(models.HookEventPullRequest)),
-				Repository:   com.IsSliceContainsStr(form.Events, string(models.HookEventRepository)),
-				Release:      com.IsSliceContainsStr(form.Events, string(models.HookEventRelease)),
+				Create:        com.IsSliceContainsStr(form.Events, string(models.HookEventCreate)),
+				Delete:        com.IsSliceContainsStr(form.Events, string(models.HookEventDelete)),
+				Fork:          com.IsSliceContainsStr(form.Events, string(models.HookEventFork)),
+				Issues:        com.IsSliceContainsStr(form.Events, string(models.HookEventIssues)),
+				IssueComment:  com.IsSliceContainsStr(form.Events, string(models.HookEventIssueComment)),
+				Push:          com.IsSliceContainsStr(form.Events, string(models.HookEventPush)),
+				PullRequest:   com.IsSliceContainsStr(form.Events, string(models.HookEventPullRequest)),
+				Repository:    com.IsSliceContainsStr(form.Events, string(models.HookEventRepository)),
+				Release:       com.IsSliceContainsStr(form.Events, string(models.HookEventRelease)),
+				TaskCompleted: com.IsSliceContainsStr(form.Events, string(models.HookEventTaskCompleted)),
 			},
 			BranchFilter: form.BranchFilter,
 		},
@@ -238,6 +239,7 @@ func editHook(ctx *context.APIContext, form *api.EditHookOption, w *models.Webho
 	w.PullRequest = com.IsSliceContainsStr(form.Events, string(models.HookEventPullRequest))
 	w.Repository = com.IsSliceContainsStr(form.Events, string(models.HookEventRepository))
 	w.Release = com.IsSliceContainsStr(form.Events, string(models.HookEventRelease))
+	w.TaskCompleted = com.IsSliceContainsStr(form.Events, string(models.HookEventTaskCompleted))
 	w.BranchFilter = form.BranchFilter
 
 	if err := w.UpdateEvent(); err != nil {
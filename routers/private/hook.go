@@ -59,22 +59,22 @@ func HookPreReceive(ctx *macaron.Context, opts private.HookOptions) {
 				return
 			}
 
+			env := os.Environ()
+			if opts.GitAlternativeObjectDirectories != "" {
+				env = append(env,
+					private.GitAlternativeObjectDirectories+"="+opts.GitAlternativeObjectDirectories)
+			}
+			if opts.GitObjectDirectory != "" {
+				env = append(env,
+					private.GitObjectDirectory+"="+opts.GitObjectDirectory)
+			}
+			if opts.GitQuarantinePath != "" {
+				env = append(env,
+					private.GitQuarantinePath+"="+opts.GitQuarantinePath)
+			}
+
 			// detect force push
 			if git.EmptySHA != oldCommitID {
-				env := os.Environ()
-				if opts.GitAlternativeObjectDirectories != "" {
-					env = append(env,
-						private.GitAlternativeObjectDirectories+"="+opts.GitAlternativeObjectDirectories)
-				}
-				if opts.GitObjectDirectory != "" {
-					env = append(env,
-						private.GitObjectDirectory+"="+opts.GitObjectDirectory)
-				}
-				if opts.GitQuarantinePath != "" {
-					env = append(env,
-						private.GitQuarantinePath+"="+opts.GitQuarantinePath)
-				}
-
 				output, err := git.NewCommand("rev-list", "--max-count=1", oldCommitID, "^"+newCommitID).RunInDirWithEnv(repo.RepoPath(), env)
 				if err != nil {
 					log.Error("Unable to detect force push between: %s and %s in %-v Error: %v", oldCommitID, newCommitID, repo, err)
@@ -106,6 +106,20 @@ func HookPreReceive(ctx *macaron.Context, opts private.HookOptions) {
 					})
 					return
 				}
+				if opts.IsForceMerge {
+					// the merge service has checked already that the user may override the protection
+					isAdmin, err := isRepoAdmin(repo, opts.UserID)
+					if err != nil {
+						log.Error("Unable to check permission of user %d in %-v Error: %v", opts.UserID, repo, err)
+						ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
+							"err": fmt.Sprintf("Unable to check permission of user %d: %v", opts.UserID, err),
+						})
+						return
+					} else if isAdmin {
+						log.Warn("User %d overrides the protection of branch %s in %-v to merge pr #%d", opts.UserID, branchName, repo, pr.Index)
+						continue
+					}
+				}
 				if !protectBranch.HasEnoughApprovals(pr) {
 					log.Warn("Forbidden: User %d cannot push to protected branch: %s in %-v and pr #%d does not have enough approvals", opts.UserID, branchName, repo, pr.Index)
 					ctx.JSON(http.StatusForbidden, map[string]interface{}{
@@ -120,12 +134,47 @@ func HookPreReceive(ctx *macaron.Context, opts private.HookOptions) {
 				})
 				return
 			}
+
+			// merges of pull requests have their commit authors checked by the merge service
+			if repo.RequireVerifiedCommitAuthors && opts.ProtectedBranchID == 0 {
+				revs := []string{newCommitID, "--not", "--all"}
+				if git.EmptySHA != oldCommitID {
+					revs = []string{oldCommitID + ".." + newCommitID}
+				}
+				if err := repo.CheckCommitAuthors(repo.RepoPath(), env, revs); err != nil {
+					if models.IsErrCommitAuthorNotAllowed(err) {
+						notAllowed := err.(models.ErrCommitAuthorNotAllowed)
+						log.Warn("Forbidden: Commit %s pushed to protected branch: %s in %-v is authored by unverified email %s", notAllowed.CommitID, branchName, repo, notAllowed.Email)
+						ctx.JSON(http.StatusForbidden, map[string]interface{}{
+							"err": fmt.Sprintf("commit %s is authored by %s, which is not a verified email address of an account nor of an allowed domain of protected branch %s", notAllowed.CommitID, notAllowed.Email, branchName),
+						})
+						return
+					}
+					log.Error("Unable to check commit authors between: %s and %s in %-v Error: %v", oldCommitID, newCommitID, repo, err)
+					ctx.JSON(http.StatusInternalServerError, map[string]interface{}{
+						"err": fmt.Sprintf("Unable to check commit authors: %v", err),
+					})
+					return
+				}
+			}
 		}
 	}
 
 	ctx.PlainText(http.StatusOK, []byte("ok"))
 }
 
+func isRepoAdmin(repo *models.Repository, userID int64) (bool, error) {
+	user, err := models.GetUserByID(userID)
+	if err != nil {
+		return false, err
+	}
+	perm, err := models.GetUserRepoPermission(repo, user)
+	if err != nil {
+		return false, err
+	}
+	return perm.IsAdmin(), nil
+}
+
 // HookPostReceive updates services and users
 func HookPostReceive(ctx *macaron.Context, opts private.HookOptions) {
 	ownerName := ctx.Params(":owner")
@@ -16,6 +16,7 @@ import (
 	"code.gitea.io/gitea/modules/git"
 	"code.gitea.io/gitea/modules/lfs"
 	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
 )
 
 // ServeData download file from io.Reader
@@ -32,12 +33,26 @@ func ServeData(ctx *context.Context, name string, reader io.Reader) error {
 	// Google Chrome dislike commas in filenames, so let's change it to a space
 	name = strings.Replace(name, ",", " ", -1)
 
-	if base.IsTextFile(buf) || ctx.QueryBool("render") {
-		ctx.Resp.Header().Set("Content-Type", "text/plain; charset=utf-8")
-	} else if base.IsImageFile(buf) || base.IsPDFFile(buf) {
-		ctx.Resp.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, name))
-	} else {
-		ctx.Resp.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+	contentType := base.DetectContentType(name, buf)
+	if ctx.QueryBool("render") {
+		contentType = "text/plain; charset=utf-8"
+	} else if setting.Repository.ForceSafeRawContentType && base.IsUnsafeContentType(contentType) {
+		if strings.HasPrefix(contentType, "image/svg+xml") {
+			// SVG images are rendered, but scripts must not run in the origin of the site
+			ctx.Resp.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+		} else {
+			contentType = "text/plain; charset=utf-8"
+		}
+	}
+	ctx.Resp.Header().Set("Content-Type", contentType)
+	ctx.Resp.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if !base.IsTextFile(buf) && !ctx.QueryBool("render") {
+		if base.IsImageFile(buf) || base.IsPDFFile(buf) || strings.HasPrefix(contentType, "image/") {
+			ctx.Resp.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, name))
+		} else {
+			ctx.Resp.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+		}
 	}
 
 	_, err := ctx.Resp.Write(buf)
@@ -24,6 +24,7 @@ import (
 	"code.gitea.io/gitea/modules/markup"
 	"code.gitea.io/gitea/modules/markup/markdown"
 	"code.gitea.io/gitea/modules/notification"
+	"code.gitea.io/gitea/modules/references"
 	"code.gitea.io/gitea/modules/setting"
 	api "code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/util"
@@ -619,6 +620,36 @@ func getBranchData(ctx *context.Context, issue *models.Issue) {
 	}
 }
 
+func containsMergeStyle(styles []models.MergeStyle, style models.MergeStyle) bool {
+	for _, s := range styles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+// composeEmailMentionMetas returns the rendering metas of the repository, which resolve the
+// verified email addresses written in the contents of issues and comments to mentions
+func composeEmailMentionMetas(ctx *context.Context, contents ...string) map[string]string {
+	metas := ctx.Repo.Repository.ComposeMetas()
+
+	var emails []string
+	for _, content := range contents {
+		emails = append(emails, references.FindAllEmailMentionsMarkdown(content)...)
+	}
+	if len(emails) == 0 {
+		return metas
+	}
+
+	mentions, err := models.GetEmailMentions(emails)
+	if err != nil {
+		log.Error("GetEmailMentions: %v", err)
+		return metas
+	}
+	return markup.ComposeEmailMentionMetas(metas, mentions)
+}
+
 // ViewIssue render issue view page
 func ViewIssue(ctx *context.Context) {
 	if ctx.Params(":type") == "issues" {
@@ -681,6 +712,10 @@ func ViewIssue(ctx *context.Context) {
 		ctx.ServerError("LoadAttributes", err)
 		return
 	}
+	issue.Reactions.HidePrivateUsers(ctx.User)
+	for _, comment := range issue.Comments {
+		comment.Reactions.HidePrivateUsers(ctx.User)
+	}
 
 	if err = filterXRefComments(ctx, issue); err != nil {
 		ctx.ServerError("filterXRefComments", err)
@@ -707,8 +742,16 @@ func ViewIssue(ctx *context.Context) {
 	}
 	ctx.Data["IssueWatch"] = iw
 
-	issue.RenderedContent = string(markdown.Render([]byte(issue.Content), ctx.Repo.RepoLink,
-		ctx.Repo.Repository.ComposeMetas()))
+	contents := make([]string, 0, len(issue.Comments)+1)
+	contents = append(contents, issue.Content)
+	for _, comment := range issue.Comments {
+		if comment.Type == models.CommentTypeComment {
+			contents = append(contents, comment.Content)
+		}
+	}
+	metas := composeEmailMentionMetas(ctx, contents...)
+
+	issue.RenderedContent = string(markdown.Render([]byte(issue.Content), ctx.Repo.RepoLink, metas))
 
 	repo := ctx.Repo.Repository
 
@@ -829,8 +872,7 @@ func ViewIssue(ctx *context.Context) {
 				return
 			}
 
-			comment.RenderedContent = string(markdown.Render([]byte(comment.Content), ctx.Repo.RepoLink,
-				ctx.Repo.Repository.ComposeMetas()))
+			comment.RenderedContent = string(markdown.Render([]byte(comment.Content), ctx.Repo.RepoLink, metas))
 
 			// Check tag.
 			tag, ok = marked[comment.PosterID]
@@ -935,36 +977,65 @@ func ViewIssue(ctx *context.Context) {
 
 		ctx.Data["AllowMerge"] = ctx.Repo.CanWrite(models.UnitTypeCode)
 		if err := pull.CheckUserAllowedToMerge(ctx.User); err != nil {
-			if !models.IsErrNotAllowedToMerge(err) {
+			if !models.IsErrNotAllowedToMerge(err) && !models.IsErrMergeOnHold(err) && !models.IsErrProtectedFilesChanged(err) {
 				ctx.ServerError("CheckUserAllowedToMerge", err)
 				return
 			}
 			ctx.Data["AllowMerge"] = false
 		}
 
-		// Check correct values and select default
-		if ms, ok := ctx.Data["MergeStyle"].(models.MergeStyle); !ok ||
-			!prConfig.IsMergeStyleAllowed(ms) {
-			if prConfig.AllowMerge {
-				ctx.Data["MergeStyle"] = models.MergeStyleMerge
-			} else if prConfig.AllowRebase {
-				ctx.Data["MergeStyle"] = models.MergeStyleRebase
-			} else if prConfig.AllowRebaseMerge {
-				ctx.Data["MergeStyle"] = models.MergeStyleRebaseMerge
-			} else if prConfig.AllowSquash {
-				ctx.Data["MergeStyle"] = models.MergeStyleSquash
-			} else {
-				ctx.Data["MergeStyle"] = ""
-			}
-		}
 		if err = pull.LoadProtectedBranch(); err != nil {
 			ctx.ServerError("LoadProtectedBranch", err)
 			return
 		}
+
+		// Check correct values and select default
+		styles, err := pull.AllowedMergeStyles()
+		if err != nil {
+			ctx.ServerError("AllowedMergeStyles", err)
+			return
+		}
+		if ms, ok := ctx.Data["MergeStyle"].(models.MergeStyle); !ok || !containsMergeStyle(styles, ms) {
+			ctx.Data["MergeStyle"] = models.MergeStyle("")
+			if defaultStyle := prConfig.GetDefaultMergeStyle(); containsMergeStyle(styles, defaultStyle) {
+				ctx.Data["MergeStyle"] = defaultStyle
+			} else if len(styles) > 0 {
+				ctx.Data["MergeStyle"] = styles[0]
+			}
+		}
+		if prConfig.AllowSquash && !issue.IsClosed {
+			if count, _, err := pull.GetSquashPreview(); err != nil {
+				log.Error("GetSquashPreview[%d]: %v", pull.ID, err)
+			} else if count > 1 {
+				ctx.Data["SquashCommitsCount"] = count
+			}
+		}
 		if pull.ProtectedBranch != nil {
-			cnt := pull.ProtectedBranch.GetGrantedApprovalsCount(pull)
-			ctx.Data["IsBlockedByApprovals"] = pull.ProtectedBranch.RequiredApprovals > 0 && cnt < pull.ProtectedBranch.RequiredApprovals
-			ctx.Data["GrantedApprovals"] = cnt
+			ctx.Data["RequireLinearHistory"] = pull.ProtectedBranch.RequireLinearHistory
+			ctx.Data["IsBlockedByApprovals"] = !pull.HasEnoughApprovals()
+			ctx.Data["GrantedApprovals"] = pull.ProtectedBranch.GetGrantedApprovalsCount(pull)
+			ctx.Data["IsBlockedByChangedProtectedFiles"] = pull.IsBlockedByChangedProtectedFiles()
+			unresolvedConversations, err := pull.CountBlockingUnresolvedConversations()
+			if err != nil {
+				ctx.ServerError("CountBlockingUnresolvedConversations", err)
+				return
+			}
+			ctx.Data["UnresolvedConversations"] = unresolvedConversations
+			ctx.Data["IsBlockedByUnresolvedConversations"] = unresolvedConversations > 0
+		}
+		if !issue.IsClosed {
+			relatedPulls, err := pull.GetRelatedPullRequests()
+			if err != nil {
+				ctx.ServerError("GetRelatedPullRequests", err)
+				return
+			}
+			for _, relatedPull := range relatedPulls {
+				if err = relatedPull.LoadIssue(); err != nil {
+					ctx.ServerError("LoadIssue", err)
+					return
+				}
+			}
+			ctx.Data["RelatedPullRequests"] = relatedPulls
 		}
 		ctx.Data["IsPullBranchDeletable"] = canDelete && pull.HeadRepo != nil && git.IsBranchExist(pull.HeadRepo.RepoPath(), pull.HeadBranch)
 
@@ -1113,7 +1184,7 @@ func UpdateIssueContent(ctx *context.Context) {
 	}
 
 	ctx.JSON(200, map[string]interface{}{
-		"content":     string(markdown.Render([]byte(issue.Content), ctx.Query("context"), ctx.Repo.Repository.ComposeMetas())),
+		"content":     string(markdown.Render([]byte(issue.Content), ctx.Query("context"), composeEmailMentionMetas(ctx, issue.Content))),
 		"attachments": attachmentsHTML(ctx, issue.Attachments),
 	})
 }
@@ -1405,7 +1476,7 @@ func UpdateCommentContent(ctx *context.Context) {
 	}
 
 	ctx.JSON(200, map[string]interface{}{
-		"content":     string(markdown.Render([]byte(comment.Content), ctx.Query("context"), ctx.Repo.Repository.ComposeMetas())),
+		"content":     string(markdown.Render([]byte(comment.Content), ctx.Query("context"), composeEmailMentionMetas(ctx, comment.Content))),
 		"attachments": attachmentsHTML(ctx, comment.Attachments),
 	})
 }
@@ -1485,6 +1556,8 @@ func ChangeIssueReaction(ctx *context.Context, form auth.ReactionForm) {
 			log.Info("CreateIssueReaction: %s", err)
 			break
 		}
+		notification.NotifyIssueReaction(ctx.User, issue, nil, reaction)
+
 		// Reload new reactions
 		issue.Reactions = nil
 		if err = issue.LoadAttributes(); err != nil {
@@ -1520,6 +1593,7 @@ func ChangeIssueReaction(ctx *context.Context, form auth.ReactionForm) {
 		return
 	}
 
+	issue.Reactions.HidePrivateUsers(ctx.User)
 	html, err := ctx.HTMLString(string(tplReactions), map[string]interface{}{
 		"ctx":       ctx.Data,
 		"ActionURL": fmt.Sprintf("%s/issues/%d/reactions", ctx.Repo.RepoLink, issue.Index),
@@ -1584,6 +1658,8 @@ func ChangeCommentReaction(ctx *context.Context, form auth.ReactionForm) {
 			log.Info("CreateCommentReaction: %s", err)
 			break
 		}
+		notification.NotifyIssueReaction(ctx.User, comment.Issue, comment, reaction)
+
 		// Reload new reactions
 		comment.Reactions = nil
 		if err = comment.LoadReactions(); err != nil {
@@ -1619,6 +1695,7 @@ func ChangeCommentReaction(ctx *context.Context, form auth.ReactionForm) {
 		return
 	}
 
+	comment.Reactions.HidePrivateUsers(ctx.User)
 	html, err := ctx.HTMLString(string(tplReactions), map[string]interface{}{
 		"ctx":       ctx.Data,
 		"ActionURL": fmt.Sprintf("%s/comments/%d/reactions", ctx.Repo.RepoLink, comment.ID),
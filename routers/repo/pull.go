@@ -11,6 +11,7 @@ import (
 	"crypto/subtle"
 	"fmt"
 	"html"
+	"io"
 	"net/http"
 	"path"
 	"strings"
@@ -419,6 +420,12 @@ func PrepareViewPullInfo(ctx *context.Context, issue *models.Issue) *git.Compare
 	if pull.IsFilesConflicted() {
 		ctx.Data["IsPullFilesConflicted"] = true
 		ctx.Data["ConflictedFiles"] = pull.ConflictedFiles
+		conflictedFileTypes, err := pull.GetConflictedFileTypes()
+		if err != nil {
+			log.Error("GetConflictedFileTypes[%d]: %v", pull.ID, err)
+		}
+		ctx.Data["ConflictedFileTypes"] = conflictedFileTypes
+		ctx.Data["HasBinaryConflicts"] = len(pull.BinaryConflictedFiles) > 0
 	}
 
 	ctx.Data["NumCommits"] = compareInfo.Commits.Len()
@@ -592,15 +599,20 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 		return
 	}
 
-	isPass, err := pull_service.IsPullCommitStatusPass(pr)
-	if err != nil {
-		ctx.ServerError("IsPullCommitStatusPass", err)
-		return
-	}
-	if !isPass && !ctx.IsUserRepoAdmin() {
-		ctx.Flash.Error(ctx.Tr("repo.pulls.no_merge_status_check"))
-		ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
-		return
+	if err := pull_service.CheckPullCommitStatus(pr); err != nil {
+		if !models.IsErrRequiredStatusMissing(err) {
+			ctx.ServerError("CheckPullCommitStatus", err)
+			return
+		}
+		if !ctx.IsUserRepoAdmin() {
+			if contexts := err.(models.ErrRequiredStatusMissing).Contexts; len(contexts) > 0 {
+				ctx.Flash.Error(ctx.Tr("repo.pulls.required_status_check_missing", strings.Join(contexts, ", ")))
+			} else {
+				ctx.Flash.Error(ctx.Tr("repo.pulls.no_merge_status_check"))
+			}
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
+		}
 	}
 
 	if ctx.HasError() {
@@ -611,15 +623,7 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 
 	message := strings.TrimSpace(form.MergeTitleField)
 	if len(message) == 0 {
-		if models.MergeStyle(form.Do) == models.MergeStyleMerge {
-			message = pr.GetDefaultMergeMessage()
-		}
-		if models.MergeStyle(form.Do) == models.MergeStyleRebaseMerge {
-			message = pr.GetDefaultMergeMessage()
-		}
-		if models.MergeStyle(form.Do) == models.MergeStyleSquash {
-			message = pr.GetDefaultSquashMessage()
-		}
+		message = pr.GetDefaultMessage(models.MergeStyle(form.Do))
 	}
 
 	form.MergeMessageField = strings.TrimSpace(form.MergeMessageField)
@@ -641,7 +645,28 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 		return
 	}
 
-	if err = pull_service.Merge(pr, ctx.User, ctx.Repo.GitRepo, models.MergeStyle(form.Do), message); err != nil {
+	if err = pull_service.MergeWithOptions(pr, ctx.User, ctx.Repo.GitRepo, &pull_service.MergeOptions{
+		Style:                      models.MergeStyle(form.Do),
+		Message:                    message,
+		UpToCommit:                 strings.TrimSpace(form.MergeUpToCommit),
+		DeleteHeadBranchAfterMerge: form.DeleteBranchAfterMerge,
+		SquashKeepAuthor:           ctx.Query("squash_keep_author") == "on",
+		AllowEmpty:                 form.AllowEmptyMerge,
+		Force:                      form.ForceMerge,
+		ForceReason:                form.ForceMergeReason,
+	}); err != nil && models.IsErrHeadBranchDeletionFailed(err) {
+		// The pull request has been merged, only the branch deletion failed
+		deletionErr := err.(models.ErrHeadBranchDeletionFailed)
+		fullBranchName := pr.HeadBranch
+		if pr.HeadRepo != nil && pr.HeadRepo.Owner != nil {
+			fullBranchName = pr.HeadRepo.Owner.Name + "/" + pr.HeadBranch
+		}
+		if deletionErr.HasNewCommits {
+			ctx.Flash.Error(ctx.Tr("repo.branch.delete_branch_has_new_commits", fullBranchName))
+		} else {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.delete_branch_after_merge_failed", fullBranchName))
+		}
+	} else if err != nil {
 		sanitize := func(x string) string {
 			runes := []rune(x)
 
@@ -655,6 +680,10 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 			ctx.Flash.Error(ctx.Tr("repo.pulls.invalid_merge_option"))
 			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
 			return
+		} else if models.IsErrLinearHistoryRequired(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.linear_history_required"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
 		} else if models.IsErrMergeConflicts(err) {
 			conflictError := err.(models.ErrMergeConflicts)
 			ctx.Flash.Error(ctx.Tr("repo.pulls.merge_conflict", sanitize(conflictError.StdErr), sanitize(conflictError.StdOut)))
@@ -670,11 +699,36 @@ func MergePullRequest(ctx *context.Context, form auth.MergePullRequestForm) {
 			ctx.Flash.Error(ctx.Tr("repo.pulls.unrelated_histories"))
 			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
 			return
+		} else if models.IsErrMergeEmptyDiff(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.merge_empty_diff"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
+		} else if models.IsErrInvalidMergeUpToCommit(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.invalid_merge_up_to_commit"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
 		} else if models.IsErrMergePushOutOfDate(err) {
 			log.Debug("MergePushOutOfDate error: %v", err)
 			ctx.Flash.Error(ctx.Tr("repo.pulls.merge_out_of_date"))
 			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
 			return
+		} else if models.IsErrProtectedFilesChanged(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.blocked_by_changed_protected_files"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
+		} else if models.IsErrUnresolvedConversations(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.blocked_by_unresolved_conversations", err.(models.ErrUnresolvedConversations).Count))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
+		} else if models.IsErrCommitAuthorNotAllowed(err) {
+			notAllowed := err.(models.ErrCommitAuthorNotAllowed)
+			ctx.Flash.Error(ctx.Tr("repo.pulls.blocked_by_unverified_commit_author", notAllowed.CommitID, notAllowed.Email))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
+		} else if models.IsErrNotAllowedToMerge(err) {
+			ctx.Flash.Error(ctx.Tr("repo.pulls.merge_not_allowed"))
+			ctx.Redirect(ctx.Repo.RepoLink + "/pulls/" + com.ToStr(pr.Index))
+			return
 		}
 		ctx.ServerError("Merge", err)
 		return
@@ -987,10 +1041,33 @@ func DownloadPullDiffOrPatch(ctx *context.Context, patch bool) {
 
 	pr := issue.PullRequest
 
-	if err := pull_service.DownloadDiffOrPatch(pr, ctx, patch); err != nil {
-		ctx.ServerError("DownloadDiffOrPatch", err)
+	// Without merge base the diff can only be computed in a temporary repository
+	if len(pr.MergeBase) == 0 {
+		if err := pull_service.DownloadDiffOrPatch(pr, ctx, patch); err != nil {
+			ctx.ServerError("DownloadDiffOrPatch", err)
+		}
+		return
+	}
+
+	var reader io.ReadCloser
+	var truncated bool
+	if patch {
+		reader, truncated, err = pull_service.GetPullRequestPatch(pr, setting.Git.MaxRawDiffBytes)
+	} else {
+		reader, truncated, err = pull_service.GetPullRequestDiff(pr, setting.Git.MaxRawDiffBytes)
+	}
+	if err != nil {
+		ctx.ServerError("GetPullRequestDiff", err)
 		return
 	}
+	defer reader.Close()
+
+	if truncated {
+		ctx.Resp.Header().Set("X-Gitea-Diff-Truncated", "true")
+	}
+	if _, err = io.Copy(ctx.Resp, reader); err != nil {
+		log.Error("Unable to write diff of pull request %d: %v", pr.ID, err)
+	}
 }
 
 // UpdatePullRequestTarget change pull request's target branch
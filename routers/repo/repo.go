@@ -345,6 +345,9 @@ func MigratePost(ctx *context.Context, form auth.MigrateRepoForm) {
 		Comments:     true,
 		PullRequests: form.PullRequests,
 		Releases:     form.Releases,
+		Resumable:    form.Resumable,
+
+		FallbackPRsToIssues: form.FallbackPRsToIssues,
 	}
 	if opts.Mirror {
 		opts.Issues = false
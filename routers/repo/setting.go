@@ -27,6 +27,7 @@ import (
 	"code.gitea.io/gitea/routers/utils"
 	"code.gitea.io/gitea/services/mailer"
 	mirror_service "code.gitea.io/gitea/services/mirror"
+	pull_service "code.gitea.io/gitea/services/pull"
 	repo_service "code.gitea.io/gitea/services/repository"
 
 	"github.com/unknwon/com"
@@ -50,6 +51,9 @@ func Settings(ctx *context.Context) {
 	ctx.Data["Title"] = ctx.Tr("repo.settings")
 	ctx.Data["PageIsSettingsOptions"] = true
 	ctx.Data["ForcePrivate"] = setting.Repository.ForcePrivate
+	if unit, err := ctx.Repo.Repository.GetUnit(models.UnitTypeIssues); err == nil {
+		ctx.Data["RepoAllowedReactions"] = strings.Join(unit.IssuesConfig().AllowedReactions, ", ")
+	}
 	ctx.HTML(200, tplSettingsOptions)
 }
 
@@ -264,6 +268,19 @@ func SettingsPost(ctx *context.Context, form auth.RepoSettingForm) {
 					},
 				})
 			} else {
+				var allowedReactions []string
+				for _, field := range strings.Split(form.AllowedReactions, ",") {
+					if len(strings.TrimSpace(field)) == 0 {
+						continue
+					}
+					content, ok := models.NormalizeReactionContent(field)
+					if !ok || !setting.UI.ReactionsMap[content] {
+						ctx.Flash.Error(ctx.Tr("repo.settings.allowed_reactions_error", field))
+						ctx.Redirect(repo.Link() + "/settings")
+						return
+					}
+					allowedReactions = append(allowedReactions, content)
+				}
 				units = append(units, models.RepoUnit{
 					RepoID: repo.ID,
 					Type:   models.UnitTypeIssues,
@@ -271,22 +288,35 @@ func SettingsPost(ctx *context.Context, form auth.RepoSettingForm) {
 						EnableTimetracker:                form.EnableTimetracker,
 						AllowOnlyContributorsToTrackTime: form.AllowOnlyContributorsToTrackTime,
 						EnableDependencies:               form.EnableIssueDependencies,
+						AllowedReactions:                 allowedReactions,
 					},
 				})
 			}
 		}
 
 		if form.EnablePulls {
+			config := &models.PullRequestsConfig{
+				IgnoreWhitespaceConflicts: form.PullsIgnoreWhitespace,
+				AllowMerge:                form.PullsAllowMerge,
+				AllowRebase:               form.PullsAllowRebase,
+				AllowRebaseMerge:          form.PullsAllowRebaseMerge,
+				AllowSquash:               form.PullsAllowSquash,
+				DefaultMergeStyle:         models.MergeStyle(form.PullsDefaultMergeStyle),
+				CloseStale:                form.PullsCloseStale,
+				AddReviewedByTrailers:     form.PullsAddReviewedByTrailers,
+				AddCoAuthoredByTrailers:   form.PullsAddCoAuthoredByTrailers,
+				NeedsRebaseLabel:          strings.TrimSpace(form.PullsNeedsRebaseLabel),
+			}
+			// An empty style falls back to the first allowed one
+			if len(config.DefaultMergeStyle) > 0 && !config.IsMergeStyleAllowed(config.DefaultMergeStyle) {
+				ctx.Flash.Error(ctx.Tr("repo.settings.pulls.default_merge_style_error"))
+				ctx.Redirect(repo.Link() + "/settings")
+				return
+			}
 			units = append(units, models.RepoUnit{
 				RepoID: repo.ID,
 				Type:   models.UnitTypePullRequests,
-				Config: &models.PullRequestsConfig{
-					IgnoreWhitespaceConflicts: form.PullsIgnoreWhitespace,
-					AllowMerge:                form.PullsAllowMerge,
-					AllowRebase:               form.PullsAllowRebase,
-					AllowRebaseMerge:          form.PullsAllowRebaseMerge,
-					AllowSquash:               form.PullsAllowSquash,
-				},
+				Config: config,
 			})
 		}
 
@@ -299,6 +329,19 @@ func SettingsPost(ctx *context.Context, form auth.RepoSettingForm) {
 		ctx.Flash.Success(ctx.Tr("repo.settings.update_settings_success"))
 		ctx.Redirect(ctx.Repo.RepoLink + "/settings")
 
+	case "commit-authors":
+		repo.RequireVerifiedCommitAuthors = form.RequireVerifiedCommitAuthors
+		repo.CommitAuthorAllowedDomains = form.CommitAuthorAllowedDomains
+		repo.CommitAuthorAllowedDomains = strings.Join(repo.GetCommitAuthorAllowedDomains(), ",")
+		if err := models.UpdateRepositoryCols(repo, "require_verified_commit_authors", "commit_author_allowed_domains"); err != nil {
+			ctx.ServerError("UpdateRepositoryCols", err)
+			return
+		}
+		log.Trace("Repository commit author settings updated: %s/%s", ctx.Repo.Owner.Name, repo.Name)
+
+		ctx.Flash.Success(ctx.Tr("repo.settings.update_settings_success"))
+		ctx.Redirect(ctx.Repo.RepoLink + "/settings")
+
 	case "admin":
 		if !ctx.User.IsAdmin {
 			ctx.Error(403)
@@ -323,6 +366,44 @@ func SettingsPost(ctx *context.Context, form auth.RepoSettingForm) {
 		ctx.Flash.Success(ctx.Tr("repo.settings.update_settings_success"))
 		ctx.Redirect(ctx.Repo.RepoLink + "/settings")
 
+	case "retest-pulls":
+		if !ctx.User.IsAdmin {
+			ctx.Error(403)
+			return
+		}
+
+		count, err := pull_service.RetestAllPullRequests(repo.ID)
+		if err != nil {
+			ctx.ServerError("RetestAllPullRequests", err)
+			return
+		}
+
+		log.Trace("Repository pull requests queued for retesting: %s/%s: %d", ctx.Repo.Owner.Name, repo.Name, count)
+
+		ctx.Flash.Info(ctx.Tr("repo.settings.admin_retest_pulls_queued", count))
+		ctx.Redirect(ctx.Repo.RepoLink + "/settings")
+
+	case "sync-pull-refs":
+		if !ctx.User.IsAdmin {
+			ctx.Error(403)
+			return
+		}
+
+		failed, err := pull_service.SyncAllPullRequestHeadRefs(repo.ID)
+		if err != nil {
+			ctx.ServerError("SyncAllPullRequestHeadRefs", err)
+			return
+		}
+
+		log.Trace("Repository pull request refs synced: %s/%s", ctx.Repo.Owner.Name, repo.Name)
+
+		if failed > 0 {
+			ctx.Flash.Error(ctx.Tr("repo.settings.admin_sync_pull_refs_failed", failed))
+		} else {
+			ctx.Flash.Success(ctx.Tr("repo.settings.admin_sync_pull_refs_success"))
+		}
+		ctx.Redirect(ctx.Repo.RepoLink + "/settings")
+
 	case "convert":
 		if !ctx.Repo.IsOwner() {
 			ctx.Error(404)
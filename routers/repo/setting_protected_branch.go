@@ -235,6 +235,10 @@ func SettingsProtectedBranchPost(ctx *context.Context, f auth.ProtectBranchForm)
 		}
 
 		protectBranch.RequiredApprovals = f.RequiredApprovals
+		protectBranch.DismissStaleApprovals = f.DismissStaleApprovals
+		protectBranch.ProtectedFilePatterns = strings.TrimSpace(f.ProtectedFilePatterns)
+		protectBranch.RequireLinearHistory = f.RequireLinearHistory
+		protectBranch.RequireResolvedConversations = f.RequireResolvedConversations
 		protectBranch.EnableApprovalsWhitelist = f.EnableApprovalsWhitelist
 		if f.EnableApprovalsWhitelist {
 			if strings.TrimSpace(f.ApprovalsWhitelistUsers) != "" {
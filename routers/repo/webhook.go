@@ -136,15 +136,16 @@ func ParseHookEvent(form auth.WebhookForm) *models.HookEvent {
 		SendEverything: form.SendEverything(),
 		ChooseEvents:   form.ChooseEvents(),
 		HookEvents: models.HookEvents{
-			Create:       form.Create,
-			Delete:       form.Delete,
-			Fork:         form.Fork,
-			Issues:       form.Issues,
-			IssueComment: form.IssueComment,
-			Release:      form.Release,
-			Push:         form.Push,
-			PullRequest:  form.PullRequest,
-			Repository:   form.Repository,
+			Create:        form.Create,
+			Delete:        form.Delete,
+			Fork:          form.Fork,
+			Issues:        form.Issues,
+			IssueComment:  form.IssueComment,
+			Release:       form.Release,
+			Push:          form.Push,
+			PullRequest:   form.PullRequest,
+			Repository:    form.Repository,
+			TaskCompleted: form.TaskCompleted,
 		},
 		BranchFilter: form.BranchFilter,
 	}
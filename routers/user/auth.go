@@ -1208,7 +1208,13 @@ func ActivateEmail(ctx *context.Context) {
 	// Verify code.
 	if email := models.VerifyActiveEmailCode(code, emailStr); email != nil {
 		if err := email.Activate(); err != nil {
+			if err == models.ErrEmailVerificationExpired {
+				ctx.Flash.Error(ctx.Tr("settings.email_verification_expired"))
+				ctx.Redirect(setting.AppSubURL + "/user/settings/account")
+				return
+			}
 			ctx.ServerError("ActivateEmail", err)
+			return
 		}
 
 		log.Trace("Email activated: %s", email.Email)
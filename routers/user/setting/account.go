@@ -88,6 +88,40 @@ func EmailPost(ctx *context.Context, form auth.AddEmailForm) {
 		ctx.Redirect(setting.AppSubURL + "/user/settings/account")
 		return
 	}
+	// Resend the confirmation email of an address which is not activated yet.
+	if ctx.Query("_method") == "SENDACTIVATION" && setting.Service.RegisterEmailConfirm {
+		email, err := models.GetEmailAddressByID(ctx.User.ID, ctx.QueryInt64("id"))
+		if err != nil {
+			if err == models.ErrEmailAddressNotExist {
+				ctx.NotFound("GetEmailAddressByID", err)
+			} else {
+				ctx.ServerError("GetEmailAddressByID", err)
+			}
+			return
+		} else if email.IsActivated {
+			ctx.NotFound("GetEmailAddressByID", nil)
+			return
+		}
+
+		if ctx.Cache.IsExist("MailResendLimit_" + ctx.User.LowerName) {
+			ctx.Flash.Error(ctx.Tr("settings.email_resend_limited"))
+			ctx.Redirect(setting.AppSubURL + "/user/settings/account")
+			return
+		}
+		if err := email.UpdateVerificationSent(); err != nil {
+			ctx.ServerError("UpdateVerificationSent", err)
+			return
+		}
+		mailer.SendActivateEmailMail(ctx.Locale, ctx.User, email)
+
+		if err := ctx.Cache.Put("MailResendLimit_"+ctx.User.LowerName, ctx.User.LowerName, 180); err != nil {
+			log.Error("Set cache(MailResendLimit) fail: %v", err)
+		}
+		log.Trace("Email activation resent: %s", email.Email)
+		ctx.Flash.Info(ctx.Tr("settings.add_email_confirmation_sent", email.Email, timeutil.MinutesToFriendly(setting.Service.ActiveCodeLives, ctx.Locale.Language())))
+		ctx.Redirect(setting.AppSubURL + "/user/settings/account")
+		return
+	}
 	// Set Email Notification Preference
 	if ctx.Query("_method") == "NOTIFICATION" {
 		preference := ctx.Query("preference")
@@ -107,6 +141,17 @@ func EmailPost(ctx *context.Context, form auth.AddEmailForm) {
 		ctx.Redirect(setting.AppSubURL + "/user/settings/account")
 		return
 	}
+	// Set Reaction Notification Preference
+	if ctx.Query("_method") == "REACTION_NOTIFICATION" {
+		notify := ctx.Query("notify_on_reactions") == "on"
+		if err := ctx.User.SetNotifyOnReactions(notify); err != nil {
+			ctx.ServerError("SetNotifyOnReactions", err)
+			return
+		}
+		log.Trace("Reaction notifications preference made %t: %s", notify, ctx.User.Name)
+		ctx.Redirect(setting.AppSubURL + "/user/settings/account")
+		return
+	}
 
 	if ctx.HasError() {
 		loadAccountData(ctx)
@@ -133,6 +178,10 @@ func EmailPost(ctx *context.Context, form auth.AddEmailForm) {
 
 	// Send confirmation email
 	if setting.Service.RegisterEmailConfirm {
+		if err := email.UpdateVerificationSent(); err != nil {
+			ctx.ServerError("UpdateVerificationSent", err)
+			return
+		}
 		mailer.SendActivateEmailMail(ctx.Locale, ctx.User, email)
 
 		if err := ctx.Cache.Put("MailResendLimit_"+ctx.User.LowerName, ctx.User.LowerName, 180); err != nil {
@@ -230,4 +279,5 @@ func loadAccountData(ctx *context.Context) {
 	}
 	ctx.Data["Emails"] = emails
 	ctx.Data["EmailNotificationsPreference"] = ctx.User.EmailNotifications()
+	ctx.Data["RegisterEmailConfirm"] = setting.Service.RegisterEmailConfirm
 }
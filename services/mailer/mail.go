@@ -205,6 +205,13 @@ func composeIssueCommentMessages(ctx *mailCommentContext, tos []string, fromMent
 		}
 	}
 
+	// Loaded so that templates can link to the branches of a pull request
+	if ctx.Issue.IsPull {
+		if err := ctx.Issue.LoadPullRequest(); err != nil {
+			log.Error("LoadPullRequest: %v", err)
+		}
+	}
+
 	mailMeta := map[string]interface{}{
 		"FallbackSubject": fallback,
 		"Body":            body,
@@ -42,10 +42,10 @@ func mailIssueCommentToParticipants(ctx *mailCommentContext, mentions []int64) e
 	}
 
 	// Enough room to avoid reallocations
-	unfiltered := make([]int64, 1, 64)
+	unfiltered := make([]int64, 0, 64)
 
 	// =========== Original poster ===========
-	unfiltered[0] = ctx.Issue.PosterID
+	unfiltered = append(unfiltered, ctx.Issue.PosterID)
 
 	// =========== Assignees ===========
 	ids, err := models.GetAssigneeIDsByIssue(ctx.Issue.ID)
@@ -61,6 +61,18 @@ func mailIssueCommentToParticipants(ctx *mailCommentContext, mentions []int64) e
 	}
 	unfiltered = append(unfiltered, ids...)
 
+	// =========== Pull request participants (additionally the requested reviewers) ===========
+	if ctx.Issue.IsPull && ctx.Issue.PullRequest != nil &&
+		(ctx.ActionType == models.ActionMergePullRequest || ctx.ActionType == models.ActionClosePullRequest) {
+		participants, err := ctx.Issue.PullRequest.GetParticipants()
+		if err != nil {
+			return fmt.Errorf("GetParticipants(%d): %v", ctx.Issue.PullRequest.ID, err)
+		}
+		for _, participant := range participants {
+			unfiltered = append(unfiltered, participant.ID)
+		}
+	}
+
 	// =========== Issue watchers ===========
 	ids, err = models.GetIssueWatchersIDs(ctx.Issue.ID)
 	if err != nil {
@@ -125,6 +137,16 @@ func mailIssueCommentBatch(ctx *mailCommentContext, ids []int64, visited map[int
 	return nil
 }
 
+// findAllMentions returns the unvalidated user names mentioned in content, including
+// the users whose verified email addresses are written in it.
+func findAllMentions(ctx models.DBContext, content string) ([]string, error) {
+	emailMentions, err := models.ResolveEmailMentions(ctx, references.FindAllEmailMentionsMarkdown(content))
+	if err != nil {
+		return nil, err
+	}
+	return append(references.FindAllMentionsMarkdown(content), emailMentions...), nil
+}
+
 // MailParticipants sends new issue thread created emails to repository watchers
 // and mentioned people.
 func MailParticipants(issue *models.Issue, doer *models.User, opType models.ActionType) error {
@@ -132,7 +154,10 @@ func MailParticipants(issue *models.Issue, doer *models.User, opType models.Acti
 }
 
 func mailParticipants(ctx models.DBContext, issue *models.Issue, doer *models.User, opType models.ActionType) (err error) {
-	rawMentions := references.FindAllMentionsMarkdown(issue.Content)
+	rawMentions, err := findAllMentions(ctx, issue.Content)
+	if err != nil {
+		return fmt.Errorf("findAllMentions [%d]: %v", issue.ID, err)
+	}
 	userMentions, err := issue.ResolveMentionsByVisibility(ctx, doer, rawMentions)
 	if err != nil {
 		return fmt.Errorf("ResolveMentionsByVisibility [%d]: %v", issue.ID, err)
@@ -214,6 +214,13 @@ func TestTemplateServices(t *testing.T) {
 		"//{{.SubjectPrefix}}//",
 		"Re: [user2/repo1] issue1 (#1)",
 		"//Re: //")
+
+	pull := models.AssertExistsAndLoadBean(t, &models.Issue{ID: 2, Repo: repo, Poster: doer}).(*models.Issue)
+	expect(t, pull, nil, doer, models.ActionCreatePullRequest, false,
+		"{{.ActionName}}",
+		"//{{.Issue.PullRequest.GetHeadBranchHTMLURL}}//",
+		"new",
+		"//"+setting.AppURL+"user2/repo1/src/branch/branch1//")
 }
 
 func testComposeIssueCommentMessage(t *testing.T, ctx *mailCommentContext, tos []string, fromMention bool, info string) *Message {
@@ -12,11 +12,13 @@ import (
 	"io"
 	"net"
 	"net/smtp"
+	"net/textproto"
 	"os"
 	"os/exec"
 	"strings"
 	"time"
 
+	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/base"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/setting"
@@ -187,6 +189,7 @@ func (s *smtpSender) Send(from string, to []string, msg io.WriterTo) error {
 
 	for _, rec := range to {
 		if err = client.Rcpt(rec); err != nil {
+			recordPermanentFailure(rec, err)
 			return fmt.Errorf("Rcpt: %v", err)
 		}
 	}
@@ -203,6 +206,17 @@ func (s *smtpSender) Send(from string, to []string, msg io.WriterTo) error {
 	return client.Quit()
 }
 
+// recordPermanentFailure records a bounce for given recipient if err
+// is a permanent (5xx) SMTP failure.
+func recordPermanentFailure(rec string, err error) {
+	if tpErr, ok := err.(*textproto.Error); !ok || tpErr.Code < 500 || tpErr.Code >= 600 {
+		return
+	}
+	if err := models.RecordEmailBounce(rec); err != nil {
+		log.Error("RecordEmailBounce [%s]: %v", rec, err)
+	}
+}
+
 // Sender sendmail mail sender
 type sendmailSender struct {
 }
@@ -20,6 +20,7 @@ import (
 	"code.gitea.io/gitea/modules/notification"
 	"code.gitea.io/gitea/modules/repository"
 	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/structs"
 	"code.gitea.io/gitea/modules/sync"
 	"code.gitea.io/gitea/modules/timeutil"
 	"code.gitea.io/gitea/modules/util"
@@ -431,6 +432,79 @@ func InitSyncMirrors() {
 	go graceful.GetManager().RunWithShutdownContext(SyncMirrors)
 }
 
+// ConvertMigrationToMirror turns a repository created by a finished one-off migration into a
+// mirror which is synced from the original source with the given interval.
+// The clone address and credentials of the migration task are reused.
+func ConvertMigrationToMirror(repo *models.Repository, interval time.Duration) error {
+	if repo.IsMirror {
+		return models.ErrMigrationNotConvertible{RepoID: repo.ID, Reason: "repository is already a mirror"}
+	}
+	if interval != 0 && interval < setting.Mirror.MinInterval {
+		return models.ErrMigrationNotConvertible{
+			RepoID: repo.ID,
+			Reason: fmt.Sprintf("mirror interval %v is shorter than the minimum of %v", interval, setting.Mirror.MinInterval),
+		}
+	}
+
+	task, err := models.GetMigratingTask(repo.ID)
+	if err != nil {
+		return err
+	}
+	if task.Status != structs.TaskStatusFinished {
+		return models.ErrMigrationNotConvertible{RepoID: repo.ID, Reason: fmt.Sprintf("migration task %d is not finished", task.ID)}
+	}
+	opts, err := task.MigrateConfig()
+	if err != nil {
+		return fmt.Errorf("MigrateConfig: %v", err)
+	}
+
+	remoteAddr, err := migrationRemoteAddress(opts)
+	if err != nil {
+		return err
+	}
+	if !git.IsRepoURLAccessible(remoteAddr) {
+		return models.ErrMigrationNotConvertible{
+			RepoID: repo.ID,
+			Reason: fmt.Sprintf("migration source %s is not reachable", util.SanitizeURLCredentials(remoteAddr, true)),
+		}
+	}
+
+	m := &models.Mirror{
+		Repo:        repo,
+		Interval:    interval,
+		EnablePrune: true,
+	}
+	// The migration removed the origin remote, so it only has to be added again
+	if _, err = git.NewCommand("remote", "add", "origin", "--mirror=fetch", remoteAddr).RunInDir(repo.RepoPath()); err != nil {
+		return fmt.Errorf("git remote add: %v", util.URLSanitizedError(err, remoteAddr))
+	}
+	// BeforeInsert schedules the first sync right away, the following ones use the interval
+	if err = models.ConvertToMirror(repo, m); err != nil {
+		// Leave the repository as it was, so that the conversion can be retried
+		if _, rmErr := git.NewCommand("remote", "rm", "origin").RunInDir(repo.RepoPath()); rmErr != nil {
+			log.Error("ConvertMigrationToMirror: git remote rm origin [%s]: %v", repo.RepoPath(), rmErr)
+		}
+		return fmt.Errorf("ConvertToMirror: %v", err)
+	}
+
+	StartToMirror(repo.ID)
+	return nil
+}
+
+// migrationRemoteAddress returns the clone address of a migration with its credentials applied,
+// the same way the migration itself cloned the repository.
+func migrationRemoteAddress(opts *structs.MigrateRepoOption) (string, error) {
+	if len(opts.AuthUsername) == 0 {
+		return opts.CloneAddr, nil
+	}
+	u, err := url.Parse(opts.CloneAddr)
+	if err != nil {
+		return "", fmt.Errorf("invalid clone address: %v", util.URLSanitizedError(err, opts.CloneAddr))
+	}
+	u.User = url.UserPassword(opts.AuthUsername, opts.AuthPassword)
+	return u.String(), nil
+}
+
 // StartToMirror adds repoID to mirror queue
 func StartToMirror(repoID int64) {
 	go mirrorQueue.Add(repoID)
@@ -0,0 +1,215 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/log"
+)
+
+// BackportConflictLabel is the label added to backport pull requests whose changes did not apply cleanly.
+// It is created in the repository if it does not exist yet.
+const BackportConflictLabel = "backport/conflicts"
+
+// BackportResult is the outcome of backporting a merged pull request to one branch
+type BackportResult struct {
+	TargetBranch string
+	// Branch is the branch created in the base repository for the backport
+	Branch string
+	// PullRequest is the pull request opened from Branch into TargetBranch
+	PullRequest *models.PullRequest
+	// Conflicted is true if the changes did not apply cleanly and were committed with conflict markers
+	Conflicted bool
+	Err        error
+}
+
+// backportPullRequest backports the commits fromSHA..toSHA, which a merge of pr added to its base branch,
+// to each of the target branches and opens a pull request for every backport
+func backportPullRequest(pr *models.PullRequest, doer *models.User, fromSHA, toSHA string, targetBranches []string) []*BackportResult {
+	results := make([]*BackportResult, 0, len(targetBranches))
+	for _, targetBranch := range targetBranches {
+		result := backport(pr, doer, fromSHA, toSHA, targetBranch)
+		if result.Err != nil {
+			log.Error("Backport of pull request [%d] to %s: %v", pr.ID, targetBranch, result.Err)
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+func backport(pr *models.PullRequest, doer *models.User, fromSHA, toSHA, targetBranch string) *BackportResult {
+	result := &BackportResult{
+		TargetBranch: targetBranch,
+		Branch:       fmt.Sprintf("backport-%d-to-%s", pr.Index, targetBranch),
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		result.Err = fmt.Errorf("LoadBaseRepo: %v", err)
+		return result
+	}
+	if err := pr.LoadIssue(); err != nil {
+		result.Err = fmt.Errorf("LoadIssue: %v", err)
+		return result
+	}
+
+	baseRepoPath := pr.BaseRepo.RepoPath()
+	if !git.IsBranchExist(baseRepoPath, targetBranch) {
+		result.Err = git.ErrBranchNotExist{Name: targetBranch}
+		return result
+	}
+	if git.IsBranchExist(baseRepoPath, result.Branch) {
+		result.Err = models.ErrBranchAlreadyExists{BranchName: result.Branch}
+		return result
+	}
+
+	tmpBasePath, err := models.CreateTemporaryPath("backport")
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer func() {
+		if err := models.RemoveTemporaryPath(tmpBasePath); err != nil {
+			log.Error("backport: RemoveTemporaryPath: %s", err)
+		}
+	}()
+
+	if err := git.Clone(baseRepoPath, tmpBasePath, git.CloneRepoOptions{Branch: targetBranch, Shared: true}); err != nil {
+		result.Err = fmt.Errorf("git clone: %v", err)
+		return result
+	}
+	if _, err := git.NewCommand("checkout", "-b", result.Branch).RunInDir(tmpBasePath); err != nil {
+		result.Err = fmt.Errorf("git checkout -b %s: %v", result.Branch, err)
+		return result
+	}
+	mergeBase, err := git.GetFullCommitID(tmpBasePath, "HEAD")
+	if err != nil {
+		result.Err = fmt.Errorf("GetFullCommitID: %v", err)
+		return result
+	}
+
+	if result.Conflicted, err = cherryPickCommits(doer, tmpBasePath, fromSHA, toSHA); err != nil {
+		result.Err = err
+		return result
+	}
+
+	var outbuf, errbuf strings.Builder
+	if err := git.NewCommand("push", "origin", "HEAD:"+git.BranchPrefix+result.Branch).
+		RunInDirTimeoutEnvPipeline(models.PushingEnvironment(doer, pr.BaseRepo), -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+		result.Err = fmt.Errorf("git push: %v\n%s\n%s", err, outbuf.String(), errbuf.String())
+		return result
+	}
+
+	var labelIDs []int64
+	content := fmt.Sprintf("Backport of #%d", pr.Index)
+	if result.Conflicted {
+		labelID, err := getBackportConflictLabelID(pr.BaseRepo)
+		if err != nil {
+			result.Err = fmt.Errorf("getBackportConflictLabelID: %v", err)
+			return result
+		}
+		labelIDs = []int64{labelID}
+		content += "\n\nThe changes did not apply cleanly, the conflicts have been committed with their conflict markers and have to be resolved."
+	}
+
+	issue := &models.Issue{
+		RepoID:   pr.BaseRepo.ID,
+		Title:    fmt.Sprintf("[Backport %s] %s", targetBranch, pr.Issue.Title),
+		PosterID: doer.ID,
+		Poster:   doer,
+		IsPull:   true,
+		Content:  content,
+	}
+	result.PullRequest = &models.PullRequest{
+		HeadRepoID: pr.BaseRepo.ID,
+		BaseRepoID: pr.BaseRepo.ID,
+		HeadBranch: result.Branch,
+		BaseBranch: targetBranch,
+		HeadRepo:   pr.BaseRepo,
+		BaseRepo:   pr.BaseRepo,
+		MergeBase:  mergeBase,
+		Type:       models.PullRequestGitea,
+	}
+	if err := NewPullRequest(pr.BaseRepo, issue, labelIDs, nil, result.PullRequest, nil); err != nil {
+		result.PullRequest = nil
+		result.Err = fmt.Errorf("NewPullRequest: %v", err)
+	}
+	return result
+}
+
+// cherryPickCommits applies the commits fromSHA..toSHA onto the checked out branch of the repository
+// at tmpBasePath. A merge commit toSHA is applied as a whole instead. Conflicts are committed with their
+// conflict markers, in which case true is returned.
+func cherryPickCommits(doer *models.User, tmpBasePath, fromSHA, toSHA string) (bool, error) {
+	sig := doer.NewGitSig()
+	env := append(os.Environ(),
+		"GIT_COMMITTER_NAME="+sig.Name,
+		"GIT_COMMITTER_EMAIL="+sig.Email,
+		// accept the prepared message when continuing after a conflict
+		"GIT_EDITOR=true",
+	)
+
+	parents, err := git.NewCommand("rev-list", "--parents", "-n", "1", toSHA).RunInDir(tmpBasePath)
+	if err != nil {
+		return false, fmt.Errorf("git rev-list --parents: %v", err)
+	}
+	args := []string{"cherry-pick", "-x", "--allow-empty", "--keep-redundant-commits"}
+	if len(strings.Fields(parents)) > 2 {
+		args = append(args, "-m", "1", toSHA)
+	} else {
+		args = append(args, fromSHA+".."+toSHA)
+	}
+
+	var outbuf, errbuf strings.Builder
+	conflicted := false
+	lastConflict := ""
+	cmd := git.NewCommand(args...)
+	for {
+		err := cmd.RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf)
+		if err == nil {
+			return conflicted, nil
+		}
+		// Cherry-pick stops at every commit that does not apply and leaves its ID in .git/CHERRY_PICK_HEAD
+		commitSha, readErr := ioutil.ReadFile(filepath.Join(tmpBasePath, ".git", "CHERRY_PICK_HEAD"))
+		if readErr != nil || strings.TrimSpace(string(commitSha)) == lastConflict {
+			return false, fmt.Errorf("git cherry-pick: %v\n%s\n%s", err, outbuf.String(), errbuf.String())
+		}
+		lastConflict = strings.TrimSpace(string(commitSha))
+		conflicted = true
+		outbuf.Reset()
+		errbuf.Reset()
+
+		if _, err := git.NewCommand("add", "--all").RunInDir(tmpBasePath); err != nil {
+			return false, fmt.Errorf("git add --all: %v", err)
+		}
+		cmd = git.NewCommand("cherry-pick", "--continue")
+	}
+}
+
+// getBackportConflictLabelID returns the ID of the BackportConflictLabel of the repository, creating the label if needed
+func getBackportConflictLabelID(repo *models.Repository) (int64, error) {
+	label, err := models.GetLabelInRepoByName(repo.ID, BackportConflictLabel)
+	if err == nil {
+		return label.ID, nil
+	} else if !models.IsErrLabelNotExist(err) {
+		return 0, err
+	}
+
+	label = &models.Label{
+		RepoID:      repo.ID,
+		Name:        BackportConflictLabel,
+		Description: "The backport did not apply cleanly and contains conflict markers",
+		Color:       "#e11d21",
+	}
+	if err := models.NewLabel(label); err != nil {
+		return 0, err
+	}
+	return label.ID, nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackportPullRequest(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	fromSHA := "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	toSHA := pushReadmeChange(t, "backport-source", "# repo1\n\nbackported\n")
+	pushReadmeChange(t, "backport-release", "# repo1\n\nchanged on release\n")
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+
+	results := backportPullRequest(pr, doer, fromSHA, toSHA, []string{"develop", "backport-release", "does-not-exist"})
+	assert.Len(t, results, 3)
+
+	readme := func(branch string) string {
+		gitRepo, err := git.OpenRepository(models.RepoPath("user2", "repo1"))
+		assert.NoError(t, err)
+		defer gitRepo.Close()
+		commit, err := gitRepo.GetBranchCommit(branch)
+		assert.NoError(t, err)
+		blob, err := commit.GetBlobByPath("README.md")
+		assert.NoError(t, err)
+		content, err := blob.GetBlobContent()
+		assert.NoError(t, err)
+		return content
+	}
+
+	// applies cleanly
+	clean := results[0]
+	assert.NoError(t, clean.Err)
+	assert.False(t, clean.Conflicted)
+	assert.Equal(t, "backport-3-to-develop", clean.Branch)
+	if assert.NotNil(t, clean.PullRequest) {
+		backportPR := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: clean.PullRequest.ID}).(*models.PullRequest)
+		assert.Equal(t, "backport-3-to-develop", backportPR.HeadBranch)
+		assert.Equal(t, "develop", backportPR.BaseBranch)
+		assert.NoError(t, backportPR.LoadIssue())
+		assert.Equal(t, "[Backport develop] issue3", backportPR.Issue.Title)
+		labels, err := models.GetLabelsByIssueID(backportPR.IssueID)
+		assert.NoError(t, err)
+		assert.Empty(t, labels)
+	}
+	assert.Equal(t, "# repo1\n\nbackported\n", readme(clean.Branch))
+
+	// conflicts are committed and the pull request is labeled
+	conflicted := results[1]
+	assert.NoError(t, conflicted.Err)
+	assert.True(t, conflicted.Conflicted)
+	if assert.NotNil(t, conflicted.PullRequest) {
+		labels, err := models.GetLabelsByIssueID(conflicted.PullRequest.IssueID)
+		assert.NoError(t, err)
+		if assert.Len(t, labels, 1) {
+			assert.Equal(t, BackportConflictLabel, labels[0].Name)
+		}
+	}
+	assert.True(t, strings.Contains(readme(conflicted.Branch), "<<<<<<<"))
+
+	assert.True(t, git.IsErrBranchNotExist(results[2].Err))
+	assert.Nil(t, results[2].PullRequest)
+
+	// the backport branch exists already
+	results = backportPullRequest(pr, doer, fromSHA, toSHA, []string{"develop"})
+	assert.True(t, models.IsErrBranchAlreadyExists(results[0].Err))
+}
@@ -10,7 +10,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"strings"
 
 	"code.gitea.io/gitea/models"
 	"code.gitea.io/gitea/modules/git"
@@ -37,6 +36,32 @@ func AddToTaskQueue(pr *models.PullRequest) {
 	})
 }
 
+// RetestAllPullRequests queues all open pull requests into the repository for conflict checking again,
+// e.g. after a force-push to a base branch. Pull requests which are already queued are not added twice.
+// The results of previous checks are discarded, so every queued pull request gets fully tested.
+// It returns the number of pull requests which have been queued.
+func RetestAllPullRequests(repoID int64) (int, error) {
+	prs, err := models.GetUnmergedPullRequestsByBaseRepo(repoID)
+	if err != nil {
+		return 0, fmt.Errorf("GetUnmergedPullRequestsByBaseRepo: %v", err)
+	}
+
+	queued := 0
+	for _, pr := range prs {
+		if pullRequestQueue.Exist(pr.ID) {
+			continue
+		}
+		pr.LastTestedHeadSHA = ""
+		pr.LastTestedBaseSHA = ""
+		if err := pr.UpdateCols("last_tested_head_sha, last_tested_base_sha"); err != nil {
+			return queued, fmt.Errorf("UpdateCols[%d]: %v", pr.ID, err)
+		}
+		AddToTaskQueue(pr)
+		queued++
+	}
+	return queued, nil
+}
+
 // checkAndUpdateStatus checks if pull request is possible to leaving checking status,
 // and set to be either conflict or mergeable.
 func checkAndUpdateStatus(pr *models.PullRequest) {
@@ -47,12 +72,160 @@ func checkAndUpdateStatus(pr *models.PullRequest) {
 
 	// Make sure there is no waiting test to process before leaving the checking status.
 	if !pullRequestQueue.Exist(pr.ID) {
-		if err := pr.UpdateCols("status, conflicted_files"); err != nil {
+		if err := pr.UpdateCols("status, conflicted_files, binary_conflicted_files, changed_protected_files, last_tested_head_sha, last_tested_base_sha"); err != nil {
 			log.Error("Update[%d]: %v", pr.ID, err)
 		}
 	}
 }
 
+// getPullRequestTestSHAs returns the commit IDs of the head and the base branch of the pull request
+// as they are seen in the base repository
+func getPullRequestTestSHAs(pr *models.PullRequest) (headSHA, baseSHA string, err error) {
+	if err = pr.LoadBaseRepo(); err != nil {
+		return "", "", fmt.Errorf("LoadBaseRepo: %v", err)
+	}
+
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return "", "", fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+
+	if headSHA, err = gitRepo.GetRefCommitID(pr.GetGitRefName()); err != nil {
+		return "", "", fmt.Errorf("GetRefCommitID(%s): %v", pr.GetGitRefName(), err)
+	}
+	if baseSHA, err = gitRepo.GetBranchCommitID(pr.BaseBranch); err != nil {
+		return "", "", fmt.Errorf("GetBranchCommitID(%s): %v", pr.BaseBranch, err)
+	}
+	return headSHA, baseSHA, nil
+}
+
+// testPullRequest tests the patch of the pull request, unless neither its head nor its base
+// changed since the last test. In that case the result of the last test is kept.
+func testPullRequest(pr *models.PullRequest) error {
+	headSHA, baseSHA, err := getPullRequestTestSHAs(pr)
+	if err != nil {
+		log.Error("getPullRequestTestSHAs[%d]: %v", pr.ID, err)
+	} else if pr.LastTestedHeadSHA == headSHA && pr.LastTestedBaseSHA == baseSHA {
+		log.Trace("PullRequest[%d]: head and base unchanged since last test - skipping", pr.ID)
+		if len(pr.ConflictedFiles) > 0 {
+			pr.Status = models.PullRequestStatusConflict
+		} else {
+			pr.Status = models.PullRequestStatusMergeable
+		}
+		return nil
+	}
+
+	if err := checkConflicts(pr); err != nil {
+		return err
+	}
+
+	pr.LastTestedHeadSHA, pr.LastTestedBaseSHA = headSHA, baseSHA
+	// A conflict without any known file can't be restored from the stored result
+	if pr.Status == models.PullRequestStatusConflict && len(pr.ConflictedFiles) == 0 {
+		pr.LastTestedHeadSHA, pr.LastTestedBaseSHA = "", ""
+	}
+	return nil
+}
+
+// checkConflicts updates the status and the conflicted files of the pull request. The cheap
+// PullRequest.QuickConflictCheck is used where possible, TestPatch otherwise.
+func checkConflicts(pr *models.PullRequest) error {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return fmt.Errorf("LoadBaseRepo: %v", err)
+	}
+	prUnit, err := pr.BaseRepo.GetUnit(models.UnitTypePullRequests)
+	if err != nil {
+		return err
+	}
+	// merge-tree can't ignore whitespace changes like git apply does
+	if prUnit.PullRequestsConfig().IgnoreWhitespaceConflicts {
+		return TestPatch(pr)
+	}
+
+	conflict, err := pr.QuickConflictCheck()
+	if err != nil {
+		if models.IsErrQuickConflictCheckUnsupported(err) {
+			log.Trace("PullRequest[%d]: %v - falling back to TestPatch", pr.ID, err)
+			return TestPatch(pr)
+		}
+		return err
+	}
+
+	if err := checkPullFilesProtection(pr, pr.BaseRepo.RepoPath(), pr.GetGitRefName()); err != nil {
+		return err
+	}
+
+	if conflict {
+		pr.Status = models.PullRequestStatusConflict
+		log.Trace("Found %d files conflicted: %v", len(pr.ConflictedFiles), pr.ConflictedFiles)
+	} else {
+		pr.Status = models.PullRequestStatusMergeable
+	}
+	return nil
+}
+
+// updateNeedsRebaseLabel adds the needs rebase label configured for the repository to the tested
+// pull request if it conflicts with its base branch or the base branch has moved on since the merge
+// base, and removes it otherwise. Only changing the labels adds an entry to the timeline.
+func updateNeedsRebaseLabel(pr *models.PullRequest) error {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return err
+	}
+	prUnit, err := pr.BaseRepo.GetUnit(models.UnitTypePullRequests)
+	if err != nil {
+		if models.IsErrUnitTypeNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	labelName := prUnit.PullRequestsConfig().NeedsRebaseLabel
+	if len(labelName) == 0 {
+		return nil
+	}
+
+	if err = pr.LoadIssue(); err != nil {
+		return err
+	} else if pr.Issue.IsClosed || pr.Status == models.PullRequestStatusChecking {
+		return nil
+	}
+
+	needsRebase := pr.Status == models.PullRequestStatusConflict
+	if !needsRebase {
+		if needsRebase, err = pr.IsBaseBranchUpdatedSinceMergeBase(); err != nil {
+			return fmt.Errorf("IsBaseBranchUpdatedSinceMergeBase: %v", err)
+		}
+	}
+
+	label, err := models.GetLabelInRepoByName(pr.BaseRepo.ID, labelName)
+	if err != nil {
+		if !models.IsErrLabelNotExist(err) {
+			return err
+		} else if !needsRebase {
+			return nil
+		}
+		label = &models.Label{
+			RepoID:      pr.BaseRepo.ID,
+			Name:        labelName,
+			Description: "Conflicts with or is behind its base branch",
+			Color:       "#fbca04",
+		}
+		if err = models.NewLabel(label); err != nil {
+			return err
+		}
+	}
+
+	doer, err := getAutomationDoer(pr.BaseRepo)
+	if err != nil {
+		return err
+	}
+	pr.Issue.Repo = pr.BaseRepo
+	if needsRebase {
+		return models.NewIssueLabel(pr.Issue, label, doer)
+	}
+	return models.DeleteIssueLabel(pr.Issue, label, doer)
+}
+
 // getMergeCommit checks if a pull request got merged
 // Returns the git.Commit of the pull request if merged
 func getMergeCommit(pr *models.PullRequest) (*git.Commit, error) {
@@ -64,24 +237,32 @@ func getMergeCommit(pr *models.PullRequest) (*git.Commit, error) {
 		}
 	}
 
-	indexTmpPath, err := ioutil.TempDir(os.TempDir(), "gitea-"+pr.BaseRepo.Name)
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
 	if err != nil {
-		return nil, fmt.Errorf("Failed to create temp dir for repository %s: %v", pr.BaseRepo.RepoPath(), err)
+		return nil, fmt.Errorf("OpenRepository: %v", err)
 	}
-	defer os.RemoveAll(indexTmpPath)
+	defer gitRepo.Close()
 
 	headFile := pr.GetGitRefName()
 
 	// Check if a pull request is merged into BaseBranch
-	_, err = git.NewCommand("merge-base", "--is-ancestor", headFile, pr.BaseBranch).RunInDirWithEnv(pr.BaseRepo.RepoPath(), []string{"GIT_INDEX_FILE=" + indexTmpPath, "GIT_DIR=" + pr.BaseRepo.RepoPath()})
+	isMerged, err := gitRepo.IsAncestor(headFile, pr.BaseBranch)
 	if err != nil {
-		// Errors are signaled by a non-zero status that is not 1
-		if strings.Contains(err.Error(), "exit status 1") {
+		// A shallow repository lacks the history to tell whether the head got merged
+		if git.IsErrShallowHistory(err) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("git merge-base --is-ancestor: %v", err)
+		return nil, err
+	} else if !isMerged {
+		return nil, nil
 	}
 
+	indexTmpPath, err := ioutil.TempDir(os.TempDir(), "gitea-"+pr.BaseRepo.Name)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create temp dir for repository %s: %v", pr.BaseRepo.RepoPath(), err)
+	}
+	defer os.RemoveAll(indexTmpPath)
+
 	commitIDBytes, err := ioutil.ReadFile(pr.BaseRepo.RepoPath() + "/" + headFile)
 	if err != nil {
 		return nil, fmt.Errorf("ReadFile(%s): %v", headFile, err)
@@ -101,12 +282,6 @@ func getMergeCommit(pr *models.PullRequest) (*git.Commit, error) {
 		mergeCommit = commitID[:40]
 	}
 
-	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
-	if err != nil {
-		return nil, fmt.Errorf("OpenRepository: %v", err)
-	}
-	defer gitRepo.Close()
-
 	commit, err := gitRepo.GetCommit(mergeCommit[:40])
 	if err != nil {
 		return nil, fmt.Errorf("GetCommit: %v", err)
@@ -196,11 +371,19 @@ func TestPullRequests(ctx context.Context) {
 				continue
 			} else if manuallyMerged(pr) {
 				continue
-			} else if err = TestPatch(pr); err != nil {
-				log.Error("testPatch[%d]: %v", pr.ID, err)
+			}
+			// Make sure the conflict check runs against the current head of the pull request
+			if err = SyncPullRequestHeadRef(pr); err != nil {
+				log.Error("SyncPullRequestHeadRef[%d]: %v", pr.ID, err)
+			}
+			if err = testPullRequest(pr); err != nil {
+				log.Error("testPullRequest[%d]: %v", pr.ID, err)
 				continue
 			}
 			checkAndUpdateStatus(pr)
+			if err = updateNeedsRebaseLabel(pr); err != nil {
+				log.Error("updateNeedsRebaseLabel[%d]: %v", pr.ID, err)
+			}
 		case <-ctx.Done():
 			pullRequestQueue.Close()
 			log.Info("PID: %d Pull Request testing shutdown", os.Getpid())
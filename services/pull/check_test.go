@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -32,3 +33,138 @@ func TestPullRequest_AddToTaskQueue(t *testing.T) {
 	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 1}).(*models.PullRequest)
 	assert.Equal(t, models.PullRequestStatusChecking, pr.Status)
 }
+
+func TestRetestAllPullRequests(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.LastTestedHeadSHA = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr.LastTestedBaseSHA = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	assert.NoError(t, pr.UpdateCols("last_tested_head_sha, last_tested_base_sha"))
+
+	count, err := RetestAllPullRequests(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}, "last_tested_head_sha = '' AND last_tested_base_sha = ''")
+
+	select {
+	case id := <-pullRequestQueue.Queue():
+		assert.EqualValues(t, "2", id)
+	case <-time.After(time.Second):
+		assert.Fail(t, "Timeout: nothing was added to pullRequestQueue")
+	}
+
+	// already queued
+	count, err = RetestAllPullRequests(1)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	pullRequestQueue.Remove(2)
+}
+
+func TestTestPullRequest(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pushReadmeChange(t, "test-head", "# repo1\n\nchanged on head\n")
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.HeadBranch = "test-head"
+	assert.NoError(t, SyncPullRequestHeadRef(pr))
+
+	assert.NoError(t, testPullRequest(pr))
+	assert.Equal(t, models.PullRequestStatusMergeable, pr.Status)
+	headSHA, baseSHA, err := getPullRequestTestSHAs(pr)
+	assert.NoError(t, err)
+	assert.Equal(t, headSHA, pr.LastTestedHeadSHA)
+	assert.Equal(t, baseSHA, pr.LastTestedBaseSHA)
+
+	// unchanged head and base keep the stored result without testing the patch
+	pr.ConflictedFiles = []string{"README.md"}
+	assert.NoError(t, testPullRequest(pr))
+	assert.Equal(t, models.PullRequestStatusConflict, pr.Status)
+
+	// a changed base is tested again
+	pr.LastTestedBaseSHA = ""
+	assert.NoError(t, testPullRequest(pr))
+	assert.Equal(t, models.PullRequestStatusMergeable, pr.Status)
+	assert.Empty(t, pr.ConflictedFiles)
+	assert.Equal(t, baseSHA, pr.LastTestedBaseSHA)
+}
+
+func TestPullRequest_QuickConflictCheck(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pushReadmeChange(t, "test-head", "# repo1\n\nchanged on head\n")
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.HeadBranch = "test-head"
+	assert.NoError(t, SyncPullRequestHeadRef(pr))
+	assert.NoError(t, pr.LoadBaseRepo())
+
+	conflicted, err := pr.QuickConflictCheck()
+	if models.IsErrQuickConflictCheckUnsupported(err) {
+		t.Skip(err.Error())
+	}
+	assert.NoError(t, err)
+	assert.False(t, conflicted)
+	assert.Empty(t, pr.ConflictedFiles)
+	assert.NotEmpty(t, pr.MergeBase)
+
+	pushReadmeChange(t, "master", "# repo1\n\nchanged on base\n")
+
+	conflicted, err = pr.QuickConflictCheck()
+	assert.NoError(t, err)
+	assert.True(t, conflicted)
+	assert.Equal(t, []string{"README.md"}, pr.ConflictedFiles)
+
+	assert.NoError(t, checkConflicts(pr))
+	assert.Equal(t, models.PullRequestStatusConflict, pr.Status)
+}
+
+func TestUpdateNeedsRebaseLabel(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	const initialCommitID = "65f1bf27bc3bf70f64657658635e66094edbcb4d"
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	_, err := git.NewCommand("update-ref", pr.GetGitRefName(), initialCommitID).RunInDir(models.RepoPath("user2", "repo1"))
+	assert.NoError(t, err)
+	pr.MergeBase = initialCommitID
+
+	// repositories have to configure the label
+	pr.Status = models.PullRequestStatusConflict
+	assert.NoError(t, updateNeedsRebaseLabel(pr))
+	models.AssertNotExistsBean(t, &models.Label{RepoID: 1, Name: "needs-rebase"})
+
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+	unit, err := repo.GetUnit(models.UnitTypePullRequests)
+	assert.NoError(t, err)
+	unit.PullRequestsConfig().NeedsRebaseLabel = "needs-rebase"
+	assert.NoError(t, models.UpdateRepositoryUnits(repo, []models.RepoUnit{*unit}))
+	pr.BaseRepo = nil
+
+	// up to date pull requests don't need the label to exist
+	pr.Status = models.PullRequestStatusMergeable
+	assert.NoError(t, updateNeedsRebaseLabel(pr))
+	models.AssertNotExistsBean(t, &models.Label{RepoID: 1, Name: "needs-rebase"})
+
+	// conflicts add the label, only once
+	pr.Status = models.PullRequestStatusConflict
+	assert.NoError(t, updateNeedsRebaseLabel(pr))
+	assert.NoError(t, updateNeedsRebaseLabel(pr))
+	label := models.AssertExistsAndLoadBean(t, &models.Label{RepoID: 1, Name: "needs-rebase"}).(*models.Label)
+	assert.True(t, models.HasIssueLabel(pr.IssueID, label.ID))
+	models.AssertCount(t, &models.Comment{IssueID: pr.IssueID, Type: models.CommentTypeLabel, LabelID: label.ID}, 1)
+
+	// mergeable again
+	pr.Status = models.PullRequestStatusMergeable
+	assert.NoError(t, updateNeedsRebaseLabel(pr))
+	assert.NoError(t, updateNeedsRebaseLabel(pr))
+	assert.False(t, models.HasIssueLabel(pr.IssueID, label.ID))
+	models.AssertCount(t, &models.Comment{IssueID: pr.IssueID, Type: models.CommentTypeLabel, LabelID: label.ID}, 2)
+
+	// the base branch moved on since the merge base
+	pushReadmeChange(t, "master", "# repo1\n\nchanged on master\n")
+	assert.NoError(t, updateNeedsRebaseLabel(pr))
+	assert.True(t, models.HasIssueLabel(pr.IssueID, label.ID))
+	models.AssertCount(t, &models.Comment{IssueID: pr.IssueID, Type: models.CommentTypeLabel, LabelID: label.ID}, 3)
+}
@@ -13,67 +13,60 @@ import (
 
 // IsCommitStatusContextSuccess returns true if all required status check contexts succeed.
 func IsCommitStatusContextSuccess(commitStatuses []*models.CommitStatus, requiredContexts []string) bool {
-	// If no specific context is required, require that last commit status is a success
-	if len(requiredContexts) == 0 {
-		status := models.CalcCommitStatus(commitStatuses)
-		if status == nil || status.State != models.CommitStatusSuccess {
-			return false
-		}
-		return true
-	}
-
-	for _, ctx := range requiredContexts {
-		var found bool
-		for _, commitStatus := range commitStatuses {
-			if commitStatus.Context == ctx {
-				if commitStatus.State != models.CommitStatusSuccess {
-					return false
-				}
+	return models.IsCommitStatusContextSuccess(commitStatuses, requiredContexts)
+}
 
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
+// IsPullCommitStatusPass returns if all required status checks PASS
+func IsPullCommitStatusPass(pr *models.PullRequest) (bool, error) {
+	if err := CheckPullCommitStatus(pr); err != nil {
+		if models.IsErrRequiredStatusMissing(err) {
+			return false, nil
 		}
+		return false, err
 	}
-	return true
+	return true, nil
 }
 
-// IsPullCommitStatusPass returns if all required status checks PASS
-func IsPullCommitStatusPass(pr *models.PullRequest) (bool, error) {
+// CheckPullCommitStatus checks that all required status checks of the pull request
+// succeeded and returns an ErrRequiredStatusMissing listing the contexts which did not.
+func CheckPullCommitStatus(pr *models.PullRequest) error {
 	if err := pr.LoadProtectedBranch(); err != nil {
-		return false, errors.Wrap(err, "GetLatestCommitStatus")
+		return errors.Wrap(err, "GetLatestCommitStatus")
 	}
 	if pr.ProtectedBranch == nil || !pr.ProtectedBranch.EnableStatusCheck {
-		return true, nil
+		return nil
 	}
 
 	// check if all required status checks are successful
 	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
 	if err != nil {
-		return false, errors.Wrap(err, "OpenRepository")
+		return errors.Wrap(err, "OpenRepository")
 	}
 	defer headGitRepo.Close()
 
 	if !headGitRepo.IsBranchExist(pr.HeadBranch) {
-		return false, errors.New("Head branch does not exist, can not merge")
+		return errors.New("Head branch does not exist, can not merge")
 	}
 
 	sha, err := headGitRepo.GetBranchCommitID(pr.HeadBranch)
 	if err != nil {
-		return false, errors.Wrap(err, "GetBranchCommitID")
+		return errors.Wrap(err, "GetBranchCommitID")
 	}
 
 	if err := pr.LoadBaseRepo(); err != nil {
-		return false, errors.Wrap(err, "LoadBaseRepo")
+		return errors.Wrap(err, "LoadBaseRepo")
 	}
 
 	commitStatuses, err := models.GetLatestCommitStatus(pr.BaseRepo, sha, 0)
 	if err != nil {
-		return false, errors.Wrap(err, "GetLatestCommitStatus")
+		return errors.Wrap(err, "GetLatestCommitStatus")
 	}
 
-	return IsCommitStatusContextSuccess(commitStatuses, pr.ProtectedBranch.StatusCheckContexts), nil
+	if !IsCommitStatusContextSuccess(commitStatuses, pr.ProtectedBranch.StatusCheckContexts) {
+		return models.ErrRequiredStatusMissing{
+			ID:       pr.ID,
+			Contexts: models.GetMissingRequiredStatusContexts(commitStatuses, pr.ProtectedBranch.StatusCheckContexts),
+		}
+	}
+	return nil
 }
@@ -8,6 +8,7 @@ package pull
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -29,9 +30,53 @@ import (
 	"github.com/mcuadros/go-version"
 )
 
+// MergeOptions represents the options of a pull request merge
+type MergeOptions struct {
+	Style   models.MergeStyle
+	Message string
+	// UpToCommit, if set, restricts the merge to the commits of the pull request up to and including this commit.
+	// The pull request stays open for the remaining commits unless UpToCommit is its head.
+	UpToCommit string
+	// DeleteHeadBranchAfterMerge deletes the head branch once the pull request has been marked as merged.
+	// A failure to delete the branch does not undo the merge and is returned as ErrHeadBranchDeletionFailed.
+	DeleteHeadBranchAfterMerge bool
+	// SquashKeepAuthor makes the poster of the pull request the author of a squash commit, the merger stays its committer.
+	// The merger is the author if it is not set or the poster has no usable email address.
+	SquashKeepAuthor bool
+	// AllowEmpty records an empty commit if the pull request does not change anything,
+	// the merge fails with ErrMergeEmptyDiff otherwise.
+	AllowEmpty bool
+	// Force lets admins of the base repository merge even if the base branch protection blocks it.
+	// The override is recorded as a comment with ForceReason, which is required.
+	Force       bool
+	ForceReason string
+	// Trailers are appended to the commit message, e.g. "Signed-off-by: Name <email>". Reviewed-by trailers
+	// are generated for the approving reviewers if enabled for the repository. Duplicates are skipped.
+	Trailers []string
+	// PreserveCommitterDate keeps the timestamps of the commits rebased by MergeStyleRebase and MergeStyleRebaseMerge
+	// meaningful, by setting their committer date to their author date with "git rebase --committer-date-is-author-date".
+	// Their committer date is the time of the merge otherwise.
+	PreserveCommitterDate bool
+	// BackportToBranches are branches of the base repository the merged changes are cherry-picked onto after the merge,
+	// each in a new branch with a pull request of its own. A failed backport does not fail the merge,
+	// the outcome for each branch is recorded in BackportResults.
+	BackportToBranches []string
+	BackportResults    []*BackportResult
+}
+
 // Merge merges pull request to base repository.
+func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, mergeStyle models.MergeStyle, message string) error {
+	return MergeWithOptions(pr, doer, baseGitRepo, &MergeOptions{
+		Style:            mergeStyle,
+		Message:          message,
+		SquashKeepAuthor: true,
+	})
+}
+
+// MergeWithOptions merges pull request to base repository with the given options.
 // FIXME: add repoWorkingPull make sure two merges does not happen at same time.
-func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, mergeStyle models.MergeStyle, message string) (err error) {
+func MergeWithOptions(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repository, opts *MergeOptions) (err error) {
+	mergeStyle, message := opts.Style, opts.Message
 	binVersion, err := git.BinVersion()
 	if err != nil {
 		log.Error("git.BinVersion: %v", err)
@@ -53,15 +98,35 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 	}
 	prConfig := prUnit.PullRequestsConfig()
 
-	if err := pr.CheckUserAllowedToMerge(doer); err != nil {
-		log.Error("CheckUserAllowedToMerge(%v): %v", doer, err)
-		return fmt.Errorf("CheckUserAllowedToMerge: %v", err)
+	overridden, err := pr.CheckUserAllowedToForceMerge(doer, opts.Force, opts.ForceReason)
+	if err != nil {
+		if models.IsErrMergeOnHold(err) || models.IsErrProtectedFilesChanged(err) || models.IsErrNotAllowedToMerge(err) || models.IsErrUnresolvedConversations(err) {
+			return err
+		}
+		log.Error("CheckUserAllowedToForceMerge(%v): %v", doer, err)
+		return fmt.Errorf("CheckUserAllowedToForceMerge: %v", err)
 	}
 
 	// Check if merge style is correct and allowed
 	if !prConfig.IsMergeStyleAllowed(mergeStyle) {
 		return models.ErrInvalidMergeStyle{ID: pr.BaseRepo.ID, Style: mergeStyle}
 	}
+	if err := pr.CheckMergeStyleAllowed(mergeStyle); err != nil {
+		return err
+	}
+
+	trailers := opts.Trailers
+	if prConfig.AddReviewedByTrailers {
+		reviewedBy, err := getReviewedByTrailers(pr)
+		if err != nil {
+			log.Error("getReviewedByTrailers: %v", err)
+			return err
+		}
+		trailers = append(append([]string{}, trailers...), reviewedBy...)
+	}
+	if message, err = addTrailers(message, trailers); err != nil {
+		return err
+	}
 
 	defer func() {
 		go AddTestPullRequestTask(doer, pr.BaseRepo.ID, pr.BaseBranch, false)
@@ -85,6 +150,29 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 
 	var outbuf, errbuf strings.Builder
 
+	// Only bring in the commits up to UpToCommit
+	isPartialMerge := false
+	if len(opts.UpToCommit) > 0 {
+		if isPartialMerge, err = resetTrackingToCommit(pr, tmpBasePath, baseBranch, trackingBranch, opts.UpToCommit); err != nil {
+			return err
+		}
+	}
+
+	// The head branch is only deleted after the merge if it still points to the merged commit
+	mergedHeadCommitID, err := git.GetFullCommitID(tmpBasePath, trackingBranch)
+	if err != nil {
+		return fmt.Errorf("Failed to get full commit id for %s: %v", trackingBranch, err)
+	}
+
+	isEmpty, err := isEmptyDiff(tmpBasePath, baseBranch, trackingBranch)
+	if err != nil {
+		log.Error("isEmptyDiff(%s, %s, %s): %v", tmpBasePath, baseBranch, trackingBranch, err)
+		return err
+	}
+	if isEmpty && !opts.AllowEmpty {
+		return models.ErrMergeEmptyDiff{ID: pr.ID, Style: mergeStyle}
+	}
+
 	// Enable sparse-checkout
 	sparseCheckoutList, err := getDiffTree(tmpBasePath, baseBranch, trackingBranch)
 	if err != nil {
@@ -182,6 +270,8 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		"GIT_COMMITTER_EMAIL="+sig.Email,
 		"GIT_COMMITTER_DATE="+commitTimeStr,
 	)
+	// emails of the commits created by the merge itself
+	mergeEmails := []string{sig.Email}
 
 	// Merge commits.
 	switch mergeStyle {
@@ -192,7 +282,7 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 			return err
 		}
 
-		if err := commitAndSignNoAuthor(pr, message, signArg, tmpBasePath, env); err != nil {
+		if err := commitAndSignNoAuthor(pr, message, signArg, tmpBasePath, env, isEmpty); err != nil {
 			log.Error("Unable to make final commit: %v", err)
 			return err
 		}
@@ -208,7 +298,12 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		errbuf.Reset()
 
 		// Rebase before merging
-		if err := git.NewCommand("rebase", baseBranch).RunInDirPipeline(tmpBasePath, &outbuf, &errbuf); err != nil {
+		cmd := git.NewCommand("rebase")
+		if opts.PreserveCommitterDate {
+			cmd.AddArguments("--committer-date-is-author-date")
+		}
+		cmd.AddArguments(baseBranch)
+		if err := cmd.RunInDirPipeline(tmpBasePath, &outbuf, &errbuf); err != nil {
 			// Rebase will leave a REBASE_HEAD file in .git if there is a conflict
 			if _, statErr := os.Stat(filepath.Join(tmpBasePath, ".git", "REBASE_HEAD")); statErr == nil {
 				// The original commit SHA1 that is failing will be in .git/rebase-apply/original-commit
@@ -241,7 +336,7 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		outbuf.Reset()
 		errbuf.Reset()
 
-		cmd := git.NewCommand("merge")
+		cmd = git.NewCommand("merge")
 		if mergeStyle == models.MergeStyleRebase {
 			cmd.AddArguments("--ff-only")
 		} else {
@@ -254,8 +349,9 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 			log.Error("Unable to merge staging into base: %v", err)
 			return err
 		}
-		if mergeStyle == models.MergeStyleRebaseMerge {
-			if err := commitAndSignNoAuthor(pr, message, signArg, tmpBasePath, env); err != nil {
+		// Rebasing drops the commits without changes, so an empty merge needs a commit of its own
+		if mergeStyle == models.MergeStyleRebaseMerge || isEmpty {
+			if err := commitAndSignNoAuthor(pr, message, signArg, tmpBasePath, env, isEmpty); err != nil {
 				log.Error("Unable to make final commit: %v", err)
 				return err
 			}
@@ -268,14 +364,42 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 			return err
 		}
 
-		sig := pr.Issue.Poster.NewGitSig()
+		sig := doer.NewGitSig()
+		if opts.SquashKeepAuthor {
+			sig = getSquashAuthorSignature(pr, doer)
+			mergeEmails = append(mergeEmails, sig.Email)
+		}
+		if prConfig.AddCoAuthoredByTrailers {
+			author := doer
+			if sig.Email != doer.GetEmail() {
+				author = pr.Issue.Poster
+			}
+			coAuthoredBy, err := getCoAuthoredByTrailers(tmpBasePath, baseBranch, trackingBranch, author, sig)
+			if err != nil {
+				log.Error("getCoAuthoredByTrailers: %v", err)
+				return err
+			}
+			if message, err = addTrailers(message, coAuthoredBy); err != nil {
+				return err
+			}
+		}
+		// Pass subject and body separately, git joins them with a blank line
+		subject, body := splitCommitMessage(message)
+		messageArgs := []string{"-m", subject}
+		if len(body) > 0 {
+			messageArgs = append(messageArgs, "-m", body)
+		}
+		cmd = git.NewCommand("commit")
+		if isEmpty {
+			cmd.AddArguments("--allow-empty")
+		}
 		if signArg == "" {
-			if err := git.NewCommand("commit", fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email), "-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+			if err := cmd.AddArguments(fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email)).AddArguments(messageArgs...).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
 				log.Error("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 				return fmt.Errorf("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 			}
 		} else {
-			if err := git.NewCommand("commit", signArg, fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email), "-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+			if err := cmd.AddArguments(signArg, fmt.Sprintf("--author='%s <%s>'", sig.Name, sig.Email)).AddArguments(messageArgs...).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
 				log.Error("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 				return fmt.Errorf("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 			}
@@ -296,6 +420,19 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		return fmt.Errorf("Failed to get full commit id for origin/%s: %v", pr.BaseBranch, err)
 	}
 
+	// Protected branches of repositories requiring verified commit authors can only
+	// receive commits from them, besides the ones created by the merge
+	if pr.BaseRepo.RequireVerifiedCommitAuthors {
+		if err := pr.LoadProtectedBranch(); err != nil {
+			return fmt.Errorf("LoadProtectedBranch: %v", err)
+		}
+		if pr.ProtectedBranch != nil && pr.ProtectedBranch.IsProtected() {
+			if err := pr.BaseRepo.CheckCommitAuthors(tmpBasePath, nil, []string{mergeBaseSHA + ".." + mergeHeadSHA}, mergeEmails...); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Now it's questionable about where this should go - either after or before the push
 	// I think in the interests of data safety - failures to push to the lfs should prevent
 	// the merge as you can always remerge.
@@ -325,6 +462,9 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 		pr.BaseRepo.Name,
 		pr.ID,
 	)
+	if overridden {
+		env = append(env, models.EnvForceMerge+"=true")
+	}
 
 	// Push back to upstream.
 	if err := git.NewCommand("push", "origin", baseBranch+":"+pr.BaseBranch).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
@@ -341,6 +481,27 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 	outbuf.Reset()
 	errbuf.Reset()
 
+	if overridden {
+		log.Warn("Pull request [%d] merged by %s overriding the protection of %s: %s", pr.ID, doer.Name, pr.BaseBranch, opts.ForceReason)
+		if err = pr.LoadIssue(); err != nil {
+			log.Error("LoadIssue [%d]: %v", pr.ID, err)
+		} else if _, err = models.CreateComment(&models.CreateCommentOptions{
+			Type:    models.CommentTypeMergeOverride,
+			Doer:    doer,
+			Repo:    pr.BaseRepo,
+			Issue:   pr.Issue,
+			Content: strings.TrimSpace(opts.ForceReason),
+		}); err != nil {
+			log.Error("CreateComment [%d]: %v", pr.ID, err)
+		}
+	}
+
+	if isPartialMerge {
+		log.Trace("Pull request [%d] partially merged up to %s", pr.ID, opts.UpToCommit)
+		cache.Remove(pr.BaseRepo.GetCommitsCountCacheKey(pr.BaseBranch, true))
+		return nil
+	}
+
 	pr.MergedCommitID, err = baseGitRepo.GetBranchCommitID(pr.BaseBranch)
 	if err != nil {
 		return fmt.Errorf("GetBranchCommit: %v", err)
@@ -350,20 +511,43 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 	pr.Merger = doer
 	pr.MergerID = doer.ID
 
+	isMerged := true
 	if err = pr.SetMerged(); err != nil {
 		log.Error("setMerged [%d]: %v", pr.ID, err)
+		isMerged = false
 	}
 
 	notification.NotifyMergePullRequest(pr, doer, baseGitRepo)
 
+	if isMerged {
+		closeIssuesOnMerge(pr, doer)
+	}
+
 	// Reset cached commit count
 	cache.Remove(pr.Issue.Repo.GetCommitsCountCacheKey(pr.BaseBranch, true))
 
+	if len(opts.BackportToBranches) > 0 && isMerged {
+		opts.BackportResults = backportPullRequest(pr, doer, mergeBaseSHA, mergeHeadSHA, opts.BackportToBranches)
+	}
+
+	var deleteErr error
+	if opts.DeleteHeadBranchAfterMerge && isMerged {
+		if err = deleteHeadBranch(pr, doer, mergedHeadCommitID); err != nil {
+			log.Error("deleteHeadBranch [%d]: %v", pr.ID, err)
+			deleteErr = models.ErrHeadBranchDeletionFailed{
+				ID:            pr.ID,
+				Branch:        pr.HeadBranch,
+				HasNewCommits: err == errHeadBranchHasNewCommits,
+				Err:           err,
+			}
+		}
+	}
+
 	// Resolve cross references
 	refs, err := pr.ResolveCrossReferences()
 	if err != nil {
 		log.Error("ResolveCrossReferences: %v", err)
-		return nil
+		return deleteErr
 	}
 
 	for _, ref := range refs {
@@ -374,23 +558,228 @@ func Merge(pr *models.PullRequest, doer *models.User, baseGitRepo *git.Repositor
 			return err
 		}
 		close := (ref.RefAction == references.XRefActionCloses)
+		// The issue may already have been closed by the closing keywords of the pull request
+		if ref.Issue.IsClosed == close {
+			continue
+		}
 		if err = issue_service.ChangeStatus(ref.Issue, doer, close); err != nil {
 			return err
 		}
 	}
 
+	return deleteErr
+}
+
+// closeIssuesOnMerge closes the issues resolved by the closing keywords of a merged pull request.
+// Issues of other repositories are only closed if the doer may write to their issues, issues
+// with open dependencies are left open.
+func closeIssuesOnMerge(pr *models.PullRequest, doer *models.User) {
+	issues, err := pr.GetClosingIssues()
+	if err != nil {
+		log.Error("PullRequest[%d].GetClosingIssues: %v", pr.ID, err)
+		return
+	}
+
+	for _, issue := range issues {
+		if issue.IsClosed {
+			continue
+		}
+		if issue.RepoID != pr.Issue.RepoID {
+			perm, err := models.GetUserRepoPermission(issue.Repo, doer)
+			if err != nil {
+				log.Error("GetUserRepoPermission: %v", err)
+				continue
+			}
+			if !perm.CanWrite(models.UnitTypeIssues) {
+				continue
+			}
+		}
+		if err = issue_service.ChangeStatus(issue, doer, true); err != nil {
+			if !models.IsErrDependenciesLeft(err) && !models.IsErrIssueWasClosed(err) {
+				log.Error("Issue[%d].ChangeStatus: %v", issue.ID, err)
+			}
+		}
+	}
+}
+
+var errHeadBranchHasNewCommits = errors.New("head branch has new commits since the merge")
+
+// deleteHeadBranch deletes the head branch of a merged pull request if the doer is allowed to,
+// the branch is neither protected nor the default branch, no other open pull request uses it
+// and it still points to mergedHeadCommitID, the commit which has been merged.
+func deleteHeadBranch(pr *models.PullRequest, doer *models.User, mergedHeadCommitID string) error {
+	if pr.HeadRepo == nil {
+		// Forked repository has already been deleted
+		return fmt.Errorf("head repository does not exist")
+	}
+	if err := pr.HeadRepo.GetOwner(); err != nil {
+		return fmt.Errorf("HeadRepo.GetOwner: %v", err)
+	}
+
+	perm, err := models.GetUserRepoPermission(pr.HeadRepo, doer)
+	if err != nil {
+		return fmt.Errorf("GetUserRepoPermission: %v", err)
+	}
+	if !perm.CanWrite(models.UnitTypeCode) {
+		return fmt.Errorf("user %s has no write permission on %s", doer.Name, pr.HeadRepo.FullName())
+	}
+
+	if pr.HeadBranch == pr.HeadRepo.DefaultBranch {
+		return fmt.Errorf("branch %s is the default branch", pr.HeadBranch)
+	}
+	if protected, err := pr.HeadRepo.IsProtectedBranch(pr.HeadBranch, doer); err != nil {
+		return fmt.Errorf("IsProtectedBranch: %v", err)
+	} else if protected {
+		return fmt.Errorf("branch %s is protected", pr.HeadBranch)
+	}
+
+	prs, err := models.GetUnmergedPullRequestsByHeadInfo(pr.HeadRepoID, pr.HeadBranch)
+	if err != nil {
+		return fmt.Errorf("GetUnmergedPullRequestsByHeadInfo: %v", err)
+	}
+	for _, other := range prs {
+		if other.ID != pr.ID {
+			return fmt.Errorf("branch %s is used by pull request %d", pr.HeadBranch, other.ID)
+		}
+	}
+
+	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
+	if err != nil {
+		return fmt.Errorf("OpenRepository[%s]: %v", pr.HeadRepo.RepoPath(), err)
+	}
+	defer headGitRepo.Close()
+
+	if !headGitRepo.IsBranchExist(pr.HeadBranch) {
+		return fmt.Errorf("branch %s does not exist", pr.HeadBranch)
+	}
+	branchCommitID, err := headGitRepo.GetBranchCommitID(pr.HeadBranch)
+	if err != nil {
+		return fmt.Errorf("GetBranchCommitID: %v", err)
+	}
+	if branchCommitID != mergedHeadCommitID {
+		return errHeadBranchHasNewCommits
+	}
+
+	if err := headGitRepo.DeleteBranch(pr.HeadBranch, git.DeleteBranchOptions{
+		Force: true,
+	}); err != nil {
+		return fmt.Errorf("DeleteBranch: %v", err)
+	}
+
+	notification.NotifyDeleteRef(doer, pr.HeadRepo, "branch", git.BranchPrefix+pr.HeadBranch)
+
+	if err := models.AddDeletePRBranchComment(doer, pr.BaseRepo, pr.IssueID, pr.HeadBranch); err != nil {
+		// Do not fail here as branch has already been deleted
+		log.Error("AddDeletePRBranchComment: %v", err)
+	}
+
 	return nil
 }
 
-func commitAndSignNoAuthor(pr *models.PullRequest, message, signArg, tmpBasePath string, env []string) error {
+// resetTrackingToCommit points the tracking branch of the temporary repository to the given commit, which must be one of
+// the commits of the pull request. It returns true if the commit is not the head of the pull request.
+func resetTrackingToCommit(pr *models.PullRequest, tmpBasePath, baseBranch, trackingBranch, commitID string) (bool, error) {
+	tmpRepo, err := git.OpenRepository(tmpBasePath)
+	if err != nil {
+		return false, fmt.Errorf("OpenRepository[%s]: %v", tmpBasePath, err)
+	}
+	defer tmpRepo.Close()
+	commits, err := tmpRepo.GetCommits(baseBranch, trackingBranch, false)
+	if err != nil {
+		log.Error("GetCommits [%s:%s -> %s:%s]: %v", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err)
+		return false, fmt.Errorf("GetCommits [%s:%s -> %s:%s]: %v", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err)
+	}
+
+	fullCommitID, err := git.GetFullCommitID(tmpBasePath, commitID)
+	if err != nil {
+		if git.IsErrNotExist(err) {
+			return false, models.ErrInvalidMergeUpToCommit{ID: pr.ID, CommitSHA: commitID}
+		}
+		return false, err
+	}
+
+	found := false
+	for _, commit := range commits {
+		if commit.ID.String() == fullCommitID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false, models.ErrInvalidMergeUpToCommit{ID: pr.ID, CommitSHA: commitID}
+	}
+
+	// GetCommits lists the newest commit first
+	if commits[0].ID.String() == fullCommitID {
+		return false, nil
+	}
+
+	var outbuf, errbuf strings.Builder
+	if err := git.NewCommand("branch", "-f", trackingBranch, fullCommitID).RunInDirPipeline(tmpBasePath, &outbuf, &errbuf); err != nil {
+		log.Error("git branch -f %s %s [%s:%s -> %s:%s]: %v\n%s\n%s", trackingBranch, fullCommitID, pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
+		return false, fmt.Errorf("git branch -f %s %s [%s:%s -> %s:%s]: %v\n%s\n%s", trackingBranch, fullCommitID, pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
+	}
+	return true, nil
+}
+
+// splitCommitMessage splits a commit message into its subject, the first line, and its body
+func splitCommitMessage(message string) (subject, body string) {
+	message = strings.TrimSpace(message)
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		return strings.TrimSpace(message[:i]), strings.TrimSpace(message[i+1:])
+	}
+	return message, ""
+}
+
+// getSquashAuthorSignature returns the signature of the poster of the pull request using an email address
+// the poster has verified. It falls back to the signature of the merger if there is none.
+func getSquashAuthorSignature(pr *models.PullRequest, doer *models.User) *git.Signature {
+	if err := pr.LoadIssue(); err != nil {
+		log.Error("LoadIssue: %v", err)
+		return doer.NewGitSig()
+	}
+	if err := pr.Issue.LoadPoster(); err != nil {
+		log.Error("LoadPoster: %v", err)
+		return doer.NewGitSig()
+	}
+	poster := pr.Issue.Poster
+	if poster == nil || poster.ID <= 0 {
+		return doer.NewGitSig()
+	}
+
+	// Private email addresses are replaced by the no-reply address anyway
+	if poster.KeepEmailPrivate || (poster.IsActive && len(poster.Email) > 0) {
+		return poster.NewGitSig()
+	}
+
+	emails, err := models.GetEmailAddresses(poster.ID)
+	if err != nil {
+		log.Error("GetEmailAddresses[%d]: %v", poster.ID, err)
+		return doer.NewGitSig()
+	}
+	for _, email := range emails {
+		if !email.IsPrimary && email.IsActivated {
+			sig := poster.NewGitSig()
+			sig.Email = email.Email
+			return sig
+		}
+	}
+	return doer.NewGitSig()
+}
+
+func commitAndSignNoAuthor(pr *models.PullRequest, message, signArg, tmpBasePath string, env []string, allowEmpty bool) error {
 	var outbuf, errbuf strings.Builder
+	cmd := git.NewCommand("commit")
+	if allowEmpty {
+		cmd.AddArguments("--allow-empty")
+	}
 	if signArg == "" {
-		if err := git.NewCommand("commit", "-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+		if err := cmd.AddArguments("-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
 			log.Error("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 			return fmt.Errorf("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 		}
 	} else {
-		if err := git.NewCommand("commit", signArg, "-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+		if err := cmd.AddArguments(signArg, "-m", message).RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
 			log.Error("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 			return fmt.Errorf("git commit [%s:%s -> %s:%s]: %v\n%s\n%s", pr.HeadRepo.FullName(), pr.HeadBranch, pr.BaseRepo.FullName(), pr.BaseBranch, err, outbuf.String(), errbuf.String())
 		}
@@ -471,3 +860,77 @@ func getDiffTree(repoPath, baseBranch, headBranch string) (string, error) {
 
 	return out.String(), nil
 }
+
+// AvailableMergeStyles returns the merge styles doer can merge the pull request with.
+// Besides the merge styles allowed by the repository and the branch protection, it drops the
+// merge styles which would fail because of conflicts, so these are never offered.
+func AvailableMergeStyles(pr *models.PullRequest, doer *models.User) ([]models.MergeStyle, error) {
+	allowed, err := pr.AvailableMergeStyles(doer)
+	if err != nil || len(allowed) == 0 {
+		return allowed, err
+	}
+
+	styles := make([]models.MergeStyle, 0, len(allowed))
+	rebaseChecked, canRebase := false, false
+	for _, style := range allowed {
+		switch style {
+		case models.MergeStyleMerge, models.MergeStyleSquash:
+			if pr.Status == models.PullRequestStatusConflict {
+				continue
+			}
+		case models.MergeStyleRebase, models.MergeStyleRebaseMerge:
+			if !rebaseChecked {
+				rebaseChecked = true
+				// Commits which are already on top of the base branch don't need to be rebased at all
+				if canRebase, err = canFastForward(pr); err != nil {
+					return nil, err
+				} else if !canRebase {
+					if canRebase, _, err = CanRebaseOnto(pr, pr.BaseBranch); err != nil {
+						return nil, err
+					}
+				}
+			}
+			if !canRebase {
+				continue
+			}
+		}
+		styles = append(styles, style)
+	}
+	return styles, nil
+}
+
+// isEmptyDiff checks whether the head branch does not change anything compared to its merge base with the base branch
+func isEmptyDiff(repoPath, baseBranch, headBranch string) (bool, error) {
+	var outbuf, errbuf strings.Builder
+	if err := git.NewCommand("diff", "--quiet", baseBranch+"..."+headBranch, "--").RunInDirPipeline(repoPath, &outbuf, &errbuf); err != nil {
+		// git diff --quiet exits with 1 if there are differences
+		if strings.Contains(err.Error(), "exit status 1") {
+			return false, nil
+		}
+		return false, fmt.Errorf("git diff --quiet [%s base:%s head:%s]: %v\n%s", repoPath, baseBranch, headBranch, err, errbuf.String())
+	}
+	return true, nil
+}
+
+// canFastForward checks whether the base branch is an ancestor of the head of the pull request
+func canFastForward(pr *models.PullRequest) (bool, error) {
+	if err := pr.GetBaseRepo(); err != nil {
+		return false, fmt.Errorf("GetBaseRepo: %v", err)
+	}
+
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return false, fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer gitRepo.Close()
+
+	canFastForward, err := gitRepo.IsAncestor(git.BranchPrefix+pr.BaseBranch, pr.GetGitRefName())
+	if err != nil {
+		// Without the history leave it to the rebase check
+		if git.IsErrShallowHistory(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return canFastForward, nil
+}
@@ -0,0 +1,104 @@
+// Copyright 2019 The Gitea Authors.
+// All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetSquashAuthorSignature(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 4}).(*models.User)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.LoadIssue())
+
+	authorOf := func(poster *models.User) string {
+		pr.Issue.Poster = poster
+		pr.Issue.PosterID = poster.ID
+		return getSquashAuthorSignature(pr, doer).Email
+	}
+
+	user1 := models.AssertExistsAndLoadBean(t, &models.User{ID: 1}).(*models.User)
+	assert.Equal(t, "user1@example.com", authorOf(user1))
+
+	// private email address
+	user2 := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	assert.Equal(t, user2.GetEmail(), authorOf(user2))
+
+	// primary email address not activated, but another one is
+	user10 := models.AssertExistsAndLoadBean(t, &models.User{ID: 10}).(*models.User)
+	user10.IsActive = false
+	assert.Equal(t, "user101@example.com", authorOf(user10))
+
+	// no activated email address at all
+	user9 := models.AssertExistsAndLoadBean(t, &models.User{ID: 9}).(*models.User)
+	assert.Equal(t, "user4@example.com", authorOf(user9))
+
+	assert.Equal(t, "user4@example.com", authorOf(models.NewGhostUser()))
+}
+
+func TestDeleteHeadBranchHasNewCommits(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 1}).(*models.PullRequest)
+	assert.NoError(t, pr.GetHeadRepo())
+	assert.NoError(t, pr.GetBaseRepo())
+	pr.HeadBranch = "develop"
+
+	// the branch has moved on since the merged commit, so it is kept
+	err := deleteHeadBranch(pr, doer, "0000000000000000000000000000000000000000")
+	assert.Equal(t, errHeadBranchHasNewCommits, err)
+	assert.True(t, git.IsBranchExist(pr.HeadRepo.RepoPath(), pr.HeadBranch))
+}
+
+func TestAvailableMergeStyles(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pushReadmeChange(t, "styles-head", "# repo1\n\nchanged on head\n")
+
+	owner := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.HeadBranch = "styles-head"
+	assert.NoError(t, SyncPullRequestHeadRef(pr))
+
+	styles, err := AvailableMergeStyles(pr, owner)
+	assert.NoError(t, err)
+	assert.Equal(t, []models.MergeStyle{models.MergeStyleMerge, models.MergeStyleRebase, models.MergeStyleRebaseMerge, models.MergeStyleSquash}, styles)
+
+	// no permission to merge
+	reader := models.AssertExistsAndLoadBean(t, &models.User{ID: 5}).(*models.User)
+	styles, err = AvailableMergeStyles(pr, reader)
+	assert.NoError(t, err)
+	assert.Empty(t, styles)
+
+	// the commits of the pull request conflict with the base branch
+	pushReadmeChange(t, "master", "# repo1\n\nchanged on base\n")
+	pr.Status = models.PullRequestStatusConflict
+	styles, err = AvailableMergeStyles(pr, owner)
+	assert.NoError(t, err)
+	assert.Empty(t, styles)
+}
+
+func TestSplitCommitMessage(t *testing.T) {
+	for _, c := range []struct {
+		message, subject, body string
+	}{
+		{"title (#1)", "title (#1)", ""},
+		{"title (#1)\n\ndescription\n\nCo-authored-by: user1 <user1@example.com>\n", "title (#1)", "description\n\nCo-authored-by: user1 <user1@example.com>"},
+		{"  title\nbody", "title", "body"},
+	} {
+		subject, body := splitCommitMessage(c.message)
+		assert.Equal(t, c.subject, subject)
+		assert.Equal(t, c.body, body)
+	}
+}
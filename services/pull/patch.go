@@ -8,10 +8,12 @@ package pull
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 
 	"code.gitea.io/gitea/models"
@@ -61,6 +63,89 @@ func DownloadDiffOrPatch(pr *models.PullRequest, w io.Writer, patch bool) error
 	return nil
 }
 
+var errDiffTooLarge = errors.New("diff exceeds the size limit")
+
+// limitedWriter writes at most remaining bytes to w and fails afterwards
+type limitedWriter struct {
+	w         io.Writer
+	remaining int64
+	exceeded  bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) <= l.remaining {
+		n, err := l.w.Write(p)
+		l.remaining -= int64(n)
+		return n, err
+	}
+
+	l.exceeded = true
+	n, err := l.w.Write(p[:l.remaining])
+	l.remaining -= int64(n)
+	if err != nil {
+		return n, err
+	}
+	return n, errDiffTooLarge
+}
+
+// GetPullRequestDiff returns a reader streaming the diff between the merge base and the head of the pull request.
+// If the diff is larger than maxBytes it is truncated to maxBytes and true is returned, maxBytes <= 0 means no limit.
+// The returned reader has to be closed by the caller.
+func GetPullRequestDiff(pr *models.PullRequest, maxBytes int) (io.ReadCloser, bool, error) {
+	return getPullRequestDiffOrPatch(pr, maxBytes, false)
+}
+
+// GetPullRequestPatch is like GetPullRequestDiff, but returns the format-patch data of the pull request.
+func GetPullRequestPatch(pr *models.PullRequest, maxBytes int) (io.ReadCloser, bool, error) {
+	return getPullRequestDiffOrPatch(pr, maxBytes, true)
+}
+
+func getPullRequestDiffOrPatch(pr *models.PullRequest, maxBytes int, patch bool) (io.ReadCloser, bool, error) {
+	if len(pr.MergeBase) == 0 {
+		return nil, false, fmt.Errorf("pull request %d has no merge base", pr.ID)
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return nil, false, fmt.Errorf("LoadBaseRepo: %v", err)
+	}
+	repoPath := pr.BaseRepo.RepoPath()
+
+	newCommand := func() *git.Command {
+		if patch {
+			return git.NewCommand("format-patch", "--binary", "--stdout", pr.MergeBase+"..."+pr.GetGitRefName())
+		}
+		return git.NewCommand("diff", "-p", "--binary", pr.MergeBase, pr.GetGitRefName())
+	}
+
+	// Measure the diff first, so that whether it gets truncated is known before it is streamed
+	truncated := false
+	if maxBytes > 0 {
+		counter := &limitedWriter{w: ioutil.Discard, remaining: int64(maxBytes)}
+		var errbuf strings.Builder
+		if err := newCommand().RunInDirPipeline(repoPath, counter, &errbuf); err != nil && !counter.exceeded {
+			return nil, false, fmt.Errorf("git diff [%s]: %v\n%s", repoPath, err, errbuf.String())
+		}
+		truncated = counter.exceeded
+	}
+
+	reader, writer := io.Pipe()
+	go func() {
+		var w io.Writer = writer
+		if truncated {
+			w = &limitedWriter{w: writer, remaining: int64(maxBytes)}
+		}
+		var errbuf strings.Builder
+		err := newCommand().RunInDirPipeline(repoPath, w, &errbuf)
+		if err != nil && truncated && w.(*limitedWriter).exceeded {
+			err = nil
+		} else if err != nil {
+			err = fmt.Errorf("git diff [%s]: %v\n%s", repoPath, err, errbuf.String())
+		}
+		_ = writer.CloseWithError(err)
+	}()
+
+	return reader, truncated, nil
+}
+
 var patchErrorSuffices = []string{
 	": already exists in index",
 	": patch does not apply",
@@ -97,6 +182,11 @@ func TestPatch(pr *models.PullRequest) error {
 		}
 	}
 	pr.MergeBase = strings.TrimSpace(pr.MergeBase)
+
+	if err := checkPullFilesProtection(pr, tmpBasePath, "tracking"); err != nil {
+		return err
+	}
+
 	tmpPatchFile, err := ioutil.TempFile("", "patch")
 	if err != nil {
 		log.Error("Unable to create temporary patch file! Error: %v", err)
@@ -123,6 +213,7 @@ func TestPatch(pr *models.PullRequest) error {
 		log.Debug("PullRequest[%d]: Patch is empty - ignoring", pr.ID)
 		pr.Status = models.PullRequestStatusMergeable
 		pr.ConflictedFiles = []string{}
+		pr.BinaryConflictedFiles = []string{}
 		return nil
 	}
 
@@ -147,6 +238,7 @@ func TestPatch(pr *models.PullRequest) error {
 	}
 	args = append(args, patchPath)
 	pr.ConflictedFiles = make([]string, 0, 5)
+	pr.BinaryConflictedFiles = []string{}
 
 	stderrReader, stderrWriter, err := os.Pipe()
 	if err != nil {
@@ -206,7 +298,7 @@ func TestPatch(pr *models.PullRequest) error {
 		if conflict {
 			pr.Status = models.PullRequestStatusConflict
 			log.Trace("Found %d files conflicted: %v", len(pr.ConflictedFiles), pr.ConflictedFiles)
-			return nil
+			return pr.ClassifyConflictedFiles(tmpBasePath, "base", "tracking")
 		}
 		return fmt.Errorf("git apply --check: %v", err)
 	}
@@ -214,3 +306,104 @@ func TestPatch(pr *models.PullRequest) error {
 
 	return nil
 }
+
+// TestPatchAgainst checks whether the pull request would merge cleanly into candidateBase,
+// a branch of its base repository, and returns the conflicting files if it would not.
+// The stored base branch, status and conflicted files of pr are left untouched,
+// and neither the base repository nor its refs/pull are written to.
+func TestPatchAgainst(pr *models.PullRequest, candidateBase string) (conflicts []string, err error) {
+	// TestPatch records its results in the pull request and createTemporaryRepo fetches
+	// the base branch of it, so run them on a copy
+	candidatePR := *pr
+	candidatePR.BaseBranch = candidateBase
+	candidatePR.ProtectedBranch = nil
+
+	if err := TestPatch(&candidatePR); err != nil {
+		return nil, err
+	}
+	if candidatePR.Status != models.PullRequestStatusConflict {
+		return []string{}, nil
+	}
+	return candidatePR.ConflictedFiles, nil
+}
+
+// checkPullFilesProtection records the files changed by the pull request which match
+// the protected file patterns of the base branch in pr.ChangedProtectedFiles.
+// headRef is the head of the pull request in the repository at repoPath.
+func checkPullFilesProtection(pr *models.PullRequest, repoPath, headRef string) error {
+	pr.ChangedProtectedFiles = nil
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return fmt.Errorf("LoadProtectedBranch: %v", err)
+	}
+	if pr.ProtectedBranch == nil || len(pr.ProtectedBranch.GetProtectedFilePatterns()) == 0 {
+		return nil
+	}
+
+	stdout, err := git.NewCommand("diff", "--name-only", "-z", pr.MergeBase, headRef).RunInDir(repoPath)
+	if err != nil {
+		return fmt.Errorf("git diff --name-only [%s]: %v", repoPath, err)
+	}
+	pr.ChangedProtectedFiles = pr.ProtectedBranch.MatchProtectedFiles(strings.Split(strings.TrimRight(stdout, "\x00"), "\x00"))
+	return nil
+}
+
+// CanRebaseOnto checks whether the commits of the pull request can be rebased cleanly onto
+// the given branch of the base repository, as needed by the rebase merge styles. If they can't,
+// the IDs of the commits which could not be applied are returned as well.
+func CanRebaseOnto(pr *models.PullRequest, baseBranch string) (bool, []string, error) {
+	// createTemporaryRepo fetches the base branch of the pull request
+	rebasePR := *pr
+	rebasePR.BaseBranch = baseBranch
+
+	tmpBasePath, err := createTemporaryRepo(&rebasePR)
+	if err != nil {
+		log.Error("CreateTemporaryPath: %v", err)
+		return false, nil, err
+	}
+	defer func() {
+		if err := models.RemoveTemporaryPath(tmpBasePath); err != nil {
+			log.Error("CanRebaseOnto: RemoveTemporaryPath: %s", err)
+		}
+	}()
+
+	sig := rebasePR.HeadRepo.MustOwner().NewGitSig()
+	env := append(os.Environ(),
+		"GIT_AUTHOR_NAME="+sig.Name,
+		"GIT_AUTHOR_EMAIL="+sig.Email,
+		"GIT_COMMITTER_NAME="+sig.Name,
+		"GIT_COMMITTER_EMAIL="+sig.Email,
+	)
+
+	var outbuf, errbuf strings.Builder
+	if err := git.NewCommand("checkout", "-b", "staging", "tracking").RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf); err != nil {
+		return false, nil, fmt.Errorf("git checkout tracking [%s]: %v\n%s\n%s", tmpBasePath, err, outbuf.String(), errbuf.String())
+	}
+	outbuf.Reset()
+	errbuf.Reset()
+
+	// Rebase will stop at every commit that does not apply and leave its ID in .git/REBASE_HEAD,
+	// skip these commits to collect all of them.
+	var conflictedCommits []string
+	cmd := git.NewCommand("rebase", "base")
+	for {
+		err := cmd.RunInDirTimeoutEnvPipeline(env, -1, tmpBasePath, &outbuf, &errbuf)
+		if err == nil {
+			break
+		}
+		commitSha, readErr := ioutil.ReadFile(filepath.Join(tmpBasePath, ".git", "REBASE_HEAD"))
+		if readErr != nil {
+			return false, nil, fmt.Errorf("git rebase [%s]: %v\n%s\n%s", tmpBasePath, err, outbuf.String(), errbuf.String())
+		}
+		sha := strings.TrimSpace(string(commitSha))
+		if len(conflictedCommits) > 0 && conflictedCommits[len(conflictedCommits)-1] == sha {
+			// Skipping the commit failed as well
+			return false, nil, fmt.Errorf("git rebase --skip [%s]: %v\n%s\n%s", tmpBasePath, err, outbuf.String(), errbuf.String())
+		}
+		conflictedCommits = append(conflictedCommits, sha)
+		outbuf.Reset()
+		errbuf.Reset()
+		cmd = git.NewCommand("rebase", "--skip")
+	}
+
+	return len(conflictedCommits) == 0, conflictedCommits, nil
+}
@@ -0,0 +1,199 @@
+// Copyright 2019 The Gitea Authors.
+// All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pushReadmeChange pushes a commit changing README.md to content on a new branch of repo1
+func pushReadmeChange(t *testing.T, branch, content string) string {
+	return pushReadmeChangeToRef(t, git.BranchPrefix+branch, content)
+}
+
+func pushReadmeChangeToRef(t *testing.T, ref, content string) string {
+	return pushFilesChangeToRef(t, ref, map[string]string{"README.md": content})
+}
+
+// pushFilesChangeToRef pushes a commit writing files, names mapped to their contents, on top of master of repo1 to ref
+func pushFilesChangeToRef(t *testing.T, ref string, files map[string]string) string {
+	tmpDir, err := ioutil.TempDir("", "rebase")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	assert.NoError(t, git.Clone(models.RepoPath("user2", "repo1"), tmpDir, git.CloneRepoOptions{}))
+	for name, content := range files {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, name), []byte(content), 0644))
+	}
+	_, err = git.NewCommand("add", "--all").RunInDir(tmpDir)
+	assert.NoError(t, err)
+
+	_, err = git.NewCommand("commit", "-a", "-m", "Change README.md on "+ref).RunInDirWithEnv(tmpDir, models.TestGitEnv(nil, nil))
+	assert.NoError(t, err)
+	_, err = git.NewCommand("push", "origin", "HEAD:"+ref).RunInDir(tmpDir)
+	assert.NoError(t, err)
+
+	sha, err := git.NewCommand("rev-parse", "HEAD").RunInDir(tmpDir)
+	assert.NoError(t, err)
+	return strings.TrimSpace(sha)
+}
+
+func TestCanRebaseOnto(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	headSha := pushReadmeChange(t, "rebase-head", "# repo1\n\nchanged on head\n")
+	pushReadmeChange(t, "rebase-base", "# repo1\n\nchanged on base\n")
+
+	pr := &models.PullRequest{
+		HeadRepoID: 1,
+		BaseRepoID: 1,
+		HeadBranch: "rebase-head",
+		BaseBranch: "master",
+	}
+
+	ok, commits, err := CanRebaseOnto(pr, "master")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Empty(t, commits)
+
+	ok, commits, err = CanRebaseOnto(pr, "rebase-base")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, []string{headSha}, commits)
+	assert.Equal(t, "master", pr.BaseBranch)
+}
+
+func TestGetPullRequestDiff(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pushReadmeChangeToRef(t, "refs/pull/99/head", "# repo1\n\nchanged on head\n")
+	pr := &models.PullRequest{
+		ID:         99,
+		Index:      99,
+		BaseRepoID: 1,
+		MergeBase:  "65f1bf27bc3bf70f64657658635e66094edbcb4d",
+	}
+
+	reader, truncated, err := GetPullRequestDiff(pr, 0)
+	assert.NoError(t, err)
+	assert.False(t, truncated)
+	diff, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Contains(t, string(diff), "+changed on head")
+
+	reader, truncated, err = GetPullRequestDiff(pr, len(diff))
+	assert.NoError(t, err)
+	assert.False(t, truncated)
+	assert.NoError(t, reader.Close())
+
+	reader, truncated, err = GetPullRequestDiff(pr, 20)
+	assert.NoError(t, err)
+	assert.True(t, truncated)
+	truncatedDiff, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Equal(t, diff[:20], truncatedDiff)
+
+	reader, truncated, err = GetPullRequestPatch(pr, 0)
+	assert.NoError(t, err)
+	assert.False(t, truncated)
+	patch, err := ioutil.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.NoError(t, reader.Close())
+	assert.Contains(t, string(patch), "Subject: [PATCH] Change README.md on refs/pull/99/head")
+}
+
+func TestCheckPullFilesProtection(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pushReadmeChange(t, "protected-files", "# repo1\n\nchanged\n")
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.HeadBranch = "protected-files"
+	assert.NoError(t, pr.GetBaseRepo())
+
+	assert.NoError(t, TestPatch(pr))
+	assert.Empty(t, pr.ChangedProtectedFiles)
+
+	assert.NoError(t, models.UpdateProtectBranch(pr.BaseRepo, &models.ProtectedBranch{
+		RepoID:                pr.BaseRepoID,
+		BranchName:            pr.BaseBranch,
+		ProtectedFilePatterns: "go.mod;*.md",
+	}, models.WhitelistOptions{}))
+	assert.NoError(t, TestPatch(pr))
+	assert.Equal(t, []string{"README.md"}, pr.ChangedProtectedFiles)
+}
+
+func TestTestPatchAgainst(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pushReadmeChange(t, "candidate-head", "# repo1\n\nchanged on head\n")
+	pushReadmeChange(t, "candidate-release", "# repo1\n\nchanged on release\n")
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.HeadBranch = "candidate-head"
+	status := pr.Status
+
+	conflicts, err := TestPatchAgainst(pr, "master")
+	assert.NoError(t, err)
+	assert.Empty(t, conflicts)
+
+	conflicts, err = TestPatchAgainst(pr, "candidate-release")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"README.md"}, conflicts)
+
+	_, err = TestPatchAgainst(pr, "does-not-exist")
+	assert.Error(t, err)
+
+	assert.Equal(t, "master", pr.BaseBranch)
+	assert.Equal(t, status, pr.Status)
+	assert.Empty(t, pr.ConflictedFiles)
+}
+
+func TestTestPatchBinaryConflicts(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pushFilesChangeToRef(t, git.BranchPrefix+"binary-head", map[string]string{
+		"README.md":  "# repo1\n\nchanged on head\n",
+		"image.bin":  "\x00\x01 head",
+		"notes.txt":  "added on head\n",
+		"tools.bin":  "\x00\x01 only on head",
+		"common.txt": "same on both\n",
+	})
+	pushFilesChangeToRef(t, git.BranchPrefix+"binary-base", map[string]string{
+		"README.md":  "# repo1\n\nchanged on base\n",
+		"image.bin":  "\x00\x01 base",
+		"notes.txt":  "added on base\n",
+		"common.txt": "same on both\n",
+	})
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.HeadBranch = "binary-head"
+	pr.BaseBranch = "binary-base"
+
+	assert.NoError(t, TestPatch(pr))
+	assert.Equal(t, models.PullRequestStatusConflict, pr.Status)
+	assert.Contains(t, pr.ConflictedFiles, "image.bin")
+	assert.Equal(t, []string{"image.bin"}, pr.BinaryConflictedFiles)
+
+	types, err := pr.GetConflictedFileTypes()
+	assert.NoError(t, err)
+	assert.True(t, types["image.bin"])
+	for _, name := range pr.ConflictedFiles {
+		if name != "image.bin" {
+			assert.False(t, types[name], name)
+		}
+	}
+}
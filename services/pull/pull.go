@@ -15,6 +15,7 @@ import (
 	"code.gitea.io/gitea/modules/graceful"
 	"code.gitea.io/gitea/modules/log"
 	"code.gitea.io/gitea/modules/notification"
+	"code.gitea.io/gitea/modules/timeutil"
 	issue_service "code.gitea.io/gitea/services/issue"
 )
 
@@ -114,7 +115,7 @@ func ChangeTargetBranch(pr *models.PullRequest, doer *models.User, targetBranch
 	if pr.Status == models.PullRequestStatusChecking {
 		pr.Status = models.PullRequestStatusMergeable
 	}
-	if err := pr.UpdateCols("status, conflicted_files, base_branch"); err != nil {
+	if err := pr.UpdateCols("status, conflicted_files, binary_conflicted_files, changed_protected_files, base_branch"); err != nil {
 		return err
 	}
 
@@ -134,6 +135,121 @@ func ChangeTargetBranch(pr *models.PullRequest, doer *models.User, targetBranch
 	return nil
 }
 
+// SetPullRequestReadyForReview marks a work in progress pull request as ready for review.
+// Only the poster of the pull request and users with write access to the base repository are allowed to.
+// The approvers whitelisted on a protected base branch are requested to review the pull request.
+func SetPullRequestReadyForReview(pr *models.PullRequest, doer *models.User) error {
+	if err := pr.LoadIssue(); err != nil {
+		return fmt.Errorf("LoadIssue: %v", err)
+	}
+	if err := pr.GetBaseRepo(); err != nil {
+		return fmt.Errorf("GetBaseRepo: %v", err)
+	}
+
+	if doer.ID != pr.Issue.PosterID {
+		perm, err := models.GetUserRepoPermission(pr.BaseRepo, doer)
+		if err != nil {
+			return fmt.Errorf("GetUserRepoPermission: %v", err)
+		}
+		if !perm.CanWrite(models.UnitTypeCode) {
+			return models.ErrUserDoesNotHaveAccessToRepo{
+				UserID:   doer.ID,
+				RepoName: pr.BaseRepo.Name,
+			}
+		}
+	}
+
+	if pr.Issue.IsClosed {
+		return models.ErrIssueIsClosed{
+			ID:     pr.Issue.ID,
+			RepoID: pr.Issue.RepoID,
+			Index:  pr.Issue.Index,
+		}
+	}
+	if !pr.IsWorkInProgress() {
+		return nil
+	}
+
+	if err := pr.SetReadyForReview(doer); err != nil {
+		return err
+	}
+
+	AddToTaskQueue(pr)
+
+	if err := requestDefaultReviewers(pr, doer); err != nil {
+		log.Error("requestDefaultReviewers[%d]: %v", pr.ID, err)
+	}
+
+	notification.NotifyPullRequestReadyForReview(doer, pr)
+
+	return nil
+}
+
+// SetMergeHold puts the pull request on hold so it can not be merged until the hold is cleared,
+// e.g. by a CI bot while it is running. Only users with write access to the base repository are allowed to.
+func SetMergeHold(pr *models.PullRequest, doer *models.User, reason string) error {
+	if err := checkCanChangeMergeHold(pr, doer); err != nil {
+		return err
+	}
+	return pr.SetMergeHold(doer, reason)
+}
+
+// ClearMergeHold releases the hold of the pull request set by SetMergeHold.
+func ClearMergeHold(pr *models.PullRequest, doer *models.User) error {
+	if err := checkCanChangeMergeHold(pr, doer); err != nil {
+		return err
+	}
+	if !pr.IsMergeOnHold {
+		return nil
+	}
+	return pr.ClearMergeHold()
+}
+
+func checkCanChangeMergeHold(pr *models.PullRequest, doer *models.User) error {
+	if err := pr.GetBaseRepo(); err != nil {
+		return fmt.Errorf("GetBaseRepo: %v", err)
+	}
+	perm, err := models.GetUserRepoPermission(pr.BaseRepo, doer)
+	if err != nil {
+		return fmt.Errorf("GetUserRepoPermission: %v", err)
+	}
+	if !perm.CanWrite(models.UnitTypeCode) {
+		return models.ErrUserDoesNotHaveAccessToRepo{
+			UserID:   doer.ID,
+			RepoName: pr.BaseRepo.Name,
+		}
+	}
+	return nil
+}
+
+// requestDefaultReviewers requests a review from the users whitelisted to approve
+// pull requests on the protected base branch, except the doer and the poster.
+func requestDefaultReviewers(pr *models.PullRequest, doer *models.User) error {
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return fmt.Errorf("LoadProtectedBranch: %v", err)
+	}
+	if pr.ProtectedBranch == nil || !pr.ProtectedBranch.EnableApprovalsWhitelist {
+		return nil
+	}
+
+	for _, id := range pr.ProtectedBranch.ApprovalsWhitelistUserIDs {
+		if id == doer.ID || id == pr.Issue.PosterID {
+			continue
+		}
+		reviewer, err := models.GetUserByID(id)
+		if err != nil {
+			if models.IsErrUserNotExist(err) {
+				continue
+			}
+			return fmt.Errorf("GetUserByID: %v", err)
+		}
+		if _, err = models.CreateReviewRequest(pr.Issue, reviewer); err != nil {
+			return fmt.Errorf("CreateReviewRequest: %v", err)
+		}
+	}
+	return nil
+}
+
 func checkForInvalidation(requests models.PullRequestList, repoID int64, doer *models.User, branch string) error {
 	repo, err := models.GetRepositoryByID(repoID)
 	if err != nil {
@@ -182,6 +298,12 @@ func AddTestPullRequestTask(doer *models.User, repoID int64, branch string, isSy
 		}
 
 		if isSync {
+			for _, pr := range prs {
+				pr.HeadUpdatedUnix = timeutil.TimeStampNow()
+				if err := pr.UpdateCols("head_updated_unix"); err != nil {
+					log.Error("UpdateCols[%d]: %v", pr.ID, err)
+				}
+			}
 			requests := models.PullRequestList(prs)
 			if err = requests.LoadAttributes(); err != nil {
 				log.Error("PullRequestList.LoadAttributes: %v", err)
@@ -192,6 +314,9 @@ func AddTestPullRequestTask(doer *models.User, repoID int64, branch string, isSy
 			if err == nil {
 				for _, pr := range prs {
 					pr.Issue.PullRequest = pr
+					if err := DismissStaleReviews(pr); err != nil {
+						log.Error("DismissStaleReviews[%d]: %v", pr.ID, err)
+					}
 					notification.NotifyPullRequestSynchronized(doer, pr)
 				}
 			}
@@ -261,3 +386,71 @@ func PushToBaseRepo(pr *models.PullRequest) (err error) {
 
 	return nil
 }
+
+// SyncPullRequestHeadRef force-updates the refs/pull/N/head ref of the base repository
+// to the current commit of the head branch, e.g. when the ref drifted after a manual edit.
+// Nothing is pushed when the ref is already up to date.
+func SyncPullRequestHeadRef(pr *models.PullRequest) error {
+	if pr.HasMerged {
+		return models.ErrPullRequestHasMerged{
+			ID:         pr.ID,
+			IssueID:    pr.IssueID,
+			HeadRepoID: pr.HeadRepoID,
+			BaseRepoID: pr.BaseRepoID,
+			HeadBranch: pr.HeadBranch,
+			BaseBranch: pr.BaseBranch,
+		}
+	}
+	if err := pr.LoadHeadRepo(); err != nil {
+		if models.IsErrRepoNotExist(err) {
+			return models.ErrPullRequestHeadRepoMissing{ID: pr.ID, HeadRepoID: pr.HeadRepoID}
+		}
+		return fmt.Errorf("LoadHeadRepo: %v", err)
+	}
+	if err := pr.LoadBaseRepo(); err != nil {
+		return fmt.Errorf("LoadBaseRepo: %v", err)
+	}
+
+	headGitRepo, err := git.OpenRepository(pr.HeadRepo.RepoPath())
+	if err != nil {
+		return fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer headGitRepo.Close()
+
+	headCommitID, err := headGitRepo.GetBranchCommitID(pr.HeadBranch)
+	if err != nil {
+		return fmt.Errorf("GetBranchCommitID: %v", err)
+	}
+
+	baseGitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return fmt.Errorf("OpenRepository: %v", err)
+	}
+	defer baseGitRepo.Close()
+
+	// A missing ref is recreated by the push as well
+	refCommitID, err := baseGitRepo.GetRefCommitID(pr.GetGitRefName())
+	if err == nil && refCommitID == headCommitID {
+		return nil
+	}
+
+	log.Trace("SyncPullRequestHeadRef[%d]: updating %s from %s to %s", pr.ID, pr.GetGitRefName(), refCommitID, headCommitID)
+	return PushToBaseRepo(pr)
+}
+
+// SyncAllPullRequestHeadRefs resyncs the head refs of all open pull requests into the repository.
+// Failures are logged and counted so that one broken pull request does not stop the repair.
+func SyncAllPullRequestHeadRefs(repoID int64) (failed int, err error) {
+	prs, err := models.GetUnmergedPullRequestsByBaseRepo(repoID)
+	if err != nil {
+		return 0, fmt.Errorf("GetUnmergedPullRequestsByBaseRepo: %v", err)
+	}
+
+	for _, pr := range prs {
+		if err := SyncPullRequestHeadRef(pr); err != nil {
+			log.Error("SyncPullRequestHeadRef[%d]: %v", pr.ID, err)
+			failed++
+		}
+	}
+	return failed, nil
+}
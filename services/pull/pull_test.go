@@ -5,4 +5,43 @@
 
 package pull
 
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
 // TODO TestPullRequest_PushToBaseRepo
+
+func TestSyncPullRequestHeadRef(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	headSha := pushReadmeChange(t, "sync-head", "# repo1\n\nchanged on head\n")
+	staleSha := pushReadmeChangeToRef(t, "refs/pull/3/head", "# repo1\n\nstale ref\n")
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	pr.HeadBranch = "sync-head"
+
+	gitRepo, err := git.OpenRepository(models.RepoPath("user2", "repo1"))
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+
+	refSha, err := gitRepo.GetRefCommitID(pr.GetGitRefName())
+	assert.NoError(t, err)
+	assert.Equal(t, staleSha, refSha)
+
+	assert.NoError(t, SyncPullRequestHeadRef(pr))
+
+	refSha, err = gitRepo.GetRefCommitID(pr.GetGitRefName())
+	assert.NoError(t, err)
+	assert.Equal(t, headSha, refSha)
+
+	// syncing an up to date ref is a no-op
+	assert.NoError(t, SyncPullRequestHeadRef(pr))
+
+	pr = models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 1}).(*models.PullRequest)
+	assert.True(t, models.IsErrPullRequestHasMerged(SyncPullRequestHeadRef(pr)))
+}
@@ -0,0 +1,68 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"fmt"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+	"code.gitea.io/gitea/modules/notification"
+)
+
+// SkippedPullRequest is a pull request which was not retargeted
+type SkippedPullRequest struct {
+	PullRequest *models.PullRequest
+	Reason      string
+}
+
+// RetargetBranchPullRequests changes the target branch of all open pull requests of the repository
+// from oldBase to newBase, e.g. after oldBase was deleted. Pull requests which would become a duplicate
+// of an existing pull request or whose head branch is the new base branch are skipped.
+// It returns the number of retargeted pull requests and the skipped ones.
+func RetargetBranchPullRequests(repoID int64, oldBase, newBase string, doer *models.User) (int, []*SkippedPullRequest, error) {
+	repo, err := models.GetRepositoryByID(repoID)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !git.IsBranchExist(repo.RepoPath(), newBase) {
+		return 0, nil, git.ErrBranchNotExist{Name: newBase}
+	}
+
+	prs, err := models.GetUnmergedPullRequestsByBaseInfo(repoID, oldBase)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var retargeted int
+	skipped := make([]*SkippedPullRequest, 0, len(prs))
+	for _, pr := range prs {
+		pr.BaseRepo = repo
+		if err = pr.LoadIssue(); err != nil {
+			return retargeted, skipped, err
+		}
+		pr.Issue.Repo = repo
+
+		if err = ChangeTargetBranch(pr, doer, newBase); err != nil {
+			if existing, ok := err.(models.ErrPullRequestAlreadyExists); ok {
+				skipped = append(skipped, &SkippedPullRequest{
+					PullRequest: pr,
+					Reason:      fmt.Sprintf("pull request #%d already exists for the branches", existing.IssueID),
+				})
+				continue
+			} else if models.IsErrBranchesEqual(err) {
+				skipped = append(skipped, &SkippedPullRequest{
+					PullRequest: pr,
+					Reason:      "head branch is equal to the new base branch",
+				})
+				continue
+			}
+			return retargeted, skipped, fmt.Errorf("ChangeTargetBranch[%d]: %v", pr.ID, err)
+		}
+		notification.NotifyPullRequestChangeTargetBranch(doer, pr, oldBase)
+		retargeted++
+	}
+	return retargeted, skipped, nil
+}
@@ -0,0 +1,51 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetargetBranchPullRequests(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	head := pushReadmeChange(t, "branch2", "# branch2")
+
+	// the merged pull request of issue 2 targets master too, but is left alone
+	retargeted, skipped, err := RetargetBranchPullRequests(1, "master", "develop", doer)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, retargeted)
+	assert.Len(t, skipped, 0)
+	assert.Equal(t, "develop", models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest).BaseBranch)
+	assert.Equal(t, "master", models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 1}).(*models.PullRequest).BaseBranch)
+	models.AssertExistsAndLoadBean(t, &models.Comment{
+		IssueID: 3,
+		Type:    models.CommentTypeChangeTargetBranch,
+		OldRef:  "master",
+		NewRef:  "develop",
+	})
+
+	// the new base branch contains the head already
+	repoPath := models.RepoPath("user2", "repo1")
+	base := models.CreateTestCommit(t, repoPath, models.TestCommitOptions{Parents: []string{head}, Message: "on top of branch2"})
+	models.UpdateTestRef(t, repoPath, git.BranchPrefix+"feature/1", base)
+	retargeted, skipped, err = RetargetBranchPullRequests(1, "develop", "feature/1", doer)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, retargeted)
+	if assert.Len(t, skipped, 1) {
+		assert.EqualValues(t, 2, skipped[0].PullRequest.ID)
+		assert.Equal(t, "head branch is equal to the new base branch", skipped[0].Reason)
+	}
+	assert.Equal(t, "develop", models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest).BaseBranch)
+
+	_, _, err = RetargetBranchPullRequests(1, "develop", "does-not-exist", doer)
+	assert.True(t, git.IsErrBranchNotExist(err))
+}
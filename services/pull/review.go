@@ -158,18 +158,121 @@ func createCodeComment(doer *models.User, repo *models.Repository, issue *models
 	})
 }
 
+// DismissStaleReviews marks the approvals of the pull request as stale if
+// the protected base branch requires dismissing approvals on new commits.
+// Otherwise only the approvals given for a head which has since been rewritten,
+// e.g. by a force-push, are marked as stale.
+func DismissStaleReviews(pr *models.PullRequest) error {
+	if err := pr.LoadProtectedBranch(); err != nil {
+		return err
+	}
+	if pr.ProtectedBranch != nil && pr.ProtectedBranch.DismissStaleApprovals {
+		return models.MarkReviewsAsStale(pr.IssueID)
+	}
+
+	approvals, err := models.FindReviews(models.FindReviewOptions{
+		Type:    models.ReviewTypeApprove,
+		IssueID: pr.IssueID,
+	})
+	if err != nil {
+		return err
+	}
+	for _, approval := range approvals {
+		// Approvals submitted before the commit was recorded can't be checked
+		if approval.Stale || len(approval.CommitID) == 0 {
+			continue
+		}
+		changed, err := pr.HeadChangedSince(approval.CommitID)
+		if err != nil {
+			return err
+		}
+		if changed {
+			if err := models.MarkReviewAsStale(approval.ID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // SubmitReview creates a review out of the existing pending review or creates a new one if no pending review exist
 func SubmitReview(doer *models.User, issue *models.Issue, reviewType models.ReviewType, content string) (*models.Review, *models.Comment, error) {
-	review, comm, err := models.SubmitReview(doer, issue, reviewType, content)
+	pr, err := issue.GetPullRequest()
 	if err != nil {
 		return nil, nil, err
 	}
 
-	pr, err := issue.GetPullRequest()
+	commitID, err := getHeadCommitID(pr)
 	if err != nil {
 		return nil, nil, err
 	}
+
+	review, comm, err := models.SubmitReview(doer, issue, reviewType, content, commitID)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	notification.NotifyPullRequestReview(pr, review, comm)
 
 	return review, comm, nil
 }
+
+// getHeadCommitID returns the commit the head of the pull request currently points to,
+// or an empty string if the head ref of the pull request does not exist (yet)
+func getHeadCommitID(pr *models.PullRequest) (string, error) {
+	if err := pr.LoadBaseRepo(); err != nil {
+		return "", err
+	}
+	if !git.IsReferenceExist(pr.BaseRepo.RepoPath(), pr.GetGitRefName()) {
+		return "", nil
+	}
+	gitRepo, err := git.OpenRepository(pr.BaseRepo.RepoPath())
+	if err != nil {
+		return "", err
+	}
+	defer gitRepo.Close()
+
+	return gitRepo.GetRefCommitID(pr.GetGitRefName())
+}
+
+// SuggestReviewer returns the user eligible to review the pull request with the fewest pending review
+// requests in its base repository, e.g. to request a review from automatically. The poster and the users
+// whose review of the pull request was requested already are not suggested, ties are broken by the lowest
+// user id. It returns nil if there is no one to suggest.
+func SuggestReviewer(pr *models.PullRequest) (*models.User, error) {
+	if err := pr.LoadIssue(); err != nil {
+		return nil, fmt.Errorf("LoadIssue: %v", err)
+	}
+
+	workload, err := models.GetReviewerWorkload(pr.BaseRepoID)
+	if err != nil {
+		return nil, fmt.Errorf("GetReviewerWorkload: %v", err)
+	}
+	requests, err := models.FindReviews(models.FindReviewOptions{
+		Type:    models.ReviewTypeRequest,
+		IssueID: pr.IssueID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("FindReviews: %v", err)
+	}
+
+	excluded := map[int64]bool{pr.Issue.PosterID: true}
+	for _, request := range requests {
+		excluded[request.ReviewerID] = true
+	}
+
+	var reviewerID int64
+	var minLoad = -1
+	for id, load := range workload {
+		if excluded[id] {
+			continue
+		}
+		if minLoad < 0 || load < minLoad || (load == minLoad && id < reviewerID) {
+			reviewerID, minLoad = id, load
+		}
+	}
+	if minLoad < 0 {
+		return nil, nil
+	}
+	return models.GetUserByID(reviewerID)
+}
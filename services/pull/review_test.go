@@ -0,0 +1,108 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestReviewer(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.LoadIssue())
+	suggest := func() int64 {
+		reviewer, err := SuggestReviewer(pr)
+		assert.NoError(t, err)
+		if reviewer == nil {
+			return 0
+		}
+		return reviewer.ID
+	}
+
+	// the owner is the only one with write access
+	assert.EqualValues(t, 2, suggest())
+
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+	for _, id := range []int64{4, 5} {
+		assert.NoError(t, repo.AddCollaborator(models.AssertExistsAndLoadBean(t, &models.User{ID: id}).(*models.User)))
+	}
+	assert.EqualValues(t, 2, suggest())
+
+	// users whose review was requested already are not suggested again
+	for _, id := range []int64{2, 4} {
+		_, err := models.CreateReviewRequest(pr.Issue, models.AssertExistsAndLoadBean(t, &models.User{ID: id}).(*models.User))
+		assert.NoError(t, err)
+	}
+	assert.EqualValues(t, 5, suggest())
+
+	_, err := models.CreateReviewRequest(pr.Issue, models.AssertExistsAndLoadBean(t, &models.User{ID: 5}).(*models.User))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, suggest())
+}
+
+func TestDismissStaleReviews(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	assert.NoError(t, pr.LoadIssue())
+	assert.NoError(t, pr.LoadHeadRepo())
+	pr.HeadBranch = "develop"
+	repoPath := pr.HeadRepo.RepoPath()
+
+	gitRepo, err := git.OpenRepository(repoPath)
+	assert.NoError(t, err)
+	defer gitRepo.Close()
+	headCommitID, err := gitRepo.GetBranchCommitID(pr.HeadBranch)
+	assert.NoError(t, err)
+
+	reviewer := models.AssertExistsAndLoadBean(t, &models.User{ID: 1}).(*models.User)
+	_, err = models.CreateReview(models.CreateReviewOptions{
+		Type:     models.ReviewTypeApprove,
+		Issue:    pr.Issue,
+		Reviewer: reviewer,
+		Official: true,
+		CommitID: headCommitID,
+	})
+	assert.NoError(t, err)
+	approvals := func() int64 {
+		assert.NoError(t, DismissStaleReviews(pr))
+		return (&models.ProtectedBranch{}).GetGrantedApprovalsCount(pr)
+	}
+	assert.EqualValues(t, 1, approvals())
+
+	// appending a commit keeps the approval
+	appended := models.CreateTestCommit(t, repoPath, models.TestCommitOptions{
+		Parents: []string{headCommitID},
+		Message: "appended",
+	})
+	models.UpdateTestRef(t, repoPath, git.BranchPrefix+pr.HeadBranch, appended)
+	assert.EqualValues(t, 1, approvals())
+
+	// force-pushing a rewritten head dismisses it
+	rewritten := models.CreateTestCommit(t, repoPath, models.TestCommitOptions{
+		Tree:    headCommitID + "^{tree}",
+		Message: "rewritten",
+	})
+	models.UpdateTestRef(t, repoPath, git.BranchPrefix+pr.HeadBranch, rewritten)
+	assert.EqualValues(t, 0, approvals())
+}
+
+func TestSubmitReviewWithoutHeadRef(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	// the fixture repository has no refs/pull/N/head
+	issue := models.AssertExistsAndLoadBean(t, &models.Issue{ID: 3}).(*models.Issue)
+	assert.NoError(t, issue.LoadRepo())
+	doer := models.AssertExistsAndLoadBean(t, &models.User{ID: 1}).(*models.User)
+	review, _, err := SubmitReview(doer, issue, models.ReviewTypeApprove, "lgtm")
+	assert.NoError(t, err)
+	assert.Empty(t, review.CommitID)
+}
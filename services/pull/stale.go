@@ -0,0 +1,111 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/log"
+	"code.gitea.io/gitea/modules/setting"
+	"code.gitea.io/gitea/modules/timeutil"
+	comment_service "code.gitea.io/gitea/services/comments"
+	issue_service "code.gitea.io/gitea/services/issue"
+
+	"github.com/unknwon/i18n"
+)
+
+// CloseStalePullRequests closes the open pull requests of the repository whose issue has not
+// been updated within inactiveFor, posting comment on each of them once it is closed. Nothing is closed
+// unless the repository has opted in, and pull requests labeled
+// models.StalePullRequestKeepOpenLabel are kept open. It returns the number of closed pull requests.
+func CloseStalePullRequests(repoID int64, inactiveFor time.Duration, comment string) (int, error) {
+	repo, err := models.GetRepositoryByID(repoID)
+	if err != nil {
+		return 0, err
+	}
+
+	unit, err := repo.GetUnit(models.UnitTypePullRequests)
+	if err != nil {
+		if models.IsErrUnitTypeNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	} else if !unit.PullRequestsConfig().CloseStale {
+		return 0, nil
+	}
+
+	doer, err := getAutomationDoer(repo)
+	if err != nil {
+		return 0, err
+	}
+
+	issues, err := models.GetStalePullRequestIssues(repoID, timeutil.TimeStamp(time.Now().Add(-inactiveFor).Unix()))
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	for _, issue := range issues {
+		issue.Repo = repo
+		if err := issue_service.ChangeStatus(issue, doer, true); err != nil {
+			if models.IsErrDependenciesLeft(err) {
+				continue
+			}
+			return closed, fmt.Errorf("ChangeStatus[%d]: %v", issue.ID, err)
+		}
+		closed++
+		if len(comment) > 0 {
+			if _, err := comment_service.CreateIssueComment(doer, repo, issue, comment, nil); err != nil {
+				return closed, fmt.Errorf("CreateIssueComment[%d]: %v", issue.ID, err)
+			}
+		}
+	}
+	return closed, nil
+}
+
+// CloseAllStalePullRequests closes the stale pull requests of all repositories which have opted in
+func CloseAllStalePullRequests(ctx context.Context) {
+	log.Trace("Doing: CloseStalePullRequests")
+
+	repoIDs, err := models.GetRepoIDsClosingStalePullRequests()
+	if err != nil {
+		log.Error("GetRepoIDsClosingStalePullRequests: %v", err)
+		return
+	}
+
+	olderThan := setting.Cron.CloseStalePullRequests.OlderThan
+	comment := i18n.Tr(setting.Langs[0], "repo.pulls.closed_as_stale", int(olderThan.Hours()/24), models.StalePullRequestKeepOpenLabel)
+	for _, repoID := range repoIDs {
+		select {
+		case <-ctx.Done():
+			log.Warn("CloseAllStalePullRequests: Aborted due to shutdown")
+			return
+		default:
+		}
+
+		if closed, err := CloseStalePullRequests(repoID, olderThan, comment); err != nil {
+			log.Error("CloseStalePullRequests[%d]: %v", repoID, err)
+		} else if closed > 0 {
+			log.Trace("Closed %d stale pull requests of repository %d", closed, repoID)
+		}
+	}
+	log.Trace("Finished: CloseStalePullRequests")
+}
+
+// getAutomationDoer returns the user changes made automatically to the pull requests of repo are
+// attributed to: the owner of the repository, or the ghost user if the repository is owned by an
+// organization, which can't act on its own.
+func getAutomationDoer(repo *models.Repository) (*models.User, error) {
+	if err := repo.GetOwner(); err != nil {
+		return nil, err
+	}
+	if repo.Owner.IsOrganization() {
+		return models.NewGhostUser(), nil
+	}
+	return repo.Owner, nil
+}
@@ -0,0 +1,65 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"testing"
+	"time"
+
+	"code.gitea.io/gitea/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloseStalePullRequests(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	const comment = "closed as stale"
+	closeStale := func(inactiveFor time.Duration) int {
+		closed, err := CloseStalePullRequests(1, inactiveFor, comment)
+		assert.NoError(t, err)
+		return closed
+	}
+
+	// repositories have to opt in
+	assert.Equal(t, 0, closeStale(time.Hour))
+
+	repo := models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository)
+	unit, err := repo.GetUnit(models.UnitTypePullRequests)
+	assert.NoError(t, err)
+	unit.PullRequestsConfig().CloseStale = true
+	assert.NoError(t, models.UpdateRepositoryUnits(repo, []models.RepoUnit{*unit}))
+
+	// issue 3 was last updated in 2000
+	assert.Equal(t, 0, closeStale(100*365*24*time.Hour))
+
+	// labeling an issue updates it, so the label is attached directly
+	label := &models.Label{RepoID: 1, Name: "Keep Open", Color: "#000000"}
+	assert.NoError(t, models.NewLabel(label))
+	models.AssertSuccessfulInsert(t, &models.IssueLabel{IssueID: 3, LabelID: label.ID})
+	assert.Equal(t, 0, closeStale(time.Hour))
+
+	// the pull request of issue 2 is merged already and left alone
+	label.Name = "needs review"
+	assert.NoError(t, models.UpdateLabel(label))
+	assert.Equal(t, 1, closeStale(time.Hour))
+	assert.True(t, models.AssertExistsAndLoadBean(t, &models.Issue{ID: 3}).(*models.Issue).IsClosed)
+	assert.False(t, models.AssertExistsAndLoadBean(t, &models.Issue{ID: 2}).(*models.Issue).IsClosed)
+	models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: 3, Type: models.CommentTypeComment, Content: comment, PosterID: 2})
+	models.AssertExistsAndLoadBean(t, &models.Comment{IssueID: 3, Type: models.CommentTypeClose, PosterID: 2})
+}
+
+func TestGetAutomationDoer(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	doer, err := getAutomationDoer(models.AssertExistsAndLoadBean(t, &models.Repository{ID: 1}).(*models.Repository))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, doer.ID)
+
+	// organizations can't act on their own
+	doer, err = getAutomationDoer(models.AssertExistsAndLoadBean(t, &models.Repository{ID: 3}).(*models.Repository))
+	assert.NoError(t, err)
+	assert.Equal(t, models.NewGhostUser(), doer)
+}
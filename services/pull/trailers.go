@@ -0,0 +1,144 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+)
+
+// trailerPattern matches a commit message trailer like "Signed-off-by: Name <email>"
+var trailerPattern = regexp.MustCompile(`^([A-Za-z0-9][A-Za-z0-9-]*)\s*:\s*(\S.*)$`)
+
+// parseTrailer returns the token and the value of a single line trailer
+func parseTrailer(trailer string) (token, value string, ok bool) {
+	if strings.ContainsAny(trailer, "\r\n") {
+		return "", "", false
+	}
+	m := trailerPattern.FindStringSubmatch(strings.TrimSpace(trailer))
+	if m == nil {
+		return "", "", false
+	}
+	return m[1], strings.TrimSpace(m[2]), true
+}
+
+// trailerKey returns the key used to detect duplicated trailers, tokens are case insensitive
+func trailerKey(token, value string) string {
+	return strings.ToLower(token) + ":" + value
+}
+
+// getReviewedByTrailers returns a Reviewed-by trailer for each reviewer whose latest review approves the pull request
+func getReviewedByTrailers(pr *models.PullRequest) ([]string, error) {
+	reviews, err := models.GetReviewersByIssueID(pr.IssueID)
+	if err != nil {
+		return nil, err
+	}
+
+	trailers := make([]string, 0, len(reviews))
+	for _, review := range reviews {
+		if review.Type != models.ReviewTypeApprove || review.Stale {
+			continue
+		}
+		trailers = append(trailers, fmt.Sprintf("Reviewed-by: %s <%s>", review.Reviewer.GitName(), review.Reviewer.GetEmail()))
+	}
+	return trailers, nil
+}
+
+// getCoAuthoredByTrailers returns a Co-authored-by trailer for each distinct author of the commits between
+// baseBranch and trackingBranch in tmpBasePath, oldest first, except the author of the squash commit.
+// Author emails are resolved to accounts by their verified email addresses, so authors who committed
+// with several addresses are listed once, with the identity of their account.
+func getCoAuthoredByTrailers(tmpBasePath, baseBranch, trackingBranch string, author *models.User, sig *git.Signature) ([]string, error) {
+	stdout, err := git.NewCommand("log", "--reverse", "--format=%aN%x00%aE", baseBranch+".."+trackingBranch).RunInDir(tmpBasePath)
+	if err != nil {
+		return nil, fmt.Errorf("git log: %v", err)
+	}
+
+	seenEmails := map[string]bool{strings.ToLower(sig.Email): true}
+	seenUsers := make(map[int64]bool)
+	if author != nil {
+		seenUsers[author.ID] = true
+	}
+
+	var trailers []string
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		fields := strings.SplitN(line, "\x00", 2)
+		if len(fields) != 2 || len(fields[1]) == 0 {
+			continue
+		}
+		name, email := fields[0], fields[1]
+		if seenEmails[strings.ToLower(email)] {
+			continue
+		}
+		seenEmails[strings.ToLower(email)] = true
+
+		user, err := models.GetUserByVerifiedEmail(email)
+		if err == nil {
+			if seenUsers[user.ID] {
+				continue
+			}
+			seenUsers[user.ID] = true
+			name, email = user.GitName(), user.GetEmail()
+		} else if !models.IsErrUserNotExist(err) {
+			return nil, err
+		}
+		trailers = append(trailers, fmt.Sprintf("Co-authored-by: %s <%s>", name, email))
+	}
+	return trailers, nil
+}
+
+// addTrailers appends the trailers to the message. They are added to the trailer block
+// if the last paragraph of the message already is one, and separated by an empty line otherwise.
+// Trailers which are already part of the message or given twice are skipped.
+func addTrailers(message string, trailers []string) (string, error) {
+	message = strings.TrimRight(message, " \t\r\n")
+
+	seen := make(map[string]bool)
+	hasTrailerBlock := false
+	if idx := strings.LastIndex(message, "\n\n"); idx >= 0 {
+		hasTrailerBlock = true
+		lines := strings.Split(message[idx+2:], "\n")
+		for _, line := range lines {
+			token, value, ok := parseTrailer(line)
+			if !ok {
+				hasTrailerBlock = false
+				break
+			}
+			seen[trailerKey(token, value)] = true
+		}
+		if !hasTrailerBlock {
+			seen = make(map[string]bool)
+		}
+	}
+
+	added := make([]string, 0, len(trailers))
+	for _, trailer := range trailers {
+		token, value, ok := parseTrailer(trailer)
+		if !ok {
+			return "", models.ErrInvalidMergeTrailer{Trailer: trailer}
+		}
+		key := trailerKey(token, value)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		added = append(added, token+": "+value)
+	}
+
+	if len(added) == 0 {
+		return message, nil
+	}
+	if len(message) == 0 {
+		return strings.Join(added, "\n"), nil
+	}
+	if hasTrailerBlock {
+		return message + "\n" + strings.Join(added, "\n"), nil
+	}
+	return message + "\n\n" + strings.Join(added, "\n"), nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2019 The Gitea Authors. All rights reserved.
+// Use of this source code is governed by a MIT-style
+// license that can be found in the LICENSE file.
+
+package pull
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code.gitea.io/gitea/models"
+	"code.gitea.io/gitea/modules/git"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAddTrailers(t *testing.T) {
+	kases := []struct {
+		message  string
+		trailers []string
+		expected string
+	}{
+		{
+			message:  "Merge branch 'feature'",
+			trailers: nil,
+			expected: "Merge branch 'feature'",
+		},
+		{
+			message:  "Merge branch 'feature'\n",
+			trailers: []string{"Signed-off-by: User One <user1@example.com>"},
+			expected: "Merge branch 'feature'\n\nSigned-off-by: User One <user1@example.com>",
+		},
+		{
+			// a single paragraph is the subject, not a trailer block
+			message:  "Fixes: something",
+			trailers: []string{"Reviewed-by: User Two <user2@example.com>"},
+			expected: "Fixes: something\n\nReviewed-by: User Two <user2@example.com>",
+		},
+		{
+			message:  "Title (#1)\n\nSigned-off-by: User One <user1@example.com>",
+			trailers: []string{"signed-off-by: User One <user1@example.com>", "Reviewed-by:User Two <user2@example.com>", "Reviewed-by: User Two <user2@example.com>"},
+			expected: "Title (#1)\n\nSigned-off-by: User One <user1@example.com>\nReviewed-by: User Two <user2@example.com>",
+		},
+		{
+			message:  "Title (#1)\n\nSome description",
+			trailers: []string{"Reviewed-by: User Two <user2@example.com>"},
+			expected: "Title (#1)\n\nSome description\n\nReviewed-by: User Two <user2@example.com>",
+		},
+	}
+	for _, kase := range kases {
+		message, err := addTrailers(kase.message, kase.trailers)
+		assert.NoError(t, err)
+		assert.Equal(t, kase.expected, message)
+	}
+
+	for _, trailer := range []string{"", "no trailer", "Signed-off-by:", "Signed off by: User", "Signed-off-by: User\nReviewed-by: User"} {
+		_, err := addTrailers("Title", []string{trailer})
+		assert.True(t, models.IsErrInvalidMergeTrailer(err), "trailer %q", trailer)
+	}
+}
+
+func TestGetReviewedByTrailers(t *testing.T) {
+	assert.NoError(t, models.PrepareTestDatabase())
+
+	user4 := models.AssertExistsAndLoadBean(t, &models.User{ID: 4}).(*models.User)
+
+	// the latest review of user 1 is a comment and the one of user 2 rejects the pull request
+	pr := models.AssertExistsAndLoadBean(t, &models.PullRequest{ID: 2}).(*models.PullRequest)
+	trailers, err := getReviewedByTrailers(pr)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Reviewed-by: " + user4.GitName() + " <" + user4.GetEmail() + ">"}, trailers)
+
+	assert.NoError(t, models.MarkReviewAsStale(8))
+	trailers, err = getReviewedByTrailers(pr)
+	assert.NoError(t, err)
+	assert.Len(t, trailers, 0)
+}
+
+func TestGetCoAuthoredByTrailers(t *testing.T) {
+	models.PrepareTestEnv(t)
+
+	user2 := models.AssertExistsAndLoadBean(t, &models.User{ID: 2}).(*models.User)
+	user10 := models.AssertExistsAndLoadBean(t, &models.User{ID: 10}).(*models.User)
+
+	tmpDir, err := ioutil.TempDir("", "co-authors")
+	assert.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+	assert.NoError(t, git.Clone(models.RepoPath("user2", "repo1"), tmpDir, git.CloneRepoOptions{}))
+
+	for i, author := range [][2]string{
+		{"user2", "user2@example.com"},
+		{"Someone Else", "someone@example.com"},
+		{"ten", "user101@example.com"},
+		{"Someone Else", "Someone@example.com"},
+		{"user10", "user10@example.com"},
+	} {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(tmpDir, "README.md"), []byte{byte('a' + i)}, 0644))
+		env := models.TestGitEnv(&git.Signature{Name: author[0], Email: author[1]}, nil)
+		_, err = git.NewCommand("commit", "-a", "-m", "Change README.md").RunInDirWithEnv(tmpDir, env)
+		assert.NoError(t, err)
+	}
+
+	trailers, err := getCoAuthoredByTrailers(tmpDir, "origin/master", "HEAD", user2, user2.NewGitSig())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Co-authored-by: Someone Else <someone@example.com>",
+		"Co-authored-by: " + user10.GitName() + " <" + user10.GetEmail() + ">",
+	}, trailers)
+
+	// the author of the squash commit is excluded by account even if it committed with another address
+	trailers, err = getCoAuthoredByTrailers(tmpDir, "origin/master", "HEAD", user10, user10.NewGitSig())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"Co-authored-by: " + user2.GitName() + " <" + user2.GetEmail() + ">",
+		"Co-authored-by: Someone Else <someone@example.com>",
+	}, trailers)
+}